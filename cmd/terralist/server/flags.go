@@ -81,6 +81,7 @@ const (
 	SamlDisableRequestIDValidationFlag   = "saml-disable-request-id-validation"
 
 	TokenSigningSecretFlag = "token-signing-secret"
+	SigningKeySecretFlag   = "signing-key-secret"
 
 	PreferredEmailDomainFlag = "oauth-preferred-email-domain"
 
@@ -90,6 +91,9 @@ const (
 	ModulesAnonymousReadFlag   = "modules-anonymous-read"
 	ProvidersAnonymousReadFlag = "providers-anonymous-read"
 
+	ModulesProxyDocsMaxSizeFlag   = "modules-proxy-docs-max-size"
+	ProvidersProxyDocsMaxSizeFlag = "providers-proxy-docs-max-size"
+
 	S3EndpointFlag             = "s3-endpoint"
 	S3BucketNameFlag           = "s3-bucket-name"
 	S3BucketRegionFlag         = "s3-bucket-region"
@@ -128,6 +132,18 @@ const (
 	MasterApiKeyFlag = "master-api-key"
 
 	AuthTokenExpirationFlag = "auth-token-expiration"
+
+	RetentionIntervalFlag = "retention-interval"
+
+	UploadMaxSizeFlag             = "upload-max-size"
+	UploadMaxDecompressedSizeFlag = "upload-max-decompressed-size"
+	UploadMaxFilesFlag            = "upload-max-files"
+	UploadMaxPathDepthFlag        = "upload-max-path-depth"
+
+	UpstreamRegistryFlag         = "upstream-registry"
+	UpstreamNamespacesFlag       = "upstream-namespaces"
+	UpstreamDeniedNamespacesFlag = "upstream-denied-namespaces"
+	UpstreamCacheTTLFlag         = "upstream-cache-ttl"
 )
 
 var flags = map[string]cli.Flag{
@@ -353,6 +369,9 @@ var flags = map[string]cli.Flag{
 		Description: "The secret to use when signing authorization tokens.",
 		Required:    true,
 	},
+	SigningKeySecretFlag: &cli.StringFlag{
+		Description: "The secret used to encrypt the provider signing keys managed by the registry. Managed signing keys are disabled if empty.",
+	},
 
 	PreferredEmailDomainFlag: &cli.StringFlag{
 		Description: "Preferred email domain for authentication. When set, the provider selects an email matching this domain if available (e.g. 'company.com'). Applies to GitHub and BitBucket.",
@@ -396,6 +415,16 @@ var flags = map[string]cli.Flag{
 		DefaultValue: false,
 	},
 
+	ModulesProxyDocsMaxSizeFlag: &cli.IntFlag{
+		Description:  "The maximum size, in KiB, of a module document stored in the database when using the proxy modules storage resolver. Set to 0 to disable.",
+		DefaultValue: 1024,
+	},
+
+	ProvidersProxyDocsMaxSizeFlag: &cli.IntFlag{
+		Description:  "The maximum size, in KiB, of a provider document stored in the database when using the proxy providers storage resolver. Set to 0 to disable.",
+		DefaultValue: 1024,
+	},
+
 	S3EndpointFlag: &cli.StringFlag{
 		Description: "The endpoint where the S3 SDK should connect.",
 	},
@@ -489,4 +518,40 @@ var flags = map[string]cli.Flag{
 		Choices:      []string{"1d", "1w", "1m", "1y", "never"},
 		DefaultValue: "1d",
 	},
+
+	RetentionIntervalFlag: &cli.StringFlag{
+		Description:  "How often the version retention policies are applied. Set to 0 to disable.",
+		DefaultValue: "1h",
+	},
+
+	UploadMaxSizeFlag: &cli.IntFlag{
+		Description:  "The maximum size, in MiB, of an artifact downloaded or uploaded. Set to 0 to disable.",
+		DefaultValue: 512,
+	},
+	UploadMaxDecompressedSizeFlag: &cli.IntFlag{
+		Description:  "The maximum size, in MiB, of the files of a module once decompressed. Set to 0 to disable.",
+		DefaultValue: 1024,
+	},
+	UploadMaxFilesFlag: &cli.IntFlag{
+		Description:  "The maximum number of files of a module. Set to 0 to disable.",
+		DefaultValue: 10000,
+	},
+	UploadMaxPathDepthFlag: &cli.IntFlag{
+		Description:  "The maximum number of elements in the path of a module file. Set to 0 to disable.",
+		DefaultValue: 32,
+	},
+
+	UpstreamRegistryFlag: &cli.StringFlag{
+		Description: "The host of the registry the upstream namespaces are resolved against (e.g. registry.terraform.io). Leave empty to disable.",
+	},
+	UpstreamNamespacesFlag: &cli.StringFlag{
+		Description: "The namespaces resolved against the upstream registry, or * for all of them. Comma separated.",
+	},
+	UpstreamDeniedNamespacesFlag: &cli.StringFlag{
+		Description: "The namespaces never resolved against the upstream registry. Comma separated.",
+	},
+	UpstreamCacheTTLFlag: &cli.StringFlag{
+		Description:  "How long the versions listed by the upstream registry are cached.",
+		DefaultValue: "1h",
+	},
 }
@@ -179,23 +179,35 @@ func (s *Command) run() error {
 	}
 
 	userConfig := server.UserConfig{ //nolint:forcetypeassert
-		LogLevel:                flags[LogLevelFlag].(*cli.StringFlag).Value,
-		Port:                    flags[PortFlag].(*cli.IntFlag).Value,
-		MetricsPort:             flags[MetricsPortFlag].(*cli.IntFlag).Value,
-		URL:                     flags[URLFlag].(*cli.StringFlag).Value,
-		CertFile:                flags[CertFileFlag].(*cli.StringFlag).Value,
-		KeyFile:                 flags[KeyFileFlag].(*cli.StringFlag).Value,
-		TokenSigningSecret:      flags[TokenSigningSecretFlag].(*cli.StringFlag).Value,
-		OauthProvider:           flags[OAuthProviderFlag].(*cli.StringFlag).Value,
-		CustomCompanyName:       flags[CustomCompanyNameFlag].(*cli.StringFlag).Value,
-		ModulesAnonymousRead:    flags[ModulesAnonymousReadFlag].(*cli.BoolFlag).Value,
-		ProvidersAnonymousRead:  flags[ProvidersAnonymousReadFlag].(*cli.BoolFlag).Value,
-		LocalTokenSigningSecret: flags[LocalTokenSigningSecretFlag].(*cli.StringFlag).Value,
-		SamlDisplayName:         flags[SamlDisplayNameFlag].(*cli.StringFlag).Value,
-		RbacPolicyPath:          flags[RbacPolicyPathFlag].(*cli.StringFlag).Value,
-		RbacDefaultRole:         flags[RbacDefaultRoleFlag].(*cli.StringFlag).Value,
-		MasterApiKey:            flags[MasterApiKeyFlag].(*cli.StringFlag).Value,
-		AuthTokenExpiration:     flags[AuthTokenExpirationFlag].(*cli.StringFlag).Value,
+		LogLevel:                  flags[LogLevelFlag].(*cli.StringFlag).Value,
+		Port:                      flags[PortFlag].(*cli.IntFlag).Value,
+		MetricsPort:               flags[MetricsPortFlag].(*cli.IntFlag).Value,
+		URL:                       flags[URLFlag].(*cli.StringFlag).Value,
+		CertFile:                  flags[CertFileFlag].(*cli.StringFlag).Value,
+		KeyFile:                   flags[KeyFileFlag].(*cli.StringFlag).Value,
+		TokenSigningSecret:        flags[TokenSigningSecretFlag].(*cli.StringFlag).Value,
+		SigningKeySecret:          flags[SigningKeySecretFlag].(*cli.StringFlag).Value,
+		OauthProvider:             flags[OAuthProviderFlag].(*cli.StringFlag).Value,
+		CustomCompanyName:         flags[CustomCompanyNameFlag].(*cli.StringFlag).Value,
+		ModulesAnonymousRead:      flags[ModulesAnonymousReadFlag].(*cli.BoolFlag).Value,
+		ProvidersAnonymousRead:    flags[ProvidersAnonymousReadFlag].(*cli.BoolFlag).Value,
+		ModulesProxyDocsMaxSize:   flags[ModulesProxyDocsMaxSizeFlag].(*cli.IntFlag).Value,
+		ProvidersProxyDocsMaxSize: flags[ProvidersProxyDocsMaxSizeFlag].(*cli.IntFlag).Value,
+		LocalTokenSigningSecret:   flags[LocalTokenSigningSecretFlag].(*cli.StringFlag).Value,
+		SamlDisplayName:           flags[SamlDisplayNameFlag].(*cli.StringFlag).Value,
+		RbacPolicyPath:            flags[RbacPolicyPathFlag].(*cli.StringFlag).Value,
+		RbacDefaultRole:           flags[RbacDefaultRoleFlag].(*cli.StringFlag).Value,
+		MasterApiKey:              flags[MasterApiKeyFlag].(*cli.StringFlag).Value,
+		AuthTokenExpiration:       flags[AuthTokenExpirationFlag].(*cli.StringFlag).Value,
+		RetentionInterval:         flags[RetentionIntervalFlag].(*cli.StringFlag).Value,
+		UploadMaxSize:             flags[UploadMaxSizeFlag].(*cli.IntFlag).Value,
+		UploadMaxDecompressed:     flags[UploadMaxDecompressedSizeFlag].(*cli.IntFlag).Value,
+		UploadMaxFiles:            flags[UploadMaxFilesFlag].(*cli.IntFlag).Value,
+		UploadMaxPathDepth:        flags[UploadMaxPathDepthFlag].(*cli.IntFlag).Value,
+		UpstreamRegistry:          flags[UpstreamRegistryFlag].(*cli.StringFlag).Value,
+		UpstreamNamespaces:        flags[UpstreamNamespacesFlag].(*cli.StringFlag).Value,
+		UpstreamDenied:            flags[UpstreamDeniedNamespacesFlag].(*cli.StringFlag).Value,
+		UpstreamCacheTTL:          flags[UpstreamCacheTTLFlag].(*cli.StringFlag).Value,
 	}
 
 	if s.RunningMode == "debug" {
@@ -1,21 +1,33 @@
 package server
 
 type UserConfig struct {
-	LogLevel                string `mapstructure:"log-level"`
-	Port                    int    `mapstructure:"port"`
-	MetricsPort             int    `mapstructure:"metrics-port"`
-	URL                     string `mapstructure:"url"`
-	CertFile                string `mapstructure:"cert-file"`
-	KeyFile                 string `mapstructure:"key-file"`
-	TokenSigningSecret      string `mapstructure:"token-signing-secret"`
-	OauthProvider           string `mapstructure:"oauth-provider"`
-	CustomCompanyName       string `mapstructure:"custom-company-name"`
-	ModulesAnonymousRead    bool   `mapstructure:"modules-anonymous-read"`
-	ProvidersAnonymousRead  bool   `mapstructure:"providers-anonymous-read"`
-	LocalTokenSigningSecret string `mapstructure:"local-token-signing-secret"`
-	SamlDisplayName         string `mapstructure:"saml-display-name"`
-	RbacPolicyPath          string `mapstructure:"rbac-policy-path"`
-	RbacDefaultRole         string `mapstructure:"rbac-default-role"`
-	MasterApiKey            string `mapstructure:"master-api-key"`
-	AuthTokenExpiration     string `mapstructure:"auth-token-expiration"`
+	LogLevel                  string `mapstructure:"log-level"`
+	Port                      int    `mapstructure:"port"`
+	MetricsPort               int    `mapstructure:"metrics-port"`
+	URL                       string `mapstructure:"url"`
+	CertFile                  string `mapstructure:"cert-file"`
+	KeyFile                   string `mapstructure:"key-file"`
+	TokenSigningSecret        string `mapstructure:"token-signing-secret"`
+	SigningKeySecret          string `mapstructure:"signing-key-secret"`
+	OauthProvider             string `mapstructure:"oauth-provider"`
+	CustomCompanyName         string `mapstructure:"custom-company-name"`
+	ModulesAnonymousRead      bool   `mapstructure:"modules-anonymous-read"`
+	ProvidersAnonymousRead    bool   `mapstructure:"providers-anonymous-read"`
+	ModulesProxyDocsMaxSize   int    `mapstructure:"modules-proxy-docs-max-size"`
+	ProvidersProxyDocsMaxSize int    `mapstructure:"providers-proxy-docs-max-size"`
+	LocalTokenSigningSecret   string `mapstructure:"local-token-signing-secret"`
+	SamlDisplayName           string `mapstructure:"saml-display-name"`
+	RbacPolicyPath            string `mapstructure:"rbac-policy-path"`
+	RbacDefaultRole           string `mapstructure:"rbac-default-role"`
+	MasterApiKey              string `mapstructure:"master-api-key"`
+	AuthTokenExpiration       string `mapstructure:"auth-token-expiration"`
+	RetentionInterval         string `mapstructure:"retention-interval"`
+	UploadMaxSize             int    `mapstructure:"upload-max-size"`
+	UploadMaxDecompressed     int    `mapstructure:"upload-max-decompressed-size"`
+	UploadMaxFiles            int    `mapstructure:"upload-max-files"`
+	UploadMaxPathDepth        int    `mapstructure:"upload-max-path-depth"`
+	UpstreamRegistry          string `mapstructure:"upstream-registry"`
+	UpstreamNamespaces        string `mapstructure:"upstream-namespaces"`
+	UpstreamDenied            string `mapstructure:"upstream-denied-namespaces"`
+	UpstreamCacheTTL          string `mapstructure:"upstream-cache-ttl"`
 }
@@ -84,6 +84,8 @@ func (c *DefaultArtifactController) Subscribe(apis ...*gin.RouterGroup) {
 				return c.Authorization.CanPerform(*user, rbac.ResourceAuthorities, rbac.ActionGet, authority.Name)
 			})
 
+			filter := provenanceFilter(ctx)
+
 			artifactsCount := 0
 			for _, a := range filteredAuthorities {
 				artifactsCount += len(a.Modules) + len(a.Providers)
@@ -92,6 +94,16 @@ func (c *DefaultArtifactController) Subscribe(apis ...*gin.RouterGroup) {
 			artifacts := make([]artifact.Artifact, 0, artifactsCount)
 			for _, a := range authorities {
 				for _, m := range a.Modules {
+					if !filter.IsEmpty() {
+						m.Versions = lo.Filter(m.Versions, func(v module.Version, _ int) bool {
+							return v.Provenance.Matches(filter)
+						})
+
+						if len(m.Versions) == 0 {
+							continue
+						}
+					}
+
 					artifact := m.ToArtifact()
 					artifact.Namespace = a.Name
 					artifact.FullName = fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)
@@ -104,6 +116,16 @@ func (c *DefaultArtifactController) Subscribe(apis ...*gin.RouterGroup) {
 				}
 
 				for _, p := range a.Providers {
+					if !filter.IsEmpty() {
+						p.Versions = lo.Filter(p.Versions, func(v provider.Version, _ int) bool {
+							return v.Provenance.Matches(filter)
+						})
+
+						if len(p.Versions) == 0 {
+							continue
+						}
+					}
+
 					artifact := p.ToArtifact()
 					artifact.Namespace = a.Name
 					artifact.FullName = fmt.Sprintf("%s/%s", a.Name, p.Name)
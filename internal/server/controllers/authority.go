@@ -1,13 +1,18 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"terralist/internal/server/handlers"
+	"terralist/internal/server/models/admission"
 	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/retention"
 	"terralist/internal/server/services"
 	"terralist/pkg/api"
 	"terralist/pkg/auth"
+	"terralist/pkg/pgp"
 	"terralist/pkg/rbac"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +34,9 @@ type AuthorityController interface {
 type DefaultAuthorityController struct {
 	AuthorityService services.AuthorityService
 	ApiKeyService    services.ApiKeyService
+	RetentionService services.RetentionService
+	AdmissionService services.AdmissionService
+	ModuleService    services.ModuleService
 
 	Authentication *handlers.Authentication
 	Authorization  *handlers.Authorization
@@ -234,6 +242,89 @@ func (c *DefaultAuthorityController) Subscribe(apis ...*gin.RouterGroup) {
 		},
 	)
 
+	api.POST(
+		"/:id/keys/:keyId/revoke",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			id, err := uuid.Parse(ctx.Param("keyId"))
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			// The key is revoked from the current date if the request has
+			// no body
+			var body authority.RevokeKeyDTO
+			if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			dto, err := c.AuthorityService.RevokeKey(authorityId, id, body)
+			if err != nil {
+				status := http.StatusConflict
+				if errors.Is(err, services.ErrKeyNotFound) {
+					status = http.StatusNotFound
+				}
+
+				ctx.JSON(status, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, dto)
+		},
+	)
+
+	api.POST(
+		"/:id/signing-key",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			body, ok := bindSigningKey(ctx)
+			if !ok {
+				return
+			}
+
+			dto, err := c.AuthorityService.CreateSigningKey(authorityId, body)
+			if err != nil {
+				signingKeyError(ctx, err)
+				return
+			}
+
+			ctx.JSON(http.StatusOK, dto)
+		},
+	)
+
+	api.POST(
+		"/:id/signing-key/rotate",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			body, ok := bindSigningKey(ctx)
+			if !ok {
+				return
+			}
+
+			dto, err := c.AuthorityService.RotateSigningKey(authorityId, body)
+			if err != nil {
+				signingKeyError(ctx, err)
+				return
+			}
+
+			ctx.JSON(http.StatusOK, dto)
+		},
+	)
+
 	api.POST(
 		"/:id/api-keys",
 		requireAuthorization(rbac.ActionUpdate, authorityComposer),
@@ -285,4 +376,197 @@ func (c *DefaultAuthorityController) Subscribe(apis ...*gin.RouterGroup) {
 			ctx.JSON(http.StatusOK, true)
 		},
 	)
+
+	api.GET(
+		"/:id/admission",
+		requireAuthorization(rbac.ActionGet, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			policy, err := c.AdmissionService.GetPolicy(authorityId)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, policy)
+		},
+	)
+
+	api.PUT(
+		"/:id/admission",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			var body admission.PolicyDTO
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			policy, err := c.AdmissionService.SetPolicy(authorityId, body)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, services.ErrInvalidAdmissionPolicy) {
+					status = http.StatusBadRequest
+				}
+
+				ctx.JSON(status, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, policy)
+		},
+	)
+
+	api.GET(
+		"/:id/retention",
+		requireAuthorization(rbac.ActionGet, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			policies, err := c.RetentionService.GetPolicies(authorityId)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, policies)
+		},
+	)
+
+	api.PUT(
+		"/:id/retention",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			var body retention.PolicyDTO
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			policy, err := c.RetentionService.SetPolicy(authorityId, body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, policy)
+		},
+	)
+
+	api.DELETE(
+		"/:id/retention/:policyId",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			id, err := uuid.Parse(ctx.Param("policyId"))
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			if err := c.RetentionService.DeletePolicy(authorityId, id); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, services.ErrRetentionPolicyNotFound) {
+					status = http.StatusNotFound
+				}
+
+				ctx.JSON(status, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, true)
+		},
+	)
+
+	api.POST(
+		"/:id/retention/dry-run",
+		requireAuthorization(rbac.ActionGet, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			report, err := c.RetentionService.Apply(authorityId, true)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, report)
+		},
+	)
+
+	api.POST(
+		"/:id/verify",
+		requireAuthorization(rbac.ActionUpdate, authorityComposer),
+		func(ctx *gin.Context) {
+			authorityId := handlers.MustGetFromContext[authority.Authority](ctx, "authority").ID
+
+			report, err := c.ModuleService.Verify(authorityId)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, services.ErrModulesNotStored) {
+					status = http.StatusBadRequest
+				}
+
+				ctx.JSON(status, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, report)
+		},
+	)
+}
+
+// bindSigningKey reads the optional private key of a signing key request.
+// The registry generates the key if the request has no body.
+func bindSigningKey(ctx *gin.Context) (authority.SigningKeyDTO, bool) {
+	var body authority.SigningKeyDTO
+	if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"errors": []string{err.Error()},
+		})
+		return body, false
+	}
+
+	return body, true
+}
+
+// signingKeyError responds to a failed signing key request.
+func signingKeyError(ctx *gin.Context, err error) {
+	status := http.StatusConflict
+	switch {
+	case errors.Is(err, services.ErrSigningDisabled), errors.Is(err, pgp.ErrInvalidPrivateKey):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrSigningKeyNotFound):
+		status = http.StatusNotFound
+	}
+
+	ctx.JSON(status, gin.H{
+		"errors": []string{err.Error()},
+	})
 }
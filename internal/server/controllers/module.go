@@ -1,17 +1,23 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"terralist/internal/server/handlers"
+	"terralist/internal/server/models/artifact"
 	"terralist/internal/server/models/module"
 	"terralist/internal/server/services"
 	"terralist/pkg/api"
+	"terralist/pkg/auth"
 	"terralist/pkg/file"
 	"terralist/pkg/rbac"
+	"terralist/pkg/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,6 +27,9 @@ import (
 const (
 	modulesTerraformApiBase = "/modules"
 	modulesDefaultApiBase   = "/api/modules"
+
+	modulesDefaultPageSize = 15
+	modulesMaxPageSize     = 100
 )
 
 // ModuleController registers the routes that handles the modules.
@@ -39,6 +48,14 @@ type DefaultModuleController struct {
 	Authentication   *handlers.Authentication
 	Authorization    *handlers.Authorization
 	AnonymousRead    bool
+
+	// MaxUploadSize is the maximum size of the archives uploaded as files,
+	// zero to disable the limit
+	MaxUploadSize int64
+
+	// UpstreamService resolves the proxied namespaces against the upstream
+	// registry, if set
+	UpstreamService services.UpstreamService
 }
 
 func (c *DefaultModuleController) TerraformApi() string {
@@ -69,9 +86,81 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 	tfApi := apis[0]
 	if !c.AnonymousRead {
 		tfApi.Use(c.Authentication.AttemptAuthentication())
+	}
+
+	// The listing endpoints span multiple modules, so instead of being guarded
+	// by the per-module authorization middleware, their results are filtered
+	// down to the modules the user can read. They must be registered before
+	// the middleware is attached to the group.
+	// Docs: https://developer.hashicorp.com/terraform/registry/api-docs#list-modules
+	tfApi.GET(
+		"",
+		func(ctx *gin.Context) {
+			c.listModules(ctx, module.ListFilter{
+				Provider: ctx.Query("provider"),
+			})
+		},
+	)
+
+	// Docs: https://developer.hashicorp.com/terraform/registry/api-docs#search-modules
+	tfApi.GET(
+		"/search",
+		func(ctx *gin.Context) {
+			query := strings.TrimSpace(ctx.Query("q"))
+			if query == "" {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{"the q query parameter is required"},
+				})
+				return
+			}
+
+			c.listModules(ctx, module.ListFilter{
+				Namespace: ctx.Query("namespace"),
+				Provider:  ctx.Query("provider"),
+				Query:     query,
+			})
+		},
+	)
+
+	tfApi.GET(
+		"/:namespace",
+		func(ctx *gin.Context) {
+			c.listModules(ctx, module.ListFilter{
+				Namespace: ctx.Param("namespace"),
+				Provider:  ctx.Query("provider"),
+			})
+		},
+	)
+
+	if !c.AnonymousRead {
 		tfApi.Use(requireAuthorization(rbac.ActionGet, slugComposer))
 	}
 
+	// Docs: https://developer.hashicorp.com/terraform/registry/api-docs#get-the-latest-version-for-a-specific-module-provider
+	tfApi.GET(
+		"/:namespace/:name/:provider",
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			provider := ctx.Param("provider")
+
+			dto, err := c.ModuleService.GetLatest(namespace, name, provider, ctx.Query("version"))
+			if errors.Is(err, version.ErrInvalidConstraint) {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			} else if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, dto)
+		},
+	)
+
 	tfApi.GET(
 		"/:namespace/:name/:provider/versions",
 		func(ctx *gin.Context) {
@@ -79,7 +168,13 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 			name := ctx.Param("name")
 			provider := ctx.Param("provider")
 
-			d, err := c.ModuleService.Get(namespace, name, provider)
+			var d *module.ListResponseDTO
+			var err error
+			if c.proxies(namespace) {
+				d, err = c.UpstreamService.GetModule(namespace, name, provider)
+			} else {
+				d, err = c.ModuleService.Get(namespace, name, provider)
+			}
 			if err != nil {
 				ctx.JSON(http.StatusNotFound, gin.H{
 					"errors": err.Error(),
@@ -100,7 +195,13 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 			provider := ctx.Param("provider")
 			version := ctx.Param("version")
 
-			location, err := c.ModuleService.GetVersionURL(namespace, name, provider, version)
+			var location *string
+			var err error
+			if c.proxies(namespace) {
+				location, err = c.UpstreamService.GetModuleVersionURL(namespace, name, provider, version)
+			} else {
+				location, err = c.ModuleService.GetVersionURL(namespace, name, provider, version)
+			}
 			if err != nil {
 				ctx.JSON(http.StatusNotFound, gin.H{
 					"errors": []string{err.Error()},
@@ -155,10 +256,11 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 
 			header := file.CreateHeader(body.Headers)
 
+			dto.Provenance = newProvenance(ctx)
+			dto.Provenance.SetSource(body.DownloadUrl)
+
 			if err := c.ModuleService.Upload(&dto, body.DownloadUrl, header); err != nil {
-				ctx.JSON(http.StatusConflict, gin.H{
-					"errors": []string{err.Error()},
-				})
+				uploadError(ctx, err)
 				return
 			}
 
@@ -182,9 +284,20 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 			provider := ctx.Param("provider")
 			version := ctx.Param("version")
 
+			if c.MaxUploadSize > 0 {
+				ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.MaxUploadSize)
+			}
+
 			form, err := ctx.MultipartForm()
 			if err != nil {
-				ctx.JSON(http.StatusBadRequest, gin.H{
+				status := http.StatusBadRequest
+
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					status = http.StatusRequestEntityTooLarge
+				}
+
+				ctx.JSON(status, gin.H{
 					"errors": []string{err.Error()},
 				})
 				return
@@ -258,15 +371,15 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 				VersionCreateDTO: module.VersionCreateDTO{
 					Version: version,
 				},
+				Provenance: newProvenance(ctx),
 			}
+			dto.Provenance.Source = artifact.SourceUploadFiles
 
 			// Pass-in local-file URI for go-getter
 			uri := fmt.Sprintf("file://%v", onDiskFile.Path())
 
 			if err := c.ModuleService.Upload(&dto, uri, nil); err != nil {
-				ctx.JSON(http.StatusConflict, gin.H{
-					"errors": []string{err.Error()},
-				})
+				uploadError(ctx, err)
 				return
 			}
 
@@ -301,6 +414,109 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 		},
 	)
 
+	// List the examples of a specific module version
+	api.GET(
+		"/:namespace/:name/:provider/:version/examples",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			provider := ctx.Param("provider")
+			version := ctx.Param("version")
+
+			examples, err := c.ModuleService.GetExamples(namespace, name, provider, version)
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{
+				"examples": examples,
+			})
+		},
+	)
+
+	// Get the documentation and the sources of an example for a specific module version
+	api.GET(
+		"/:namespace/:name/:provider/:version/examples/*examplePath",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			provider := ctx.Param("provider")
+			version := ctx.Param("version")
+			examplePath := strings.TrimPrefix(ctx.Param("examplePath"), "/")
+
+			example, err := c.ModuleService.GetExample(namespace, name, provider, version, examplePath)
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, example)
+		},
+	)
+
+	// Compare the interfaces of two module versions
+	api.GET(
+		"/:namespace/:name/:provider/diff",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			provider := ctx.Param("provider")
+			from := ctx.Query("from")
+			to := ctx.Query("to")
+
+			if from == "" || to == "" {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{"the from and to query parameters are required"},
+				})
+				return
+			}
+
+			diff, err := c.ModuleService.Diff(namespace, name, provider, from, to)
+			if err != nil {
+				status := http.StatusNotFound
+				if errors.Is(err, services.ErrArchiveNotInspectable) {
+					status = http.StatusUnprocessableEntity
+				}
+
+				ctx.JSON(status, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, diff)
+		},
+	)
+
+	// Deprecate a module version
+	api.POST(
+		"/:namespace/:name/:provider/:version/deprecate",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		c.setVersionStatus(artifact.StatusDeprecated),
+	)
+
+	// Yank a module version
+	api.POST(
+		"/:namespace/:name/:provider/:version/yank",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		c.setVersionStatus(artifact.StatusYanked),
+	)
+
+	// Restore a deprecated or yanked module version
+	api.POST(
+		"/:namespace/:name/:provider/:version/restore",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		c.setVersionStatus(artifact.StatusActive),
+	)
+
 	// Delete a module
 	api.DELETE(
 		"/:namespace/:name/:provider/remove",
@@ -355,6 +571,202 @@ func (c *DefaultModuleController) Subscribe(apis ...*gin.RouterGroup) {
 	)
 }
 
+// uploadError responds with the reason why a module version could not be
+// uploaded.
+func uploadError(ctx *gin.Context, err error) {
+	var admissionErr *services.AdmissionError
+	if errors.As(err, &admissionErr) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"errors":     []string{err.Error()},
+			"violations": admissionErr.Violations,
+		})
+		return
+	}
+
+	var breakingErr *services.BreakingChangeError
+	if errors.As(err, &breakingErr) {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"errors":           []string{err.Error()},
+			"breaking_changes": breakingErr.Changes,
+		})
+		return
+	}
+
+	status := http.StatusConflict
+	if errors.Is(err, file.ErrLimitExceeded) {
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	ctx.JSON(status, gin.H{
+		"errors": []string{err.Error()},
+	})
+}
+
+// setVersionStatus returns a handler which updates the lifecycle status of
+// a module version.
+func (c *DefaultModuleController) setVersionStatus(status artifact.Status) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorityID, ok := c.resolveAuthorityID(ctx)
+		if !ok {
+			return
+		}
+
+		name := ctx.Param("name")
+		provider := ctx.Param("provider")
+		version := ctx.Param("version")
+
+		var body artifact.UpdateStatusDTO
+		if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"errors": []string{err.Error()},
+			})
+			return
+		}
+
+		if err := c.ModuleService.SetVersionStatus(authorityID, name, provider, version, status, body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"errors": []string{err.Error()},
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"errors": []string{},
+		})
+	}
+}
+
+// listModules responds with a page of the modules matching a given filter
+// that can be read by the current user.
+func (c *DefaultModuleController) listModules(ctx *gin.Context, filter module.ListFilter) {
+	offset, limit, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"errors": []string{err.Error()},
+		})
+		return
+	}
+
+	readable := func(m module.ListItemDTO) bool { return true }
+	if !c.AnonymousRead {
+		user, err := handlers.GetFromContext[auth.User](ctx, "user")
+		if err != nil {
+			user = &auth.User{
+				Name: rbac.SubjectAnonymous,
+			}
+		}
+
+		readable = func(m module.ListItemDTO) bool {
+			object := fmt.Sprintf("%s/%s/%s", m.Namespace, m.Name, m.Provider)
+			return c.Authorization.CanPerform(*user, rbac.ResourceModules, rbac.ActionGet, object)
+		}
+	}
+
+	// The modules are read in batches, until the page and the module after
+	// it are found. The offset counts the readable modules only, so it is
+	// passed to the database when all of them are readable. Otherwise, the
+	// modules before the offset are read again for every page, in batches
+	// as large as the largest page.
+	skip := offset
+	filter.Limit = limit + 1
+	if c.AnonymousRead {
+		filter.Offset = offset
+		skip = 0
+	} else {
+		filter.Limit = modulesMaxPageSize + 1
+	}
+
+	var items []module.ListItemDTO
+	for {
+		batch, read, err := c.ModuleService.List(filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"errors": []string{err.Error()},
+			})
+			return
+		}
+
+		for _, m := range batch {
+			if !readable(m) {
+				continue
+			}
+
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			items = append(items, m)
+		}
+
+		// The service leaves some modules out of the batch, so the end of
+		// the modules is told by the number of modules read
+		if len(items) > limit || read < filter.Limit {
+			break
+		}
+
+		filter.Offset += filter.Limit
+	}
+
+	ctx.JSON(http.StatusOK, paginateModules(items, offset, limit))
+}
+
+// parsePagination extracts the offset and limit query parameters.
+func parsePagination(ctx *gin.Context) (int, int, error) {
+	offset := 0
+	if raw := ctx.Query("offset"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return 0, 0, fmt.Errorf("offset should be a non-negative integer")
+		}
+
+		offset = v
+	}
+
+	limit := modulesDefaultPageSize
+	if raw := ctx.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			return 0, 0, fmt.Errorf("limit should be a positive integer")
+		}
+
+		limit = min(v, modulesMaxPageSize)
+	}
+
+	return offset, limit, nil
+}
+
+// paginateModules builds the response of a page of modules. The items
+// start at the offset, and hold the module after the page if there is one.
+func paginateModules(items []module.ListItemDTO, offset, limit int) module.SearchResponseDTO {
+	meta := module.ListMetaDTO{
+		Limit:         limit,
+		CurrentOffset: offset,
+	}
+
+	if offset > 0 {
+		prev := max(offset-limit, 0)
+		meta.PrevOffset = &prev
+	}
+
+	if len(items) > limit {
+		next := offset + limit
+		meta.NextOffset = &next
+		items = items[:limit]
+	}
+
+	return module.SearchResponseDTO{
+		Meta:    meta,
+		Modules: append([]module.ListItemDTO{}, items...),
+	}
+}
+
+// proxies returns true if a namespace is resolved against the upstream
+// registry.
+func (c *DefaultModuleController) proxies(namespace string) bool {
+	return c.UpstreamService != nil && c.UpstreamService.Proxies(namespace)
+}
+
 // resolveAuthorityID resolves the authority ID from the namespace URL parameter.
 func (c *DefaultModuleController) resolveAuthorityID(ctx *gin.Context) (uuid.UUID, bool) {
 	namespace := ctx.Param("namespace")
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"terralist/internal/server/handlers"
+	"terralist/internal/server/models/artifact"
+	"terralist/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newProvenance records who is publishing an artifact version, as set in the
+// context by the authentication middleware. The source of the version is set
+// by the caller.
+func newProvenance(ctx *gin.Context) artifact.Provenance {
+	p := artifact.Provenance{
+		AuthMethod: ctx.GetString("authMethod"),
+		ApiKey:     ctx.GetString("apiKeyID"),
+		ClientIP:   ctx.ClientIP(),
+	}
+
+	if user, err := handlers.GetFromContext[auth.User](ctx, "user"); err == nil {
+		p.PublishedBy = user.Name
+		p.PublisherEmail = user.Email
+	}
+
+	return p
+}
+
+// provenanceFilter returns the provenance filter set in the query of a
+// listing request.
+func provenanceFilter(ctx *gin.Context) artifact.ProvenanceFilter {
+	return artifact.ProvenanceFilter{
+		PublishedBy: ctx.Query("published_by"),
+		AuthMethod:  ctx.Query("auth_method"),
+		ApiKey:      ctx.Query("api_key"),
+		Source:      ctx.Query("source"),
+		CommitSHA:   ctx.Query("commit_sha"),
+	}
+}
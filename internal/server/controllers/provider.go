@@ -1,22 +1,43 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"terralist/internal/server/handlers"
+	"terralist/internal/server/models/artifact"
 	"terralist/internal/server/models/provider"
 	"terralist/internal/server/services"
 	"terralist/pkg/api"
+	"terralist/pkg/file"
 	"terralist/pkg/rbac"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 const (
 	providersTerraformApiBase = "/providers"
 	providersDefaultApiBase   = "/api/providers"
+	providersMirrorApiBase    = "/providers-mirror"
+
+	mirrorIndexFile = "index.json"
+	mirrorFileExt   = ".json"
+
+	// docsFormField is the form field under which the documentation archive
+	// is sent along the release files
+	docsFormField = "docs"
+
+	// providerMaxUploadFiles is the maximum number of files uploaded with a
+	// provider release, which bounds the size of the whole upload
+	providerMaxUploadFiles = 64
 )
 
 // ProviderController registers the routes that handles the modules.
@@ -26,6 +47,10 @@ type ProviderController interface {
 	// TerraformApi returns the endpoint where Terraform can query
 	// providers.
 	TerraformApi() string
+
+	// MirrorApi returns the endpoint where Terraform can query providers
+	// using the provider network mirror protocol.
+	MirrorApi() string
 }
 
 // DefaultProviderController is a concrete implementation of ProviderController.
@@ -35,12 +60,21 @@ type DefaultProviderController struct {
 	Authentication   *handlers.Authentication
 	Authorization    *handlers.Authorization
 	AnonymousRead    bool
+
+	// MaxUploadSize is the maximum size of each file uploaded with a
+	// provider release, zero to disable the limit
+	MaxUploadSize int64
+
+	// UpstreamService resolves the proxied namespaces against the upstream
+	// registry, if set
+	UpstreamService services.UpstreamService
 }
 
 func (c *DefaultProviderController) Paths() []string {
 	return []string{
 		providersTerraformApiBase,
 		providersDefaultApiBase,
+		providersMirrorApiBase,
 	}
 }
 
@@ -48,6 +82,10 @@ func (c *DefaultProviderController) TerraformApi() string {
 	return providersTerraformApiBase + "/"
 }
 
+func (c *DefaultProviderController) MirrorApi() string {
+	return providersMirrorApiBase + "/"
+}
+
 func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 	requireAuthorization := c.Authorization.RequireAuthorization(rbac.ResourceProviders)
 
@@ -73,7 +111,13 @@ func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 			namespace := ctx.Param("namespace")
 			name := ctx.Param("name")
 
-			d, err := c.ProviderService.Get(namespace, name)
+			var d *provider.VersionListProviderDTO
+			var err error
+			if c.proxies(namespace) {
+				d, err = c.UpstreamService.GetProvider(namespace, name)
+			} else {
+				d, err = c.ProviderService.Get(namespace, name)
+			}
 			if err != nil {
 				ctx.JSON(http.StatusNotFound, gin.H{
 					"errors": err.Error(),
@@ -95,7 +139,13 @@ func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 			os := ctx.Param("os")
 			arch := ctx.Param("arch")
 
-			dto, err := c.ProviderService.GetVersion(namespace, name, version, os, arch)
+			var dto *provider.DownloadPlatformDTO
+			var err error
+			if c.proxies(namespace) {
+				dto, err = c.UpstreamService.GetProviderVersion(namespace, name, version, os, arch)
+			} else {
+				dto, err = c.ProviderService.GetVersion(namespace, name, version, os, arch)
+			}
 			if err != nil {
 				ctx.JSON(http.StatusNotFound, gin.H{
 					"errors": []string{err.Error()},
@@ -106,6 +156,47 @@ func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 		},
 	)
 
+	// mirrorApi should be compliant with the Provider Network Mirror Protocol.
+	// The hostname of the provider source address is ignored, the providers
+	// are looked up by their namespace and type
+	// Docs: https://developer.hashicorp.com/terraform/internals/provider-network-mirror-protocol
+	mirrorApi := apis[2]
+	if !c.AnonymousRead {
+		mirrorApi.Use(c.Authentication.AttemptAuthentication())
+		mirrorApi.Use(requireAuthorization(rbac.ActionGet, slugComposer))
+	}
+
+	mirrorApi.GET(
+		"/:hostname/:namespace/:name/:file",
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			fileName := ctx.Param("file")
+
+			var dto any
+			var err error
+
+			switch {
+			case fileName == mirrorIndexFile:
+				dto, err = c.ProviderService.GetMirrorVersions(namespace, name)
+			case strings.HasSuffix(fileName, mirrorFileExt):
+				version := strings.TrimSuffix(fileName, mirrorFileExt)
+				dto, err = c.ProviderService.GetMirrorArchives(namespace, name, version)
+			default:
+				err = fmt.Errorf("unknown mirror file %s", fileName)
+			}
+
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, dto)
+		},
+	)
+
 	// api holds the routes that are not described by the Terraform protocol
 	api := apis[1]
 	api.Use(c.Authentication.AttemptAuthentication())
@@ -113,6 +204,51 @@ func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 	// This is a protected endpoint, every request should be authenticated.
 	api.Use(c.Authentication.RequireAuthentication())
 
+	// List the documentation pages of a specific provider version
+	api.GET(
+		"/:namespace/:name/:version/docs",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			version := ctx.Param("version")
+
+			docs, err := c.ProviderService.GetDocs(namespace, name, version)
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{
+				"docs": docs,
+			})
+		},
+	)
+
+	// Get a documentation page of a specific provider version
+	api.GET(
+		"/:namespace/:name/:version/docs/*docPath",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			namespace := ctx.Param("namespace")
+			name := ctx.Param("name")
+			version := ctx.Param("version")
+			docPath := strings.TrimPrefix(ctx.Param("docPath"), "/")
+
+			doc, err := c.ProviderService.GetDoc(namespace, name, version, docPath)
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, doc)
+		},
+	)
+
 	// Upload a new provider version
 	api.POST(
 		"/:namespace/:name/:version/upload",
@@ -138,19 +274,271 @@ func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 			body.Name = name
 			body.Version = version
 
+			body.Provenance = newProvenance(ctx)
+			body.Provenance.SetSource(body.ShaSums.URL)
+
 			if err := c.ProviderService.Upload(&body); err != nil {
-				ctx.JSON(http.StatusConflict, gin.H{
+				releaseError(ctx, err)
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{
+				"errors": []string{},
+			})
+		},
+	)
+
+	// Upload a new provider version (with files)
+	api.POST(
+		"/:namespace/:name/:version/upload-files",
+		requireAuthorization(rbac.ActionCreate, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			name := ctx.Param("name")
+			version := ctx.Param("version")
+
+			if c.MaxUploadSize > 0 {
+				ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.MaxUploadSize*providerMaxUploadFiles)
+			}
+
+			form, err := ctx.MultipartForm()
+			if err != nil {
+				status := http.StatusBadRequest
+
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					status = http.StatusRequestEntityTooLarge
+				}
+
+				ctx.JSON(status, gin.H{
 					"errors": []string{err.Error()},
 				})
 				return
 			}
 
+			var files []file.File
+			defer func() {
+				// Remove the files the form spilled to the disk
+				if err := form.RemoveAll(); err != nil {
+					log.Error().
+						Err(err).
+						Str("artifact", "provider").
+						Str("name", name).
+						Str("version", version).
+						Msg("could not remove the uploaded files")
+				}
+			}()
+
+			count := 0
+			for _, headers := range form.File {
+				count += len(headers)
+			}
+
+			if count > providerMaxUploadFiles {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{fmt.Sprintf("expecting at most %d files, got %d", providerMaxUploadFiles, count)},
+				})
+				return
+			}
+
+			// The release files can be sent under any form field, except the
+			// one of the documentation archive
+			var docsHeaders []*multipart.FileHeader
+			for field, headers := range form.File {
+				if field == docsFormField {
+					docsHeaders = headers
+					continue
+				}
+
+				for _, h := range headers {
+					if c.MaxUploadSize > 0 && h.Size > c.MaxUploadSize {
+						ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+							"errors": []string{fmt.Sprintf("%s exceeds the maximum upload size", h.Filename)},
+						})
+						return
+					}
+
+					f, err := h.Open()
+					if err != nil {
+						ctx.JSON(http.StatusInternalServerError, gin.H{
+							"errors": []string{"cannot read the uploaded file", err.Error()},
+						})
+						return
+					}
+					defer f.Close()
+
+					files = append(files, file.NewStreamingFile(filepath.Base(h.Filename), f, h.Size))
+				}
+			}
+
+			body := provider.CreateProviderDTO{
+				AuthorityID: authorityID,
+				Name:        name,
+				Version:     version,
+				Provenance:  newProvenance(ctx),
+			}
+			body.Provenance.Source = artifact.SourceUploadFiles
+
+			if len(docsHeaders) > 1 {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{"expecting at most one archive file containing the documentation"},
+				})
+				return
+			}
+
+			if len(docsHeaders) == 1 {
+				h := docsHeaders[0]
+				if c.MaxUploadSize > 0 && h.Size > c.MaxUploadSize {
+					ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+						"errors": []string{fmt.Sprintf("%s exceeds the maximum upload size", h.Filename)},
+					})
+					return
+				}
+
+				tempDir, err := os.MkdirTemp("", "terralist-upload-*")
+				if err != nil {
+					ctx.JSON(http.StatusInternalServerError, gin.H{
+						"errors": []string{"cannot create temp directory", err.Error()},
+					})
+					return
+				}
+				defer os.RemoveAll(tempDir)
+
+				// The archive keeps its extension, so go-getter can unpack it
+				docsPath := filepath.Join(tempDir, filepath.Base(h.Filename))
+				if err := ctx.SaveUploadedFile(h, docsPath); err != nil {
+					ctx.JSON(http.StatusInternalServerError, gin.H{
+						"errors": []string{"cannot save content to the local disk", err.Error()},
+					})
+					return
+				}
+
+				// Pass-in local-file URI for go-getter
+				body.DocsURL = fmt.Sprintf("file://%v", docsPath)
+			}
+
+			if err := c.ProviderService.UploadFiles(&body, files); err != nil {
+				releaseError(ctx, err)
+				return
+			}
+
 			ctx.JSON(http.StatusOK, gin.H{
 				"errors": []string{},
 			})
 		},
 	)
 
+	// Import a new provider version from its release
+	api.POST(
+		"/:namespace/:name/:version/import",
+		requireAuthorization(rbac.ActionCreate, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			name := ctx.Param("name")
+			version := ctx.Param("version")
+
+			var body provider.ImportProviderDTO
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			body.AuthorityID = authorityID
+			body.Name = name
+			body.Version = version
+
+			body.Provenance = newProvenance(ctx)
+			if body.URL != "" {
+				body.Provenance.SetSource(body.URL)
+			} else {
+				body.Provenance.SetSource(body.ArchiveURL)
+			}
+
+			if err := c.ProviderService.Import(&body); err != nil {
+				releaseError(ctx, err)
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{
+				"errors": []string{},
+			})
+		},
+	)
+
+	// Add platforms to an existing provider version
+	api.POST(
+		"/:namespace/:name/:version/platforms",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			name := ctx.Param("name")
+			version := ctx.Param("version")
+
+			var body provider.CreateProviderDTO
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			if len(body.Platforms) == 0 {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{"at least one platform should be added"},
+				})
+				return
+			}
+
+			body.AuthorityID = authorityID
+			body.Name = name
+			body.Version = version
+
+			if err := c.ProviderService.AddPlatforms(&body); err != nil {
+				releaseError(ctx, err)
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{
+				"errors": []string{},
+			})
+		},
+	)
+
+	// Deprecate a provider version
+	api.POST(
+		"/:namespace/:name/:version/deprecate",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		c.setVersionStatus(artifact.StatusDeprecated),
+	)
+
+	// Yank a provider version
+	api.POST(
+		"/:namespace/:name/:version/yank",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		c.setVersionStatus(artifact.StatusYanked),
+	)
+
+	// Restore a deprecated or yanked provider version
+	api.POST(
+		"/:namespace/:name/:version/restore",
+		requireAuthorization(rbac.ActionUpdate, slugComposer),
+		c.setVersionStatus(artifact.StatusActive),
+	)
+
 	// Delete a provider
 	api.DELETE(
 		"/:namespace/:name/remove",
@@ -203,6 +591,62 @@ func (c *DefaultProviderController) Subscribe(apis ...*gin.RouterGroup) {
 	)
 }
 
+// releaseError responds with the reason why a provider release could not be
+// uploaded or imported.
+func releaseError(ctx *gin.Context, err error) {
+	status := http.StatusConflict
+	switch {
+	case errors.Is(err, services.ErrInvalidProviderRelease),
+		errors.Is(err, services.ErrProvidersNotStored):
+		status = http.StatusBadRequest
+	case errors.Is(err, file.ErrLimitExceeded):
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	ctx.JSON(status, gin.H{
+		"errors": []string{err.Error()},
+	})
+}
+
+// setVersionStatus returns a handler which updates the lifecycle status of
+// a provider version.
+func (c *DefaultProviderController) setVersionStatus(status artifact.Status) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorityID, ok := c.resolveAuthorityID(ctx)
+		if !ok {
+			return
+		}
+
+		name := ctx.Param("name")
+		version := ctx.Param("version")
+
+		var body artifact.UpdateStatusDTO
+		if err := ctx.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"errors": []string{err.Error()},
+			})
+			return
+		}
+
+		if err := c.ProviderService.SetVersionStatus(authorityID, name, version, status, body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"errors": []string{err.Error()},
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"errors": []string{},
+		})
+	}
+}
+
+// proxies returns true if a namespace is resolved against the upstream
+// registry.
+func (c *DefaultProviderController) proxies(namespace string) bool {
+	return c.UpstreamService != nil && c.UpstreamService.Proxies(namespace)
+}
+
 // resolveAuthorityID resolves the authority ID from the namespace URL parameter.
 func (c *DefaultProviderController) resolveAuthorityID(ctx *gin.Context) (uuid.UUID, bool) {
 	namespace := ctx.Param("namespace")
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"terralist/internal/server/handlers"
+	"terralist/internal/server/models/webhook"
+	"terralist/internal/server/services"
+	"terralist/pkg/api"
+	"terralist/pkg/rbac"
+	"terralist/pkg/vcs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const (
+	webhooksDefaultApiBase  = "/api/webhooks"
+	webhooksReceiverApiBase = "/webhooks"
+
+	// webhookMaxPayloadSize is the maximum size of a webhook payload, as
+	// documented by GitHub
+	webhookMaxPayloadSize = 25 << 20
+)
+
+// WebhookController registers the routes that handle the git webhooks.
+type WebhookController interface {
+	api.RestController
+
+	// ReceiverApi returns the endpoint where the git webhooks are sent.
+	ReceiverApi() string
+}
+
+// DefaultWebhookController is a concrete implementation of
+// WebhookController.
+type DefaultWebhookController struct {
+	WebhookService   services.WebhookService
+	WebhookPublisher *services.WebhookPublisher
+	AuthorityService services.AuthorityService
+	Authentication   *handlers.Authentication
+	Authorization    *handlers.Authorization
+
+	// ReceiverURL is the public URL of the receiver endpoint
+	ReceiverURL string
+}
+
+func (c *DefaultWebhookController) ReceiverApi() string {
+	return webhooksReceiverApiBase
+}
+
+func (c *DefaultWebhookController) Paths() []string {
+	return []string{
+		webhooksDefaultApiBase,
+		webhooksReceiverApiBase,
+	}
+}
+
+func (c *DefaultWebhookController) Subscribe(apis ...*gin.RouterGroup) {
+	requireAuthorization := c.Authorization.RequireAuthorization(rbac.ResourceModules)
+
+	slugComposer := func(ctx *gin.Context) string {
+		namespace := ctx.Param("namespace")
+		name := ctx.Param("name")
+		provider := ctx.Param("provider")
+
+		return fmt.Sprintf("%s/%s/%s", namespace, name, provider)
+	}
+
+	api := apis[0]
+	api.Use(c.Authentication.AttemptAuthentication())
+
+	// This is a protected endpoint, every request should be authenticated.
+	api.Use(c.Authentication.RequireAuthentication())
+
+	api.GET(
+		"/:namespace/:name/:provider",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			regs, err := c.WebhookService.GetRegistrations(authorityID, ctx.Param("name"), ctx.Param("provider"))
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, lo.Map(regs, func(r webhook.RegistrationDTO, _ int) webhook.RegistrationDTO {
+				return c.withURL(r)
+			}))
+		},
+	)
+
+	api.POST(
+		"/:namespace/:name/:provider",
+		requireAuthorization(rbac.ActionCreate, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			var body webhook.CreateRegistrationDTO
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			reg, err := c.WebhookService.CreateRegistration(authorityID, ctx.Param("name"), ctx.Param("provider"), body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusCreated, c.withURL(*reg))
+		},
+	)
+
+	api.DELETE(
+		"/:namespace/:name/:provider/:id",
+		requireAuthorization(rbac.ActionDelete, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			id, err := uuid.Parse(ctx.Param("id"))
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			if err := c.WebhookService.DeleteRegistration(authorityID, ctx.Param("name"), ctx.Param("provider"), id); err != nil {
+				ctx.JSON(webhookErrorStatus(err), gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{
+				"errors": []string{},
+			})
+		},
+	)
+
+	api.GET(
+		"/:namespace/:name/:provider/:id/deliveries",
+		requireAuthorization(rbac.ActionGet, slugComposer),
+		func(ctx *gin.Context) {
+			authorityID, ok := c.resolveAuthorityID(ctx)
+			if !ok {
+				return
+			}
+
+			id, err := uuid.Parse(ctx.Param("id"))
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			status := webhook.DeliveryStatus(ctx.Query("status"))
+
+			deliveries, err := c.WebhookService.GetDeliveries(authorityID, ctx.Param("name"), ctx.Param("provider"), id, status)
+			if err != nil {
+				ctx.JSON(webhookErrorStatus(err), gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, deliveries)
+		},
+	)
+
+	// The receiver is not authenticated, the requests are verified using
+	// the secret of the registration instead.
+	receiver := apis[1]
+
+	receiver.POST(
+		"/:id",
+		func(ctx *gin.Context) {
+			id, err := uuid.Parse(ctx.Param("id"))
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"errors": []string{services.ErrWebhookNotFound.Error()},
+				})
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, webhookMaxPayloadSize))
+			if err != nil {
+				ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			deliveries, err := c.WebhookService.Receive(id, ctx.Request.Header, body)
+			if errors.Is(err, vcs.ErrUnsupportedEvent) {
+				// Other events (e.g. pings) are acknowledged, so they are not
+				// reported as failures by the sender
+				ctx.JSON(http.StatusOK, gin.H{
+					"errors":     []string{},
+					"deliveries": []webhook.DeliveryDTO{},
+				})
+				return
+			}
+
+			if err != nil {
+				ctx.JSON(webhookErrorStatus(err), gin.H{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+
+			// Fetching the repository takes longer than the senders are
+			// willing to wait, so the versions are published in background
+			// (the deliveries which could not be scheduled are reported as
+			// failed)
+			for _, d := range deliveries {
+				_ = c.WebhookPublisher.Enqueue(d)
+			}
+
+			ctx.JSON(http.StatusAccepted, gin.H{
+				"errors": []string{},
+				"deliveries": lo.Map(deliveries, func(d *webhook.Delivery, _ int) webhook.DeliveryDTO {
+					return d.ToDTO()
+				}),
+			})
+		},
+	)
+}
+
+// withURL sets the receiver URL of a webhook registration.
+func (c *DefaultWebhookController) withURL(r webhook.RegistrationDTO) webhook.RegistrationDTO {
+	r.URL = fmt.Sprintf("%s/%s", c.ReceiverURL, r.ID)
+	return r
+}
+
+// resolveAuthorityID resolves the authority ID from the namespace URL parameter.
+func (c *DefaultWebhookController) resolveAuthorityID(ctx *gin.Context) (uuid.UUID, bool) {
+	namespace := ctx.Param("namespace")
+
+	authority, err := c.AuthorityService.GetByName(namespace)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"errors": []string{fmt.Sprintf("authority %q not found", namespace)},
+		})
+		return uuid.UUID{}, false
+	}
+
+	return authority.ID, true
+}
+
+// webhookErrorStatus returns the HTTP status matching a webhook error.
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, vcs.ErrMissingSignature), errors.Is(err, vcs.ErrInvalidSignature):
+		return http.StatusUnauthorized
+	}
+
+	return http.StatusBadRequest
+}
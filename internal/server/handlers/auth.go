@@ -14,6 +14,7 @@ import (
 	"terralist/pkg/session"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 )
@@ -70,40 +71,71 @@ func (a *Authentication) parseTerraformCLI(c *gin.Context) (*auth.User, error) {
 	return user, nil
 }
 
-// parseApiKey parses a request context, and, if the user is authenticated
-// from an API Key it returns the user.
-// If the user is authenticated, but not from an API Key, it returns an
+// extractApiKey returns the API key a request is authenticated with.
+// If the request is authenticated, but not with an API Key, it returns an
 // ErrUnexpectedOrigin error.
-func (a *Authentication) parseApiKey(c *gin.Context) (*auth.User, error) {
+func extractApiKey(c *gin.Context) (string, error) {
 	authorizationHeader := c.GetHeader("Authorization")
 	apiKeyHeader := c.GetHeader("X-API-Key")
 	if authorizationHeader == "" && apiKeyHeader == "" {
-		return nil, fmt.Errorf("%w: neither Authorization nor X-API-Key headers are set", ErrMissing)
+		return "", fmt.Errorf("%w: neither Authorization nor X-API-Key headers are set", ErrMissing)
 	}
 
 	apiKey := ""
 	if authorizationHeader != "" {
 		if !strings.HasPrefix(authorizationHeader, "Bearer ") {
-			return nil, fmt.Errorf("%w: Authorization header not properly set", ErrInvalidFormat)
+			return "", fmt.Errorf("%w: Authorization header not properly set", ErrInvalidFormat)
 		}
 
 		var bearerToken string
 		_, err := fmt.Sscanf(authorizationHeader, "Bearer %s", &bearerToken)
 		if err != nil {
-			return nil, fmt.Errorf("%w: Authorization header cannot be parsed", ErrInvalidFormat)
+			return "", fmt.Errorf("%w: Authorization header cannot be parsed", ErrInvalidFormat)
 		}
 
 		if !strings.HasPrefix(bearerToken, "x-api-key:") {
-			return nil, fmt.Errorf("%w: terraform-cli", ErrUnexpectedOrigin)
+			return "", fmt.Errorf("%w: terraform-cli", ErrUnexpectedOrigin)
 		}
 
 		if _, err := fmt.Sscanf(bearerToken, "x-api-key:%s", &apiKey); err != nil {
-			return nil, fmt.Errorf("%w: no api-key found", ErrInvalidFormat)
+			return "", fmt.Errorf("%w: no api-key found", ErrInvalidFormat)
 		}
 	} else {
 		apiKey = apiKeyHeader
 	}
 
+	return apiKey, nil
+}
+
+// MasterApiKeyID identifies the master API key, which has no record.
+const MasterApiKeyID = "master"
+
+// apiKeyID returns the ID of the record of an API key, or MasterApiKeyID for
+// the master API key.
+func (a *Authentication) apiKeyID(apiKey string) string {
+	if a.MasterApiKey != "" && apiKey == a.MasterApiKey {
+		return MasterApiKeyID
+	}
+
+	// The keys are granted as the IDs of their records
+	id, err := uuid.Parse(apiKey)
+	if err != nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+// parseApiKey parses a request context, and, if the user is authenticated
+// from an API Key it returns the user.
+// If the user is authenticated, but not from an API Key, it returns an
+// ErrUnexpectedOrigin error.
+func (a *Authentication) parseApiKey(c *gin.Context) (*auth.User, error) {
+	apiKey, err := extractApiKey(c)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check master API key (config-driven, full access).
 	if a.MasterApiKey != "" && apiKey == a.MasterApiKey {
 		return &auth.User{
@@ -164,8 +196,10 @@ func (a *Authentication) parseActiveSession(c *gin.Context) (*auth.User, error)
 }
 
 // parseUser iteratively check all possible authentication methods and selects
-// the first one that validates the user.
-func (a *Authentication) parseUser(c *gin.Context) (*auth.User, []error) {
+// the first one that validates the user. It also returns the method the user
+// was authenticated with.
+func (a *Authentication) parseUser(c *gin.Context) (*auth.User, auth.Method, []error) {
+	methods := []auth.Method{auth.MethodCLIToken, auth.MethodApiKey, auth.MethodSession}
 	users := make([]*auth.User, 3)
 	errs := make([]error, 3)
 
@@ -173,34 +207,25 @@ func (a *Authentication) parseUser(c *gin.Context) (*auth.User, []error) {
 	users[1], errs[1] = a.parseApiKey(c)
 	users[2], errs[2] = a.parseActiveSession(c)
 
-	user := lo.Reduce(users, func(acc *auth.User, cur *auth.User, _ int) *auth.User {
-		if acc != nil {
-			return acc
-		}
-
-		if cur != nil {
-			return cur
+	for i, user := range users {
+		if user != nil {
+			return user, methods[i], nil
 		}
-
-		return acc
-	}, nil)
-
-	if user == nil {
-		log.Debug().
-			Ctx(c).
-			Any("users", users).
-			Errs("errors", errs).
-			Msg("Cannot find any authenticated user.")
-		return nil, errs
 	}
 
-	return user, nil
+	log.Debug().
+		Ctx(c).
+		Any("users", users).
+		Errs("errors", errs).
+		Msg("Cannot find any authenticated user.")
+
+	return nil, "", errs
 }
 
 // AttemptAuthentication is a gin handler that attempts to get the authenticated user.
 func (a *Authentication) AttemptAuthentication() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		user, errs := a.parseUser(ctx)
+		user, method, errs := a.parseUser(ctx)
 		if errs != nil {
 			ctx.Set("authErrors", lo.Map(errs, func(err error, _ int) string { return err.Error() }))
 			return
@@ -209,6 +234,13 @@ func (a *Authentication) AttemptAuthentication() gin.HandlerFunc {
 		ctx.Set("user", user)
 		ctx.Set("userName", user.Name)
 		ctx.Set("userEmail", user.Email)
+		ctx.Set("authMethod", string(method))
+
+		if method == auth.MethodApiKey {
+			if apiKey, err := extractApiKey(ctx); err == nil {
+				ctx.Set("apiKeyID", a.apiKeyID(apiKey))
+			}
+		}
 
 		if user.Authority != "" {
 			ctx.Set("authorityName", user.Authority)
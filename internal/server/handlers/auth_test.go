@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -10,7 +12,9 @@ import (
 	"terralist/internal/server/services"
 	"terralist/pkg/auth"
 	"terralist/pkg/rbac"
+	"terralist/pkg/session/cookie"
 
+	"github.com/gin-gonic/gin"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -374,3 +378,76 @@ p, owner@example.com, modules, create, my-authority/*/*, allow
 		})
 	})
 }
+
+func TestAttemptAuthentication_Method(t *testing.T) {
+	Convey("Subject: Recording how a user authenticated", t, func() {
+		gin.SetMode(gin.TestMode)
+
+		store, err := (&cookie.Creator{}).New(&cookie.Config{
+			Name:   "test-session",
+			Secret: "test-secret",
+		})
+		So(err, ShouldBeNil)
+
+		authentication := &Authentication{
+			MasterApiKey: "master-key",
+			Store:        store,
+		}
+
+		Convey("Given a request authenticated with an API key", func() {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx.Request.Header.Set("X-API-Key", "master-key")
+
+			Convey("When the user is authenticated", func() {
+				authentication.AttemptAuthentication()(ctx)
+
+				Convey("The method and the ID of the key should be set", func() {
+					So(ctx.GetString("userName"), ShouldEqual, "terralist-admin")
+					So(ctx.GetString("authMethod"), ShouldEqual, string(auth.MethodApiKey))
+					So(ctx.GetString("apiKeyID"), ShouldEqual, MasterApiKeyID)
+				})
+			})
+		})
+
+		Convey("Given a request authenticated with a standalone API key", func() {
+			mockStandaloneApiKeyService := services.NewMockStandaloneApiKeyService(t)
+			authentication.StandaloneApiKeyService = mockStandaloneApiKeyService
+
+			key := "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+			mockStandaloneApiKeyService.
+				On("Authenticate", key).
+				Return(&auth.User{Name: "apikey:" + key}, nil)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx.Request.Header.Set("X-API-Key", key)
+
+			Convey("When the user is authenticated", func() {
+				authentication.AttemptAuthentication()(ctx)
+
+				Convey("The ID of the key record should be set", func() {
+					So(ctx.GetString("authMethod"), ShouldEqual, string(auth.MethodApiKey))
+					So(ctx.GetString("apiKeyID"), ShouldEqual, key)
+				})
+			})
+		})
+
+		Convey("Given an anonymous request", func() {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+			Convey("When the user is authenticated", func() {
+				authentication.AttemptAuthentication()(ctx)
+
+				Convey("No method should be set", func() {
+					_, ok := ctx.Get("authMethod")
+					So(ok, ShouldBeFalse)
+				})
+			})
+		})
+	})
+}
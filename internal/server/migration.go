@@ -1,30 +1,101 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"terralist/internal/server/models/admission"
 	"terralist/internal/server/models/apikey"
 	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/blob"
+	"terralist/internal/server/models/document"
 	"terralist/internal/server/models/module"
 	"terralist/internal/server/models/provider"
+	"terralist/internal/server/models/retention"
+	"terralist/internal/server/models/webhook"
 	"terralist/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// dataMigration is a migration of the existing rows, which is only run
+// once. Its version is recorded when it is applied.
+type dataMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+func (dataMigration) TableName() string {
+	return "data_migrations"
+}
+
+// dataMigrations are the data migrations, in the order they are applied.
+// New migrations are appended with the next version, which must not be
+// used by a background migration either.
+var dataMigrations = []struct {
+	version int
+	name    string
+	migrate func(*database.DB) error
+}{
+	{1, "link provider version keys", linkProviderVersionKeys},
+}
+
+// backgroundMigration is a data migration which is run once the server
+// started, since it may take long. Its version is recorded once it
+// completes, so it is run again on the next start until then.
+type backgroundMigration struct {
+	version int
+	name    string
+}
+
+// contentMigration moves the keys stored before the content-addressed
+// storage to blobs.
+var contentMigration = backgroundMigration{2, "adopt content-addressed keys"}
+
 type InitialMigration struct{}
 
 func (*InitialMigration) Migrate(db *database.DB) error {
+	if err := migrateLegacyModuleParents(db); err != nil {
+		return err
+	}
+
+	if err := removeDuplicateWebhookDeliveries(db); err != nil {
+		return err
+	}
+
 	if err := db.AutoMigrate(
 		&authority.Authority{},
 		&authority.Key{},
+		&authority.SigningKey{},
 		&authority.ApiKey{},
 		&apikey.ApiKey{},
 		&apikey.Policy{},
 		&provider.Provider{},
 		&provider.Version{},
 		&provider.Platform{},
+		&provider.Doc{},
+		&provider.VersionKey{},
 		&module.Module{},
 		&module.Version{},
 		&module.Submodule{},
+		&module.Example{},
+		&module.ExampleFile{},
+		&module.Variable{},
+		&module.Output{},
 		&module.Provider{},
 		&module.Dependency{},
+		&retention.Policy{},
+		&admission.Policy{},
+		&webhook.Registration{},
+		&webhook.Delivery{},
+		&blob.Blob{},
+		&blob.Reference{},
+		&document.Document{},
+		&dataMigration{},
 	); err != nil {
 		return err
 	}
@@ -34,5 +105,217 @@ func (*InitialMigration) Migrate(db *database.DB) error {
 		return err
 	}
 
+	return migrateData(db)
+}
+
+// migrateData applies the data migrations which were not applied yet. Each
+// migration is recorded in the same transaction it is applied in, so the
+// concurrent instances wait for it and do not apply it again.
+func migrateData(db *database.DB) error {
+	for _, m := range dataMigrations {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			applied := tx.
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&dataMigration{
+					Version:   m.version,
+					Name:      m.name,
+					AppliedAt: time.Now(),
+				})
+			if applied.Error != nil {
+				return applied.Error
+			}
+
+			if applied.RowsAffected == 0 {
+				return nil
+			}
+
+			return m.migrate(tx)
+		}); err != nil {
+			return fmt.Errorf("could not apply the data migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// runBackgroundMigration runs a background migration, unless it was
+// already applied, and records it once it completes.
+func runBackgroundMigration(db *database.DB, m backgroundMigration, run func() error) error {
+	var applied int64
+	if err := db.Model(&dataMigration{}).Where("version = ?", m.version).Count(&applied).Error; err != nil {
+		return err
+	}
+
+	if applied > 0 {
+		return nil
+	}
+
+	if err := run(); err != nil {
+		return err
+	}
+
+	return db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&dataMigration{
+			Version:   m.version,
+			Name:      m.name,
+			AppliedAt: time.Now(),
+		}).
+		Error
+}
+
+// migrateLegacyModuleParents migrates the module providers and dependencies
+// which referenced both versions and submodules through a single parent_id
+// column, which cannot satisfy both foreign keys, to a dedicated column for
+// each parent.
+func migrateLegacyModuleParents(db *database.DB) error {
+	if db.Migrator().HasColumn(&module.Provider{}, "parent_id") {
+		ptn := (module.Provider{}).TableName()
+
+		for _, column := range []string{"VersionID", "SubmoduleID"} {
+			if !db.Migrator().HasColumn(&module.Provider{}, column) {
+				if err := db.Migrator().AddColumn(&module.Provider{}, column); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			for column, parent := range map[string]string{
+				"version_id":   (module.Version{}).TableName(),
+				"submodule_id": (module.Submodule{}).TableName(),
+			} {
+				if !tx.Migrator().HasTable(parent) {
+					continue
+				}
+
+				if err := tx.Exec(fmt.Sprintf(
+					"UPDATE %s SET %s = parent_id WHERE parent_id IN (SELECT id FROM %s)",
+					ptn,
+					column,
+					parent,
+				)).Error; err != nil {
+					return err
+				}
+			}
+
+			// The providers of removed parents were never visible
+			return tx.Exec(fmt.Sprintf(
+				"DELETE FROM %s WHERE version_id IS NULL AND submodule_id IS NULL",
+				ptn,
+			)).Error
+		}); err != nil {
+			return err
+		}
+
+		// The legacy foreign keys have the same names as the new ones, but
+		// reference the parent_id column
+		for _, parent := range []any{&module.Version{}, &module.Submodule{}} {
+			if db.Migrator().HasConstraint(parent, "Providers") {
+				if err := db.Migrator().DropConstraint(parent, "Providers"); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := db.Migrator().DropColumn(&module.Provider{}, "parent_id"); err != nil {
+			return err
+		}
+	}
+
+	// The legacy dependencies held nothing but their parent, so there is
+	// nothing to migrate
+	if db.Migrator().HasColumn(&module.Dependency{}, "parent_id") {
+		if err := db.Migrator().DropTable(&module.Dependency{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeDuplicateWebhookDeliveries removes the webhook deliveries recorded
+// again for each redelivery, before a tag was only recorded once per
+// delivery, so the unique index of the deliveries can be created. The most
+// recent row of each delivery is kept.
+func removeDuplicateWebhookDeliveries(db *database.DB) error {
+	if !db.Migrator().HasTable(&webhook.Delivery{}) ||
+		db.Migrator().HasIndex(&webhook.Delivery{}, "idx_webhook_deliveries_tag") {
+		return nil
+	}
+
+	var duplicates []struct {
+		RegistrationID uuid.UUID
+		DeliveryID     string
+		Tag            string
+	}
+
+	if err := db.
+		Model(&webhook.Delivery{}).
+		Select("registration_id, delivery_id, tag").
+		Group("registration_id, delivery_id, tag").
+		Having("COUNT(*) > 1").
+		Scan(&duplicates).
+		Error; err != nil {
+		return err
+	}
+
+	for _, d := range duplicates {
+		var ids []uuid.UUID
+		if err := db.
+			Model(&webhook.Delivery{}).
+			Where("registration_id = ? AND delivery_id = ? AND tag = ?", d.RegistrationID, d.DeliveryID, d.Tag).
+			Order("updated_at DESC").
+			Pluck("id", &ids).
+			Error; err != nil {
+			return err
+		}
+
+		if err := db.Where("id IN ?", ids[1:]).Delete(&webhook.Delivery{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linkProviderVersionKeys links the provider versions uploaded before their
+// signing keys were linked to them, using the ID of their signing key.
+func linkProviderVersionKeys(db *database.DB) error {
+	vtn := (provider.Version{}).TableName()
+	vktn := (provider.VersionKey{}).TableName()
+
+	var versions []provider.Version
+	if err := db.
+		Preload("Provider").
+		Where(fmt.Sprintf("%s.signing_key_id <> ''", vtn)).
+		Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.version_id = %s.id)", vktn, vktn, vtn)).
+		Find(&versions).
+		Error; err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		var k authority.Key
+		err := db.
+			Where("authority_id = ? AND LOWER(key_id) = LOWER(?)", v.Provider.AuthorityID, v.SigningKeyID).
+			First(&k).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The key was removed, so the version cannot be verified anymore
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if err := db.Create(&provider.VersionKey{
+			VersionID: v.ID,
+			KeyID:     k.ID,
+			SignedAt:  v.CreatedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -2,9 +2,17 @@ package server
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/models/provider"
+	"terralist/internal/server/models/webhook"
+	"terralist/pkg/database/entity"
+
 	"github.com/glebarez/sqlite"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -60,6 +68,323 @@ func TestInitialMigrationDropsModuleDocumentationDefault(t *testing.T) {
 	}
 }
 
+type legacyModuleProvider struct {
+	ID        uuid.UUID `gorm:"primary_key;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ParentID  uuid.UUID
+	Name      string `gorm:"not null"`
+	Namespace string `gorm:"not null"`
+	Source    string `gorm:"not null"`
+	Version   string `gorm:"not null"`
+}
+
+func (legacyModuleProvider) TableName() string {
+	return "module_providers"
+}
+
+func TestInitialMigrationRecreatesLegacyModuleProviders(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:legacy-providers?mode=memory&_pragma=foreign_keys(1)"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&legacyModuleProvider{}); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if err := (&InitialMigration{}).Migrate(db); err != nil {
+		t.Fatalf("failed to run initial migration: %v", err)
+	}
+
+	if db.Migrator().HasColumn(&module.Provider{}, "parent_id") {
+		t.Fatal("expected module_providers.parent_id to be removed")
+	}
+
+	// A version and one of its submodules both requiring providers must
+	// satisfy the foreign keys of the module_providers table.
+	a := authority.Authority{Name: "team-a"}
+	if err := db.Create(&a).Error; err != nil {
+		t.Fatalf("failed to persist authority: %v", err)
+	}
+
+	subID := uuid.New()
+	m := module.Module{
+		AuthorityID: a.ID,
+		Name:        "vpc",
+		Provider:    "aws",
+		Versions: []module.Version{
+			{
+				Version:   "1.0.0",
+				Location:  "location",
+				Providers: []module.Provider{{Name: "aws", Namespace: "hashicorp", Source: "hashicorp/aws"}},
+				Submodules: []module.Submodule{
+					{
+						Entity:    entity.Entity{ID: subID},
+						Path:      "modules/subnet",
+						Providers: []module.Provider{{Name: "aws", Namespace: "hashicorp", Source: "hashicorp/aws"}},
+						Variables: []module.Variable{{Name: "zone"}},
+					},
+				},
+			},
+		},
+	}
+
+	if err := db.Create(&m).Error; err != nil {
+		t.Fatalf("failed to persist module: %v", err)
+	}
+
+	var count int64
+	if err := db.Model(&module.Provider{}).Where("submodule_id = ?", subID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count submodule providers: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 submodule provider, got %d", count)
+	}
+}
+
+type legacyParentVersion struct {
+	ID        uuid.UUID `gorm:"primary_key;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ModuleID  uuid.UUID
+	Version   string                 `gorm:"not null"`
+	Location  string                 `gorm:"not null"`
+	Providers []legacyModuleProvider `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (legacyParentVersion) TableName() string {
+	return "module_versions"
+}
+
+type legacyParentSubmodule struct {
+	ID        uuid.UUID `gorm:"primary_key;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	VersionID uuid.UUID
+	Path      string                 `gorm:"not null"`
+	Providers []legacyModuleProvider `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (legacyParentSubmodule) TableName() string {
+	return "module_submodules"
+}
+
+func TestInitialMigrationMigratesLegacyModuleProviders(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:legacy-provider-rows?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&legacyModuleProvider{}, &legacyParentVersion{}, &legacyParentSubmodule{}); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	versionID, subID := uuid.New(), uuid.New()
+	if err := db.Create(&legacyParentVersion{
+		ID:       versionID,
+		ModuleID: uuid.New(),
+		Version:  "1.0.0",
+		Location: "location",
+		Providers: []legacyModuleProvider{
+			{ID: uuid.New(), Name: "aws", Namespace: "hashicorp", Source: "hashicorp/aws", Version: ">= 5.0"},
+		},
+	}).Error; err != nil {
+		t.Fatalf("failed to persist legacy version: %v", err)
+	}
+
+	if err := db.Create(&legacyParentSubmodule{
+		ID:        subID,
+		VersionID: versionID,
+		Path:      "modules/subnet",
+		Providers: []legacyModuleProvider{
+			{ID: uuid.New(), Name: "random", Namespace: "hashicorp", Source: "hashicorp/random"},
+		},
+	}).Error; err != nil {
+		t.Fatalf("failed to persist legacy submodule: %v", err)
+	}
+
+	// A provider of a version which no longer exists
+	if err := db.Create(&legacyModuleProvider{ID: uuid.New(), ParentID: uuid.New(), Name: "null"}).Error; err != nil {
+		t.Fatalf("failed to persist legacy provider: %v", err)
+	}
+
+	if err := (&InitialMigration{}).Migrate(db); err != nil {
+		t.Fatalf("failed to run initial migration: %v", err)
+	}
+
+	if db.Migrator().HasColumn(&module.Provider{}, "parent_id") {
+		t.Fatal("expected module_providers.parent_id to be removed")
+	}
+
+	var providers []module.Provider
+	if err := db.Order("name").Find(&providers).Error; err != nil {
+		t.Fatalf("failed to load providers: %v", err)
+	}
+
+	if len(providers) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(providers))
+	}
+
+	if p := providers[0]; p.Name != "aws" || p.VersionID == nil || *p.VersionID != versionID || p.SubmoduleID != nil {
+		t.Fatalf("expected the aws provider to belong to version %s, got %+v", versionID, p)
+	}
+
+	if p := providers[1]; p.Name != "random" || p.SubmoduleID == nil || *p.SubmoduleID != subID || p.VersionID != nil {
+		t.Fatalf("expected the random provider to belong to submodule %s, got %+v", subID, p)
+	}
+
+	if p := providers[0]; p.Source != "hashicorp/aws" || p.Version != ">= 5.0" {
+		t.Fatalf("expected the aws provider to keep its requirement, got %+v", p)
+	}
+
+	var ddl string
+	if err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'module_providers'").Scan(&ddl).Error; err != nil {
+		t.Fatalf("failed to inspect migrated schema: %v", err)
+	}
+
+	if strings.Contains(ddl, "parent_id") || !strings.Contains(ddl, "FOREIGN KEY (`version_id`)") {
+		t.Fatalf("expected the foreign keys to reference the new columns, got %s", ddl)
+	}
+}
+
+type legacyWebhookDelivery struct {
+	ID             uuid.UUID `gorm:"primary_key;"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	RegistrationID uuid.UUID `gorm:"not null;index"`
+	DeliveryID     string
+	Tag            string
+	Status         string `gorm:"not null"`
+}
+
+func (legacyWebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func TestInitialMigrationRemovesDuplicateWebhookDeliveries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:webhook-deliveries?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&legacyWebhookDelivery{}); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	registrationID := uuid.New()
+	latest := uuid.New()
+	now := time.Now()
+	for _, d := range []legacyWebhookDelivery{
+		{ID: uuid.New(), UpdatedAt: now.Add(-time.Hour), RegistrationID: registrationID, DeliveryID: "72d3162e", Tag: "v1.0.0", Status: "failed"},
+		{ID: latest, UpdatedAt: now, RegistrationID: registrationID, DeliveryID: "72d3162e", Tag: "v1.0.0", Status: "succeeded"},
+		{ID: uuid.New(), UpdatedAt: now, RegistrationID: registrationID, DeliveryID: "72d3162e", Tag: "v1.1.0", Status: "succeeded"},
+	} {
+		if err := db.Create(&d).Error; err != nil {
+			t.Fatalf("failed to persist legacy delivery: %v", err)
+		}
+	}
+
+	if err := (&InitialMigration{}).Migrate(db); err != nil {
+		t.Fatalf("failed to run initial migration: %v", err)
+	}
+
+	var deliveries []webhook.Delivery
+	if err := db.Order("tag").Find(&deliveries).Error; err != nil {
+		t.Fatalf("failed to load deliveries: %v", err)
+	}
+
+	if len(deliveries) != 2 || deliveries[0].ID != latest {
+		t.Fatalf("expected the most recent delivery of each tag to be kept, got %+v", deliveries)
+	}
+
+	if !db.Migrator().HasIndex(&webhook.Delivery{}, "idx_webhook_deliveries_tag") {
+		t.Fatal("expected the deliveries to be unique")
+	}
+}
+
+func TestInitialMigrationAppliesDataMigrationsOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:data-migrations?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	if err := (&InitialMigration{}).Migrate(db); err != nil {
+		t.Fatalf("failed to run initial migration: %v", err)
+	}
+
+	a := authority.Authority{Name: "hashicorp", Keys: []authority.Key{{KeyId: "34365D9472D7468F"}}}
+	if err := db.Create(&a).Error; err != nil {
+		t.Fatalf("failed to persist authority: %v", err)
+	}
+
+	p := provider.Provider{
+		AuthorityID: a.ID,
+		Name:        "random",
+		Versions:    []provider.Version{{Version: "3.6.0", SigningKeyID: "34365d9472d7468f"}},
+	}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("failed to persist provider: %v", err)
+	}
+
+	// The version is not linked to its key, as the migration already ran
+	if err := (&InitialMigration{}).Migrate(db); err != nil {
+		t.Fatalf("failed to run initial migration again: %v", err)
+	}
+
+	var links int64
+	if err := db.Model(&provider.VersionKey{}).Count(&links).Error; err != nil {
+		t.Fatalf("failed to count version keys: %v", err)
+	}
+	if links != 0 {
+		t.Fatalf("expected the data migrations not to run again, got %d version keys", links)
+	}
+
+	var applied []dataMigration
+	if err := db.Find(&applied).Error; err != nil {
+		t.Fatalf("failed to load data migrations: %v", err)
+	}
+	if len(applied) != len(dataMigrations) {
+		t.Fatalf("expected %d applied data migrations, got %d", len(dataMigrations), len(applied))
+	}
+}
+
+func TestRunBackgroundMigrationRecordsCompletion(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:background-migrations?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	if err := (&InitialMigration{}).Migrate(db); err != nil {
+		t.Fatalf("failed to run initial migration: %v", err)
+	}
+
+	runs := 0
+	failing := func() error {
+		runs++
+		return errors.New("storage failure")
+	}
+	succeeding := func() error {
+		runs++
+		return nil
+	}
+
+	if err := runBackgroundMigration(db, contentMigration, failing); err == nil {
+		t.Fatal("expected the failure of the migration to be returned")
+	}
+
+	// The migration is run again until it completes, and no longer after
+	for _, run := range []func() error{succeeding, succeeding} {
+		if err := runBackgroundMigration(db, contentMigration, run); err != nil {
+			t.Fatalf("failed to run background migration: %v", err)
+		}
+	}
+
+	if runs != 2 {
+		t.Fatalf("expected the migration to run 2 times, got %d", runs)
+	}
+}
+
 func documentationColumnDefault(db *gorm.DB) (sql.NullString, error) {
 	var columns []tableInfo
 	if err := db.Raw("PRAGMA table_info('module_versions')").Scan(&columns).Error; err != nil {
@@ -0,0 +1,114 @@
+package admission
+
+import (
+	"fmt"
+
+	"terralist/pkg/database/entity"
+	"terralist/pkg/docs"
+
+	"github.com/google/uuid"
+)
+
+// SemverEnforcement describes how the module versions introducing breaking
+// changes without a major version bump are handled.
+type SemverEnforcement string
+
+const (
+	SemverEnforcementOff    SemverEnforcement = "off"
+	SemverEnforcementWarn   SemverEnforcement = "warn"
+	SemverEnforcementReject SemverEnforcement = "reject"
+)
+
+// Policy holds the rules the module versions uploaded to an authority must
+// follow. The configuration of the modules is always validated, whatever
+// the policy.
+type Policy struct {
+	entity.Entity
+	AuthorityID uuid.UUID `gorm:"not null;uniqueIndex"`
+
+	// RequireReadme rejects modules without a README.md in the root module
+	RequireReadme bool `gorm:"not null;default:false"`
+
+	// RequireVariableDescriptions rejects modules declaring variables
+	// without a description
+	RequireVariableDescriptions bool `gorm:"not null;default:false"`
+
+	// RequireRequiredVersion rejects modules whose root module does not
+	// constrain the Terraform version
+	RequireRequiredVersion bool `gorm:"not null;default:false"`
+
+	// SemverEnforcement compares the interface of the uploaded modules with
+	// the one of the previous version of the same major line
+	SemverEnforcement SemverEnforcement `gorm:"not null;default:off"`
+}
+
+func (Policy) TableName() string {
+	return "admission_policies"
+}
+
+// ToRules returns the validation rules enforced by the policy.
+func (p Policy) ToRules() docs.ValidationRules {
+	return docs.ValidationRules{
+		RequireReadme:               p.RequireReadme,
+		RequireVariableDescriptions: p.RequireVariableDescriptions,
+		RequireRequiredVersion:      p.RequireRequiredVersion,
+	}
+}
+
+// GetSemverEnforcement returns how the breaking changes are handled.
+func (p Policy) GetSemverEnforcement() SemverEnforcement {
+	if p.SemverEnforcement == "" {
+		return SemverEnforcementOff
+	}
+
+	return p.SemverEnforcement
+}
+
+func (p Policy) ToDTO() PolicyDTO {
+	return PolicyDTO{
+		RequireReadme:               p.RequireReadme,
+		RequireVariableDescriptions: p.RequireVariableDescriptions,
+		RequireRequiredVersion:      p.RequireRequiredVersion,
+		SemverEnforcement:           p.GetSemverEnforcement(),
+	}
+}
+
+type PolicyDTO struct {
+	RequireReadme               bool              `json:"require_readme"`
+	RequireVariableDescriptions bool              `json:"require_variable_descriptions"`
+	RequireRequiredVersion      bool              `json:"require_required_version"`
+	SemverEnforcement           SemverEnforcement `json:"semver_enforcement"`
+}
+
+// RequiresArchive returns true if the policy can only be enforced by
+// inspecting the module archives.
+func (d PolicyDTO) RequiresArchive() bool {
+	return d.RequireReadme ||
+		d.RequireVariableDescriptions ||
+		d.RequireRequiredVersion ||
+		d.SemverEnforcement == SemverEnforcementReject
+}
+
+func (d PolicyDTO) Validate() error {
+	switch d.SemverEnforcement {
+	case "", SemverEnforcementOff, SemverEnforcementWarn, SemverEnforcementReject:
+		return nil
+	default:
+		return fmt.Errorf("unknown semver enforcement %q", d.SemverEnforcement)
+	}
+}
+
+func (d PolicyDTO) ToPolicy(authorityID uuid.UUID) Policy {
+	enforcement := d.SemverEnforcement
+	if enforcement == "" {
+		enforcement = SemverEnforcementOff
+	}
+
+	return Policy{
+		AuthorityID:                 authorityID,
+		RequireReadme:               d.RequireReadme,
+		RequireVariableDescriptions: d.RequireVariableDescriptions,
+		RequireRequiredVersion:      d.RequireRequiredVersion,
+		SemverEnforcement:           enforcement,
+	}
+}
@@ -5,9 +5,40 @@ const (
 	TypeProvider = "provider"
 )
 
+// Status is the lifecycle status of an artifact version.
+type Status string
+
+const (
+	// StatusActive is the status of a regular version.
+	StatusActive Status = "active"
+
+	// StatusDeprecated marks a version which is still listed, but which
+	// should no longer be used.
+	StatusDeprecated Status = "deprecated"
+
+	// StatusYanked marks a version which is no longer listed, but which can
+	// still be downloaded when it is pinned exactly.
+	StatusYanked Status = "yanked"
+)
+
+// VersionStatus describes the lifecycle status of an artifact version.
+type VersionStatus struct {
+	Status      Status `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// UpdateStatusDTO holds the details of a version status change.
+type UpdateStatusDTO struct {
+	Reason      string `json:"reason"`
+	Replacement string `json:"replacement"`
+}
+
 type Version struct {
 	Tag           string `json:"tag"`
 	Documentation string `json:"documentation"`
+	VersionStatus
+	Provenance *Provenance `json:"provenance,omitempty"`
 }
 
 type Artifact struct {
@@ -18,6 +49,11 @@ type Artifact struct {
 	Provider  string   `json:"provider"`
 	Type      string   `json:"type"`
 	Versions  []string `json:"versions"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	// Statuses holds the status of the versions which are not active
+	Statuses  map[string]VersionStatus `json:"statuses,omitempty"`
+	CreatedAt string                   `json:"created_at"`
+	UpdatedAt string                   `json:"updated_at"`
+	// Provenance holds who published the versions and from where, when it
+	// was recorded
+	Provenance map[string]Provenance `json:"provenance,omitempty"`
 }
@@ -0,0 +1,132 @@
+package artifact
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SourceUploadFiles is the source of the versions uploaded as files, rather
+// than downloaded from a URL.
+const SourceUploadFiles = "upload-files"
+
+var (
+	commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+	// scpLike matches the scp-like git sources (e.g. git@github.com:org/repo),
+	// which are not URLs
+	scpLike = regexp.MustCompile(`^(?:[^@/:]+@)?([^@/:]+):([^/].*)$`)
+)
+
+// Provenance records who published an artifact version and from where.
+type Provenance struct {
+	// PublishedBy is the name of the user who published the version
+	PublishedBy string `json:"published_by,omitempty"`
+
+	// PublisherEmail is the email of the user who published the version
+	PublisherEmail string `json:"publisher_email,omitempty"`
+
+	// AuthMethod is the way the publisher authenticated: session, cli_token
+	// or api_key
+	AuthMethod string `json:"auth_method,omitempty"`
+
+	// ApiKey is the ID of the API key the version was published with, if
+	// any, or "master" for the master API key
+	ApiKey string `json:"api_key,omitempty"`
+
+	// Source is the URL the version was downloaded from, without its
+	// credentials, or "upload-files" if its files were uploaded
+	Source string `json:"source,omitempty"`
+
+	// CommitSHA is the git commit the version was built from, when it can
+	// be derived from the source
+	CommitSHA string `json:"commit_sha,omitempty"`
+
+	ClientIP string `json:"client_ip,omitempty"`
+}
+
+// IsEmpty returns true if nothing is known about the provenance of a
+// version, such as for the versions published before it was recorded.
+func (p Provenance) IsEmpty() bool {
+	return p == Provenance{}
+}
+
+// SetSource records the URL a version was downloaded from. The credentials
+// and the query parameters of the URL are dropped, except for the git ref,
+// which is also used to derive the commit SHA.
+func (p *Provenance) SetSource(rawURL string) {
+	getter := ""
+	if i := strings.Index(rawURL, "::"); i >= 0 {
+		getter, rawURL = rawURL[:i+2], rawURL[i+2:]
+	}
+
+	// The scp-like sources are recorded as the ssh URLs they stand for
+	if m := scpLike.FindStringSubmatch(rawURL); m != nil {
+		rawURL = "ssh://" + m[1] + "/" + m[2]
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The URL is not recorded, since it may hold credentials
+		return
+	}
+
+	ref := u.Query().Get("ref")
+
+	u.User = nil
+	u.Fragment = ""
+	u.RawQuery = ""
+	if ref != "" {
+		u.RawQuery = url.Values{"ref": []string{ref}}.Encode()
+	}
+
+	p.Source = getter + u.String()
+
+	switch {
+	case commitSHA.MatchString(ref):
+		p.CommitSHA = ref
+	default:
+		// Archives of a commit, such as the ones downloaded from GitHub
+		// (e.g. /archive/<sha>.zip)
+		for _, segment := range strings.Split(u.Path, "/") {
+			segment = strings.TrimSuffix(segment, ".tar.gz")
+			segment = strings.TrimSuffix(segment, path.Ext(segment))
+
+			if commitSHA.MatchString(segment) {
+				p.CommitSHA = segment
+				break
+			}
+		}
+	}
+}
+
+// Matches returns true if the provenance satisfies a filter. Empty filter
+// fields match any value.
+func (p Provenance) Matches(f ProvenanceFilter) bool {
+	return (f.PublishedBy == "" || strings.EqualFold(p.PublishedBy, f.PublishedBy) || strings.EqualFold(p.PublisherEmail, f.PublishedBy)) &&
+		(f.AuthMethod == "" || p.AuthMethod == f.AuthMethod) &&
+		(f.ApiKey == "" || p.ApiKey == f.ApiKey) &&
+		(f.Source == "" || strings.Contains(p.Source, f.Source)) &&
+		(f.CommitSHA == "" || strings.HasPrefix(p.CommitSHA, strings.ToLower(f.CommitSHA)))
+}
+
+// ProvenanceFilter selects the versions by their provenance.
+type ProvenanceFilter struct {
+	// PublishedBy matches the name or the email of the publisher
+	PublishedBy string
+	AuthMethod  string
+	ApiKey      string
+
+	// Source matches the versions whose source contains the given value
+	Source string
+
+	// CommitSHA matches the versions whose commit SHA starts with the given
+	// value
+	CommitSHA string
+}
+
+// IsEmpty returns true if the filter matches all the versions.
+func (f ProvenanceFilter) IsEmpty() bool {
+	return f == ProvenanceFilter{}
+}
@@ -1,8 +1,11 @@
 package authority
 
 import (
+	"terralist/internal/server/models/admission"
 	"terralist/internal/server/models/module"
 	"terralist/internal/server/models/provider"
+	"terralist/internal/server/models/retention"
+	"terralist/internal/server/models/webhook"
 	"terralist/pkg/database/entity"
 
 	"github.com/samber/lo"
@@ -15,10 +18,16 @@ type Authority struct {
 	PolicyURL string              `gorm:"not null"`
 	Public    bool                `gorm:"not null;default:false"`
 	Owner     string              `gorm:"not null;index"`
+	Upstream  bool                `gorm:"not null;default:false"`
 	Keys      []Key               `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	ApiKeys   []ApiKey            `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Modules   []module.Module     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Providers []provider.Provider `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	SigningKeys          []SigningKey           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RetentionPolicies    []retention.Policy     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	AdmissionPolicy      *admission.Policy      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	WebhookRegistrations []webhook.Registration `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 func (Authority) TableName() string {
@@ -72,6 +81,9 @@ type AuthorityCreateDTO struct {
 	PolicyURL string `json:"policy_url"`
 	Public    bool   `json:"public"`
 	Owner     string `json:"owner"`
+
+	// Upstream is set on the authorities created by the upstream cache
+	Upstream bool `json:"-"`
 }
 
 func (d AuthorityCreateDTO) ToAuthority() Authority {
@@ -80,5 +92,6 @@ func (d AuthorityCreateDTO) ToAuthority() Authority {
 		PolicyURL: d.PolicyURL,
 		Public:    d.Public,
 		Owner:     d.Owner,
+		Upstream:  d.Upstream,
 	}
 }
@@ -1,6 +1,9 @@
 package authority
 
 import (
+	"time"
+
+	"terralist/internal/server/models/provider"
 	"terralist/pkg/database/entity"
 
 	"github.com/google/uuid"
@@ -12,17 +15,43 @@ type Key struct {
 	KeyId          string `gorm:"not null"`
 	AsciiArmor     string `gorm:"size:10000,not null"`
 	TrustSignature string `gorm:"size:10000,not null"`
+
+	// RevokedAt is the date from which the key is no longer trusted. The
+	// versions it signed after this date are flagged.
+	RevokedAt *time.Time
+
+	SignedVersions []provider.VersionKey `gorm:"foreignKey:KeyID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 func (Key) TableName() string {
 	return "authority_keys"
 }
 
+// IsRevoked returns true if the key is revoked. A key revoked from a future
+// date is trusted until then.
+func (k Key) IsRevoked() bool {
+	return k.RevokedAt != nil && !k.RevokedAt.After(time.Now())
+}
+
 type KeyDTO struct {
-	ID             string `json:"id"`
-	KeyId          string `json:"key_id"`
-	AsciiArmor     string `json:"ascii_armor"`
-	TrustSignature string `json:"trust_signature"`
+	ID             string     `json:"id"`
+	KeyId          string     `json:"key_id"`
+	AsciiArmor     string     `json:"ascii_armor"`
+	TrustSignature string     `json:"trust_signature"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RevokeKeyDTO describes the revocation of a key. The key is revoked from
+// the current date if no date is given.
+type RevokeKeyDTO struct {
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// RevokedKeyDTO describes a revoked key, along with the number of provider
+// versions flagged since it signed them after its revocation date.
+type RevokedKeyDTO struct {
+	KeyDTO
+	FlaggedVersions int64 `json:"flagged_versions"`
 }
 
 func (k Key) ToKeyDTO() KeyDTO {
@@ -31,6 +60,7 @@ func (k Key) ToKeyDTO() KeyDTO {
 		KeyId:          k.KeyId,
 		AsciiArmor:     k.AsciiArmor,
 		TrustSignature: k.TrustSignature,
+		RevokedAt:      k.RevokedAt,
 	}
 }
 
@@ -0,0 +1,35 @@
+package authority
+
+import (
+	"time"
+
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+)
+
+// SigningKey is the private half of an authority key managed by the
+// registry, used to sign the provider versions uploaded without a signature.
+// Its public half is published as a Key of the authority.
+type SigningKey struct {
+	entity.Entity
+	AuthorityID uuid.UUID `gorm:"not null;index"`
+	KeyId       string    `gorm:"not null"`
+
+	// PrivateKey is the sealed private key, it is erased once the key is
+	// retired
+	PrivateKey string `gorm:"size:20000"`
+	Active     bool   `gorm:"not null;default:false"`
+	RetiredAt  *time.Time
+}
+
+func (SigningKey) TableName() string {
+	return "authority_signing_keys"
+}
+
+// SigningKeyDTO describes the signing key to be set for an authority. If no
+// private key is given, a new one is generated by the registry.
+type SigningKeyDTO struct {
+	AsciiArmor string `json:"ascii_armor"`
+	Passphrase string `json:"passphrase"`
+}
@@ -0,0 +1,44 @@
+package blob
+
+import (
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+)
+
+// Blob is a content stored once in a resolver datastore, no matter how many
+// keys it was stored under.
+type Blob struct {
+	entity.Entity
+
+	// Namespace is the resolver datastore the blob is stored in
+	Namespace string `gorm:"not null;uniqueIndex:idx_blobs_namespace_digest"`
+
+	// Digest is the hex-encoded SHA-256 checksum of the content
+	Digest string `gorm:"not null;uniqueIndex:idx_blobs_namespace_digest"`
+
+	// Key is the key under which the content is stored in the datastore
+	Key  string `gorm:"not null"`
+	Size int64
+
+	// ReferenceCount is the number of keys referencing the blob
+	ReferenceCount int `gorm:"not null;default:0"`
+}
+
+func (Blob) TableName() string {
+	return "blobs"
+}
+
+// Reference binds a key, as returned to the callers of the resolver, to the
+// blob holding its content.
+type Reference struct {
+	entity.Entity
+	Namespace string    `gorm:"not null;uniqueIndex:idx_blob_references_namespace_key"`
+	Key       string    `gorm:"not null;uniqueIndex:idx_blob_references_namespace_key"`
+	BlobID    uuid.UUID `gorm:"not null;index"`
+	Blob      Blob      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (Reference) TableName() string {
+	return "blob_references"
+}
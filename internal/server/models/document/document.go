@@ -0,0 +1,25 @@
+package document
+
+import (
+	"terralist/pkg/database/entity"
+)
+
+// Document is a text file, such as the documentation of a module, stored in
+// the database when no resolver datastore is configured.
+type Document struct {
+	entity.Entity
+
+	// Key is the key under which the document would be stored in a resolver
+	// datastore
+	Key string `gorm:"not null;uniqueIndex"`
+
+	// Size is the size of the document, before compression
+	Size int64
+
+	// Content is the gzip-compressed content of the document
+	Content []byte
+}
+
+func (Document) TableName() string {
+	return "documents"
+}
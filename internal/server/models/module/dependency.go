@@ -6,18 +6,39 @@ import (
 	"github.com/google/uuid"
 )
 
+// Dependency is a module call declared by a module. It belongs either to a
+// module version (the root module) or to one of its submodules.
 type Dependency struct {
 	entity.Entity
-	ParentID uuid.UUID
+	VersionID   *uuid.UUID
+	SubmoduleID *uuid.UUID
+	Name        string `gorm:"not null"`
+	Source      string `gorm:"not null"`
+	Version     string `gorm:"not null"`
 }
 
 func (Dependency) TableName() string {
 	return "module_dependencies"
 }
 
+func (d Dependency) ToDTO() DependencyDTO {
+	return DependencyDTO{
+		Name:    d.Name,
+		Source:  d.Source,
+		Version: d.Version,
+	}
+}
+
 type DependencyDTO struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
 }
 
 func (d DependencyDTO) ToDependency() Dependency {
-	return Dependency{}
+	return Dependency{
+		Name:    d.Name,
+		Source:  d.Source,
+		Version: d.Version,
+	}
 }
@@ -0,0 +1,33 @@
+package module
+
+// ChangeDTO describes how an element of the module interface changed between
+// two versions.
+type ChangeDTO struct {
+	Name     string   `json:"name"`
+	Change   string   `json:"change"`
+	Breaking bool     `json:"breaking"`
+	Details  []string `json:"details,omitempty"`
+}
+
+// SubmoduleChangeDTO describes how a submodule changed between two versions.
+type SubmoduleChangeDTO struct {
+	ChangeDTO
+	Diff *InterfaceDiffDTO `json:"diff,omitempty"`
+}
+
+// InterfaceDiffDTO holds the changes of a module interface.
+type InterfaceDiffDTO struct {
+	Variables []ChangeDTO `json:"variables"`
+	Outputs   []ChangeDTO `json:"outputs"`
+	Providers []ChangeDTO `json:"providers"`
+}
+
+// DiffDTO holds the changes between the interfaces of two versions of a
+// module.
+type DiffDTO struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Breaking bool   `json:"breaking"`
+	InterfaceDiffDTO
+	Submodules []SubmoduleChangeDTO `json:"submodules"`
+}
@@ -0,0 +1,58 @@
+package module
+
+import (
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// Example is a usage sample shipped with a module version, under its
+// "examples" directory. Its documentation and sources are stored next to the
+// module documentation.
+type Example struct {
+	entity.Entity
+	VersionID uuid.UUID
+	Path      string        `gorm:"not null"`
+	Files     []ExampleFile `gorm:"foreignKey:ExampleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (Example) TableName() string {
+	return "module_examples"
+}
+
+func (e Example) ToDTO() ExampleDTO {
+	return ExampleDTO{
+		Path:  e.Path,
+		Files: lo.Map(e.Files, func(f ExampleFile, _ int) string { return f.Name }),
+	}
+}
+
+// ExampleFile is a Terraform source file of an example.
+type ExampleFile struct {
+	entity.Entity
+	ExampleID uuid.UUID
+	Name      string `gorm:"not null"`
+}
+
+func (ExampleFile) TableName() string {
+	return "module_example_files"
+}
+
+type ExampleDTO struct {
+	Path  string   `json:"path"`
+	Files []string `json:"files"`
+}
+
+// ExampleSourceDTO holds the content of an example source file.
+type ExampleSourceDTO struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// ExampleDetailsDTO holds the documentation and the sources of an example.
+type ExampleDetailsDTO struct {
+	Path          string             `json:"path"`
+	Documentation string             `json:"documentation"`
+	Files         []ExampleSourceDTO `json:"files"`
+}
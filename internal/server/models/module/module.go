@@ -2,6 +2,8 @@ package module
 
 import (
 	"fmt"
+	"slices"
+	"time"
 
 	"terralist/internal/server/models/artifact"
 	"terralist/pkg/database/entity"
@@ -31,6 +33,11 @@ func (m Module) ToListResponseDTO() ListResponseDTO {
 	module := ModuleDTO{}
 
 	for _, version := range m.Versions {
+		// Yanked versions are not listed, but can still be downloaded
+		if version.IsYanked() {
+			continue
+		}
+
 		v := VersionListDTO{
 			Version: version.Version,
 		}
@@ -49,15 +56,25 @@ func (m Module) ToArtifact() artifact.Artifact {
 		Name:     m.Name,
 		Provider: m.Provider,
 		Type:     artifact.TypeModule,
-		Versions: lo.Map(m.Versions, func(v Version, _ int) string {
+		Versions: lo.Map(sortedVersions(m.Versions), func(v Version, _ int) string {
 			return v.Version
 		}),
-		CreatedAt: m.CreatedAt.Format("2006-01-02T15:04:05"),
-		UpdatedAt: m.UpdatedAt.Format("2006-01-02T15:04:05"),
+		Statuses:   versionStatuses(m.Versions),
+		Provenance: versionProvenances(m.Versions),
+		CreatedAt:  m.CreatedAt.Format("2006-01-02T15:04:05"),
+		UpdatedAt:  m.UpdatedAt.Format("2006-01-02T15:04:05"),
 	}
 }
 
 func (m Module) GetVersion(v string) *Version {
+	// Prefer an exact match, since versions which only differ by their build
+	// metadata have the same precedence
+	for _, ver := range m.Versions {
+		if ver.Version == v {
+			return &ver
+		}
+	}
+
 	vv := version.Version(v)
 
 	for _, ver := range m.Versions {
@@ -69,6 +86,56 @@ func (m Module) GetVersion(v string) *Version {
 	return nil
 }
 
+// GetLatestVersion returns the highest stable version of the module.
+// If the module only has pre-release versions, the highest pre-release
+// version is returned instead. Yanked versions are never returned.
+func (m Module) GetLatestVersion() *Version {
+	var latest, latestPreRelease *Version
+
+	for i, ver := range m.Versions {
+		if ver.IsYanked() {
+			continue
+		}
+
+		vv := version.Version(ver.Version)
+
+		if vv.PreRelease() != "" {
+			if latestPreRelease == nil || version.Compare(vv, version.Version(latestPreRelease.Version)) > 0 {
+				latestPreRelease = &m.Versions[i]
+			}
+			continue
+		}
+
+		if latest == nil || version.Compare(vv, version.Version(latest.Version)) > 0 {
+			latest = &m.Versions[i]
+		}
+	}
+
+	if latest == nil {
+		return latestPreRelease
+	}
+
+	return latest
+}
+
+// ToListItemDTO maps the module and its latest version to the DTO
+// used by the registry listing endpoints.
+func (m Module) ToListItemDTO(namespace string) ListItemDTO {
+	dto := ListItemDTO{
+		Namespace: namespace,
+		Name:      m.Name,
+		Provider:  m.Provider,
+	}
+
+	if v := m.GetLatestVersion(); v != nil {
+		dto.ID = fmt.Sprintf("%s/%s/%s/%s", namespace, m.Name, m.Provider, v.Version)
+		dto.Version = v.Version
+		dto.PublishedAt = v.CreatedAt.Format(time.RFC3339)
+	}
+
+	return dto
+}
+
 type ListResponseDTO struct {
 	Modules []ModuleDTO `json:"modules"`
 }
@@ -77,11 +144,68 @@ type ModuleDTO struct {
 	Versions []VersionListDTO `json:"versions"`
 }
 
+// ListFilter holds the criteria used to filter modules when listing or
+// searching the registry.
+type ListFilter struct {
+	Namespace string
+	Provider  string
+	Query     string
+
+	// Offset and Limit select a page of the matching modules, ordered by
+	// namespace, name and provider. A page only holds the modules with an
+	// installable version, while a zero limit returns all of them, yanked
+	// versions included.
+	Offset int
+	Limit  int
+}
+
+// ListItemDTO describes a module (at its latest version) as returned by
+// the registry listing and search endpoints.
+type ListItemDTO struct {
+	ID          string `json:"id"`
+	Owner       string `json:"owner"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Provider    string `json:"provider"`
+	Description string `json:"description"`
+	Source      string `json:"source"`
+	PublishedAt string `json:"published_at"`
+	Downloads   int    `json:"downloads"`
+	Verified    bool   `json:"verified"`
+}
+
+// ListMetaDTO holds the pagination details of a listing response.
+type ListMetaDTO struct {
+	Limit         int  `json:"limit"`
+	CurrentOffset int  `json:"current_offset"`
+	NextOffset    *int `json:"next_offset,omitempty"`
+	PrevOffset    *int `json:"prev_offset,omitempty"`
+}
+
+// SearchResponseDTO is the paginated response of the registry listing
+// and search endpoints.
+type SearchResponseDTO struct {
+	Meta    ListMetaDTO   `json:"meta"`
+	Modules []ListItemDTO `json:"modules"`
+}
+
+// LatestDTO describes the latest version of a module, together with all
+// the versions available for it.
+type LatestDTO struct {
+	ListItemDTO
+	Versions   []string               `json:"versions"`
+	Submodules []SubmoduleResponseDTO `json:"submodules"`
+}
+
 type CreateDTO struct {
 	VersionCreateDTO
 	AuthorityID uuid.UUID
 	Name        string `json:"name"`
 	Provider    string `json:"provider"`
+
+	// Provenance is recorded by the registry, it cannot be set by the clients
+	Provenance artifact.Provenance `json:"-"`
 }
 
 type CreateFromURLDTO struct {
@@ -90,47 +214,63 @@ type CreateFromURLDTO struct {
 }
 
 func (d CreateDTO) ToModule() Module {
-	var providers []Provider
-	for _, p := range d.Root.Providers {
-		providers = append(providers, p.ToProvider())
-	}
+	variables, outputs, providers, dependencies := d.Root.toEntities()
 
-	var dependencies []Dependency
-	for _, dep := range d.Root.Dependencies {
-		dependencies = append(dependencies, dep.ToDependency())
-	}
-
-	out := Module{
+	return Module{
 		AuthorityID: d.AuthorityID,
 		Name:        d.Name,
 		Provider:    d.Provider,
 		Versions: []Version{
 			{
 				Version:      d.Version,
+				Variables:    variables,
+				Outputs:      outputs,
 				Providers:    providers,
 				Dependencies: dependencies,
+				Submodules:   lo.Map(d.Submodules, func(s SubmoduleDTO, _ int) Submodule { return s.ToSubmodule() }),
+				Provenance:   d.Provenance,
 			},
 		},
 	}
+}
 
-	for _, submodule := range d.Submodules {
-		var submoduleProviders []Provider
-		for _, p := range submodule.Providers {
-			providers = append(providers, p.ToProvider())
-		}
+// sortedVersions returns a copy of the given versions, sorted in ascending
+// order of precedence.
+func sortedVersions(versions []Version) []Version {
+	out := slices.Clone(versions)
+	version.Sort(out, func(v Version) version.Version { return version.Version(v.Version) })
 
-		var submoduleDependencies []Dependency
-		for _, dep := range submodule.Dependencies {
-			dependencies = append(dependencies, dep.ToDependency())
+	return out
+}
+
+// versionStatuses returns the status of the versions which are not active.
+func versionStatuses(versions []Version) map[string]artifact.VersionStatus {
+	out := map[string]artifact.VersionStatus{}
+	for _, v := range versions {
+		if status := v.GetStatus(); status.Status != artifact.StatusActive {
+			out[v.Version] = status
 		}
+	}
 
-		s := Submodule{
-			Path:         submodule.Path,
-			Providers:    submoduleProviders,
-			Dependencies: submoduleDependencies,
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// versionProvenances returns the provenance of the versions for which it was
+// recorded.
+func versionProvenances(versions []Version) map[string]artifact.Provenance {
+	out := map[string]artifact.Provenance{}
+	for _, v := range versions {
+		if !v.Provenance.IsEmpty() {
+			out[v.Version] = v.Provenance
 		}
+	}
 
-		out.Versions[0].Submodules = append(out.Versions[0].Submodules, s)
+	if len(out) == 0 {
+		return nil
 	}
 
 	return out
@@ -0,0 +1,44 @@
+package module
+
+import (
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+)
+
+// Output is an output value declared by a module. It belongs either to a
+// module version (the root module) or to one of its submodules.
+type Output struct {
+	entity.Entity
+	VersionID   *uuid.UUID
+	SubmoduleID *uuid.UUID
+	Name        string `gorm:"not null"`
+	Description string
+	Sensitive   bool
+}
+
+func (Output) TableName() string {
+	return "module_outputs"
+}
+
+func (o Output) ToDTO() OutputDTO {
+	return OutputDTO{
+		Name:        o.Name,
+		Description: o.Description,
+		Sensitive:   o.Sensitive,
+	}
+}
+
+type OutputDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Sensitive   bool   `json:"sensitive"`
+}
+
+func (d OutputDTO) ToOutput() Output {
+	return Output{
+		Name:        d.Name,
+		Description: d.Description,
+		Sensitive:   d.Sensitive,
+	}
+}
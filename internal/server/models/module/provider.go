@@ -6,19 +6,32 @@ import (
 	"github.com/google/uuid"
 )
 
+// Provider is a provider required by a module, as declared in its
+// required_providers block. It belongs either to a module version (the root
+// module) or to one of its submodules.
 type Provider struct {
 	entity.Entity
-	ParentID  uuid.UUID
-	Name      string `gorm:"not null"`
-	Namespace string `gorm:"not null"`
-	Source    string `gorm:"not null"`
-	Version   string `gorm:"not null"`
+	VersionID   *uuid.UUID
+	SubmoduleID *uuid.UUID
+	Name        string `gorm:"not null"`
+	Namespace   string `gorm:"not null"`
+	Source      string `gorm:"not null"`
+	Version     string `gorm:"not null"`
 }
 
 func (Provider) TableName() string {
 	return "module_providers"
 }
 
+func (p Provider) ToDTO() ProviderDTO {
+	return ProviderDTO{
+		Name:      p.Name,
+		Namespace: p.Namespace,
+		Source:    p.Source,
+		Version:   p.Version,
+	}
+}
+
 type ProviderDTO struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
@@ -10,8 +10,10 @@ type Submodule struct {
 	entity.Entity
 	VersionID    uuid.UUID
 	Path         string       `gorm:"not null"`
-	Providers    []Provider   `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Dependencies []Dependency `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Variables    []Variable   `gorm:"foreignKey:SubmoduleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Outputs      []Output     `gorm:"foreignKey:SubmoduleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Providers    []Provider   `gorm:"foreignKey:SubmoduleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Dependencies []Dependency `gorm:"foreignKey:SubmoduleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 func (Submodule) TableName() string {
@@ -20,16 +22,29 @@ func (Submodule) TableName() string {
 
 func (s Submodule) ToDTO() SubmoduleResponseDTO {
 	return SubmoduleResponseDTO{
-		Path: s.Path,
+		Path:    s.Path,
+		RootDTO: newRootDTO(s.Variables, s.Outputs, s.Providers, s.Dependencies),
 	}
 }
 
 type SubmoduleDTO struct {
-	Path         string          `json:"path"`
-	Providers    []ProviderDTO   `json:"providers"`
-	Dependencies []DependencyDTO `json:"dependencies"`
+	Path string `json:"path"`
+	RootDTO
+}
+
+func (d SubmoduleDTO) ToSubmodule() Submodule {
+	variables, outputs, providers, dependencies := d.RootDTO.toEntities()
+
+	return Submodule{
+		Path:         d.Path,
+		Variables:    variables,
+		Outputs:      outputs,
+		Providers:    providers,
+		Dependencies: dependencies,
+	}
 }
 
 type SubmoduleResponseDTO struct {
 	Path string `json:"path"`
+	RootDTO
 }
@@ -0,0 +1,70 @@
+package module
+
+import (
+	"encoding/json"
+
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+)
+
+// Variable is an input variable declared by a module. It belongs either to a
+// module version (the root module) or to one of its submodules.
+type Variable struct {
+	entity.Entity
+	VersionID   *uuid.UUID
+	SubmoduleID *uuid.UUID
+	Name        string `gorm:"not null"`
+	Type        string
+	Description string
+	// Default holds the JSON encoded default value
+	Default   *string
+	Required  bool
+	Sensitive bool
+}
+
+func (Variable) TableName() string {
+	return "module_variables"
+}
+
+func (v Variable) ToDTO() VariableDTO {
+	var def json.RawMessage
+	if v.Default != nil {
+		def = json.RawMessage(*v.Default)
+	}
+
+	return VariableDTO{
+		Name:        v.Name,
+		Type:        v.Type,
+		Description: v.Description,
+		Default:     def,
+		Required:    v.Required,
+		Sensitive:   v.Sensitive,
+	}
+}
+
+type VariableDTO struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+	Required    bool            `json:"required"`
+	Sensitive   bool            `json:"sensitive"`
+}
+
+func (d VariableDTO) ToVariable() Variable {
+	var def *string
+	if len(d.Default) > 0 {
+		s := string(d.Default)
+		def = &s
+	}
+
+	return Variable{
+		Name:        d.Name,
+		Type:        d.Type,
+		Description: d.Description,
+		Default:     def,
+		Required:    d.Required,
+		Sensitive:   d.Sensitive,
+	}
+}
@@ -0,0 +1,24 @@
+package module
+
+// MismatchDTO describes a module version whose stored archive does not
+// match the checksum recorded at upload.
+type MismatchDTO struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Version  string `json:"version"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VerificationReportDTO describes the outcome of verifying the stored
+// archives of the modules of an authority.
+type VerificationReportDTO struct {
+	// Verified is the number of archives matching their checksum
+	Verified int `json:"verified"`
+
+	// Unverified is the number of versions uploaded without a checksum
+	Unverified int `json:"unverified"`
+
+	Mismatches []MismatchDTO `json:"mismatches"`
+}
@@ -1,28 +1,66 @@
 package module
 
 import (
+	"time"
+
 	"terralist/internal/server/models/artifact"
 	"terralist/pkg/database/entity"
 
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 )
 
 type Version struct {
 	entity.Entity
-	ModuleID      uuid.UUID
-	Module        Module
-	Version       string `gorm:"not null"`
-	Location      string `gorm:"not null"`
-	Documentation *string
-	Providers     []Provider   `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Dependencies  []Dependency `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Submodules    []Submodule  `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ModuleID         uuid.UUID
+	Module           Module
+	Version          string `gorm:"not null"`
+	Location         string `gorm:"not null"`
+	Checksum         string
+	Documentation    *string
+	Status           artifact.Status `gorm:"not null;default:active"`
+	StatusReason     string
+	Replacement      string
+	LastDownloadedAt *time.Time
+	Provenance       artifact.Provenance `gorm:"embedded;embeddedPrefix:provenance_"`
+	Variables        []Variable          `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Outputs          []Output            `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Providers        []Provider          `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Dependencies     []Dependency        `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Submodules       []Submodule         `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Examples         []Example           `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 func (Version) TableName() string {
 	return "module_versions"
 }
 
+// GetStatus returns the lifecycle status of the version.
+func (v Version) GetStatus() artifact.VersionStatus {
+	status := v.Status
+	if status == "" {
+		status = artifact.StatusActive
+	}
+
+	return artifact.VersionStatus{
+		Status:      status,
+		Reason:      v.StatusReason,
+		Replacement: v.Replacement,
+	}
+}
+
+// SetStatus updates the lifecycle status of the version.
+func (v *Version) SetStatus(status artifact.Status, d artifact.UpdateStatusDTO) {
+	v.Status = status
+	v.StatusReason = d.Reason
+	v.Replacement = d.Replacement
+}
+
+// IsYanked returns true if the version was yanked.
+func (v Version) IsYanked() bool {
+	return v.Status == artifact.StatusYanked
+}
+
 func (v Version) ToDTO() VersionDTO {
 	var submodulesDTO []SubmoduleResponseDTO
 	for _, sm := range v.Submodules {
@@ -35,22 +73,63 @@ func (v Version) ToDTO() VersionDTO {
 		doc = &s
 	}
 
+	var provenance *artifact.Provenance
+	if !v.Provenance.IsEmpty() {
+		p := v.Provenance
+		provenance = &p
+	}
+
+	root := newRootDTO(v.Variables, v.Outputs, v.Providers, v.Dependencies)
+
 	return VersionDTO{
 		Version:       v.Version,
+		Checksum:      v.Checksum,
 		Documentation: doc,
+		VersionStatus: v.GetStatus(),
+		Provenance:    provenance,
+		Root:          &root,
 		Submodules:    submodulesDTO,
 	}
 }
 
+// RootDTO describes the interface of a module: its inputs, outputs, required
+// providers and module calls.
 type RootDTO struct {
+	Inputs       []VariableDTO   `json:"inputs"`
+	Outputs      []OutputDTO     `json:"outputs"`
 	Providers    []ProviderDTO   `json:"providers"`
 	Dependencies []DependencyDTO `json:"dependencies"`
 }
 
+func newRootDTO(variables []Variable, outputs []Output, providers []Provider, dependencies []Dependency) RootDTO {
+	return RootDTO{
+		Inputs:       lo.Map(variables, func(v Variable, _ int) VariableDTO { return v.ToDTO() }),
+		Outputs:      lo.Map(outputs, func(o Output, _ int) OutputDTO { return o.ToDTO() }),
+		Providers:    lo.Map(providers, func(p Provider, _ int) ProviderDTO { return p.ToDTO() }),
+		Dependencies: lo.Map(dependencies, func(d Dependency, _ int) DependencyDTO { return d.ToDTO() }),
+	}
+}
+
+func (d RootDTO) toEntities() ([]Variable, []Output, []Provider, []Dependency) {
+	return lo.Map(d.Inputs, func(v VariableDTO, _ int) Variable { return v.ToVariable() }),
+		lo.Map(d.Outputs, func(o OutputDTO, _ int) Output { return o.ToOutput() }),
+		lo.Map(d.Providers, func(p ProviderDTO, _ int) Provider { return p.ToProvider() }),
+		lo.Map(d.Dependencies, func(dep DependencyDTO, _ int) Dependency { return dep.ToDependency() })
+}
+
+// SetInterface replaces the interface of the version's root module.
+func (v *Version) SetInterface(d RootDTO) {
+	v.Variables, v.Outputs, v.Providers, v.Dependencies = d.toEntities()
+}
+
 type VersionDTO struct {
-	Version       string                 `json:"version"`
-	Documentation *string                `json:"documentation,omitempty"`
-	Submodules    []SubmoduleResponseDTO `json:"submodules,omitempty"`
+	Version       string  `json:"version"`
+	Checksum      string  `json:"checksum,omitempty"`
+	Documentation *string `json:"documentation,omitempty"`
+	artifact.VersionStatus
+	Provenance *artifact.Provenance   `json:"provenance,omitempty"`
+	Root       *RootDTO               `json:"root,omitempty"`
+	Submodules []SubmoduleResponseDTO `json:"submodules,omitempty"`
 }
 
 func (v VersionDTO) ToArtifactVersion() artifact.Version {
@@ -61,9 +140,20 @@ func (v VersionDTO) ToArtifactVersion() artifact.Version {
 	return artifact.Version{
 		Tag:           v.Version,
 		Documentation: doc,
+		VersionStatus: v.VersionStatus,
+		Provenance:    v.Provenance,
 	}
 }
 
+// VersionLocation holds where the archive of a module version is stored.
+type VersionLocation struct {
+	Location string
+
+	// Checksum is the SHA-256 checksum of the archive, empty if the archive
+	// is not stored by Terralist
+	Checksum string
+}
+
 type VersionCreateDTO struct {
 	Version    string         `json:"version"`
 	Root       RootDTO        `json:"root,omitempty"`
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+)
+
+// Doc is a page of the documentation shipped with a provider version, such
+// as the overview or the page of a resource. Its content is stored next to
+// the provider files.
+type Doc struct {
+	entity.Entity
+	VersionID   uuid.UUID
+	Path        string `gorm:"not null"`
+	Category    string `gorm:"not null"`
+	Title       string
+	Subcategory string
+	Description string
+}
+
+func (Doc) TableName() string {
+	return "provider_docs"
+}
+
+func (d Doc) ToDTO() DocDTO {
+	return DocDTO{
+		Path:        d.Path,
+		Category:    d.Category,
+		Title:       d.Title,
+		Subcategory: d.Subcategory,
+		Description: d.Description,
+	}
+}
+
+type DocDTO struct {
+	Path        string `json:"path"`
+	Category    string `json:"category"`
+	Title       string `json:"title"`
+	Subcategory string `json:"subcategory,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// DocDetailsDTO holds the metadata and the content of a documentation page.
+type DocDetailsDTO struct {
+	DocDTO
+	Content string `json:"content"`
+}
@@ -16,6 +16,11 @@ type Platform struct {
 	Architecture string `gorm:"not null"`
 	Location     string `gorm:"not null"`
 	ShaSum       string `gorm:"not null"`
+
+	// Hash is the h1: hash of the package content, the one recorded by
+	// Terraform in the dependency lock files. It is only known for the
+	// packages stored by the registry.
+	Hash string
 }
 
 func (Platform) TableName() string {
@@ -33,6 +38,24 @@ func (p Platform) ToVersionListPlatformDTO() VersionListPlatformDTO {
 	}
 }
 
+// Hashes returns the hashes of the package advertised to the network
+// mirror clients.
+func (p Platform) Hashes() []string {
+	hashes := []string{}
+	if p.Hash != "" {
+		hashes = append(hashes, p.Hash)
+	}
+
+	return append(hashes, fmt.Sprintf("zh:%s", strings.ToLower(p.ShaSum)))
+}
+
+func (p Platform) ToMirrorArchiveDTO() MirrorArchiveDTO {
+	return MirrorArchiveDTO{
+		URL:    p.Location,
+		Hashes: p.Hashes(),
+	}
+}
+
 type CreatePlatformDTO struct {
 	System       string `json:"os"`
 	Architecture string `json:"arch"`
@@ -99,3 +122,10 @@ type PublicKeyDTO struct {
 	Source         string `json:"string"`
 	SourceURL      string `json:"source_url"`
 }
+
+// MirrorArchiveDTO describes a package in the provider network mirror
+// protocol.
+type MirrorArchiveDTO struct {
+	URL    string   `json:"url"`
+	Hashes []string `json:"hashes"`
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"slices"
 	"strings"
 
 	"terralist/internal/server/models/artifact"
@@ -24,16 +25,51 @@ func (Provider) TableName() string {
 
 func (p Provider) ToVersionListProviderDTO() VersionListProviderDTO {
 	var versions []VersionListVersionDTO
+	var warnings []string
 	for _, v := range p.Versions {
+		// Yanked versions are not listed, but can still be downloaded
+		if v.IsYanked() {
+			continue
+		}
+
 		versions = append(versions, v.ToVersionListVersionDTO())
+
+		if warning := v.Warning(); warning != "" {
+			warnings = append(warnings, warning)
+		}
 	}
 
 	return VersionListProviderDTO{
 		Versions: versions,
+		Warnings: warnings,
+	}
+}
+
+func (p Provider) ToMirrorVersionsDTO() MirrorVersionsDTO {
+	versions := map[string]struct{}{}
+	for _, v := range p.Versions {
+		// Yanked versions are not listed, but can still be downloaded
+		if v.IsYanked() {
+			continue
+		}
+
+		versions[v.Version] = struct{}{}
+	}
+
+	return MirrorVersionsDTO{
+		Versions: versions,
 	}
 }
 
 func (p Provider) GetVersion(v string) *Version {
+	// Prefer an exact match, since versions which only differ by their build
+	// metadata have the same precedence
+	for _, ver := range p.Versions {
+		if ver.Version == v {
+			return &ver
+		}
+	}
+
 	vv := version.Version(v)
 
 	for _, ver := range p.Versions {
@@ -50,14 +86,22 @@ func (p Provider) ToArtifact() artifact.Artifact {
 		ID:   p.ID.String(),
 		Name: p.Name,
 		Type: artifact.TypeProvider,
-		Versions: lo.Map(p.Versions, func(v Version, _ int) string {
+		Versions: lo.Map(sortedVersions(p.Versions), func(v Version, _ int) string {
 			return v.Version
 		}),
-		CreatedAt: p.CreatedAt.Format("2006-01-02T15:04:05"),
-		UpdatedAt: p.UpdatedAt.Format("2006-01-02T15:04:05"),
+		Statuses:   versionStatuses(p.Versions),
+		Provenance: versionProvenances(p.Versions),
+		CreatedAt:  p.CreatedAt.Format("2006-01-02T15:04:05"),
+		UpdatedAt:  p.UpdatedAt.Format("2006-01-02T15:04:05"),
 	}
 }
 
+// MirrorVersionsDTO lists the versions of a provider in the provider network
+// mirror protocol.
+type MirrorVersionsDTO struct {
+	Versions map[string]struct{} `json:"versions"`
+}
+
 type CreateProviderDTO struct {
 	AuthorityID uuid.UUID
 	Name        string
@@ -66,6 +110,13 @@ type CreateProviderDTO struct {
 	Protocols   []string                 `json:"protocols"`
 	Platforms   []CreatePlatformDTO      `json:"platforms"`
 	Headers     map[string]string        `json:"headers,omitempty"`
+
+	// DocsURL is the location of an archive of the documentation generated
+	// by tfplugindocs, optional
+	DocsURL string `json:"docs_url,omitempty"`
+
+	// Provenance is recorded by the registry, it cannot be set by the clients
+	Provenance artifact.Provenance `json:"-"`
 }
 
 func (d CreateProviderDTO) ToProvider() Provider {
@@ -84,6 +135,7 @@ func (d CreateProviderDTO) ToProvider() Provider {
 				ShaSumsSignatureUrl: d.ShaSums.SignatureURL,
 				Protocols:           strings.Join(d.Protocols, ","),
 				Platforms:           platforms,
+				Provenance:          d.Provenance,
 			},
 		},
 	}
@@ -94,6 +146,76 @@ type CreateProviderShaSumsDTO struct {
 	SignatureURL string `json:"signature_url"`
 }
 
+// ImportProviderDTO describes a provider version to be imported from its
+// GoReleaser release, either from the URL where the release files are
+// published or from an archive of the dist directory.
+type ImportProviderDTO struct {
+	AuthorityID uuid.UUID
+	Name        string
+	Version     string
+	URL         string            `json:"url"`
+	ArchiveURL  string            `json:"archive_url"`
+	DocsURL     string            `json:"docs_url,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+
+	// Provenance is recorded by the registry, it cannot be set by the clients
+	Provenance artifact.Provenance `json:"-"`
+}
+
+func (d ImportProviderDTO) ToCreateProviderDTO() CreateProviderDTO {
+	return CreateProviderDTO{
+		AuthorityID: d.AuthorityID,
+		Name:        d.Name,
+		Version:     d.Version,
+		Headers:     d.Headers,
+		DocsURL:     d.DocsURL,
+		Provenance:  d.Provenance,
+	}
+}
+
 type VersionListProviderDTO struct {
 	Versions []VersionListVersionDTO `json:"versions"`
+	Warnings []string                `json:"warnings,omitempty"`
+}
+
+// sortedVersions returns a copy of the given versions, sorted in ascending
+// order of precedence.
+func sortedVersions(versions []Version) []Version {
+	out := slices.Clone(versions)
+	version.Sort(out, func(v Version) version.Version { return version.Version(v.Version) })
+
+	return out
+}
+
+// versionStatuses returns the status of the versions which are not active.
+func versionStatuses(versions []Version) map[string]artifact.VersionStatus {
+	out := map[string]artifact.VersionStatus{}
+	for _, v := range versions {
+		if status := v.GetStatus(); status.Status != artifact.StatusActive {
+			out[v.Version] = status
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// versionProvenances returns the provenance of the versions for which it was
+// recorded.
+func versionProvenances(versions []Version) map[string]artifact.Provenance {
+	out := map[string]artifact.Provenance{}
+	for _, v := range versions {
+		if !v.Provenance.IsEmpty() {
+			out[v.Version] = v.Provenance
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
 }
@@ -1,7 +1,10 @@
 package provider
 
 import (
+	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"terralist/internal/server/models/artifact"
 	"terralist/pkg/database/entity"
@@ -13,17 +16,103 @@ type Version struct {
 	entity.Entity
 	ProviderID          uuid.UUID
 	Provider            Provider
-	Version             string     `gorm:"not null"`
-	Protocols           string     `gorm:"not null"`
-	ShaSumsUrl          string     `gorm:"shasums_url"`
-	ShaSumsSignatureUrl string     `gorm:"shasums_signature_url"`
-	Platforms           []Platform `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Version             string `gorm:"not null"`
+	Protocols           string `gorm:"not null"`
+	ShaSumsUrl          string `gorm:"shasums_url"`
+	ShaSumsSignatureUrl string `gorm:"shasums_signature_url"`
+	SigningKeyID        string
+	Status              artifact.Status `gorm:"not null;default:active"`
+	StatusReason        string
+	Replacement         string
+	LastDownloadedAt    *time.Time
+	Provenance          artifact.Provenance `gorm:"embedded;embeddedPrefix:provenance_"`
+	Platforms           []Platform          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Docs                []Doc               `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	SigningKeys         []VersionKey        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	// SignatureRevoked is set if the key which signed the version was
+	// revoked before the version was signed
+	SignatureRevoked bool `gorm:"not null;default:false"`
 }
 
 func (Version) TableName() string {
 	return "provider_versions"
 }
 
+// GetStatus returns the lifecycle status of the version.
+func (v Version) GetStatus() artifact.VersionStatus {
+	status := v.Status
+	if status == "" {
+		status = artifact.StatusActive
+	}
+
+	return artifact.VersionStatus{
+		Status:      status,
+		Reason:      v.StatusReason,
+		Replacement: v.Replacement,
+	}
+}
+
+// SetStatus updates the lifecycle status of the version.
+func (v *Version) SetStatus(status artifact.Status, d artifact.UpdateStatusDTO) {
+	v.Status = status
+	v.StatusReason = d.Reason
+	v.Replacement = d.Replacement
+}
+
+// IsYanked returns true if the version was yanked.
+func (v Version) IsYanked() bool {
+	return v.Status == artifact.StatusYanked
+}
+
+// Warning returns a warning message to be displayed by Terraform if the
+// version is deprecated or signed with a revoked key, or an empty string
+// otherwise.
+func (v Version) Warning() string {
+	if v.Status != artifact.StatusDeprecated {
+		return v.revocationWarning()
+	}
+
+	warning := fmt.Sprintf("Version %s is deprecated", v.Version)
+	if v.StatusReason != "" {
+		warning += fmt.Sprintf(": %s", strings.TrimSuffix(v.StatusReason, "."))
+	}
+	warning += "."
+
+	if v.Replacement != "" {
+		warning += fmt.Sprintf(" Use version %s instead.", v.Replacement)
+	}
+
+	if revocation := v.revocationWarning(); revocation != "" {
+		warning += " " + revocation
+	}
+
+	return warning
+}
+
+// revocationWarning returns a warning message if the version was signed
+// with a revoked key, or an empty string otherwise.
+func (v Version) revocationWarning() string {
+	if !v.SignatureRevoked {
+		return ""
+	}
+
+	return fmt.Sprintf("Version %s is signed with key %s, which was revoked before the version was signed.", v.Version, v.SigningKeyID)
+}
+
+// SignedWith links the version to the key which signed its SHA256SUMS file,
+// replacing any previous link. The revoked keys cannot sign new versions.
+func (v *Version) SignedWith(id uuid.UUID, keyId string, at time.Time) {
+	v.SigningKeyID = keyId
+	v.SignatureRevoked = false
+	v.SigningKeys = []VersionKey{{KeyID: id, SignedAt: at}}
+}
+
+// IsSignedBy returns true if the version is linked to a key.
+func (v Version) IsSignedBy(keyID uuid.UUID) bool {
+	return slices.ContainsFunc(v.SigningKeys, func(k VersionKey) bool { return k.KeyID == keyID })
+}
+
 func (v Version) ToVersionListVersionDTO() VersionListVersionDTO {
 	var platforms []VersionListPlatformDTO
 	for _, p := range v.Platforms {
@@ -38,8 +127,27 @@ func (v Version) ToVersionListVersionDTO() VersionListVersionDTO {
 }
 
 func (v Version) ToArtifactVersion() artifact.Version {
+	var provenance *artifact.Provenance
+	if !v.Provenance.IsEmpty() {
+		p := v.Provenance
+		provenance = &p
+	}
+
 	return artifact.Version{
-		Tag: v.Version,
+		Tag:           v.Version,
+		VersionStatus: v.GetStatus(),
+		Provenance:    provenance,
+	}
+}
+
+func (v Version) ToMirrorArchivesDTO() MirrorArchivesDTO {
+	archives := map[string]MirrorArchiveDTO{}
+	for _, p := range v.Platforms {
+		archives[p.String()] = p.ToMirrorArchiveDTO()
+	}
+
+	return MirrorArchivesDTO{
+		Archives: archives,
 	}
 }
 
@@ -48,3 +156,9 @@ type VersionListVersionDTO struct {
 	Protocols []string                 `json:"protocols"`
 	Platforms []VersionListPlatformDTO `json:"platforms"`
 }
+
+// MirrorArchivesDTO lists the packages of a version in the provider network
+// mirror protocol, by os_arch.
+type MirrorArchivesDTO struct {
+	Archives map[string]MirrorArchiveDTO `json:"archives"`
+}
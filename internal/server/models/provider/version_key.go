@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"time"
+
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+)
+
+// VersionKey links a provider version to the authority key which signed its
+// SHA256SUMS file. The key is only referenced by its ID, since the
+// authorities hold the providers.
+type VersionKey struct {
+	entity.Entity
+	VersionID uuid.UUID `gorm:"not null;uniqueIndex:idx_provider_version_keys_version_key"`
+	KeyID     uuid.UUID `gorm:"not null;uniqueIndex:idx_provider_version_keys_version_key;index"`
+	SignedAt  time.Time `gorm:"not null"`
+}
+
+func (VersionKey) TableName() string {
+	return "provider_version_keys"
+}
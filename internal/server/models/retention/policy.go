@@ -0,0 +1,234 @@
+package retention
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"terralist/internal/server/models/artifact"
+	"terralist/pkg/database/entity"
+	"terralist/pkg/version"
+
+	"github.com/google/uuid"
+)
+
+// Policy holds the retention rules of an authority. A policy without an
+// artifact type and name is the default policy of the authority, while
+// the others override it for a specific module or provider.
+//
+// Rules set to nil are inherited from the authority default policy, and
+// rules set to zero are disabled.
+type Policy struct {
+	entity.Entity
+	AuthorityID  uuid.UUID `gorm:"not null;uniqueIndex:idx_retention_policies_target"`
+	ArtifactType string    `gorm:"not null;default:'';uniqueIndex:idx_retention_policies_target"`
+	ArtifactName string    `gorm:"not null;default:'';uniqueIndex:idx_retention_policies_target"`
+
+	// KeepLastStable is the number of most recent stable versions to keep
+	KeepLastStable *int
+
+	// PreReleaseMaxAgeDays is the number of days after which pre-release
+	// versions are removed
+	PreReleaseMaxAgeDays *int
+
+	// KeepDownloadedDays protects versions which were downloaded in the
+	// last number of days from being removed
+	KeepDownloadedDays *int
+}
+
+func (Policy) TableName() string {
+	return "retention_policies"
+}
+
+// IsDefault returns true if the policy applies to every artifact of the
+// authority.
+func (p Policy) IsDefault() bool {
+	return p.ArtifactType == "" && p.ArtifactName == ""
+}
+
+// Merge returns the policy which results from overriding the rules of p
+// with the rules set in the given override.
+func (p Policy) Merge(override Policy) Policy {
+	out := p
+	out.ArtifactType = override.ArtifactType
+	out.ArtifactName = override.ArtifactName
+
+	if override.KeepLastStable != nil {
+		out.KeepLastStable = override.KeepLastStable
+	}
+
+	if override.PreReleaseMaxAgeDays != nil {
+		out.PreReleaseMaxAgeDays = override.PreReleaseMaxAgeDays
+	}
+
+	if override.KeepDownloadedDays != nil {
+		out.KeepDownloadedDays = override.KeepDownloadedDays
+	}
+
+	return out
+}
+
+// Candidate is an artifact version evaluated by a retention policy.
+type Candidate struct {
+	Version          string
+	CreatedAt        time.Time
+	LastDownloadedAt *time.Time
+
+	// Yanked versions cannot be installed, so they do not count as one of
+	// the stable versions to keep
+	Yanked bool
+}
+
+// Removal is a version which should be removed according to a retention
+// policy.
+type Removal struct {
+	Version string
+	Reason  string
+}
+
+// Evaluate returns the versions which should be removed from an artifact
+// according to the policy. Versions which are not valid semantic versions
+// are never removed.
+func (p Policy) Evaluate(candidates []Candidate, now time.Time) []Removal {
+	var removals []Removal
+
+	sorted := slices.Clone(candidates)
+	version.Sort(sorted, func(c Candidate) version.Version { return version.Version(c.Version) })
+
+	// Walk the versions from the most recent one, so the number of stable
+	// versions already seen can be counted
+	stableSeen := 0
+	for i := len(sorted) - 1; i >= 0; i-- {
+		c := sorted[i]
+		v := version.Version(c.Version)
+		if !v.Valid() {
+			continue
+		}
+
+		var reason string
+		if v.PreRelease() == "" {
+			if !c.Yanked {
+				stableSeen++
+			}
+
+			// A yanked version is removed once the stable versions to keep
+			// were all seen
+			if keep := value(p.KeepLastStable); keep > 0 && (stableSeen > keep || c.Yanked && stableSeen == keep) {
+				reason = fmt.Sprintf("not one of the last %d stable versions", keep)
+			}
+		} else if days := value(p.PreReleaseMaxAgeDays); days > 0 && c.CreatedAt.Before(now.AddDate(0, 0, -days)) {
+			reason = fmt.Sprintf("pre-release older than %d days", days)
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		if days := value(p.KeepDownloadedDays); days > 0 && c.LastDownloadedAt != nil &&
+			c.LastDownloadedAt.After(now.AddDate(0, 0, -days)) {
+			continue
+		}
+
+		removals = append(removals, Removal{
+			Version: c.Version,
+			Reason:  reason,
+		})
+	}
+
+	return removals
+}
+
+func (p Policy) ToDTO() PolicyDTO {
+	return PolicyDTO{
+		ID:                   p.ID.String(),
+		ArtifactType:         p.ArtifactType,
+		ArtifactName:         p.ArtifactName,
+		KeepLastStable:       p.KeepLastStable,
+		PreReleaseMaxAgeDays: p.PreReleaseMaxAgeDays,
+		KeepDownloadedDays:   p.KeepDownloadedDays,
+	}
+}
+
+// PolicyDTO describes a retention policy. The artifact type and name are
+// empty for the default policy of an authority. Module names are in the
+// NAME/PROVIDER format.
+type PolicyDTO struct {
+	ID                   string `json:"id"`
+	ArtifactType         string `json:"artifact_type,omitempty"`
+	ArtifactName         string `json:"artifact_name,omitempty"`
+	KeepLastStable       *int   `json:"keep_last_stable"`
+	PreReleaseMaxAgeDays *int   `json:"prerelease_max_age_days"`
+	KeepDownloadedDays   *int   `json:"keep_downloaded_days"`
+}
+
+// Validate checks that the policy targets a known artifact type and that
+// all its rules are non-negative.
+func (d PolicyDTO) Validate() error {
+	switch d.ArtifactType {
+	case "":
+		if d.ArtifactName != "" {
+			return fmt.Errorf("artifact_type is required when artifact_name is set")
+		}
+	case artifact.TypeModule, artifact.TypeProvider:
+		if d.ArtifactName == "" {
+			return fmt.Errorf("artifact_name is required when artifact_type is set")
+		}
+	default:
+		return fmt.Errorf("unknown artifact type %q", d.ArtifactType)
+	}
+
+	rules := []struct {
+		name  string
+		value *int
+	}{
+		{"keep_last_stable", d.KeepLastStable},
+		{"prerelease_max_age_days", d.PreReleaseMaxAgeDays},
+		{"keep_downloaded_days", d.KeepDownloadedDays},
+	}
+
+	for _, rule := range rules {
+		if rule.value != nil && *rule.value < 0 {
+			return fmt.Errorf("%s should be a non-negative integer", rule.name)
+		}
+	}
+
+	return nil
+}
+
+func (d PolicyDTO) ToPolicy(authorityID uuid.UUID) Policy {
+	return Policy{
+		AuthorityID:          authorityID,
+		ArtifactType:         d.ArtifactType,
+		ArtifactName:         d.ArtifactName,
+		KeepLastStable:       d.KeepLastStable,
+		PreReleaseMaxAgeDays: d.PreReleaseMaxAgeDays,
+		KeepDownloadedDays:   d.KeepDownloadedDays,
+	}
+}
+
+// RemovalDTO describes a version removed (or which would be removed) by
+// the retention policies.
+type RemovalDTO struct {
+	Type      string `json:"type"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Provider  string `json:"provider,omitempty"`
+	Version   string `json:"version"`
+	Reason    string `json:"reason"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReportDTO describes the outcome of applying the retention policies of
+// an authority.
+type ReportDTO struct {
+	DryRun   bool         `json:"dry_run"`
+	Removals []RemovalDTO `json:"removals"`
+}
+
+func value(v *int) int {
+	if v == nil {
+		return 0
+	}
+
+	return *v
+}
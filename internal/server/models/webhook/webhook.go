@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"terralist/pkg/database/entity"
+	"terralist/pkg/vcs"
+	"terralist/pkg/version"
+
+	"github.com/google/uuid"
+)
+
+// Registration maps the tags pushed to a git repository to the versions of
+// a module.
+type Registration struct {
+	entity.Entity
+	AuthorityID uuid.UUID `gorm:"not null;index"`
+	Name        string    `gorm:"not null"`
+	Provider    string    `gorm:"not null"`
+	Kind        vcs.Kind  `gorm:"not null"`
+	Repository  string    `gorm:"not null"`
+	// CloneURL overrides the URL derived from the kind and the repository,
+	// e.g. for self-hosted instances, to use SSH or to clone through a mirror
+	CloneURL   string
+	TagPrefix  string
+	Secret     string     `gorm:"not null"`
+	Deliveries []Delivery `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (Registration) TableName() string {
+	return "webhook_registrations"
+}
+
+// VersionFromTag derives the module version from a tag. The tag prefix of
+// the registration and a leading "v" are removed. It returns false if the
+// tag does not match the prefix or is not a semantic version.
+func (r Registration) VersionFromTag(tag string) (string, bool) {
+	v, ok := strings.CutPrefix(tag, r.TagPrefix)
+	if !ok {
+		return "", false
+	}
+
+	v = strings.TrimPrefix(v, "v")
+	if !version.Version(v).Valid() {
+		return "", false
+	}
+
+	return v, true
+}
+
+// SourceURL returns the go-getter URL from which a tag can be downloaded.
+// The repository is cloned from the registered URL, rather than from the
+// one sent in the events, which cannot be trusted.
+func (r Registration) SourceURL(tag string) string {
+	cloneURL := r.CloneURL
+	if cloneURL == "" {
+		cloneURL = r.Kind.CloneURL(r.Repository)
+	}
+
+	return fmt.Sprintf("git::%s?ref=%s", cloneURL, url.QueryEscape(tag))
+}
+
+func (r Registration) ToDTO() RegistrationDTO {
+	return RegistrationDTO{
+		ID:         r.ID.String(),
+		Kind:       r.Kind,
+		Repository: r.Repository,
+		CloneURL:   r.CloneURL,
+		TagPrefix:  r.TagPrefix,
+		CreatedAt:  r.CreatedAt.Format("2006-01-02T15:04:05"),
+	}
+}
+
+// RegistrationDTO describes a webhook registration. The secret is only
+// returned when the registration is created.
+type RegistrationDTO struct {
+	ID         string   `json:"id"`
+	Kind       vcs.Kind `json:"kind"`
+	Repository string   `json:"repository"`
+	CloneURL   string   `json:"clone_url,omitempty"`
+	TagPrefix  string   `json:"tag_prefix,omitempty"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// CreateRegistrationDTO holds the details of a new webhook registration. If
+// the secret is empty, a random one is generated.
+type CreateRegistrationDTO struct {
+	Kind       vcs.Kind `json:"kind"`
+	Repository string   `json:"repository"`
+	CloneURL   string   `json:"clone_url"`
+	TagPrefix  string   `json:"tag_prefix"`
+	Secret     string   `json:"secret"`
+}
+
+func (d CreateRegistrationDTO) ToRegistration(authorityID uuid.UUID, name, provider string) Registration {
+	return Registration{
+		AuthorityID: authorityID,
+		Name:        name,
+		Provider:    provider,
+		Kind:        d.Kind,
+		Repository:  d.Repository,
+		CloneURL:    d.CloneURL,
+		TagPrefix:   d.TagPrefix,
+		Secret:      d.Secret,
+	}
+}
+
+// DeliveryStatus is the outcome of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending marks a delivery whose module version is being
+	// published.
+	DeliveryPending DeliveryStatus = "pending"
+
+	// DeliverySucceeded marks a delivery whose module version was published.
+	DeliverySucceeded DeliveryStatus = "succeeded"
+
+	// DeliveryFailed marks a delivery whose module version could not be
+	// published.
+	DeliveryFailed DeliveryStatus = "failed"
+
+	// DeliveryIgnored marks a delivery for a tag which does not map to a
+	// module version.
+	DeliveryIgnored DeliveryStatus = "ignored"
+)
+
+// Delivery is a tag received through a webhook registration. A tag is
+// recorded once per delivery, so the redeliveries update the same row.
+type Delivery struct {
+	entity.Entity
+	RegistrationID uuid.UUID `gorm:"not null;uniqueIndex:idx_webhook_deliveries_tag"`
+	DeliveryID     string    `gorm:"uniqueIndex:idx_webhook_deliveries_tag"`
+	Event          string
+	Tag            string `gorm:"uniqueIndex:idx_webhook_deliveries_tag"`
+	Commit         string
+	Version        string
+	Source         string
+	Status         DeliveryStatus `gorm:"not null"`
+	Error          string
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d Delivery) ToDTO() DeliveryDTO {
+	return DeliveryDTO{
+		ID:         d.ID.String(),
+		DeliveryID: d.DeliveryID,
+		Event:      d.Event,
+		Tag:        d.Tag,
+		Commit:     d.Commit,
+		Version:    d.Version,
+		Source:     d.Source,
+		Status:     d.Status,
+		Error:      d.Error,
+		CreatedAt:  d.CreatedAt.Format("2006-01-02T15:04:05"),
+		UpdatedAt:  d.UpdatedAt.Format("2006-01-02T15:04:05"),
+	}
+}
+
+type DeliveryDTO struct {
+	ID         string         `json:"id"`
+	DeliveryID string         `json:"delivery_id,omitempty"`
+	Event      string         `json:"event"`
+	Tag        string         `json:"tag"`
+	Commit     string         `json:"commit,omitempty"`
+	Version    string         `json:"version,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	Status     DeliveryStatus `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  string         `json:"created_at"`
+	UpdatedAt  string         `json:"updated_at"`
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"terralist/internal/server/models/admission"
+	"terralist/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// AdmissionPolicyRepository describes a service that can interact with the
+// admission policies database.
+type AdmissionPolicyRepository interface {
+	// Find searches for the admission policy of an authority.
+	Find(authorityID uuid.UUID) (*admission.Policy, error)
+
+	// Upsert either updates or creates a new (if the authority has no
+	// policy) admission policy.
+	Upsert(admission.Policy) (*admission.Policy, error)
+}
+
+// DefaultAdmissionPolicyRepository is a concrete implementation of
+// AdmissionPolicyRepository.
+type DefaultAdmissionPolicyRepository struct {
+	Database database.Engine
+}
+
+func (r *DefaultAdmissionPolicyRepository) Find(authorityID uuid.UUID) (*admission.Policy, error) {
+	var policies []admission.Policy
+
+	err := r.Database.Handler().
+		Where("authority_id = ?", authorityID).
+		Limit(1).
+		Find(&policies).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(policies) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &policies[0], nil
+}
+
+func (r *DefaultAdmissionPolicyRepository) Upsert(p admission.Policy) (*admission.Policy, error) {
+	current, err := r.Find(p.AuthorityID)
+	if err == nil {
+		p.ID = current.ID
+		p.CreatedAt = current.CreatedAt
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err := r.Database.Handler().Save(&p).Error; err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -6,6 +6,7 @@ import (
 	"slices"
 
 	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/provider"
 	"terralist/pkg/database"
 
 	"github.com/google/uuid"
@@ -29,6 +30,15 @@ type AuthorityRepository interface {
 	// Upsert either updates or creates a new (if it does not already exist) authority.
 	Upsert(authority.Authority) (*authority.Authority, error)
 
+	// RevokeKey persists the revocation date of a key, and flags the
+	// provider versions it signed after this date. It returns the number of
+	// flagged versions.
+	RevokeKey(*authority.Key) (int64, error)
+
+	// CountSignedVersions returns the number of provider versions linked to
+	// the key which signed them.
+	CountSignedVersions(keyID uuid.UUID) (int64, error)
+
 	// Delete removes an authority with all its data (api keys, providers).
 	Delete(uuid.UUID) error
 }
@@ -143,10 +153,11 @@ func (r *DefaultAuthorityRepository) Upsert(a authority.Authority) (*authority.A
 		if err == nil {
 			a.Name = current.Name
 			a.Owner = current.Owner
+			a.Upstream = current.Upstream
 		}
 
 		for _, key := range current.Keys {
-			if !slices.Contains(a.Keys, key) {
+			if !slices.ContainsFunc(a.Keys, func(k authority.Key) bool { return k.ID == key.ID }) {
 				toDeleteKeys = append(toDeleteKeys, key)
 			}
 		}
@@ -171,6 +182,56 @@ func (r *DefaultAuthorityRepository) Upsert(a authority.Authority) (*authority.A
 	return &a, nil
 }
 
+func (r *DefaultAuthorityRepository) RevokeKey(k *authority.Key) (int64, error) {
+	var flagged int64
+
+	vktn := (provider.VersionKey{}).TableName()
+	signedBy := fmt.Sprintf("id IN (SELECT version_id FROM %s WHERE key_id = ?)", vktn)
+	signedAfter := fmt.Sprintf("id IN (SELECT version_id FROM %s WHERE key_id = ? AND signed_at > ?)", vktn)
+
+	if err := r.Database.Handler().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(k).UpdateColumn("revoked_at", k.RevokedAt).Error; err != nil {
+			return err
+		}
+
+		// The key may have been revoked before with another date
+		if err := tx.Model(&provider.Version{}).
+			Where(signedBy, k.ID).
+			UpdateColumn("signature_revoked", false).
+			Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&provider.Version{}).
+			Where(signedAfter, k.ID, *k.RevokedAt).
+			UpdateColumn("signature_revoked", true)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		flagged = result.RowsAffected
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseFailure, err)
+	}
+
+	return flagged, nil
+}
+
+func (r *DefaultAuthorityRepository) CountSignedVersions(keyID uuid.UUID) (int64, error) {
+	var count int64
+
+	if err := r.Database.Handler().
+		Model(&provider.VersionKey{}).
+		Where("key_id = ?", keyID).
+		Count(&count).
+		Error; err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseFailure, err)
+	}
+
+	return count, nil
+}
+
 func (r *DefaultAuthorityRepository) Delete(id uuid.UUID) error {
 	a, err := r.FindByID(id)
 	if err != nil {
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"terralist/internal/server/models/blob"
+	"terralist/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlobRepository describes a service that can interact with the blobs
+// database.
+type BlobRepository interface {
+	// FindReference searches for the reference of a key, along with its
+	// blob.
+	FindReference(namespace, key string) (*blob.Reference, error)
+
+	// Reference binds a key to the blob holding the same content as the
+	// given one. If no blob holds it yet, the given blob is created when its
+	// content is already stored (i.e. it has a key), otherwise ErrNotFound
+	// is returned. The blob is locked until it is referenced, so it cannot
+	// be released meanwhile. It returns the referenced blob and, if the key
+	// was previously bound to another blob which is no longer referenced,
+	// the released blob.
+	Reference(namespace, key string, b blob.Blob) (*blob.Blob, *blob.Blob, error)
+
+	// Release removes the reference of a key. If the blob of the key is no
+	// longer referenced, it is removed and returned.
+	Release(namespace, key string) (*blob.Blob, error)
+}
+
+// DefaultBlobRepository is a concrete implementation of BlobRepository.
+type DefaultBlobRepository struct {
+	Database database.Engine
+}
+
+func (r *DefaultBlobRepository) FindReference(namespace, key string) (*blob.Reference, error) {
+	return findReference(r.Database.Handler(), namespace, key)
+}
+
+func (r *DefaultBlobRepository) Reference(namespace, key string, b blob.Blob) (*blob.Blob, *blob.Blob, error) {
+	var referenced, released *blob.Blob
+
+	err := r.Database.Handler().Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+
+		current, err := findBlob(locked, namespace, b.Digest)
+		if errors.Is(err, ErrNotFound) {
+			if b.Key == "" {
+				return ErrNotFound
+			}
+
+			b.Namespace = namespace
+			b.ReferenceCount = 0
+
+			// The blob may be created by a concurrent upload of the same
+			// content, which waits for it and references it instead
+			if err := tx.
+				Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "namespace"}, {Name: "digest"}},
+					DoNothing: true,
+				}).
+				Create(&b).
+				Error; err != nil {
+				return err
+			}
+
+			if current, err = findBlob(locked, namespace, b.Digest); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		ref, err := findReference(tx, namespace, key)
+		if errors.Is(err, ErrNotFound) {
+			ref = &blob.Reference{
+				Namespace: namespace,
+				Key:       key,
+			}
+		} else if err != nil {
+			return err
+		} else if ref.BlobID == current.ID {
+			referenced = current
+			return nil
+		} else {
+			released, err = releaseBlob(tx, ref.BlobID)
+			if err != nil {
+				return err
+			}
+		}
+
+		ref.BlobID = current.ID
+		ref.Blob = blob.Blob{}
+		if err := tx.Save(ref).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(current).
+			Update("reference_count", gorm.Expr("reference_count + 1")).
+			Error; err != nil {
+			return err
+		}
+
+		current.ReferenceCount++
+		referenced = current
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return referenced, released, nil
+}
+
+func (r *DefaultBlobRepository) Release(namespace, key string) (*blob.Blob, error) {
+	var released *blob.Blob
+
+	err := r.Database.Handler().Transaction(func(tx *gorm.DB) error {
+		ref, err := findReference(tx, namespace, key)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(ref).Error; err != nil {
+			return err
+		}
+
+		released, err = releaseBlob(tx, ref.BlobID)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return released, nil
+}
+
+func findBlob(db *gorm.DB, namespace, digest string) (*blob.Blob, error) {
+	var blobs []blob.Blob
+
+	err := db.
+		Where(&blob.Blob{Namespace: namespace, Digest: digest}).
+		Limit(1).
+		Find(&blobs).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(blobs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &blobs[0], nil
+}
+
+func findReference(db *gorm.DB, namespace, key string) (*blob.Reference, error) {
+	var refs []blob.Reference
+
+	err := db.
+		Preload("Blob").
+		Where(&blob.Reference{Namespace: namespace, Key: key}).
+		Limit(1).
+		Find(&refs).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(refs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &refs[0], nil
+}
+
+// releaseBlob decrements the reference count of a blob, removing it when it
+// is no longer referenced. The removed blob is returned.
+func releaseBlob(tx *gorm.DB, id uuid.UUID) (*blob.Blob, error) {
+	if err := tx.Model(&blob.Blob{}).
+		Where("id = ?", id).
+		Update("reference_count", gorm.Expr("reference_count - 1")).
+		Error; err != nil {
+		return nil, err
+	}
+
+	var b blob.Blob
+	if err := tx.Where("id = ?", id).First(&b).Error; err != nil {
+		return nil, err
+	}
+
+	if b.ReferenceCount > 0 {
+		return nil, nil
+	}
+
+	if err := tx.Delete(&b).Error; err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
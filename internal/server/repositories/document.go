@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"terralist/internal/server/models/document"
+	"terralist/pkg/database"
+)
+
+// DocumentRepository describes a service that can interact with the
+// documents database.
+type DocumentRepository interface {
+	// Find searches for the document stored under a given key.
+	Find(key string) (*document.Document, error)
+
+	// Upsert creates a document or replaces the content of the document
+	// stored under the same key.
+	Upsert(d document.Document) (*document.Document, error)
+
+	// Delete removes the document stored under a given key, if any.
+	Delete(key string) error
+}
+
+// DefaultDocumentRepository is a concrete implementation of
+// DocumentRepository.
+type DefaultDocumentRepository struct {
+	Database database.Engine
+}
+
+func (r *DefaultDocumentRepository) Find(key string) (*document.Document, error) {
+	var documents []document.Document
+
+	err := r.Database.Handler().
+		Where(&document.Document{Key: key}).
+		Limit(1).
+		Find(&documents).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(documents) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &documents[0], nil
+}
+
+func (r *DefaultDocumentRepository) Upsert(d document.Document) (*document.Document, error) {
+	current, err := r.Find(d.Key)
+	if err == nil {
+		d.ID = current.ID
+		d.CreatedAt = current.CreatedAt
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err := r.Database.Handler().Save(&d).Error; err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (r *DefaultDocumentRepository) Delete(key string) error {
+	if err := r.Database.Handler().
+		Where(&document.Document{Key: key}).
+		Delete(&document.Document{}).
+		Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseFailure, err)
+	}
+
+	return nil
+}
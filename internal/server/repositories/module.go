@@ -3,8 +3,10 @@ package repositories
 import (
 	"errors"
 	"fmt"
-	"sort"
+	"strings"
+	"time"
 
+	"terralist/internal/server/models/artifact"
 	"terralist/internal/server/models/authority"
 	"terralist/internal/server/models/module"
 	"terralist/pkg/database"
@@ -13,16 +15,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// likeEscaper escapes the wildcards of a LIKE pattern, using "!" as the
+// escape character.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
 // ModuleRepository describes a service that can interact with the modules database.
 type ModuleRepository interface {
 	// Find searches for a specific module.
 	Find(namespace, name, provider string) (*module.Module, error)
 
+	// FindAll searches for all modules matching a given filter.
+	FindAll(filter module.ListFilter) ([]*module.Module, error)
+
 	// FindVersion searches for a specific module version.
 	FindVersion(namespace, name, provider, version string) (*module.Version, error)
 
-	// FindVersionLocation searches for a specific module version location.
-	FindVersionLocation(namespace, name, provider, version string) (*string, error)
+	// FindVersionLocation searches for a specific module version location,
+	// along with the checksum of its archive.
+	FindVersionLocation(namespace, name, provider, version string) (*module.VersionLocation, error)
 
 	// Upsert either updates or creates a new (if it does not already exist) module.
 	Upsert(n module.Module) (*module.Module, error)
@@ -30,6 +40,13 @@ type ModuleRepository interface {
 	// Delete removes a module with all its data (versions).
 	Delete(*module.Module) error
 
+	// UpdateVersionStatus persists the lifecycle status of a version.
+	UpdateVersionStatus(v *module.Version) error
+
+	// MarkVersionDownloaded records the last time a specific module version
+	// was downloaded.
+	MarkVersionDownloaded(namespace, name, provider, version string, at time.Time) error
+
 	// DeleteVersion removes a version from a module.
 	DeleteVersion(*module.Version) error
 }
@@ -61,11 +78,16 @@ func (r *DefaultModuleRepository) Find(namespace, name, provider string) (*modul
 			),
 			namespace,
 		).
+		Preload("Versions.Variables").
+		Preload("Versions.Outputs").
 		Preload("Versions.Providers").
 		Preload("Versions.Dependencies").
 		Preload("Versions.Submodules").
+		Preload("Versions.Submodules.Variables").
+		Preload("Versions.Submodules.Outputs").
 		Preload("Versions.Submodules.Providers").
 		Preload("Versions.Submodules.Dependencies").
+		Preload("Versions.Examples.Files").
 		First(&m).
 		Error
 
@@ -77,16 +99,89 @@ func (r *DefaultModuleRepository) Find(namespace, name, provider string) (*modul
 		}
 	}
 
-	sort.Slice(m.Versions, func(i, j int) bool {
-		lhs := version.Version(m.Versions[i].Version)
-		rhs := version.Version(m.Versions[j].Version)
-
-		return version.Compare(lhs, rhs) <= 0
+	version.Sort(m.Versions, func(v module.Version) version.Version {
+		return version.Version(v.Version)
 	})
 
 	return &m, nil
 }
 
+func (r *DefaultModuleRepository) FindAll(filter module.ListFilter) ([]*module.Module, error) {
+	var modules []*module.Module
+
+	atn := (authority.Authority{}).TableName()
+	mtn := (module.Module{}).TableName()
+	vtn := (module.Version{}).TableName()
+
+	query := r.Database.Handler().
+		Joins(fmt.Sprintf("JOIN %s ON %s.id = %s.authority_id", atn, atn, mtn))
+
+	if filter.Namespace != "" {
+		query = query.Where(fmt.Sprintf("LOWER(%s.name) = LOWER(?)", atn), filter.Namespace)
+	}
+
+	if filter.Provider != "" {
+		query = query.Where(fmt.Sprintf("LOWER(%s.provider) = LOWER(?)", mtn), filter.Provider)
+	}
+
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Query)) + "%"
+
+		query = query.Where(
+			fmt.Sprintf(
+				"(LOWER(%s.name) LIKE ? ESCAPE '!' OR LOWER(%s.provider) LIKE ? ESCAPE '!' OR LOWER(%s.name) LIKE ? ESCAPE '!')",
+				mtn,
+				mtn,
+				atn,
+			),
+			pattern,
+			pattern,
+			pattern,
+		)
+	}
+
+	if filter.Limit > 0 {
+		// Modules without installable versions cannot be listed, so they
+		// are not counted in the pages
+		query = query.Where(
+			fmt.Sprintf(
+				"EXISTS (SELECT 1 FROM %s WHERE %s.module_id = %s.id AND %s.status <> ?)",
+				vtn,
+				vtn,
+				mtn,
+				vtn,
+			),
+			artifact.StatusYanked,
+		)
+
+		// A page only needs the latest version of each module
+		query = query.
+			Preload("Versions").
+			Offset(filter.Offset).
+			Limit(filter.Limit)
+	} else {
+		query = query.Where(
+			fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s.module_id = %s.id)", vtn, vtn, mtn),
+		)
+
+		query = query.
+			Preload("Versions").
+			Preload("Versions.Submodules").
+			Preload("Versions.Examples.Files")
+	}
+
+	err := query.
+		Order(fmt.Sprintf("%s.name, %s.name, %s.provider", atn, mtn, mtn)).
+		Find(&modules).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	return modules, nil
+}
+
 func (r *DefaultModuleRepository) FindVersion(namespace, name, provider, version string) (*module.Version, error) {
 	ver := module.Version{}
 
@@ -119,11 +214,16 @@ func (r *DefaultModuleRepository) FindVersion(namespace, name, provider, version
 			namespace,
 		).
 		Where(fmt.Sprintf("%s.version = ?", vtn), version).
+		Preload("Variables").
+		Preload("Outputs").
 		Preload("Providers").
 		Preload("Dependencies").
 		Preload("Submodules").
+		Preload("Submodules.Variables").
+		Preload("Submodules.Outputs").
 		Preload("Submodules.Providers").
 		Preload("Submodules.Dependencies").
+		Preload("Examples.Files").
 		First(&ver).
 		Error
 
@@ -144,8 +244,8 @@ func (r *DefaultModuleRepository) FindVersion(namespace, name, provider, version
 	return &ver, nil
 }
 
-func (r *DefaultModuleRepository) FindVersionLocation(namespace, name, provider, version string) (*string, error) {
-	var location string
+func (r *DefaultModuleRepository) FindVersionLocation(namespace, name, provider, version string) (*module.VersionLocation, error) {
+	var location module.VersionLocation
 
 	atn := (authority.Authority{}).TableName()
 	mtn := (module.Module{}).TableName()
@@ -153,7 +253,7 @@ func (r *DefaultModuleRepository) FindVersionLocation(namespace, name, provider,
 
 	res := r.Database.Handler().
 		Table(vtn).
-		Select(fmt.Sprintf("%s.location", vtn)).
+		Select(fmt.Sprintf("%s.location, %s.checksum", vtn, vtn)).
 		Joins(
 			fmt.Sprintf(
 				"JOIN %s ON %s.id = %s.module_id AND LOWER(%s.name) = LOWER(?) AND LOWER(%s.provider) = LOWER(?)",
@@ -219,3 +319,35 @@ func (r *DefaultModuleRepository) Delete(m *module.Module) error {
 func (r *DefaultModuleRepository) DeleteVersion(v *module.Version) error {
 	return r.Database.Handler().Delete(v).Error
 }
+
+func (r *DefaultModuleRepository) UpdateVersionStatus(v *module.Version) error {
+	return r.Database.Handler().
+		Model(v).
+		Select("Status", "StatusReason", "Replacement").
+		Updates(v).
+		Error
+}
+
+func (r *DefaultModuleRepository) MarkVersionDownloaded(namespace, name, provider, version string, at time.Time) error {
+	atn := (authority.Authority{}).TableName()
+	mtn := (module.Module{}).TableName()
+
+	modules := r.Database.Handler().
+		Table(mtn).
+		Select(fmt.Sprintf("%s.id", mtn)).
+		Joins(fmt.Sprintf("JOIN %s ON %s.id = %s.authority_id", atn, atn, mtn)).
+		Where(
+			fmt.Sprintf("LOWER(%s.name) = LOWER(?) AND LOWER(%s.provider) = LOWER(?) AND LOWER(%s.name) = LOWER(?)", mtn, mtn, atn),
+			name,
+			provider,
+			namespace,
+		)
+
+	// The update time is not changed, since downloads are not changes of
+	// the version itself
+	return r.Database.Handler().
+		Model(&module.Version{}).
+		Where("version = ? AND module_id IN (?)", version, modules).
+		UpdateColumn("last_downloaded_at", at).
+		Error
+}
@@ -3,13 +3,14 @@ package repositories
 import (
 	"errors"
 	"fmt"
-	"sort"
+	"time"
 
 	"terralist/internal/server/models/authority"
 	"terralist/internal/server/models/provider"
 	"terralist/pkg/database"
 	"terralist/pkg/version"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -18,6 +19,9 @@ type ProviderRepository interface {
 	// Find searches for a specific provider.
 	Find(namespace, name string) (*provider.Provider, error)
 
+	// FindAll searches for all providers of a namespace.
+	FindAll(namespace string) ([]*provider.Provider, error)
+
 	// FindVersionPlatform searches for a specific platform binary metadata
 	// of a provider version.
 	FindVersionPlatform(namespace, name, version, os, arch string) (*provider.Platform, error)
@@ -28,6 +32,17 @@ type ProviderRepository interface {
 	// Delete removes a provider with all its data (versions).
 	Delete(*provider.Provider) error
 
+	// UpdateVersionStatus persists the lifecycle status of a version.
+	UpdateVersionStatus(v *provider.Version) error
+
+	// AddVersionPlatforms adds platforms to a version, along with the
+	// SHA256SUMS file listing them and the key which signed it.
+	AddVersionPlatforms(v *provider.Version, platforms []provider.Platform) error
+
+	// MarkVersionDownloaded records the last time a provider version was
+	// downloaded.
+	MarkVersionDownloaded(versionID uuid.UUID, at time.Time) error
+
 	// DeleteVersion removes a version from a provider.
 	DeleteVersion(p *provider.Provider, version string) error
 }
@@ -60,6 +75,8 @@ func (r *DefaultProviderRepository) Find(namespace, name string) (*provider.Prov
 		).
 		Preload("Versions").
 		Preload("Versions.Platforms").
+		Preload("Versions.Docs").
+		Preload("Versions.SigningKeys").
 		First(&p).
 		Error
 
@@ -71,16 +88,42 @@ func (r *DefaultProviderRepository) Find(namespace, name string) (*provider.Prov
 		}
 	}
 
-	sort.Slice(p.Versions, func(i, j int) bool {
-		lhs := version.Version(p.Versions[i].Version)
-		rhs := version.Version(p.Versions[j].Version)
-
-		return version.Compare(lhs, rhs) <= 0
+	version.Sort(p.Versions, func(v provider.Version) version.Version {
+		return version.Version(v.Version)
 	})
 
 	return &p, nil
 }
 
+func (r *DefaultProviderRepository) FindAll(namespace string) ([]*provider.Provider, error) {
+	var providers []*provider.Provider
+
+	atn := (authority.Authority{}).TableName()
+	ptn := (provider.Provider{}).TableName()
+
+	err := r.Database.Handler().
+		Joins(
+			fmt.Sprintf(
+				"JOIN %s ON %s.id = %s.authority_id AND LOWER(%s.name) = LOWER(?)",
+				atn,
+				atn,
+				ptn,
+				atn,
+			),
+			namespace,
+		).
+		Preload("Versions").
+		Order(fmt.Sprintf("%s.name", ptn)).
+		Find(&providers).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	return providers, nil
+}
+
 func (r *DefaultProviderRepository) FindVersionPlatform(
 	namespace, name, version, os, arch string,
 ) (*provider.Platform, error) {
@@ -124,6 +167,7 @@ func (r *DefaultProviderRepository) FindVersionPlatform(
 		).
 		Where(fmt.Sprintf("%s.system = ? AND %s.architecture = ?", pltn, pltn), os, arch).
 		Preload("Version.Provider").
+		Preload("Version.SigningKeys").
 		First(&p).
 		Error
 
@@ -184,3 +228,60 @@ func (r *DefaultProviderRepository) DeleteVersion(p *provider.Provider, version
 
 	return nil
 }
+
+func (r *DefaultProviderRepository) UpdateVersionStatus(v *provider.Version) error {
+	return r.Database.Handler().
+		Model(v).
+		Select("Status", "StatusReason", "Replacement").
+		Updates(v).
+		Error
+}
+
+func (r *DefaultProviderRepository) AddVersionPlatforms(v *provider.Version, platforms []provider.Platform) error {
+	for i := range platforms {
+		platforms[i].VersionID = v.ID
+	}
+
+	if err := r.Database.Handler().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(v).
+			Select("ShaSumsUrl", "ShaSumsSignatureUrl", "SigningKeyID", "SignatureRevoked").
+			Updates(v).
+			Error; err != nil {
+			return err
+		}
+
+		// The key links are replaced, since the SHA256SUMS file may have
+		// been signed again
+		if err := tx.Where("version_id = ?", v.ID).Delete(&provider.VersionKey{}).Error; err != nil {
+			return err
+		}
+
+		if len(v.SigningKeys) > 0 {
+			for i := range v.SigningKeys {
+				v.SigningKeys[i].VersionID = v.ID
+			}
+
+			if err := tx.Create(&v.SigningKeys).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&platforms).Error
+	}); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseFailure, err)
+	}
+
+	v.Platforms = append(v.Platforms, platforms...)
+
+	return nil
+}
+
+func (r *DefaultProviderRepository) MarkVersionDownloaded(versionID uuid.UUID, at time.Time) error {
+	// The update time is not changed, since downloads are not changes of
+	// the version itself
+	return r.Database.Handler().
+		Model(&provider.Version{}).
+		Where("id = ?", versionID).
+		UpdateColumn("last_downloaded_at", at).
+		Error
+}
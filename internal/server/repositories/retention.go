@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"fmt"
+
+	"terralist/internal/server/models/retention"
+	"terralist/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// RetentionPolicyRepository describes a service that can interact with the
+// retention policies database.
+type RetentionPolicyRepository interface {
+	// FindAll searches for all retention policies of an authority.
+	FindAll(authorityID uuid.UUID) ([]*retention.Policy, error)
+
+	// Upsert either updates or creates a new (if there is no policy for the
+	// same artifact) retention policy.
+	Upsert(retention.Policy) (*retention.Policy, error)
+
+	// Delete removes a retention policy of an authority.
+	Delete(authorityID uuid.UUID, id uuid.UUID) error
+}
+
+// DefaultRetentionPolicyRepository is a concrete implementation of
+// RetentionPolicyRepository.
+type DefaultRetentionPolicyRepository struct {
+	Database database.Engine
+}
+
+func (r *DefaultRetentionPolicyRepository) FindAll(authorityID uuid.UUID) ([]*retention.Policy, error) {
+	var policies []*retention.Policy
+
+	err := r.Database.Handler().
+		Where("authority_id = ?", authorityID).
+		Order("artifact_type, artifact_name").
+		Find(&policies).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	return policies, nil
+}
+
+func (r *DefaultRetentionPolicyRepository) Upsert(p retention.Policy) (*retention.Policy, error) {
+	var current []retention.Policy
+
+	err := r.Database.Handler().
+		Where(
+			"authority_id = ? AND artifact_type = ? AND artifact_name = ?",
+			p.AuthorityID,
+			p.ArtifactType,
+			p.ArtifactName,
+		).
+		Limit(1).
+		Find(&current).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(current) > 0 {
+		p.ID = current[0].ID
+		p.CreatedAt = current[0].CreatedAt
+	}
+
+	if err := r.Database.Handler().Save(&p).Error; err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *DefaultRetentionPolicyRepository) Delete(authorityID uuid.UUID, id uuid.UUID) error {
+	res := r.Database.Handler().
+		Where("authority_id = ? AND id = ?", authorityID, id).
+		Delete(&retention.Policy{})
+
+	if err := res.Error; err != nil {
+		return err
+	}
+
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
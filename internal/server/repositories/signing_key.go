@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"terralist/internal/server/models/authority"
+	"terralist/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SigningKeyRepository describes a service that can interact with the
+// signing keys managed by the registry.
+type SigningKeyRepository interface {
+	// FindActive searches for the key an authority currently signs with.
+	FindActive(authorityID uuid.UUID) (*authority.SigningKey, error)
+
+	// Rotate retires the active signing key of an authority, if any, and
+	// replaces it with a new one. The public half of the new key is added
+	// to the keys of the authority, the public halves of the retired keys
+	// are kept so the versions they signed can still be verified. It returns
+	// the public key added to the authority.
+	Rotate(k authority.SigningKey, public authority.Key) (*authority.Key, error)
+}
+
+// DefaultSigningKeyRepository is a concrete implementation of
+// SigningKeyRepository.
+type DefaultSigningKeyRepository struct {
+	Database database.Engine
+}
+
+func (r *DefaultSigningKeyRepository) FindActive(authorityID uuid.UUID) (*authority.SigningKey, error) {
+	var keys []authority.SigningKey
+
+	err := r.Database.Handler().
+		Where("authority_id = ? AND active = ?", authorityID, true).
+		Limit(1).
+		Find(&keys).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &keys[0], nil
+}
+
+func (r *DefaultSigningKeyRepository) Rotate(k authority.SigningKey, public authority.Key) (*authority.Key, error) {
+	k.Active = true
+	public.AuthorityID = k.AuthorityID
+
+	if err := r.Database.Handler().Transaction(func(tx *gorm.DB) error {
+		// The private half of the retired keys is erased, they can no longer
+		// sign new versions
+		if err := tx.Model(&authority.SigningKey{}).
+			Where("authority_id = ? AND active = ?", k.AuthorityID, true).
+			Updates(map[string]any{
+				"active":      false,
+				"private_key": "",
+				"retired_at":  time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&public).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&k).Error
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseFailure, err)
+	}
+
+	return &public, nil
+}
@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"terralist/internal/server/models/webhook"
+	"terralist/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookRepository describes a service that can interact with the webhooks
+// database.
+type WebhookRepository interface {
+	// FindRegistration searches for a webhook registration by its ID.
+	FindRegistration(id uuid.UUID) (*webhook.Registration, error)
+
+	// FindRegistrations searches for all webhook registrations of a module.
+	FindRegistrations(authorityID uuid.UUID, name, provider string) ([]*webhook.Registration, error)
+
+	// CreateRegistration creates a new webhook registration.
+	CreateRegistration(webhook.Registration) (*webhook.Registration, error)
+
+	// DeleteRegistration removes a webhook registration with all its
+	// deliveries.
+	DeleteRegistration(*webhook.Registration) error
+
+	// FindDeliveries searches for the most recent deliveries of a webhook
+	// registration, optionally filtered by their status.
+	FindDeliveries(registrationID uuid.UUID, status webhook.DeliveryStatus, limit int) ([]*webhook.Delivery, error)
+
+	// FindDelivery searches for the delivery of a tag by the identifier
+	// assigned by the sender.
+	FindDelivery(registrationID uuid.UUID, deliveryID, tag string) (*webhook.Delivery, error)
+
+	// UpsertDelivery either updates or creates a new (if it does not already
+	// exist) webhook delivery.
+	UpsertDelivery(webhook.Delivery) (*webhook.Delivery, error)
+
+	// PruneDeliveries removes the deliveries of a webhook registration
+	// which are not pending, except for the most recent ones.
+	PruneDeliveries(registrationID uuid.UUID, keep int) error
+}
+
+// webhookPruneBatchSize is the maximum number of deliveries removed at once
+// by PruneDeliveries.
+const webhookPruneBatchSize = 1000
+
+// DefaultWebhookRepository is a concrete implementation of WebhookRepository.
+type DefaultWebhookRepository struct {
+	Database database.Engine
+}
+
+func (r *DefaultWebhookRepository) FindRegistration(id uuid.UUID) (*webhook.Registration, error) {
+	reg := webhook.Registration{}
+
+	err := r.Database.Handler().
+		Where("id = ?", id).
+		First(&reg).
+		Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		} else {
+			return nil, fmt.Errorf("error while querying the database: %v", err)
+		}
+	}
+
+	return &reg, nil
+}
+
+func (r *DefaultWebhookRepository) FindRegistrations(authorityID uuid.UUID, name, provider string) ([]*webhook.Registration, error) {
+	var regs []*webhook.Registration
+
+	err := r.Database.Handler().
+		Where("authority_id = ? AND LOWER(name) = LOWER(?) AND LOWER(provider) = LOWER(?)", authorityID, name, provider).
+		Order("created_at").
+		Find(&regs).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	return regs, nil
+}
+
+func (r *DefaultWebhookRepository) CreateRegistration(reg webhook.Registration) (*webhook.Registration, error) {
+	if err := r.Database.Handler().Create(&reg).Error; err != nil {
+		return nil, err
+	}
+
+	return &reg, nil
+}
+
+func (r *DefaultWebhookRepository) DeleteRegistration(reg *webhook.Registration) error {
+	return r.Database.Handler().Delete(reg).Error
+}
+
+func (r *DefaultWebhookRepository) FindDeliveries(
+	registrationID uuid.UUID,
+	status webhook.DeliveryStatus,
+	limit int,
+) ([]*webhook.Delivery, error) {
+	var deliveries []*webhook.Delivery
+
+	query := r.Database.Handler().
+		Where("registration_id = ?", registrationID)
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).
+		Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *DefaultWebhookRepository) FindDelivery(registrationID uuid.UUID, deliveryID, tag string) (*webhook.Delivery, error) {
+	d := webhook.Delivery{}
+
+	err := r.Database.Handler().
+		Where("registration_id = ? AND delivery_id = ? AND tag = ?", registrationID, deliveryID, tag).
+		First(&d).
+		Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		} else {
+			return nil, fmt.Errorf("error while querying the database: %v", err)
+		}
+	}
+
+	return &d, nil
+}
+
+func (r *DefaultWebhookRepository) UpsertDelivery(d webhook.Delivery) (*webhook.Delivery, error) {
+	if err := r.Database.Handler().Save(&d).Error; err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (r *DefaultWebhookRepository) PruneDeliveries(registrationID uuid.UUID, keep int) error {
+	var ids []uuid.UUID
+
+	// The deliveries past the most recent ones are selected first, since
+	// MySQL does not support LIMIT in subqueries
+	err := r.Database.Handler().
+		Model(&webhook.Delivery{}).
+		Where("registration_id = ? AND status <> ?", registrationID, webhook.DeliveryPending).
+		Order("created_at DESC").
+		Offset(keep).
+		Limit(webhookPruneBatchSize).
+		Pluck("id", &ids).
+		Error
+
+	if err != nil {
+		return fmt.Errorf("error while querying the database: %v", err)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return r.Database.Handler().
+		Where("id IN ?", ids).
+		Delete(&webhook.Delivery{}).
+		Error
+}
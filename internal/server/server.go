@@ -25,6 +25,7 @@ import (
 	"terralist/pkg/file"
 	"terralist/pkg/metrics"
 	"terralist/pkg/rbac"
+	"terralist/pkg/registry"
 	"terralist/pkg/session"
 	"terralist/pkg/storage"
 	"terralist/pkg/storage/local"
@@ -37,6 +38,14 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// The module versions published from webhooks are fetched by a few
+	// workers, so a burst of tags cannot exhaust the server
+	webhookPublishWorkers   = 4
+	webhookPublishQueueSize = 100
+	webhookPublishTimeout   = 15 * time.Minute
+)
+
 // Server represents the Terralist server.
 type Server struct {
 	Port        int
@@ -53,6 +62,10 @@ type Server struct {
 	Resolver storage.Resolver
 
 	Readiness *atomic.Bool
+
+	RetentionJanitor *services.RetentionJanitor
+	WebhookPublisher *services.WebhookPublisher
+	ContentMigration *services.ContentMigration
 }
 
 // Config holds the server configuration that isn't configurable by the user.
@@ -316,8 +329,14 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Database: config.Database,
 	}
 
+	signingKeyRepository := &repositories.DefaultSigningKeyRepository{
+		Database: config.Database,
+	}
+
 	authorityService := &services.DefaultAuthorityService{
-		AuthorityRepository: authorityRepository,
+		AuthorityRepository:  authorityRepository,
+		SigningKeyRepository: signingKeyRepository,
+		SigningSecret:        userConfig.SigningKeySecret,
 	}
 
 	apiKeyRepository := &repositories.DefaultApiKeyRepository{
@@ -366,11 +385,108 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Database: config.Database,
 	}
 
+	// Sizes are configured in MiB
+	uploadLimits := file.Limits{
+		MaxDownloadSize:     int64(userConfig.UploadMaxSize) << 20,
+		MaxDecompressedSize: int64(userConfig.UploadMaxDecompressed) << 20,
+		MaxFiles:            userConfig.UploadMaxFiles,
+		MaxPathDepth:        userConfig.UploadMaxPathDepth,
+	}
+
+	fetcher := file.NewLimitedFetcher(uploadLimits)
+
+	// Each distinct content is stored once in the datastores, the keys only
+	// reference it
+	blobRepository := &repositories.DefaultBlobRepository{
+		Database: config.Database,
+	}
+
+	var modulesContent, providersContent *services.ContentResolver
+	modulesResolver, providersResolver := config.ModulesResolver, config.ProvidersResolver
+
+	if config.ModulesResolver != nil {
+		modulesContent = &services.ContentResolver{
+			Resolver:       config.ModulesResolver,
+			BlobRepository: blobRepository,
+			Namespace:      "modules",
+			Fetcher:        file.NewFetcher(),
+		}
+		modulesResolver = modulesContent
+	}
+
+	if config.ProvidersResolver != nil {
+		providersContent = &services.ContentResolver{
+			Resolver:       config.ProvidersResolver,
+			BlobRepository: blobRepository,
+			Namespace:      "providers",
+			Fetcher:        file.NewFetcher(),
+		}
+		providersResolver = providersContent
+	}
+
+	admissionService := &services.DefaultAdmissionService{
+		AdmissionPolicyRepository: &repositories.DefaultAdmissionPolicyRepository{
+			Database: config.Database,
+		},
+	}
+
 	moduleService := &services.DefaultModuleService{
 		ModuleRepository: moduleRepository,
 		AuthorityService: authorityService,
-		Resolver:         config.ModulesResolver,
-		Fetcher:          file.NewFetcher(),
+		Resolver:         modulesResolver,
+		Fetcher:          fetcher,
+		AdmissionService: admissionService,
+	}
+
+	// Without a storage resolver, the modules documentation is kept in the
+	// database
+	if modulesResolver == nil {
+		moduleService.DocumentStore = &services.DatabaseDocumentStore{
+			DocumentRepository: &repositories.DefaultDocumentRepository{
+				Database: config.Database,
+			},
+			MaxSize: int64(userConfig.ModulesProxyDocsMaxSize) * 1024,
+		}
+	}
+
+	providerRepository := &repositories.DefaultProviderRepository{
+		Database: config.Database,
+	}
+
+	providerService := &services.DefaultProviderService{
+		ProviderRepository: providerRepository,
+		AuthorityService:   authorityService,
+		Resolver:           providersResolver,
+		Fetcher:            fetcher,
+	}
+
+	// Likewise, the providers documentation is kept in the database
+	if providersResolver == nil {
+		providerService.DocumentStore = &services.DatabaseDocumentStore{
+			DocumentRepository: &repositories.DefaultDocumentRepository{
+				Database: config.Database,
+			},
+			MaxSize: int64(userConfig.ProvidersProxyDocsMaxSize) * 1024,
+		}
+	}
+
+	var upstreamService services.UpstreamService
+	if userConfig.UpstreamRegistry != "" {
+		upstreamCacheTTL, err := time.ParseDuration(userConfig.UpstreamCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream cache ttl: %v", err)
+		}
+
+		upstreamService = &services.DefaultUpstreamService{
+			Registry:           registry.NewClient(userConfig.UpstreamRegistry),
+			ProviderRepository: providerRepository,
+			AuthorityService:   authorityService,
+			ModuleService:      moduleService,
+			ProviderService:    providerService,
+			AllowedNamespaces:  splitList(userConfig.UpstreamNamespaces),
+			DeniedNamespaces:   splitList(userConfig.UpstreamDenied),
+			TTL:                upstreamCacheTTL,
+		}
 	}
 
 	moduleController := &controllers.DefaultModuleController{
@@ -379,34 +495,59 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Authentication:   authentication,
 		Authorization:    authorization,
 		AnonymousRead:    userConfig.ModulesAnonymousRead,
+		MaxUploadSize:    uploadLimits.MaxDownloadSize,
+		UpstreamService:  upstreamService,
 	}
 
 	apiV1Group.Register(moduleController)
 
-	providerRepository := &repositories.DefaultProviderRepository{
-		Database: config.Database,
-	}
-
-	providerService := &services.DefaultProviderService{
-		ProviderRepository: providerRepository,
-		AuthorityService:   authorityService,
-		Resolver:           config.ProvidersResolver,
-		Fetcher:            file.NewFetcher(),
-	}
-
 	providerController := &controllers.DefaultProviderController{
 		ProviderService:  providerService,
 		AuthorityService: authorityService,
 		Authentication:   authentication,
 		Authorization:    authorization,
 		AnonymousRead:    userConfig.ProvidersAnonymousRead,
+		MaxUploadSize:    uploadLimits.MaxDownloadSize,
+		UpstreamService:  upstreamService,
 	}
 
 	apiV1Group.Register(providerController)
 
+	contentMigration := &services.ContentMigration{
+		AuthorityService:   authorityService,
+		ModuleRepository:   moduleRepository,
+		ProviderRepository: providerRepository,
+		ModulesResolver:    modulesContent,
+		ProvidersResolver:  providersContent,
+	}
+
+	retentionService := &services.DefaultRetentionService{
+		RetentionPolicyRepository: &repositories.DefaultRetentionPolicyRepository{
+			Database: config.Database,
+		},
+		ModuleRepository:   moduleRepository,
+		ProviderRepository: providerRepository,
+		AuthorityService:   authorityService,
+		ModuleService:      moduleService,
+		ProviderService:    providerService,
+	}
+
+	retentionInterval, err := time.ParseDuration(userConfig.RetentionInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retention interval: %v", err)
+	}
+
+	var retentionJanitor *services.RetentionJanitor
+	if retentionInterval > 0 {
+		retentionJanitor = services.NewRetentionJanitor(retentionService, retentionInterval)
+	}
+
 	authorityController := &controllers.DefaultAuthorityController{
 		AuthorityService: authorityService,
 		ApiKeyService:    apiKeyService,
+		RetentionService: retentionService,
+		AdmissionService: admissionService,
+		ModuleService:    moduleService,
 
 		Authentication: authentication,
 		Authorization:  authorization,
@@ -433,8 +574,35 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 
 	apiV1Group.Register(artifactController)
 
-	modulesLocal := local.UnwrapResolver(config.ModulesResolver)
-	providersLocal := local.UnwrapResolver(config.ProvidersResolver)
+	webhookService := &services.DefaultWebhookService{
+		WebhookRepository: &repositories.DefaultWebhookRepository{
+			Database: config.Database,
+		},
+		ModuleService: moduleService,
+	}
+
+	webhookPublisher := services.NewWebhookPublisher(
+		webhookService,
+		webhookPublishWorkers,
+		webhookPublishQueueSize,
+		webhookPublishTimeout,
+	)
+
+	webhookController := &controllers.DefaultWebhookController{
+		WebhookService:   webhookService,
+		WebhookPublisher: webhookPublisher,
+		AuthorityService: authorityService,
+		Authentication:   authentication,
+		Authorization:    authorization,
+	}
+
+	webhookController.ReceiverURL = strings.TrimSuffix(hostURL.String(), "/") +
+		apiV1Group.Prefix() + webhookController.ReceiverApi()
+
+	apiV1Group.Register(webhookController)
+
+	modulesLocal := local.UnwrapResolver(modulesResolver)
+	providersLocal := local.UnwrapResolver(providersResolver)
 	if modulesLocal != nil || providersLocal != nil {
 		localJWTManager, err := jwt.New(userConfig.LocalTokenSigningSecret)
 		if err != nil {
@@ -442,8 +610,8 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		}
 
 		filesController := &controllers.DefaultFileServer{
-			ModulesResolver:   config.ModulesResolver,
-			ProvidersResolver: config.ProvidersResolver,
+			ModulesResolver:   modulesResolver,
+			ProvidersResolver: providersResolver,
 			JWT:               localJWTManager,
 		}
 
@@ -490,6 +658,10 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Database: config.Database,
 
 		Readiness: readiness,
+
+		RetentionJanitor: retentionJanitor,
+		WebhookPublisher: webhookPublisher,
+		ContentMigration: contentMigration,
 	}, nil
 }
 
@@ -515,6 +687,18 @@ func redirectWithError(uri string, state string, err oauth.Error) string {
 	)
 }
 
+// splitList splits a comma separated list, ignoring the empty elements.
+func splitList(list string) []string {
+	var elements []string
+	for _, e := range strings.Split(list, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			elements = append(elements, e)
+		}
+	}
+
+	return elements
+}
+
 // Start initializes the routes and starts serving.
 func (s *Server) Start() error {
 	useTLS := s.CertFile != "" && s.KeyFile != ""
@@ -567,6 +751,16 @@ func (s *Server) Start() error {
 			log.Error().AnErr("error", err).Send()
 		}
 	}()
+
+	// Move the keys stored before the content-addressed storage in
+	// background, since their content may have to be downloaded
+	if s.ContentMigration != nil {
+		go func() {
+			if err := runBackgroundMigration(s.Database.Handler(), contentMigration, s.ContentMigration.Run); err != nil {
+				log.Error().Err(err).Msg("could not migrate to the content-addressed storage")
+			}
+		}()
+	}
 	<-stop
 
 	log.Warn().Msg("Received interrupt signal, waiting for in-progress operations to complete")
@@ -580,6 +774,14 @@ func (s *Server) waitForDrain() {
 	// Mark the service as unavailable
 	s.Readiness.Store(false)
 
+	if s.RetentionJanitor != nil {
+		s.RetentionJanitor.Stop()
+	}
+
+	if s.WebhookPublisher != nil {
+		s.WebhookPublisher.Stop()
+	}
+
 	drainComplete := make(chan bool, 1)
 
 	go func() {
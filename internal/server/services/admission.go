@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"terralist/internal/server/models/admission"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/docs"
+	"terralist/pkg/file"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAdmissionPolicy = errors.New("invalid admission policy")
+)
+
+// AdmissionError is returned when a module version does not follow the
+// admission policy of its authority.
+type AdmissionError struct {
+	Violations []docs.Violation
+}
+
+func (e *AdmissionError) Error() string {
+	if len(e.Violations) == 1 {
+		return fmt.Sprintf("module rejected: %s", e.Violations[0].Message)
+	}
+
+	return fmt.Sprintf("module rejected: %s (and %d other violations)", e.Violations[0].Message, len(e.Violations)-1)
+}
+
+// AdmissionService describes a service that validates the module versions
+// before they are published.
+type AdmissionService interface {
+	// GetPolicy returns the admission policy of an authority. Authorities
+	// without a policy get the default one, which enforces no optional rule.
+	GetPolicy(authorityID uuid.UUID) (*admission.PolicyDTO, error)
+
+	// SetPolicy creates or replaces the admission policy of an authority.
+	SetPolicy(authorityID uuid.UUID, d admission.PolicyDTO) (*admission.PolicyDTO, error)
+
+	// Validate checks a module against the admission policy of an authority.
+	// It returns an *AdmissionError listing the violations, if any.
+	Validate(authorityID uuid.UUID, moduleFS *file.FS) error
+}
+
+// DefaultAdmissionService is a concrete implementation of AdmissionService.
+type DefaultAdmissionService struct {
+	AdmissionPolicyRepository repositories.AdmissionPolicyRepository
+}
+
+func (s *DefaultAdmissionService) GetPolicy(authorityID uuid.UUID) (*admission.PolicyDTO, error) {
+	p, err := s.policy(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := p.ToDTO()
+	return &dto, nil
+}
+
+func (s *DefaultAdmissionService) SetPolicy(authorityID uuid.UUID, d admission.PolicyDTO) (*admission.PolicyDTO, error) {
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdmissionPolicy, err)
+	}
+
+	p, err := s.AdmissionPolicyRepository.Upsert(d.ToPolicy(authorityID))
+	if err != nil {
+		return nil, err
+	}
+
+	dto := p.ToDTO()
+	return &dto, nil
+}
+
+func (s *DefaultAdmissionService) Validate(authorityID uuid.UUID, moduleFS *file.FS) error {
+	p, err := s.policy(authorityID)
+	if err != nil {
+		return err
+	}
+
+	if violations := docs.ValidateModule(moduleFS, p.ToRules()); len(violations) > 0 {
+		return &AdmissionError{Violations: violations}
+	}
+
+	return nil
+}
+
+// policy returns the admission policy of an authority, or the default one
+// if the authority has none.
+func (s *DefaultAdmissionService) policy(authorityID uuid.UUID) (*admission.Policy, error) {
+	p, err := s.AdmissionPolicyRepository.Find(authorityID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return &admission.Policy{AuthorityID: authorityID}, nil
+	}
+
+	return p, err
+}
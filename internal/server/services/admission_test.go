@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"terralist/internal/server/models/admission"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/docs"
+	"terralist/pkg/file"
+
+	"github.com/google/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateModuleAdmission(t *testing.T) {
+	Convey("Subject: Validate a module against the admission policy", t, func() {
+		mockAdmissionPolicyRepository := repositories.NewMockAdmissionPolicyRepository(t)
+
+		admissionService := &DefaultAdmissionService{
+			AdmissionPolicyRepository: mockAdmissionPolicyRepository,
+		}
+
+		authorityID, _ := uuid.NewRandom()
+
+		moduleFS := file.MustNewFS([]file.File{
+			file.NewInMemoryFile("main.tf", []byte(`
+				variable "name" {
+				  description = "The name of the VPC"
+				}
+			`)),
+		})
+
+		invalidFS := file.MustNewFS([]file.File{
+			file.NewInMemoryFile("main.tf", []byte(`
+				variable "name" {
+			`)),
+		})
+
+		Convey("Given an authority without admission policy", func() {
+			mockAdmissionPolicyRepository.
+				On("Find", authorityID).
+				Return(nil, repositories.ErrNotFound)
+
+			Convey("When a valid module is validated", func() {
+				err := admissionService.Validate(authorityID, moduleFS)
+
+				Convey("No error should be returned", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+
+			Convey("When a module with an invalid configuration is validated", func() {
+				err := admissionService.Validate(authorityID, invalidFS)
+
+				Convey("The configuration errors should be returned", func() {
+					var admissionErr *AdmissionError
+					So(errors.As(err, &admissionErr), ShouldBeTrue)
+					So(admissionErr.Violations[0].Rule, ShouldEqual, docs.RuleValidConfiguration)
+				})
+			})
+		})
+
+		Convey("Given an authority requiring a README", func() {
+			mockAdmissionPolicyRepository.
+				On("Find", authorityID).
+				Return(&admission.Policy{
+					AuthorityID:   authorityID,
+					RequireReadme: true,
+				}, nil)
+
+			Convey("When a module without README is validated", func() {
+				err := admissionService.Validate(authorityID, moduleFS)
+
+				Convey("The missing README should be reported", func() {
+					var admissionErr *AdmissionError
+					So(errors.As(err, &admissionErr), ShouldBeTrue)
+					So(admissionErr.Violations, ShouldHaveLength, 1)
+					So(admissionErr.Violations[0].Rule, ShouldEqual, docs.RuleReadme)
+				})
+			})
+		})
+
+		Convey("Given the admission policies database fails", func() {
+			expectedErr := errors.New("error while querying the database")
+
+			mockAdmissionPolicyRepository.
+				On("Find", authorityID).
+				Return(nil, expectedErr)
+
+			Convey("When a module is validated", func() {
+				err := admissionService.Validate(authorityID, moduleFS)
+
+				Convey("The error should be returned", func() {
+					So(err, ShouldEqual, expectedErr)
+				})
+			})
+		})
+	})
+}
+
+func TestSetAdmissionPolicy(t *testing.T) {
+	Convey("Subject: Set the admission policy of an authority", t, func() {
+		mockAdmissionPolicyRepository := repositories.NewMockAdmissionPolicyRepository(t)
+
+		admissionService := &DefaultAdmissionService{
+			AdmissionPolicyRepository: mockAdmissionPolicyRepository,
+		}
+
+		authorityID, _ := uuid.NewRandom()
+
+		Convey("Given a policy with an unknown semver enforcement", func() {
+			d := admission.PolicyDTO{SemverEnforcement: "block"}
+
+			Convey("When the policy is set", func() {
+				_, err := admissionService.SetPolicy(authorityID, d)
+
+				Convey("The policy should be rejected", func() {
+					So(errors.Is(err, ErrInvalidAdmissionPolicy), ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("Given a policy without semver enforcement", func() {
+			mockAdmissionPolicyRepository.
+				On("Upsert", admission.Policy{AuthorityID: authorityID, SemverEnforcement: admission.SemverEnforcementOff}).
+				Return(func(p admission.Policy) *admission.Policy { return &p }, nil)
+
+			Convey("When the policy is set", func() {
+				policy, err := admissionService.SetPolicy(authorityID, admission.PolicyDTO{})
+
+				Convey("The semver enforcement should be disabled", func() {
+					So(err, ShouldBeNil)
+					So(policy.SemverEnforcement, ShouldEqual, admission.SemverEnforcementOff)
+				})
+			})
+		})
+	})
+}
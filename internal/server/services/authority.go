@@ -2,15 +2,25 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"terralist/internal/server/models/authority"
 	"terralist/internal/server/repositories"
+	"terralist/pkg/pgp"
 
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 )
 
 var (
-	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyNotFound        = errors.New("key not found")
+	ErrSigningDisabled    = errors.New("managed signing keys are disabled")
+	ErrSigningKeyExists   = errors.New("the authority already has a signing key")
+	ErrSigningKeyNotFound = errors.New("the authority has no signing key")
+	ErrSigningKeyInUse    = errors.New("the key is the signing key of the authority")
+	ErrKeySignedVersions  = errors.New("the key signed provider versions, revoke it instead")
 )
 
 // AuthorityService describes a service that can interact with the authorities database.
@@ -37,16 +47,43 @@ type AuthorityService interface {
 	AddKey(uuid.UUID, authority.KeyDTO) (*authority.KeyDTO, error)
 
 	// RemoveKey removes an existing key from an existing authority.
-	// If no keys are left, the entire authority is removed.
+	// If no keys are left, the entire authority is removed. A key which
+	// signed provider versions cannot be removed, as Terraform could no
+	// longer verify them, so it should be revoked instead.
 	RemoveKey(uuid.UUID, uuid.UUID) error
 
+	// RevokeKey revokes a key of an authority from a given date. The key
+	// is kept so the versions it signed can still be verified, but it can no
+	// longer sign new versions once the date is reached, and the versions it
+	// signed after the date are flagged.
+	RevokeKey(uuid.UUID, uuid.UUID, authority.RevokeKeyDTO) (*authority.RevokedKeyDTO, error)
+
+	// CreateSigningKey sets the key the registry signs the providers of an
+	// authority with. The key is generated if no private key is given. Its
+	// public half is added to the keys of the authority.
+	CreateSigningKey(uuid.UUID, authority.SigningKeyDTO) (*authority.KeyDTO, error)
+
+	// RotateSigningKey replaces the signing key of an authority. The retired
+	// key can no longer sign, but its public half is kept so the versions it
+	// signed can still be verified.
+	RotateSigningKey(uuid.UUID, authority.SigningKeyDTO) (*authority.KeyDTO, error)
+
+	// Sign signs a content with the signing key of an authority, and returns
+	// the signature along with the ID of the key.
+	Sign(authorityID uuid.UUID, content []byte) ([]byte, string, error)
+
 	// Delete removes an existing authority.
 	Delete(id uuid.UUID) error
 }
 
 // DefaultAuthorityService is a concrete implementation of AuthorityService.
 type DefaultAuthorityService struct {
-	AuthorityRepository repositories.AuthorityRepository
+	AuthorityRepository  repositories.AuthorityRepository
+	SigningKeyRepository repositories.SigningKeyRepository
+
+	// SigningSecret seals the signing keys managed by the registry. The
+	// managed signing keys are disabled if it is empty.
+	SigningSecret string
 }
 
 func (s *DefaultAuthorityService) GetByID(id uuid.UUID) (*authority.Authority, error) {
@@ -129,6 +166,19 @@ func (s *DefaultAuthorityService) RemoveKey(authorityID uuid.UUID, keyID uuid.UU
 	l := len(a.Keys)
 	for i, key := range a.Keys {
 		if key.ID == keyID {
+			if s.isSigningKey(authorityID, key.KeyId) {
+				return ErrSigningKeyInUse
+			}
+
+			signed, err := s.AuthorityRepository.CountSignedVersions(key.ID)
+			if err != nil {
+				return err
+			}
+
+			if signed > 0 {
+				return ErrKeySignedVersions
+			}
+
 			a.Keys = append(a.Keys[:i], a.Keys[i+1:]...)
 			break
 		}
@@ -148,6 +198,160 @@ func (s *DefaultAuthorityService) RemoveKey(authorityID uuid.UUID, keyID uuid.UU
 	return err
 }
 
+func (s *DefaultAuthorityService) RevokeKey(authorityID uuid.UUID, keyID uuid.UUID, in authority.RevokeKeyDTO) (*authority.RevokedKeyDTO, error) {
+	a, err := s.AuthorityRepository.FindByID(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := lo.Find(a.Keys, func(k authority.Key) bool {
+		return k.ID == keyID
+	})
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	// The registry would keep signing with its active signing key, so it
+	// has to be rotated first
+	if s.isSigningKey(authorityID, key.KeyId) {
+		return nil, ErrSigningKeyInUse
+	}
+
+	// A key revoked from a future date keeps signing versions until then
+	revokedAt := time.Now()
+	if in.RevokedAt != nil {
+		revokedAt = *in.RevokedAt
+	}
+	key.RevokedAt = &revokedAt
+
+	flagged, err := s.AuthorityRepository.RevokeKey(&key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authority.RevokedKeyDTO{
+		KeyDTO:          key.ToKeyDTO(),
+		FlaggedVersions: flagged,
+	}, nil
+}
+
+func (s *DefaultAuthorityService) CreateSigningKey(authorityID uuid.UUID, in authority.SigningKeyDTO) (*authority.KeyDTO, error) {
+	if s.SigningSecret == "" {
+		return nil, ErrSigningDisabled
+	}
+
+	if _, err := s.SigningKeyRepository.FindActive(authorityID); err == nil {
+		return nil, ErrSigningKeyExists
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	return s.setSigningKey(authorityID, in)
+}
+
+func (s *DefaultAuthorityService) RotateSigningKey(authorityID uuid.UUID, in authority.SigningKeyDTO) (*authority.KeyDTO, error) {
+	if s.SigningSecret == "" {
+		return nil, ErrSigningDisabled
+	}
+
+	if _, err := s.SigningKeyRepository.FindActive(authorityID); errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrSigningKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s.setSigningKey(authorityID, in)
+}
+
+func (s *DefaultAuthorityService) Sign(authorityID uuid.UUID, content []byte) ([]byte, string, error) {
+	if s.SigningSecret == "" {
+		return nil, "", ErrSigningDisabled
+	}
+
+	k, err := s.SigningKeyRepository.FindActive(authorityID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, "", ErrSigningKeyNotFound
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	key, err := pgp.OpenPrivateKey(k.PrivateKey, s.SigningSecret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	signature, err := key.Sign(content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return signature, k.KeyId, nil
+}
+
+// setSigningKey generates or imports a signing key for an authority, and
+// makes it the active one.
+func (s *DefaultAuthorityService) setSigningKey(authorityID uuid.UUID, in authority.SigningKeyDTO) (*authority.KeyDTO, error) {
+	a, err := s.AuthorityRepository.FindByID(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var key *pgp.PrivateKey
+	if in.AsciiArmor != "" {
+		key, err = pgp.ReadPrivateKey(in.AsciiArmor, in.Passphrase)
+	} else {
+		email := ""
+		if strings.Contains(a.Owner, "@") {
+			email = a.Owner
+		}
+
+		key, err = pgp.GenerateKey(a.Name, "Terralist signing key", email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("could not export the public key: %v", err)
+	}
+
+	sealed, err := key.Seal(s.SigningSecret)
+	if err != nil {
+		return nil, fmt.Errorf("could not seal the private key: %v", err)
+	}
+
+	public, err := s.SigningKeyRepository.Rotate(authority.SigningKey{
+		AuthorityID: authorityID,
+		KeyId:       key.KeyID(),
+		PrivateKey:  sealed,
+	}, authority.Key{
+		KeyId:      key.KeyID(),
+		AsciiArmor: publicKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dto := public.ToKeyDTO()
+	return &dto, nil
+}
+
+// isSigningKey returns true if a key is the one the registry signs the
+// providers of an authority with.
+func (s *DefaultAuthorityService) isSigningKey(authorityID uuid.UUID, keyId string) bool {
+	if s.SigningKeyRepository == nil {
+		return false
+	}
+
+	k, err := s.SigningKeyRepository.FindActive(authorityID)
+	if err != nil {
+		return false
+	}
+
+	return k.KeyId == keyId
+}
+
 func (s *DefaultAuthorityService) Delete(id uuid.UUID) error {
 	return s.AuthorityRepository.Delete(id)
 }
@@ -1,13 +1,19 @@
 package services
 
 import (
+	"bytes"
 	"errors"
 	"testing"
+	"time"
 
 	"terralist/internal/server/models/authority"
 	"terralist/internal/server/repositories"
 	"terralist/pkg/database/entity"
+	"terralist/pkg/pgp"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/google/uuid"
 	"github.com/mazen160/go-random"
 	. "github.com/smartystreets/goconvey/convey"
@@ -274,6 +280,10 @@ func TestRemoveKey(t *testing.T) {
 						},
 					}, nil)
 
+				mockAuthorityRepository.
+					On("CountSignedVersions", keyID).
+					Return(int64(0), nil)
+
 				mockAuthorityRepository.
 					On("Delete", authorityID).
 					Return(nil)
@@ -307,6 +317,10 @@ func TestRemoveKey(t *testing.T) {
 						},
 					}, nil)
 
+				mockAuthorityRepository.
+					On("CountSignedVersions", keyID).
+					Return(int64(0), nil)
+
 				mockAuthorityRepository.
 					On("Upsert", mock.AnythingOfType("authority.Authority")).
 					Return(&authority.Authority{}, nil)
@@ -322,6 +336,33 @@ func TestRemoveKey(t *testing.T) {
 
 			})
 
+			Convey("If the key signed provider versions", func() {
+				mockAuthorityRepository.
+					On("FindByID", authorityID).
+					Return(&authority.Authority{
+						Keys: []authority.Key{
+							{
+								Entity: entity.Entity{
+									ID: keyID,
+								},
+							},
+						},
+					}, nil)
+
+				mockAuthorityRepository.
+					On("CountSignedVersions", keyID).
+					Return(int64(2), nil)
+
+				Convey("When the service is queried", func() {
+					err := authorityService.RemoveKey(authorityID, keyID)
+
+					Convey("The key should be kept", func() {
+						So(err, ShouldEqual, ErrKeySignedVersions)
+						mockAuthorityRepository.AssertNotCalled(t, "Delete", authorityID)
+					})
+				})
+			})
+
 			Convey("If the authority does not exists", func() {
 				mockAuthorityRepository.
 					On("FindByID", authorityID).
@@ -339,6 +380,223 @@ func TestRemoveKey(t *testing.T) {
 	})
 }
 
+func TestRevokeKey(t *testing.T) {
+	Convey("Subject: Revoke authority keys", t, func() {
+		mockAuthorityRepository := repositories.NewMockAuthorityRepository(t)
+
+		authorityService := &DefaultAuthorityService{
+			AuthorityRepository: mockAuthorityRepository,
+		}
+
+		Convey("Given an authority with a key", func() {
+			authorityID, _ := uuid.NewRandom()
+			keyID, _ := uuid.NewRandom()
+
+			mockAuthorityRepository.
+				On("FindByID", authorityID).
+				Return(&authority.Authority{
+					Keys: []authority.Key{
+						{
+							Entity: entity.Entity{
+								ID: keyID,
+							},
+							KeyId: "34365D9472D7468F",
+						},
+					},
+				}, nil)
+
+			Convey("When an unknown key is revoked", func() {
+				otherKeyID, _ := uuid.NewRandom()
+
+				_, err := authorityService.RevokeKey(authorityID, otherKeyID, authority.RevokeKeyDTO{})
+
+				Convey("A key not found error should be returned", func() {
+					So(errors.Is(err, ErrKeyNotFound), ShouldBeTrue)
+				})
+			})
+
+			Convey("When the key is revoked from a future date", func() {
+				revokedAt := time.Now().Add(time.Hour)
+
+				mockAuthorityRepository.
+					On("RevokeKey", mock.MatchedBy(func(k *authority.Key) bool {
+						return k.ID == keyID && k.RevokedAt.Equal(revokedAt)
+					})).
+					Return(int64(0), nil)
+
+				resp, err := authorityService.RevokeKey(authorityID, keyID, authority.RevokeKeyDTO{RevokedAt: &revokedAt})
+
+				Convey("The revocation should be scheduled", func() {
+					So(err, ShouldBeNil)
+					So(resp.RevokedAt.Equal(revokedAt), ShouldBeTrue)
+					So(resp.FlaggedVersions, ShouldEqual, 0)
+				})
+			})
+
+			Convey("When the key is revoked from a past date", func() {
+				revokedAt := time.Now().Add(-24 * time.Hour)
+
+				mockAuthorityRepository.
+					On("RevokeKey", mock.MatchedBy(func(k *authority.Key) bool {
+						return k.ID == keyID && k.RevokedAt.Equal(revokedAt)
+					})).
+					Return(int64(2), nil)
+
+				dto, err := authorityService.RevokeKey(authorityID, keyID, authority.RevokeKeyDTO{RevokedAt: &revokedAt})
+
+				Convey("The versions signed after the date should be flagged", func() {
+					So(err, ShouldBeNil)
+					So(dto.ID, ShouldEqual, keyID.String())
+					So(dto.RevokedAt, ShouldNotBeNil)
+					So(dto.FlaggedVersions, ShouldEqual, 2)
+				})
+			})
+
+			Convey("When the key is revoked without a date", func() {
+				mockAuthorityRepository.
+					On("RevokeKey", mock.AnythingOfType("*authority.Key")).
+					Return(int64(0), nil)
+
+				dto, err := authorityService.RevokeKey(authorityID, keyID, authority.RevokeKeyDTO{})
+
+				Convey("The key should be revoked from the current date", func() {
+					So(err, ShouldBeNil)
+					So(*dto.RevokedAt, ShouldHappenWithin, time.Minute, time.Now())
+				})
+			})
+		})
+	})
+}
+
+func TestSigningKey(t *testing.T) {
+	Convey("Subject: Manage the signing key of an authority", t, func() {
+		mockAuthorityRepository := repositories.NewMockAuthorityRepository(t)
+		mockSigningKeyRepository := repositories.NewMockSigningKeyRepository(t)
+
+		authorityService := &DefaultAuthorityService{
+			AuthorityRepository:  mockAuthorityRepository,
+			SigningKeyRepository: mockSigningKeyRepository,
+			SigningSecret:        "secret",
+		}
+
+		Convey("Given an authority ID", func() {
+			authorityID, _ := uuid.NewRandom()
+
+			Convey("If the managed signing keys are disabled", func() {
+				authorityService.SigningSecret = ""
+
+				Convey("When a signing key is created", func() {
+					_, err := authorityService.CreateSigningKey(authorityID, authority.SigningKeyDTO{})
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrSigningDisabled), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("If the authority already has a signing key", func() {
+				mockSigningKeyRepository.
+					On("FindActive", authorityID).
+					Return(&authority.SigningKey{}, nil)
+
+				Convey("When a signing key is created", func() {
+					_, err := authorityService.CreateSigningKey(authorityID, authority.SigningKeyDTO{})
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrSigningKeyExists), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("If the authority has no signing key", func() {
+				mockSigningKeyRepository.
+					On("FindActive", authorityID).
+					Return(nil, repositories.ErrNotFound).
+					Once()
+
+				mockAuthorityRepository.
+					On("FindByID", authorityID).
+					Return(&authority.Authority{Name: "hashicorp"}, nil)
+
+				privateKey, keyID := newTestPrivateKey()
+
+				Convey("When a private key is imported", func() {
+					var stored authority.SigningKey
+					mockSigningKeyRepository.
+						On("Rotate", mock.AnythingOfType("authority.SigningKey"), mock.AnythingOfType("authority.Key")).
+						Return(func(k authority.SigningKey, public authority.Key) (*authority.Key, error) {
+							stored = k
+							return &public, nil
+						})
+
+					dto, err := authorityService.CreateSigningKey(authorityID, authority.SigningKeyDTO{
+						AsciiArmor: privateKey,
+					})
+
+					Convey("Its public half should be returned", func() {
+						So(err, ShouldBeNil)
+						So(dto.KeyId, ShouldEqual, keyID)
+						So(dto.AsciiArmor, ShouldContainSubstring, "PGP PUBLIC KEY BLOCK")
+					})
+
+					Convey("It should be stored sealed", func() {
+						So(stored.KeyId, ShouldEqual, keyID)
+						So(stored.PrivateKey, ShouldNotBeEmpty)
+						So(stored.PrivateKey, ShouldNotContainSubstring, "PGP PRIVATE KEY BLOCK")
+					})
+
+					Convey("When a content is signed", func() {
+						mockSigningKeyRepository.
+							On("FindActive", authorityID).
+							Return(&stored, nil)
+
+						content := []byte("SHA256SUMS")
+						signature, signedBy, err := authorityService.Sign(authorityID, content)
+
+						Convey("The signature should match the public key", func() {
+							So(err, ShouldBeNil)
+							So(signedBy, ShouldEqual, keyID)
+							So(pgp.Verify(dto.AsciiArmor, content, signature), ShouldBeNil)
+						})
+					})
+				})
+
+				Convey("When an invalid private key is imported", func() {
+					_, err := authorityService.CreateSigningKey(authorityID, authority.SigningKeyDTO{
+						AsciiArmor: "not a key",
+					})
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, pgp.ErrInvalidPrivateKey), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("If the authority has no signing key to rotate", func() {
+				mockSigningKeyRepository.
+					On("FindActive", authorityID).
+					Return(nil, repositories.ErrNotFound)
+
+				Convey("When the signing key is rotated", func() {
+					_, err := authorityService.RotateSigningKey(authorityID, authority.SigningKeyDTO{})
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrSigningKeyNotFound), ShouldBeTrue)
+					})
+				})
+
+				Convey("When a content is signed", func() {
+					_, _, err := authorityService.Sign(authorityID, []byte("SHA256SUMS"))
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrSigningKeyNotFound), ShouldBeTrue)
+					})
+				})
+			})
+		})
+	})
+}
+
 func TestDeleteAuthority(t *testing.T) {
 	Convey("Subject: Creating authorities", t, func() {
 		mockAuthorityRepository := repositories.NewMockAuthorityRepository(t)
@@ -364,3 +622,26 @@ func TestDeleteAuthority(t *testing.T) {
 		})
 	})
 }
+
+func newTestPrivateKey() (string, string) {
+	entity, err := openpgp.NewEntity("Terralist", "test", "test@terralist.io", &packet.Config{RSABits: 1024})
+	if err != nil {
+		panic(err)
+	}
+
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		panic(err)
+	}
+
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+
+	return armored.String(), entity.PrimaryKey.KeyIdString()
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"terralist/internal/server/models/blob"
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/file"
+	"terralist/pkg/storage"
+	"terralist/pkg/storage/local"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ContentResolver is a storage.Resolver which stores each distinct content
+// once, under a key derived from its SHA-256 digest. The keys returned to the
+// callers are the same as the ones of the wrapped resolver, and reference the
+// blob holding their content. A blob is purged once no key references it.
+//
+// Keys stored before the content-addressed storage are passed through to the
+// wrapped resolver, until they are adopted.
+type ContentResolver struct {
+	Resolver       storage.Resolver
+	BlobRepository repositories.BlobRepository
+
+	// Namespace isolates the blobs of the resolver from the ones of other
+	// resolvers sharing the same database. It is also the key prefix of the
+	// blobs, so they are served the same way as the other keys.
+	Namespace string
+
+	// Fetcher downloads the content of the keys which are adopted
+	Fetcher file.Fetcher
+}
+
+func (r *ContentResolver) Unwrap() storage.Resolver {
+	return r.Resolver
+}
+
+func (r *ContentResolver) Store(in *storage.StoreInput) (string, error) {
+	key := fmt.Sprintf("%s/%s", in.KeyPrefix, in.FileName)
+
+	digest, err := file.Checksum(in.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	// Reference the blob already holding the content, if any
+	_, released, err := r.BlobRepository.Reference(r.Namespace, key, blob.Blob{Digest: digest})
+	if errors.Is(err, repositories.ErrNotFound) {
+		// The content was never stored, or its blob was released meanwhile
+		released, err = r.storeBlob(key, digest, in)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not reference the stored content: %w", err)
+	}
+
+	if released != nil {
+		r.purge(released.Key)
+	}
+
+	return key, nil
+}
+
+// storeBlob stores a content in a new blob and references it by the given
+// key. It returns the blob released by the key, if any.
+func (r *ContentResolver) storeBlob(key, digest string, in *storage.StoreInput) (*blob.Blob, error) {
+	// Each upload is stored under its own key, so it can be purged without
+	// affecting the concurrent uploads of the same content
+	blobKey, err := r.Resolver.Store(&storage.StoreInput{
+		Reader:      in.Reader,
+		Size:        in.Size,
+		ContentType: in.ContentType,
+		KeyPrefix:   fmt.Sprintf("%s/blobs/sha256/%s/%s", r.Namespace, digest, uuid.NewString()),
+		FileName:    in.FileName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	referenced, released, err := r.BlobRepository.Reference(r.Namespace, key, blob.Blob{
+		Digest: digest,
+		Key:    blobKey,
+		Size:   in.Size,
+	})
+	if err != nil {
+		r.purge(blobKey)
+		return nil, err
+	}
+
+	// The same content was stored by a concurrent upload, so the blob
+	// created by the other upload is kept
+	if referenced.Key != blobKey {
+		r.purge(blobKey)
+	}
+
+	return released, nil
+}
+
+func (r *ContentResolver) Find(key string) (string, error) {
+	ref, err := r.BlobRepository.FindReference(r.Namespace, key)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return r.Resolver.Find(key)
+	} else if err != nil {
+		return "", err
+	}
+
+	return r.Resolver.Find(ref.Blob.Key)
+}
+
+func (r *ContentResolver) Purge(key string) error {
+	released, err := r.BlobRepository.Release(r.Namespace, key)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return r.Resolver.Purge(key)
+	} else if err != nil {
+		return err
+	}
+
+	if released != nil {
+		return r.Resolver.Purge(released.Key)
+	}
+
+	return nil
+}
+
+// Open reads the content stored under a key. The caller must invoke the
+// cleanup function once the file is no longer needed.
+func (r *ContentResolver) Open(key string) (file.File, func(), error) {
+	ref, err := r.BlobRepository.FindReference(r.Namespace, key)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return r.open(key)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return r.open(ref.Blob.Key)
+}
+
+// Adopt moves the content of a key stored before the content-addressed
+// storage to a blob. It returns false if the key was already referenced.
+func (r *ContentResolver) Adopt(key string) (bool, error) {
+	if _, err := r.BlobRepository.FindReference(r.Namespace, key); err == nil {
+		return false, nil
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return false, err
+	}
+
+	content, cleanup, err := r.open(key)
+	if errors.Is(err, local.ErrFileNotFound) || errors.Is(err, file.ErrNotFound) {
+		// Nothing was stored under the key (e.g. submodules without
+		// documentation)
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer cleanup()
+	defer content.Close()
+
+	if _, err := r.Store(&storage.StoreInput{
+		Reader:      content,
+		Size:        content.Metadata().Size(),
+		ContentType: file.ContentType(content),
+		KeyPrefix:   path.Dir(key),
+		FileName:    path.Base(key),
+	}); err != nil {
+		return false, err
+	}
+
+	// The blob is stored under another key, so the original copy is no
+	// longer needed
+	if err := r.Resolver.Purge(key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// open reads the content stored under a key of the wrapped resolver.
+func (r *ContentResolver) open(key string) (file.File, func(), error) {
+	if lr := local.UnwrapResolver(r.Resolver); lr != nil {
+		f, err := lr.GetObject(key)
+		return f, func() {}, err
+	}
+
+	if r.Fetcher == nil {
+		return nil, nil, fmt.Errorf("no fetcher configured to download %s", key)
+	}
+
+	rawURL, err := r.Resolver.Find(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The content is stored as is, even if it is an archive
+	return r.Fetcher.FetchFile(path.Base(key), withQueryParam(rawURL, "archive", "false"), nil)
+}
+
+// withQueryParam appends a query parameter to a URL. The existing query is
+// kept as is, since it may be signed.
+func withQueryParam(rawURL, key, value string) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+
+	return rawURL + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
+// purge removes a blob from the wrapped resolver. Failures are only
+// logged, since the blob is no longer referenced.
+func (r *ContentResolver) purge(key string) {
+	if err := r.Resolver.Purge(key); err != nil {
+		log.Warn().
+			AnErr("Error", err).
+			Str("Key", key).
+			Msg("Could not purge blob, require manual clean-up")
+	}
+}
+
+// ContentMigration adopts the keys stored before the content-addressed
+// storage, so their content is deduplicated and reference counted.
+type ContentMigration struct {
+	AuthorityService   AuthorityService
+	ModuleRepository   repositories.ModuleRepository
+	ProviderRepository repositories.ProviderRepository
+
+	// The resolvers are nil when the artifacts are not stored by Terralist
+	ModulesResolver   *ContentResolver
+	ProvidersResolver *ContentResolver
+}
+
+// Run adopts the keys of all module and provider versions. Keys which cannot
+// be adopted are skipped, and an error is returned once the others are
+// adopted, so they are retried on the next run.
+func (m *ContentMigration) Run() error {
+	authorities, err := m.AuthorityService.GetAll()
+	if err != nil {
+		return err
+	}
+
+	adopted, failed := 0, 0
+	adopt := func(r *ContentResolver, key string) {
+		if key == "" {
+			return
+		}
+
+		ok, err := r.Adopt(key)
+		if err != nil {
+			log.Warn().
+				AnErr("Error", err).
+				Str("Key", key).
+				Msg("Could not move key to the content-addressed storage")
+
+			failed++
+			return
+		}
+
+		if ok {
+			adopted++
+		}
+	}
+
+	for _, a := range authorities {
+		if m.ModulesResolver != nil {
+			modules, err := m.ModuleRepository.FindAll(module.ListFilter{Namespace: a.Name})
+			if err != nil {
+				return err
+			}
+
+			for _, mod := range modules {
+				for _, v := range mod.Versions {
+					adopt(m.ModulesResolver, v.Location)
+
+					if v.Documentation != nil {
+						adopt(m.ModulesResolver, *v.Documentation)
+					}
+
+					for _, sm := range v.Submodules {
+						adopt(m.ModulesResolver, submoduleDocsKey(a.Name, mod.Name, mod.Provider, v.Version, sm.Path))
+					}
+				}
+			}
+		}
+
+		if m.ProvidersResolver != nil {
+			providers, err := m.ProviderRepository.FindAll(a.Name)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range providers {
+				// Platforms are only loaded when searching for a single provider
+				prov, err := m.ProviderRepository.Find(a.Name, p.Name)
+				if err != nil {
+					return err
+				}
+
+				for _, v := range prov.Versions {
+					adopt(m.ProvidersResolver, v.ShaSumsUrl)
+					adopt(m.ProvidersResolver, v.ShaSumsSignatureUrl)
+
+					for _, plat := range v.Platforms {
+						adopt(m.ProvidersResolver, plat.Location)
+					}
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d keys could not be moved, %d keys were moved", failed, adopted)
+	}
+
+	log.Info().
+		Int("keys", adopted).
+		Msg("content-addressed storage migration completed")
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"terralist/internal/server/models/blob"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/database/entity"
+	"terralist/pkg/storage"
+
+	"github.com/google/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestContentResolverStore(t *testing.T) {
+	Convey("Subject: Store a file in the content-addressed storage", t, func() {
+		mockResolver := storage.NewMockResolver(t)
+		mockBlobRepository := repositories.NewMockBlobRepository(t)
+
+		resolver := &ContentResolver{
+			Resolver:       mockResolver,
+			BlobRepository: mockBlobRepository,
+			Namespace:      "modules",
+		}
+
+		content := "module archive"
+		sum := sha256.Sum256([]byte(content))
+		digest := hex.EncodeToString(sum[:])
+
+		in := &storage.StoreInput{
+			Reader:      strings.NewReader(content),
+			Size:        int64(len(content)),
+			ContentType: "application/zip",
+			KeyPrefix:   "modules/acme/vpc/aws",
+			FileName:    "1.0.0.zip",
+		}
+
+		blobKey := "modules/blobs/sha256/" + digest + "/upload/1.0.0.zip"
+
+		Convey("Given a content which is not stored yet", func() {
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", blob.Blob{Digest: digest}).
+				Return(nil, nil, repositories.ErrNotFound).
+				Once()
+
+			mockResolver.
+				On("Store", mock.MatchedBy(func(in *storage.StoreInput) bool {
+					return strings.HasPrefix(in.KeyPrefix, "modules/blobs/sha256/"+digest+"/") && in.FileName == "1.0.0.zip"
+				})).
+				Return(blobKey, nil)
+
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", mock.MatchedBy(func(b blob.Blob) bool {
+					return b.Digest == digest && b.Key == blobKey && b.Size == int64(len(content))
+				})).
+				Return(func(_, _ string, b blob.Blob) *blob.Blob {
+					b.ReferenceCount = 1
+					return &b
+				}, nil, nil)
+
+			Convey("When the file is stored", func() {
+				key, err := resolver.Store(in)
+
+				Convey("The content should be stored under its digest", func() {
+					So(err, ShouldBeNil)
+					So(key, ShouldEqual, "modules/acme/vpc/aws/1.0.0.zip")
+				})
+			})
+		})
+
+		Convey("Given a content which is stored concurrently", func() {
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", blob.Blob{Digest: digest}).
+				Return(nil, nil, repositories.ErrNotFound).
+				Once()
+
+			mockResolver.
+				On("Store", mock.AnythingOfType("*storage.StoreInput")).
+				Return(blobKey, nil)
+
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", mock.MatchedBy(func(b blob.Blob) bool {
+					return b.Key == blobKey
+				})).
+				Return(&blob.Blob{Digest: digest, Key: "modules/blobs/sha256/" + digest + "/0.9.0.zip"}, nil, nil)
+
+			mockResolver.
+				On("Purge", blobKey).
+				Return(nil)
+
+			Convey("When the file is stored", func() {
+				_, err := resolver.Store(in)
+
+				Convey("The blob stored by the other upload should be kept", func() {
+					So(err, ShouldBeNil)
+					mockResolver.AssertCalled(t, "Purge", blobKey)
+				})
+			})
+		})
+
+		Convey("Given a content which cannot be referenced once stored", func() {
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", blob.Blob{Digest: digest}).
+				Return(nil, nil, repositories.ErrNotFound).
+				Once()
+
+			mockResolver.
+				On("Store", mock.AnythingOfType("*storage.StoreInput")).
+				Return(blobKey, nil)
+
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", mock.MatchedBy(func(b blob.Blob) bool {
+					return b.Key == blobKey
+				})).
+				Return(nil, nil, errors.New("database is locked"))
+
+			mockResolver.
+				On("Purge", blobKey).
+				Return(nil)
+
+			Convey("When the file is stored", func() {
+				_, err := resolver.Store(in)
+
+				Convey("The stored content should be purged", func() {
+					So(err, ShouldNotBeNil)
+					mockResolver.AssertCalled(t, "Purge", blobKey)
+				})
+			})
+		})
+
+		Convey("Given a content which is already stored", func() {
+			id, _ := uuid.NewRandom()
+			stored := &blob.Blob{
+				Entity:         entity.Entity{ID: id},
+				Digest:         digest,
+				Key:            "modules/blobs/sha256/" + digest + "/0.9.0.zip",
+				ReferenceCount: 1,
+			}
+
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", blob.Blob{Digest: digest}).
+				Return(stored, nil, nil)
+
+			Convey("When the file is stored", func() {
+				key, err := resolver.Store(in)
+
+				Convey("The content should only be referenced", func() {
+					So(err, ShouldBeNil)
+					So(key, ShouldEqual, "modules/acme/vpc/aws/1.0.0.zip")
+					mockResolver.AssertNotCalled(t, "Store", mock.Anything)
+				})
+			})
+		})
+
+		Convey("Given a key which referenced another content", func() {
+			id, _ := uuid.NewRandom()
+			stored := &blob.Blob{
+				Entity: entity.Entity{ID: id},
+				Digest: digest,
+				Key:    blobKey,
+			}
+
+			mockBlobRepository.
+				On("Reference", "modules", "modules/acme/vpc/aws/1.0.0.zip", blob.Blob{Digest: digest}).
+				Return(stored, &blob.Blob{Key: "modules/blobs/sha256/previous/1.0.0.zip"}, nil)
+
+			mockResolver.
+				On("Purge", "modules/blobs/sha256/previous/1.0.0.zip").
+				Return(nil)
+
+			Convey("When the file is stored", func() {
+				_, err := resolver.Store(in)
+
+				Convey("The content which is no longer referenced should be purged", func() {
+					So(err, ShouldBeNil)
+					mockResolver.AssertCalled(t, "Purge", "modules/blobs/sha256/previous/1.0.0.zip")
+				})
+			})
+		})
+	})
+}
+
+func TestContentResolverPurge(t *testing.T) {
+	Convey("Subject: Purge a file from the content-addressed storage", t, func() {
+		mockResolver := storage.NewMockResolver(t)
+		mockBlobRepository := repositories.NewMockBlobRepository(t)
+
+		resolver := &ContentResolver{
+			Resolver:       mockResolver,
+			BlobRepository: mockBlobRepository,
+			Namespace:      "providers",
+		}
+
+		key := "providers/acme/aws/1.0.0/terraform-provider-aws_1.0.0_linux_amd64.zip"
+
+		Convey("Given a content referenced by other keys", func() {
+			mockBlobRepository.
+				On("Release", "providers", key).
+				Return(nil, nil)
+
+			Convey("When the file is purged", func() {
+				err := resolver.Purge(key)
+
+				Convey("The content should be kept", func() {
+					So(err, ShouldBeNil)
+					mockResolver.AssertNotCalled(t, "Purge", mock.Anything)
+				})
+			})
+		})
+
+		Convey("Given a content referenced only by the key", func() {
+			mockBlobRepository.
+				On("Release", "providers", key).
+				Return(&blob.Blob{Key: "providers/blobs/sha256/abc/provider.zip"}, nil)
+
+			mockResolver.
+				On("Purge", "providers/blobs/sha256/abc/provider.zip").
+				Return(nil)
+
+			Convey("When the file is purged", func() {
+				err := resolver.Purge(key)
+
+				Convey("The content should be purged", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given a key stored before the content-addressed storage", func() {
+			mockBlobRepository.
+				On("Release", "providers", key).
+				Return(nil, repositories.ErrNotFound)
+
+			mockResolver.
+				On("Purge", key).
+				Return(nil)
+
+			Convey("When the file is purged", func() {
+				err := resolver.Purge(key)
+
+				Convey("The key should be purged from the datastore", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+	})
+}
+
+func TestContentResolverFind(t *testing.T) {
+	Convey("Subject: Find a file in the content-addressed storage", t, func() {
+		mockResolver := storage.NewMockResolver(t)
+		mockBlobRepository := repositories.NewMockBlobRepository(t)
+
+		resolver := &ContentResolver{
+			Resolver:       mockResolver,
+			BlobRepository: mockBlobRepository,
+			Namespace:      "modules",
+		}
+
+		key := "modules/acme/vpc/aws/1.0.0.zip"
+
+		Convey("Given a referenced key", func() {
+			mockBlobRepository.
+				On("FindReference", "modules", key).
+				Return(&blob.Reference{Key: key, Blob: blob.Blob{Key: "modules/blobs/sha256/abc/1.0.0.zip"}}, nil)
+
+			mockResolver.
+				On("Find", "modules/blobs/sha256/abc/1.0.0.zip").
+				Return("https://example.com/blob", nil)
+
+			Convey("When the file is searched", func() {
+				url, err := resolver.Find(key)
+
+				Convey("The URL of the content should be returned", func() {
+					So(err, ShouldBeNil)
+					So(url, ShouldEqual, "https://example.com/blob")
+				})
+			})
+		})
+
+		Convey("Given a key stored before the content-addressed storage", func() {
+			mockBlobRepository.
+				On("FindReference", "modules", key).
+				Return(nil, repositories.ErrNotFound)
+
+			mockResolver.
+				On("Find", key).
+				Return("https://example.com/legacy", nil)
+
+			Convey("When the file is searched", func() {
+				url, err := resolver.Find(key)
+
+				Convey("The URL of the key should be returned", func() {
+					So(err, ShouldBeNil)
+					So(url, ShouldEqual, "https://example.com/legacy")
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"terralist/internal/server/models/document"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/storage"
+)
+
+var (
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrDocumentTooLarge = errors.New("document exceeds the maximum size")
+
+	// documentClient reads the documents kept in the resolver datastores
+	documentClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// DocumentStore stores the text files generated for the artifacts, such as
+// the documentation of the modules.
+type DocumentStore interface {
+	// Put stores a document under a given key and returns the key under
+	// which it can be read back.
+	Put(key, content string) (string, error)
+
+	// Get returns the content of the document stored under a given key.
+	Get(key string) (string, error)
+
+	// Delete removes the document stored under a given key. If the key does
+	// not exist, it will not return an error.
+	Delete(key string) error
+}
+
+// ResolverDocumentStore is a DocumentStore keeping the documents in a
+// resolver datastore, next to the artifacts.
+type ResolverDocumentStore struct {
+	Resolver storage.Resolver
+}
+
+func (s *ResolverDocumentStore) Put(key, content string) (string, error) {
+	contentType := "text/plain; charset=utf-8"
+	if path.Ext(key) == ".md" {
+		contentType = "text/markdown; charset=utf-8"
+	}
+
+	return s.Resolver.Store(&storage.StoreInput{
+		Reader:      strings.NewReader(content),
+		Size:        int64(len(content)),
+		ContentType: contentType,
+		KeyPrefix:   path.Dir(key),
+		FileName:    path.Base(key),
+	})
+}
+
+func (s *ResolverDocumentStore) Get(key string) (string, error) {
+	url, err := s.Resolver.Find(key)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDocumentNotFound, err)
+	}
+
+	resp, err := documentClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// The cloud resolvers only presign the URLs, so the missing documents
+	// are reported by the datastore. Without the permission to list the
+	// bucket, S3 answers 403 instead of 404.
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: status code %d while fetching %s", ErrDocumentNotFound, resp.StatusCode, key)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d while fetching %s", resp.StatusCode, key)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+func (s *ResolverDocumentStore) Delete(key string) error {
+	return s.Resolver.Purge(key)
+}
+
+// DatabaseDocumentStore is a DocumentStore keeping the documents compressed
+// in the database. It is used when the artifacts are not stored by the
+// registry, so the documentation does not have to be generated from the
+// original archives on every request.
+type DatabaseDocumentStore struct {
+	DocumentRepository repositories.DocumentRepository
+
+	// MaxSize is the maximum size, in bytes, of a document before
+	// compression. Zero means no limit.
+	MaxSize int64
+}
+
+func (s *DatabaseDocumentStore) Put(key, content string) (string, error) {
+	size := int64(len(content))
+	if s.MaxSize > 0 && size > s.MaxSize {
+		return "", fmt.Errorf("%w: %s has %d bytes, the limit is %d", ErrDocumentTooLarge, key, size, s.MaxSize)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return "", err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	if _, err := s.DocumentRepository.Upsert(document.Document{
+		Key:     key,
+		Size:    size,
+		Content: buf.Bytes(),
+	}); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (s *DatabaseDocumentStore) Get(key string) (string, error) {
+	d, err := s.DocumentRepository.Find(key)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrDocumentNotFound, key)
+	} else if err != nil {
+		return "", err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(d.Content))
+	if err != nil {
+		return "", fmt.Errorf("could not decompress document %s: %v", key, err)
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("could not decompress document %s: %v", key, err)
+	}
+
+	return string(content), nil
+}
+
+func (s *DatabaseDocumentStore) Delete(key string) error {
+	return s.DocumentRepository.Delete(key)
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"terralist/internal/server/models/document"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/storage"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDatabaseDocumentStore(t *testing.T) {
+	Convey("Subject: Store documents in the database", t, func() {
+		mockDocumentRepository := repositories.NewMockDocumentRepository(t)
+
+		store := &DatabaseDocumentStore{
+			DocumentRepository: mockDocumentRepository,
+			MaxSize:            1024,
+		}
+
+		key := "modules/acme/vpc/aws/1.0.0.md"
+
+		Convey("Given a document within the size limit", func() {
+			content := "# VPC\n\n" + strings.Repeat("Creates a VPC. ", 50)
+
+			var saved document.Document
+			mockDocumentRepository.
+				On("Upsert", mock.AnythingOfType("document.Document")).
+				Run(func(args mock.Arguments) {
+					saved = args.Get(0).(document.Document)
+				}).
+				Return(&document.Document{}, nil)
+
+			Convey("When the document is stored", func() {
+				location, err := store.Put(key, content)
+
+				Convey("It should be stored compressed under its key", func() {
+					So(err, ShouldBeNil)
+					So(location, ShouldEqual, key)
+					So(saved.Key, ShouldEqual, key)
+					So(saved.Size, ShouldEqual, len(content))
+					So(len(saved.Content), ShouldBeLessThan, len(content))
+				})
+
+				Convey("And the document is read back", func() {
+					mockDocumentRepository.
+						On("Find", key).
+						Return(&saved, nil)
+
+					doc, err := store.Get(key)
+
+					Convey("The original content should be returned", func() {
+						So(err, ShouldBeNil)
+						So(doc, ShouldEqual, content)
+					})
+				})
+			})
+		})
+
+		Convey("Given a document exceeding the size limit", func() {
+			content := strings.Repeat("a", 1025)
+
+			Convey("When the document is stored", func() {
+				_, err := store.Put(key, content)
+
+				Convey("It should be rejected", func() {
+					So(errors.Is(err, ErrDocumentTooLarge), ShouldBeTrue)
+					mockDocumentRepository.AssertNotCalled(t, "Upsert", mock.Anything)
+				})
+			})
+		})
+
+		Convey("Given a document which is not stored", func() {
+			mockDocumentRepository.
+				On("Find", key).
+				Return(nil, repositories.ErrNotFound)
+
+			Convey("When the document is requested", func() {
+				_, err := store.Get(key)
+
+				Convey("A not found error should be returned", func() {
+					So(errors.Is(err, ErrDocumentNotFound), ShouldBeTrue)
+				})
+			})
+		})
+	})
+}
+
+func TestResolverDocumentStore(t *testing.T) {
+	Convey("Subject: Read documents from a resolver datastore", t, func() {
+		mockResolver := storage.NewMockResolver(t)
+
+		store := &ResolverDocumentStore{
+			Resolver: mockResolver,
+		}
+
+		key := "modules/acme/vpc/aws/1.0.0/submodules/nat.md"
+
+		Convey("Given a datastore presigning the URLs of missing documents", func() {
+			for _, status := range []int{http.StatusNotFound, http.StatusForbidden} {
+				Convey(fmt.Sprintf("If the datastore answers with status %d", status), func() {
+					server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(status)
+					}))
+					defer server.Close()
+
+					mockResolver.
+						On("Find", key).
+						Return(server.URL+"/"+key, nil)
+
+					Convey("When the document is requested", func() {
+						_, err := store.Get(key)
+
+						Convey("A not found error should be returned", func() {
+							So(errors.Is(err, ErrDocumentNotFound), ShouldBeTrue)
+						})
+					})
+				})
+			}
+		})
+
+		Convey("Given a datastore failing to serve the document", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			mockResolver.
+				On("Find", key).
+				Return(server.URL+"/"+key, nil)
+
+			Convey("When the document is requested", func() {
+				_, err := store.Get(key)
+
+				Convey("The failure should not be reported as a missing document", func() {
+					So(err, ShouldNotBeNil)
+					So(errors.Is(err, ErrDocumentNotFound), ShouldBeFalse)
+				})
+			})
+		})
+	})
+}
@@ -1,13 +1,18 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
+	"terralist/internal/server/models/admission"
+	"terralist/internal/server/models/artifact"
 	"terralist/internal/server/models/module"
 	"terralist/internal/server/repositories"
 	"terralist/pkg/docs"
@@ -18,19 +23,70 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
 )
 
+var (
+	ErrModulesNotStored      = errors.New("module archives are not stored by the registry")
+	ErrArchiveNotInspectable = errors.New("module archive could not be inspected")
+)
+
+// BreakingChangeError is returned when a module version introduces breaking
+// changes since the previous version of the same major line.
+type BreakingChangeError struct {
+	Version  string
+	Previous string
+	Changes  []docs.BreakingChange
+}
+
+func (e *BreakingChangeError) Error() string {
+	return fmt.Sprintf(
+		"version %s introduces %d breaking changes since version %s, a major version bump is required",
+		e.Version,
+		len(e.Changes),
+		e.Previous,
+	)
+}
+
+// fileOpener is implemented by the resolvers which can read back the files
+// they store.
+type fileOpener interface {
+	Open(key string) (file.File, func(), error)
+}
+
 // ModuleService describes a service that holds the business logic for modules registry.
 type ModuleService interface {
 	// Get returns a specific module.
 	Get(namespace, name, provider string) (*module.ListResponseDTO, error)
 
+	// List returns the latest version of every module matching a given
+	// filter, along with the number of modules read from the database. The
+	// count includes the modules left out of the list, so it tells whether
+	// the page was full.
+	List(filter module.ListFilter) ([]module.ListItemDTO, int, error)
+
+	// GetLatest returns the details of the latest version of a module. If a
+	// version constraint is given (e.g. "~> 1.2"), the latest version
+	// matching it is returned instead.
+	GetLatest(namespace, name, provider, constraint string) (*module.LatestDTO, error)
+
 	// GetVersion returns a module version.
 	GetVersion(namespace, name, provider, version string) (*module.VersionDTO, error)
 
 	// GetSubmoduleDocumentation returns documentation for a specific submodule within a module version.
 	GetSubmoduleDocumentation(namespace, name, provider, version, submodulePath string) (string, error)
 
+	// GetExamples returns the examples shipped with a module version.
+	GetExamples(namespace, name, provider, version string) ([]module.ExampleDTO, error)
+
+	// GetExample returns the documentation and the sources of a specific
+	// example within a module version.
+	GetExample(namespace, name, provider, version, examplePath string) (*module.ExampleDetailsDTO, error)
+
+	// Diff compares the interfaces of two versions of a module, as declared
+	// in their archives.
+	Diff(namespace, name, provider, from, to string) (*module.DiffDTO, error)
+
 	// GetVersionURL returns a public URL from which a specific a module version can be
 	// downloaded.
 	GetVersionURL(namespace, name, provider, version string) (*string, error)
@@ -39,13 +95,26 @@ type ModuleService interface {
 	// If the module does not exist, it will be created.
 	Upload(dto *module.CreateDTO, url string, header http.Header) error
 
+	// UploadContext is like Upload, but the download of the module is
+	// canceled when the given context is done.
+	UploadContext(ctx context.Context, dto *module.CreateDTO, url string, header http.Header) error
+
 	// Delete removes a module with all its data from the system.
 	Delete(authorityID uuid.UUID, name string, provider string) error
 
+	// SetVersionStatus updates the lifecycle status of a module version.
+	// Yanked versions are no longer listed, but can still be downloaded when
+	// pinned exactly.
+	SetVersionStatus(authorityID uuid.UUID, name, provider, version string, status artifact.Status, d artifact.UpdateStatusDTO) error
+
 	// DeleteVersion removes a module version from the system.
 	// If the version removed is the only module version available, the entire
 	// module will be removed.
 	DeleteVersion(authorityID uuid.UUID, name string, provider string, version string) error
+
+	// Verify re-computes the checksums of the archives stored for the
+	// modules of an authority and reports the ones which do not match.
+	Verify(authorityID uuid.UUID) (*module.VerificationReportDTO, error)
 }
 
 // DefaultModuleService is the concrete implementation of ModuleService.
@@ -54,6 +123,24 @@ type DefaultModuleService struct {
 	AuthorityService AuthorityService
 	Resolver         storage.Resolver
 	Fetcher          file.Fetcher
+
+	// AdmissionService validates the uploaded modules, if set
+	AdmissionService AdmissionService
+
+	// DocumentStore stores the documentation of the modules when the
+	// archives are not stored by the registry, if set
+	DocumentStore DocumentStore
+}
+
+// documents returns the store holding the documentation of the modules. The
+// documentation is kept next to the archives, when they are stored by the
+// registry.
+func (s *DefaultModuleService) documents() DocumentStore {
+	if s.Resolver != nil {
+		return &ResolverDocumentStore{Resolver: s.Resolver}
+	}
+
+	return s.DocumentStore
 }
 
 func (s *DefaultModuleService) Get(namespace, name, provider string) (*module.ListResponseDTO, error) {
@@ -69,52 +156,105 @@ func (s *DefaultModuleService) Get(namespace, name, provider string) (*module.Li
 	return &dto, nil
 }
 
-func (s *DefaultModuleService) GetVersion(namespace, name, provider, version string) (*module.VersionDTO, error) {
-	v, err := s.ModuleRepository.FindVersion(namespace, name, provider, version)
+func (s *DefaultModuleService) List(filter module.ListFilter) ([]module.ListItemDTO, int, error) {
+	modules, err := s.ModuleRepository.FindAll(filter)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	result := v.ToDTO()
-	dto := &result
+	namespaces := map[uuid.UUID]string{}
 
-	if s.Resolver != nil && v.Documentation != nil && *v.Documentation != "" {
-		url, err := s.Resolver.Find(*v.Documentation)
-		if err != nil {
-			log.Warn().
-				Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
-				Err(err).
-				Msg("no documentation for module")
+	items := make([]module.ListItemDTO, 0, len(modules))
+	for _, m := range modules {
+		// Skip the modules without any version left to install
+		if m.GetLatestVersion() == nil {
+			continue
+		}
 
-			return dto, nil
+		namespace, ok := namespaces[m.AuthorityID]
+		if !ok {
+			a, err := s.AuthorityService.GetByID(m.AuthorityID)
+			if err != nil {
+				log.Warn().
+					Str("module", m.String()).
+					Str("authorityID", m.AuthorityID.String()).
+					Err(err).
+					Msg("could not resolve module authority, skipping")
+
+				continue
+			}
+
+			namespace = a.Name
+			namespaces[m.AuthorityID] = namespace
 		}
 
-		resp, err := http.Get(url)
-		if err != nil {
-			log.Warn().
-				Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
-				Str("url", url).
-				Err(err).
-				Msg("could not fetch module's documentation")
+		items = append(items, m.ToListItemDTO(namespace))
+	}
 
-			return dto, nil
+	return items, len(modules), nil
+}
+
+func (s *DefaultModuleService) GetLatest(namespace, name, provider, constraint string) (*module.LatestDTO, error) {
+	m, err := s.ModuleRepository.Find(namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := *m
+	if constraint != "" {
+		cs, err := version.ParseConstraints(constraint)
+		if err != nil {
+			return nil, err
 		}
-		defer resp.Body.Close()
 
-		body, err := io.ReadAll(resp.Body)
+		matching.Versions = lo.Filter(m.Versions, func(v module.Version, _ int) bool {
+			return cs.Check(version.Version(v.Version))
+		})
+	}
+
+	v := matching.GetLatestVersion()
+	if v == nil && constraint != "" {
+		return nil, fmt.Errorf("module %s/%s/%s has no versions matching %s", namespace, name, provider, constraint)
+	} else if v == nil {
+		return nil, fmt.Errorf("module %s/%s/%s has no versions", namespace, name, provider)
+	}
+
+	dto := &module.LatestDTO{
+		ListItemDTO: matching.ToListItemDTO(namespace),
+		Versions: lo.FilterMap(m.Versions, func(v module.Version, _ int) (string, bool) {
+			return v.Version, !v.IsYanked()
+		}),
+		Submodules: lo.Map(v.Submodules, func(sm module.Submodule, _ int) module.SubmoduleResponseDTO {
+			return sm.ToDTO()
+		}),
+	}
+
+	return dto, nil
+}
+
+func (s *DefaultModuleService) GetVersion(namespace, name, provider, version string) (*module.VersionDTO, error) {
+	v, err := s.ModuleRepository.FindVersion(namespace, name, provider, version)
+	if err != nil {
+		return nil, err
+	}
+
+	result := v.ToDTO()
+	dto := &result
+
+	store := s.documents()
+	if store != nil && v.Documentation != nil && *v.Documentation != "" {
+		doc, err := store.Get(*v.Documentation)
 		if err != nil {
 			log.Warn().
 				Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
-				Str("url", url).
 				Err(err).
-				Msg("could not read module documentation")
+				Msg("no documentation for module")
 
 			return dto, nil
 		}
 
-		bodyStr := string(body)
-		if bodyStr != "" {
-			dto.Documentation = &bodyStr
+		if doc != "" {
+			dto.Documentation = &doc
 		}
 	}
 
@@ -140,73 +280,66 @@ func (s *DefaultModuleService) GetSubmoduleDocumentation(namespace, name, provid
 		return "", fmt.Errorf("submodule %s not found in module %s/%s/%s/%s", submodulePath, namespace, name, provider, version)
 	}
 
-	if s.Resolver == nil {
-		if s.Fetcher == nil {
-			return "", fmt.Errorf("no fetcher configured to fetch submodule documentation")
-		}
+	if store := s.documents(); store != nil {
+		doc, err := store.Get(submoduleDocsKey(namespace, name, provider, version, submodulePath))
+		if err == nil {
+			// An empty document is stored for the submodules uploaded
+			// without documentation
+			if doc == "" {
+				return submoduleDocsNotAvailable, nil
+			}
 
-		archive, cleanup, err := s.Fetcher.Fetch(version, v.Location, nil)
-		if err != nil {
-			return "", fmt.Errorf("could not fetch module archive for submodule docs: %w", err)
+			return doc, nil
 		}
-		defer cleanup()
-		defer archive.Close()
 
-		archiveFile, ok := archive.(*file.ArchiveFile)
-		if !ok {
-			return "", fmt.Errorf("fetched module is not an archive")
-		}
+		if !errors.Is(err, ErrDocumentNotFound) {
+			log.Warn().
+				Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
+				Str("submodulePath", submodulePath).
+				Err(err).
+				Msg("could not read submodule documentation")
 
-		targetPath := submodulePath
-		if resolvedPath := resolveSubmodulePath(archiveFile.FS(), submodulePath); resolvedPath != "" {
-			targetPath = resolvedPath
+			return "", err
 		}
 
-		return docs.GetModuleDocumentation(archiveFile.FS(), targetPath)
-	}
-
-	// Construct the documentation file path
-	docsFileName := fmt.Sprintf("%s_%s.md", version, strings.ReplaceAll(submodulePath, "/", "__"))
-	docsKey := fmt.Sprintf("modules/%s/%s/%s/submodules/%s", namespace, name, provider, docsFileName)
+		// The archives stored by the registry are documented when they are
+		// uploaded, so a missing documentation is not looked up again
+		if s.Resolver != nil {
+			log.Warn().
+				Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
+				Str("submodulePath", submodulePath).
+				Err(err).
+				Msg("no documentation for submodule")
 
-	url, err := s.Resolver.Find(docsKey)
-	if err != nil {
-		log.Warn().
-			Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
-			Str("submodulePath", submodulePath).
-			Err(err).
-			Msg("no documentation for submodule")
+			// Return a helpful message instead of empty string or error
+			return submoduleDocsNotAvailable, nil
+		}
+	}
 
-		// Return a helpful message instead of empty string or error
-		return "# Documentation Not Available\n\nNo documentation file found for this submodule.", nil
+	// In proxy mode, the versions uploaded before their documentation was
+	// stored are documented from their archive
+	if s.Fetcher == nil {
+		return "", fmt.Errorf("no fetcher configured to fetch submodule documentation")
 	}
 
-	resp, err := http.Get(url)
+	archive, cleanup, err := s.Fetcher.Fetch(version, v.Location, nil)
 	if err != nil {
-		log.Warn().
-			Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
-			Str("submodulePath", submodulePath).
-			Str("url", url).
-			Err(err).
-			Msg("could not fetch submodule's documentation")
-
-		return "", err
+		return "", fmt.Errorf("could not fetch module archive for submodule docs: %w", err)
 	}
-	defer resp.Body.Close()
+	defer cleanup()
+	defer archive.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Warn().
-			Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
-			Str("submodulePath", submodulePath).
-			Str("url", url).
-			Err(err).
-			Msg("could not read submodule documentation")
+	archiveFile, ok := archive.(*file.ArchiveFile)
+	if !ok {
+		return "", fmt.Errorf("fetched module is not an archive")
+	}
 
-		return "", err
+	targetPath := submodulePath
+	if resolvedPath := resolveSubmodulePath(archiveFile.FS(), submodulePath); resolvedPath != "" {
+		targetPath = resolvedPath
 	}
 
-	return string(body), nil
+	return docs.GetModuleDocumentation(archiveFile.FS(), targetPath)
 }
 
 func resolveSubmodulePath(moduleFS *file.FS, submodulePath string) string {
@@ -270,18 +403,258 @@ func resolveSubmodulePath(moduleFS *file.FS, submodulePath string) string {
 	return resolvedPath
 }
 
+func (s *DefaultModuleService) GetExamples(namespace, name, provider, version string) ([]module.ExampleDTO, error) {
+	v, err := s.ModuleRepository.FindVersion(namespace, name, provider, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(v.Examples, func(e module.Example, _ int) module.ExampleDTO {
+		return e.ToDTO()
+	}), nil
+}
+
+func (s *DefaultModuleService) GetExample(namespace, name, provider, version, examplePath string) (*module.ExampleDetailsDTO, error) {
+	v, err := s.ModuleRepository.FindVersion(namespace, name, provider, version)
+	if err != nil {
+		return nil, err
+	}
+
+	example, ok := lo.Find(v.Examples, func(e module.Example) bool {
+		return e.Path == examplePath
+	})
+	if !ok {
+		return nil, fmt.Errorf("example %s not found in module %s/%s/%s/%s", examplePath, namespace, name, provider, version)
+	}
+
+	if store := s.documents(); store != nil {
+		dto, err := s.storedExample(store, namespace, name, provider, version, example)
+		if err == nil || !errors.Is(err, ErrDocumentNotFound) || s.Resolver != nil {
+			return dto, err
+		}
+
+		// In proxy mode, the versions uploaded before their examples were
+		// stored are documented from their archive
+	}
+
+	if s.Fetcher == nil {
+		return nil, fmt.Errorf("no fetcher configured to fetch example documentation")
+	}
+
+	archive, cleanup, err := s.Fetcher.Fetch(version, v.Location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch module archive for example docs: %w", err)
+	}
+	defer cleanup()
+	defer archive.Close()
+
+	archiveFile, ok := archive.(*file.ArchiveFile)
+	if !ok {
+		return nil, fmt.Errorf("fetched module is not an archive")
+	}
+
+	examples, err := docs.FindExamples(archiveFile.FS())
+	if err != nil {
+		return nil, fmt.Errorf("could not scan module archive for examples: %w", err)
+	}
+
+	info, ok := lo.Find(examples, func(e docs.ExampleInfo) bool {
+		return e.Path == examplePath
+	})
+	if !ok {
+		return nil, fmt.Errorf("example %s not found in module archive", examplePath)
+	}
+
+	return &module.ExampleDetailsDTO{
+		Path:          info.Path,
+		Documentation: info.Documentation,
+		Files: lo.Map(info.Files, func(f docs.ExampleFile, _ int) module.ExampleSourceDTO {
+			return module.ExampleSourceDTO{Name: f.Name, Content: f.Content}
+		}),
+	}, nil
+}
+
+// storedExample reads the documentation and the sources of an example from the
+// document store.
+func (s *DefaultModuleService) storedExample(store DocumentStore, namespace, name, provider, version string, example module.Example) (*module.ExampleDetailsDTO, error) {
+	dto := &module.ExampleDetailsDTO{
+		Path:  example.Path,
+		Files: []module.ExampleSourceDTO{},
+	}
+
+	doc, err := store.Get(exampleDocsKey(namespace, name, provider, version, example.Path))
+	if err != nil {
+		log.Warn().
+			Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
+			Str("examplePath", example.Path).
+			Err(err).
+			Msg("no documentation for example")
+
+		// Return a helpful message instead of empty string or error
+		doc = "# Documentation Not Available\n\nNo documentation file found for this example."
+	}
+	dto.Documentation = doc
+
+	for _, f := range example.Files {
+		content, err := store.Get(exampleSourceKey(namespace, name, provider, version, example.Path, f.Name))
+		if err != nil {
+			return nil, fmt.Errorf("could not read example file %s: %w", f.Name, err)
+		}
+
+		dto.Files = append(dto.Files, module.ExampleSourceDTO{
+			Name:    f.Name,
+			Content: content,
+		})
+	}
+
+	return dto, nil
+}
+
+// submoduleDocsNotAvailable is returned for the submodules which have no
+// documentation.
+const submoduleDocsNotAvailable = "# Documentation Not Available\n\nNo documentation file found for this submodule."
+
+// submoduleDocsKey returns the key under which the documentation of a
+// submodule is stored.
+func submoduleDocsKey(namespace, name, provider, version, submodulePath string) string {
+	return fmt.Sprintf(
+		"modules/%s/%s/%s/submodules/%s_%s.md",
+		namespace,
+		name,
+		provider,
+		version,
+		strings.ReplaceAll(submodulePath, "/", "__"),
+	)
+}
+
+// exampleDocsKey returns the key under which the documentation of an example
+// is stored.
+func exampleDocsKey(namespace, name, provider, version, examplePath string) string {
+	return fmt.Sprintf(
+		"modules/%s/%s/%s/examples/%s_%s.md",
+		namespace,
+		name,
+		provider,
+		version,
+		strings.ReplaceAll(examplePath, "/", "__"),
+	)
+}
+
+// exampleSourceKey returns the key under which a source file of an example is
+// stored, next to the documentation of the example.
+func exampleSourceKey(namespace, name, provider, version, examplePath, fileName string) string {
+	return fmt.Sprintf(
+		"modules/%s/%s/%s/examples/%s_%s/%s",
+		namespace,
+		name,
+		provider,
+		version,
+		strings.ReplaceAll(examplePath, "/", "__"),
+		strings.ReplaceAll(fileName, "/", "__"),
+	)
+}
+
+func (s *DefaultModuleService) Diff(namespace, name, provider, from, to string) (*module.DiffDTO, error) {
+	lhs, err := s.inspectVersion(namespace, name, provider, from)
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := s.inspectVersion(namespace, name, provider, to)
+	if err != nil {
+		return nil, err
+	}
+
+	d := docs.DiffModules(lhs, rhs)
+
+	return &module.DiffDTO{
+		From:             from,
+		To:               to,
+		Breaking:         d.Breaking(),
+		InterfaceDiffDTO: toInterfaceDiffDTO(d),
+		Submodules: lo.Map(d.Submodules, func(c docs.SubmoduleChange, _ int) module.SubmoduleChangeDTO {
+			dto := module.SubmoduleChangeDTO{ChangeDTO: toChangeDTO(c.Change)}
+			if c.Diff != nil {
+				diff := toInterfaceDiffDTO(c.Diff)
+				dto.Diff = &diff
+			}
+
+			return dto
+		}),
+	}, nil
+}
+
+// inspectVersion returns the interfaces of the root module and submodules of
+// a module version. They are read from the database; the archive of the
+// version is only inspected if it was uploaded before they were recorded.
+func (s *DefaultModuleService) inspectVersion(namespace, name, provider, version string) (*docs.ModuleDefinition, error) {
+	v, err := s.ModuleRepository.FindVersion(namespace, name, provider, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if def := storedDefinition(v); def != nil {
+		return def, nil
+	}
+
+	if s.Fetcher == nil {
+		return nil, fmt.Errorf("%w: no fetcher configured", ErrArchiveNotInspectable)
+	}
+
+	location := v.Location
+	if s.Resolver != nil {
+		location, err = s.Resolver.Find(v.Location)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not resolve location: %v", ErrArchiveNotInspectable, err)
+		}
+	}
+
+	archive, cleanup, err := s.Fetcher.Fetch(version, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not fetch version %s: %v", ErrArchiveNotInspectable, version, err)
+	}
+	defer cleanup()
+	defer archive.Close()
+
+	archiveFile, ok := archive.(*file.ArchiveFile)
+	if !ok {
+		return nil, fmt.Errorf("%w: version %s is not an archive", ErrArchiveNotInspectable, version)
+	}
+
+	def, err := docs.InspectArchive(archiveFile.FS())
+	if err != nil {
+		return nil, fmt.Errorf("%w: version %s: %v", ErrArchiveNotInspectable, version, err)
+	}
+
+	return def, nil
+}
+
 func (s *DefaultModuleService) GetVersionURL(namespace, name, provider, version string) (*string, error) {
 	location, err := s.ModuleRepository.FindVersionLocation(namespace, name, provider, version)
 	if err != nil {
 		return nil, err
 	}
 
+	// Downloads are tracked for the retention policies, so a failure should
+	// not prevent the module from being downloaded
+	if err := s.ModuleRepository.MarkVersionDownloaded(namespace, name, provider, version, time.Now()); err != nil {
+		log.Warn().
+			Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", namespace, name, provider, version)).
+			Err(err).
+			Msg("could not record module download")
+	}
+
 	if s.Resolver != nil {
-		url, err := s.Resolver.Find(*location)
+		url, err := s.Resolver.Find(location.Location)
 		if err != nil {
 			return nil, fmt.Errorf("could not resolve location: %v", err)
 		}
 
+		// Let go-getter verify the downloaded archive
+		if location.Checksum != "" {
+			url = withQueryParam(url, "checksum", "sha256:"+location.Checksum)
+		}
+
 		// Record download metrics
 		metrics.RecordRequest(namespace, "download")
 		metrics.RecordArtifactDownload("module", namespace)
@@ -293,10 +666,14 @@ func (s *DefaultModuleService) GetVersionURL(namespace, name, provider, version
 	metrics.RecordRequest(namespace, "download")
 	metrics.RecordArtifactDownload("module", namespace)
 
-	return location, nil
+	return &location.Location, nil
 }
 
 func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header http.Header) error {
+	return s.UploadContext(context.Background(), d, url, header)
+}
+
+func (s *DefaultModuleService) UploadContext(ctx context.Context, d *module.CreateDTO, url string, header http.Header) error {
 	// Validate version
 	if semVer := version.Version(d.Version); !semVer.Valid() {
 		return fmt.Errorf("version should respect the semantic versioning standard (semver.org)")
@@ -320,7 +697,7 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 	}
 
 	// Download module files
-	archive, cleanup, err := s.Fetcher.Fetch(d.Version, url, header)
+	archive, cleanup, err := s.Fetcher.FetchContext(ctx, d.Version, url, header)
 	if err != nil {
 		return err
 	}
@@ -329,11 +706,46 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 
 	var mdDocs = ""
 	var submoduleDocs = make(map[string]string)
+	var examples []docs.ExampleInfo
+
+	archiveFile, isArchive := archive.(*file.ArchiveFile)
+
+	// The admission policy of the authority can only be enforced on
+	// archives, so anything else is rejected rather than stored unchecked
+	if !isArchive && s.AdmissionService != nil {
+		policy, err := s.AdmissionService.GetPolicy(a.ID)
+		if err != nil {
+			return err
+		}
+
+		if policy.RequiresArchive() {
+			return fmt.Errorf("%w: the admission policy cannot be enforced", ErrArchiveNotInspectable)
+		}
+	}
+
+	if isArchive {
+		// Reject the module before storing anything, if it does not follow
+		// the admission policy of the authority
+		if s.AdmissionService != nil {
+			if err := s.AdmissionService.Validate(a.ID, archiveFile.FS()); err != nil {
+				return err
+			}
+
+			if err := s.checkSemver(a.ID, a.Name, current, d.Version, archiveFile.FS()); err != nil {
+				return err
+			}
+		}
 
-	if archiveFile, ok := archive.(*file.ArchiveFile); ok {
 		// Generate main module documentation
 		markdown, err := docs.GetModuleDocumentation(archiveFile.FS(), "")
-		if err != nil {
+		if err != nil && s.AdmissionService != nil {
+			// The admission rules, e.g. a required README, cannot be trusted
+			// for a module whose documentation cannot be generated
+			return &AdmissionError{Violations: []docs.Violation{{
+				Rule:    docs.RuleValidConfiguration,
+				Message: fmt.Sprintf("could not generate the documentation: %v", err),
+			}}}
+		} else if err != nil {
 			log.Warn().
 				Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
 				Err(err).
@@ -342,6 +754,16 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 
 		mdDocs = markdown
 
+		// Extract the root module interface
+		if iface, err := docs.InspectModule(archiveFile.FS(), ""); err != nil {
+			log.Warn().
+				Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
+				Err(err).
+				Msg("failed to inspect module")
+		} else {
+			m.Versions[0].SetInterface(toModuleInterfaceDTO(iface))
+		}
+
 		// Scan and generate documentation for submodules
 		submodules, err := docs.FindSubmodules(archiveFile.FS())
 		if err != nil {
@@ -357,11 +779,39 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 
 			for _, sm := range submodules {
 				submoduleDocs[sm.Path] = sm.Documentation
-				m.Versions[0].Submodules = append(m.Versions[0].Submodules, module.Submodule{
-					Path: sm.Path,
-				})
+
+				submodule := module.SubmoduleDTO{Path: sm.Path}
+				if iface, err := docs.InspectModule(archiveFile.FS(), sm.Path); err != nil {
+					log.Warn().
+						Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
+						Str("submodulePath", sm.Path).
+						Err(err).
+						Msg("failed to inspect submodule")
+				} else {
+					submodule.RootDTO = toModuleInterfaceDTO(iface)
+				}
+
+				m.Versions[0].Submodules = append(m.Versions[0].Submodules, submodule.ToSubmodule())
 			}
 		}
+
+		// Scan the usage examples shipped with the module
+		examples, err = docs.FindExamples(archiveFile.FS())
+		if err != nil {
+			log.Warn().
+				Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
+				Err(err).
+				Msg("failed to scan for examples")
+		}
+
+		for _, e := range examples {
+			m.Versions[0].Examples = append(m.Versions[0].Examples, module.Example{
+				Path: e.Path,
+				Files: lo.Map(e.Files, func(f docs.ExampleFile, _ int) module.ExampleFile {
+					return module.ExampleFile{Name: f.Name}
+				}),
+			})
+		}
 	} else {
 		log.Warn().
 			Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
@@ -369,6 +819,11 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 	}
 
 	if s.Resolver != nil {
+		checksum, err := file.Checksum(archive)
+		if err != nil {
+			return err
+		}
+
 		// Upload the module archive to the resolver datastore
 		location, err := s.Resolver.Store(&storage.StoreInput{
 			Reader:      archive,
@@ -388,55 +843,47 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 
 		// Update the module location
 		m.Versions[0].Location = location
+		m.Versions[0].Checksum = checksum
+	} else {
+		// Terralist is using a proxy provider.
+		m.Versions[0].Location = url
+	}
 
-		// Upload the module documentation to the resolver datastore
-		docsFile := file.NewStreamingFile(
-			fmt.Sprintf("%s.md", d.Version),
-			strings.NewReader(mdDocs),
-			int64(len(mdDocs)),
+	// Store the documentation next to the archive or, in proxy mode, in the
+	// document store
+	if store := s.documents(); store != nil {
+		docsLocation, err := store.Put(
+			fmt.Sprintf("modules/%s/%s/%s/%s.md", a.Name, m.Name, m.Provider, d.Version),
+			mdDocs,
 		)
-		docsLocation, err := s.Resolver.Store(&storage.StoreInput{
-			Reader:      docsFile,
-			Size:        docsFile.Metadata().Size(),
-			ContentType: "text/markdown; charset=utf-8",
-			KeyPrefix: fmt.Sprintf(
-				"modules/%s/%s/%s",
-				a.Name,
-				m.Name,
-				m.Provider,
-			),
-			FileName: docsFile.Name(),
-		})
-		if err != nil {
+		if errors.Is(err, ErrDocumentTooLarge) {
+			log.Warn().
+				Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
+				Err(err).
+				Msg("module documentation is too large to be stored")
+		} else if err != nil {
 			return fmt.Errorf("could store the new version's documentation: %v", err)
+		} else {
+			// Update the module documentation location
+			m.Versions[0].Documentation = &docsLocation
 		}
 
-		// Update the module documentation location
-		m.Versions[0].Documentation = &docsLocation
-
-		// Upload submodule documentation to the resolver datastore
+		// Store the submodules documentation. An empty document is stored for
+		// the submodules without one, so their documentation is not looked
+		// up again in the archive
 		for submodulePath, submoduleDoc := range submoduleDocs {
-			if submoduleDoc == "" {
-				continue
-			}
+			key := submoduleDocsKey(a.Name, m.Name, m.Provider, d.Version, submodulePath)
 
-			submoduleDocsFile := file.NewStreamingFile(
-				fmt.Sprintf("%s_%s.md", d.Version, strings.ReplaceAll(submodulePath, "/", "__")),
-				strings.NewReader(submoduleDoc),
-				int64(len(submoduleDoc)),
-			)
-			submoduleDocsLocation, err := s.Resolver.Store(&storage.StoreInput{
-				Reader:      submoduleDocsFile,
-				Size:        submoduleDocsFile.Metadata().Size(),
-				ContentType: "text/markdown; charset=utf-8",
-				KeyPrefix: fmt.Sprintf(
-					"modules/%s/%s/%s/submodules",
-					a.Name,
-					m.Name,
-					m.Provider,
-				),
-				FileName: submoduleDocsFile.Name(),
-			})
+			submoduleDocsLocation, err := store.Put(key, submoduleDoc)
+			if errors.Is(err, ErrDocumentTooLarge) {
+				log.Warn().
+					Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
+					Str("submodulePath", submodulePath).
+					Err(err).
+					Msg("submodule documentation is too large to be stored")
+
+				submoduleDocsLocation, err = store.Put(key, "")
+			}
 			if err != nil {
 				log.Warn().
 					Str("moduleSlug", fmt.Sprintf("%s/%s/%s", a.Name, m.Name, m.Provider)).
@@ -452,15 +899,11 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 				Str("docsLocation", submoduleDocsLocation).
 				Msg("stored submodule documentation")
 		}
-	} else {
-		// Terralist is using a proxy provider.
-		m.Versions[0].Location = url
 
-		// The documentation of a module can get pretty large and since we have no place to store it
-		// it will end up in the database, increasing the disk size enormously.
-		// To avoid this, for now, it's better to not have documentation at all while using the proxy
-		// provider.
-		// m.Versions[0].Documentation = mdDocs
+		// Store the examples documentation and sources
+		for _, e := range examples {
+			storeExample(store, a.Name, m.Name, m.Provider, d.Version, e)
+		}
 	}
 
 	// Only add the new version if the module already exists
@@ -485,6 +928,149 @@ func (s *DefaultModuleService) Upload(d *module.CreateDTO, url string, header ht
 	return nil
 }
 
+// checkSemver compares the interface of a new module version with the one of
+// the highest previous version of the same major line. Depending on the
+// admission policy of the authority, breaking changes are rejected or only
+// reported.
+func (s *DefaultModuleService) checkSemver(authorityID uuid.UUID, namespace string, current *module.Module, newVersion string, moduleFS *file.FS) error {
+	if current == nil {
+		return nil
+	}
+
+	policy, err := s.AdmissionService.GetPolicy(authorityID)
+	if err != nil {
+		return err
+	}
+
+	if policy.SemverEnforcement != admission.SemverEnforcementWarn &&
+		policy.SemverEnforcement != admission.SemverEnforcementReject {
+		return nil
+	}
+
+	previous := previousInMajorLine(current.Versions, newVersion)
+	if previous == nil {
+		return nil
+	}
+
+	slug := fmt.Sprintf("%s/%s/%s", namespace, current.Name, current.Provider)
+
+	// The comparison is best-effort, the versions which cannot be inspected
+	// are published as usual
+	lhs, err := s.inspectVersion(namespace, current.Name, current.Provider, previous.Version)
+	if err != nil {
+		log.Warn().
+			Str("moduleSlug", slug).
+			Str("previousVersion", previous.Version).
+			Err(err).
+			Msg("could not inspect previous version, skipping breaking changes detection")
+
+		return nil
+	}
+
+	rhs, err := docs.InspectArchive(moduleFS)
+	if err != nil {
+		log.Warn().
+			Str("moduleSlug", slug).
+			Str("version", newVersion).
+			Err(err).
+			Msg("could not inspect module, skipping breaking changes detection")
+
+		return nil
+	}
+
+	changes := docs.DiffModules(lhs, rhs).BreakingChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if policy.SemverEnforcement == admission.SemverEnforcementReject {
+		return &BreakingChangeError{
+			Version:  newVersion,
+			Previous: previous.Version,
+			Changes:  changes,
+		}
+	}
+
+	log.Warn().
+		Str("moduleSlug", slug).
+		Str("version", newVersion).
+		Str("previousVersion", previous.Version).
+		Int("changes", len(changes)).
+		Msg("module version introduces breaking changes without a major version bump")
+
+	return nil
+}
+
+// previousInMajorLine returns the highest version lower than v, sharing its
+// major version. Versions 0.x.y are only compatible within the same minor
+// version. Yanked versions are ignored.
+func previousInMajorLine(versions []module.Version, v string) *module.Version {
+	target := version.Version(v)
+
+	var previous *module.Version
+	for i := range versions {
+		candidate := version.Version(versions[i].Version)
+
+		if versions[i].IsYanked() || !candidate.Valid() || candidate.Major() != target.Major() {
+			continue
+		}
+
+		if target.Major() == "0" && candidate.Minor() != target.Minor() {
+			continue
+		}
+
+		if version.Compare(candidate, target) >= 0 {
+			continue
+		}
+
+		if previous == nil || version.Compare(candidate, version.Version(previous.Version)) > 0 {
+			previous = &versions[i]
+		}
+	}
+
+	return previous
+}
+
+// storeExample stores the documentation and the sources of an example.
+// Failures are only logged, since the examples are not required to use the
+// module.
+func storeExample(store DocumentStore, namespace, name, provider, version string, e docs.ExampleInfo) {
+	type entry struct {
+		key     string
+		content string
+	}
+
+	var entries []entry
+
+	// An example without documentation is shown with a placeholder instead
+	if e.Documentation != "" {
+		entries = append(entries, entry{
+			key:     exampleDocsKey(namespace, name, provider, version, e.Path),
+			content: e.Documentation,
+		})
+	}
+
+	// The sources are stored even if empty, since every listed file is read
+	// back
+	for _, f := range e.Files {
+		entries = append(entries, entry{
+			key:     exampleSourceKey(namespace, name, provider, version, e.Path, f.Name),
+			content: f.Content,
+		})
+	}
+
+	for _, en := range entries {
+		if _, err := store.Put(en.key, en.content); err != nil {
+			log.Warn().
+				Str("moduleSlug", fmt.Sprintf("%s/%s/%s", namespace, name, provider)).
+				Str("examplePath", e.Path).
+				Str("key", en.key).
+				Err(err).
+				Msg("failed to store example file")
+		}
+	}
+}
+
 func (s *DefaultModuleService) Delete(authorityID uuid.UUID, name string, provider string) error {
 	a, err := s.AuthorityService.GetByID(authorityID)
 	if err != nil {
@@ -496,10 +1082,8 @@ func (s *DefaultModuleService) Delete(authorityID uuid.UUID, name string, provid
 		return fmt.Errorf("module %s/%s/%s is not uploaded to this registry", a.Name, name, provider)
 	}
 
-	if s.Resolver != nil {
-		for _, ver := range m.Versions {
-			s.deleteVersion(a.Name, &ver)
-		}
+	for _, ver := range m.Versions {
+		s.deleteVersion(a.Name, &ver)
 	}
 
 	if err := s.ModuleRepository.Delete(m); err != nil {
@@ -530,9 +1114,7 @@ func (s *DefaultModuleService) DeleteVersion(authorityID uuid.UUID, name string,
 		return fmt.Errorf("module %s/%s/%s does not contain version %s", a.Name, name, provider, version)
 	}
 
-	if s.Resolver != nil {
-		s.deleteVersion(a.Name, v)
-	}
+	s.deleteVersion(a.Name, v)
 
 	if len(m.Versions) == 1 {
 		if err := s.ModuleRepository.Delete(m); err != nil {
@@ -549,21 +1131,143 @@ func (s *DefaultModuleService) DeleteVersion(authorityID uuid.UUID, name string,
 	return nil
 }
 
+func (s *DefaultModuleService) Verify(authorityID uuid.UUID) (*module.VerificationReportDTO, error) {
+	opener, ok := s.Resolver.(fileOpener)
+	if !ok {
+		return nil, ErrModulesNotStored
+	}
+
+	a, err := s.AuthorityService.GetByID(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := s.ModuleRepository.FindAll(module.ListFilter{Namespace: a.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &module.VerificationReportDTO{
+		Mismatches: []module.MismatchDTO{},
+	}
+
+	for _, m := range modules {
+		for _, v := range m.Versions {
+			if v.Checksum == "" {
+				report.Unverified++
+				continue
+			}
+
+			actual, err := s.checksum(opener, v.Location)
+			if err != nil {
+				report.Mismatches = append(report.Mismatches, module.MismatchDTO{
+					Name:     m.Name,
+					Provider: m.Provider,
+					Version:  v.Version,
+					Expected: v.Checksum,
+					Error:    err.Error(),
+				})
+				continue
+			}
+
+			if actual != v.Checksum {
+				report.Mismatches = append(report.Mismatches, module.MismatchDTO{
+					Name:     m.Name,
+					Provider: m.Provider,
+					Version:  v.Version,
+					Expected: v.Checksum,
+					Actual:   actual,
+				})
+				continue
+			}
+
+			report.Verified++
+		}
+	}
+
+	if len(report.Mismatches) > 0 {
+		log.Warn().
+			Str("authority", a.Name).
+			Int("mismatches", len(report.Mismatches)).
+			Msg("stored module archives do not match their checksum")
+	}
+
+	return report, nil
+}
+
+// checksum reads a stored module archive and computes its checksum.
+func (s *DefaultModuleService) checksum(opener fileOpener, location string) (string, error) {
+	archive, cleanup, err := opener.Open(location)
+	if err != nil {
+		return "", fmt.Errorf("could not read the stored archive: %w", err)
+	}
+	defer cleanup()
+	defer archive.Close()
+
+	return file.Checksum(archive)
+}
+
+func (s *DefaultModuleService) SetVersionStatus(
+	authorityID uuid.UUID,
+	name, provider, version string,
+	status artifact.Status,
+	d artifact.UpdateStatusDTO,
+) error {
+	a, err := s.AuthorityService.GetByID(authorityID)
+	if err != nil {
+		return err
+	}
+
+	m, err := s.ModuleRepository.Find(a.Name, name, provider)
+	if err != nil {
+		return fmt.Errorf("module %s/%s/%s is not uploaded to this registry", a.Name, name, provider)
+	}
+
+	v := m.GetVersion(version)
+	if v == nil {
+		return fmt.Errorf("module %s/%s/%s does not contain version %s", a.Name, name, provider, version)
+	}
+
+	d, err = validateVersionStatus(v.Version, status, d, func(r string) (*artifact.VersionStatus, bool) {
+		rv := m.GetVersion(r)
+		if rv == nil {
+			return nil, false
+		}
+
+		st := rv.GetStatus()
+		return &st, true
+	})
+	if err != nil {
+		return err
+	}
+
+	v.SetStatus(status, d)
+
+	return s.ModuleRepository.UpdateVersionStatus(v)
+}
+
 // deleteVersion removes the files for a specific module version.
 func (s *DefaultModuleService) deleteVersion(namespace string, v *module.Version) {
 	// Delete the module archive
-	if err := s.Resolver.Purge(v.Location); err != nil {
-		log.Warn().
-			AnErr("Error", err).
-			Str("Module", v.Module.String()).
-			Str("Version", v.Version).
-			Str("Key", v.Location).
-			Msg("Could not purge module archive, require manual clean-up")
+	if s.Resolver != nil {
+		if err := s.Resolver.Purge(v.Location); err != nil {
+			log.Warn().
+				AnErr("Error", err).
+				Str("Module", v.Module.String()).
+				Str("Version", v.Version).
+				Str("Key", v.Location).
+				Msg("Could not purge module archive, require manual clean-up")
+		}
+	}
+
+	store := s.documents()
+	if store == nil {
+		return
 	}
 
 	// Delete the module documentation
 	if v.Documentation != nil && *v.Documentation != "" {
-		if err := s.Resolver.Purge(*v.Documentation); err != nil {
+		if err := store.Delete(*v.Documentation); err != nil {
 			log.Warn().
 				AnErr("Error", err).
 				Str("Module", v.Module.String()).
@@ -575,15 +1279,9 @@ func (s *DefaultModuleService) deleteVersion(namespace string, v *module.Version
 
 	// Delete documentation for all submodules
 	for _, sm := range v.Submodules {
-		// Construct the documentation file path using the same convention as Upload
-		docsFileName := fmt.Sprintf("%s_%s.md", v.Version, strings.ReplaceAll(sm.Path, "/", "__"))
-		docsKey := fmt.Sprintf("modules/%s/%s/%s/submodules/%s",
-			namespace,
-			v.Module.Name,
-			v.Module.Provider,
-			docsFileName)
-
-		if err := s.Resolver.Purge(docsKey); err != nil {
+		docsKey := submoduleDocsKey(namespace, v.Module.Name, v.Module.Provider, v.Version, sm.Path)
+
+		if err := store.Delete(docsKey); err != nil {
 			log.Warn().
 				AnErr("Error", err).
 				Str("Module", v.Module.String()).
@@ -593,4 +1291,162 @@ func (s *DefaultModuleService) deleteVersion(namespace string, v *module.Version
 				Msg("Could not purge submodule documentation, require manual clean-up")
 		}
 	}
+
+	// Delete the documentation and the sources of all examples
+	for _, e := range v.Examples {
+		keys := []string{exampleDocsKey(namespace, v.Module.Name, v.Module.Provider, v.Version, e.Path)}
+		for _, f := range e.Files {
+			keys = append(keys, exampleSourceKey(namespace, v.Module.Name, v.Module.Provider, v.Version, e.Path, f.Name))
+		}
+
+		for _, key := range keys {
+			if err := store.Delete(key); err != nil {
+				log.Warn().
+					AnErr("Error", err).
+					Str("Module", v.Module.String()).
+					Str("Version", v.Version).
+					Str("ExamplePath", e.Path).
+					Str("Key", key).
+					Msg("Could not purge example file, require manual clean-up")
+			}
+		}
+	}
+}
+
+// toModuleInterfaceDTO maps the interface extracted from the module files to
+// its DTO.
+func toModuleInterfaceDTO(iface *docs.ModuleInterface) module.RootDTO {
+	return module.RootDTO{
+		Inputs: lo.Map(iface.Variables, func(v docs.Variable, _ int) module.VariableDTO {
+			var def json.RawMessage
+			if v.Default != nil {
+				def = json.RawMessage(*v.Default)
+			}
+
+			return module.VariableDTO{
+				Name:        v.Name,
+				Type:        v.Type,
+				Description: v.Description,
+				Default:     def,
+				Required:    v.Required,
+				Sensitive:   v.Sensitive,
+			}
+		}),
+		Outputs: lo.Map(iface.Outputs, func(o docs.Output, _ int) module.OutputDTO {
+			return module.OutputDTO{
+				Name:        o.Name,
+				Description: o.Description,
+				Sensitive:   o.Sensitive,
+			}
+		}),
+		Providers: lo.Map(iface.RequiredProviders, func(p docs.RequiredProvider, _ int) module.ProviderDTO {
+			return module.ProviderDTO{
+				Name:      p.Name,
+				Namespace: p.Namespace,
+				Source:    p.Source,
+				Version:   strings.Join(p.VersionConstraints, ", "),
+			}
+		}),
+		Dependencies: lo.Map(iface.ModuleCalls, func(c docs.ModuleCall, _ int) module.DependencyDTO {
+			return module.DependencyDTO{
+				Name:    c.Name,
+				Source:  c.Source,
+				Version: c.Version,
+			}
+		}),
+	}
+}
+
+// storedDefinition returns the interfaces of a module version, as recorded
+// in the database, or nil if none was recorded.
+func storedDefinition(v *module.Version) *docs.ModuleDefinition {
+	recorded := func(variables []module.Variable, outputs []module.Output, providers []module.Provider, dependencies []module.Dependency) bool {
+		return len(variables) > 0 || len(outputs) > 0 || len(providers) > 0 || len(dependencies) > 0
+	}
+
+	found := recorded(v.Variables, v.Outputs, v.Providers, v.Dependencies)
+	for _, sm := range v.Submodules {
+		found = found || recorded(sm.Variables, sm.Outputs, sm.Providers, sm.Dependencies)
+	}
+
+	if !found {
+		return nil
+	}
+
+	def := &docs.ModuleDefinition{
+		Root:       toDocsInterface(v.Variables, v.Outputs, v.Providers, v.Dependencies),
+		Submodules: map[string]*docs.ModuleInterface{},
+	}
+
+	for _, sm := range v.Submodules {
+		def.Submodules[sm.Path] = toDocsInterface(sm.Variables, sm.Outputs, sm.Providers, sm.Dependencies)
+	}
+
+	return def
+}
+
+// toDocsInterface maps a module interface recorded in the database to the one
+// extracted from the module files.
+func toDocsInterface(variables []module.Variable, outputs []module.Output, providers []module.Provider, dependencies []module.Dependency) *docs.ModuleInterface {
+	return &docs.ModuleInterface{
+		Variables: lo.Map(variables, func(v module.Variable, _ int) docs.Variable {
+			return docs.Variable{
+				Name:        v.Name,
+				Type:        v.Type,
+				Description: v.Description,
+				Default:     v.Default,
+				Required:    v.Required,
+				Sensitive:   v.Sensitive,
+			}
+		}),
+		Outputs: lo.Map(outputs, func(o module.Output, _ int) docs.Output {
+			return docs.Output{
+				Name:        o.Name,
+				Description: o.Description,
+				Sensitive:   o.Sensitive,
+			}
+		}),
+		RequiredProviders: lo.Map(providers, func(p module.Provider, _ int) docs.RequiredProvider {
+			var constraints []string
+			if p.Version != "" {
+				constraints = []string{p.Version}
+			}
+
+			return docs.RequiredProvider{
+				Name:               p.Name,
+				Namespace:          p.Namespace,
+				Source:             p.Source,
+				VersionConstraints: constraints,
+			}
+		}),
+		ModuleCalls: lo.Map(dependencies, func(d module.Dependency, _ int) docs.ModuleCall {
+			return docs.ModuleCall{
+				Name:    d.Name,
+				Source:  d.Source,
+				Version: d.Version,
+			}
+		}),
+	}
+}
+
+// toInterfaceDiffDTO maps the changes of a module interface to their DTO.
+func toInterfaceDiffDTO(d *docs.InterfaceDiff) module.InterfaceDiffDTO {
+	mapChanges := func(changes []docs.Change) []module.ChangeDTO {
+		return lo.Map(changes, func(c docs.Change, _ int) module.ChangeDTO { return toChangeDTO(c) })
+	}
+
+	return module.InterfaceDiffDTO{
+		Variables: mapChanges(d.Variables),
+		Outputs:   mapChanges(d.Outputs),
+		Providers: mapChanges(d.Providers),
+	}
+}
+
+func toChangeDTO(c docs.Change) module.ChangeDTO {
+	return module.ChangeDTO{
+		Name:     c.Name,
+		Change:   string(c.Type),
+		Breaking: c.Breaking,
+		Details:  c.Details,
+	}
 }
@@ -6,11 +6,16 @@ import (
 	"strings"
 	"testing"
 
+	"terralist/internal/server/models/admission"
+	"terralist/internal/server/models/artifact"
 	"terralist/internal/server/models/authority"
 	"terralist/internal/server/models/module"
 	"terralist/internal/server/repositories"
+	"terralist/pkg/database/entity"
+	"terralist/pkg/docs"
 	"terralist/pkg/file"
 	"terralist/pkg/storage"
+	"terralist/pkg/version"
 
 	"github.com/google/uuid"
 	"github.com/mazen160/go-random"
@@ -68,6 +73,211 @@ func TestGetModule(t *testing.T) {
 	})
 }
 
+func TestListModules(t *testing.T) {
+	Convey("Subject: List modules", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+		}
+
+		Convey("Given a filter", func() {
+			filter := module.ListFilter{Query: "vpc"}
+			authorityID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+			Convey("If the database contains matching modules", func() {
+				mockModuleRepository.
+					On("FindAll", filter).
+					Return([]*module.Module{
+						{
+							AuthorityID: authorityID,
+							Name:        "vpc",
+							Provider:    "aws",
+							Versions: []module.Version{
+								{Version: "1.2.0"},
+								{Version: "1.10.0"},
+								{Version: "1.9.0"},
+								{Version: "2.0.0-rc.1"},
+							},
+						},
+						{
+							AuthorityID: authorityID,
+							Name:        "empty",
+							Provider:    "aws",
+						},
+						{
+							AuthorityID: authorityID,
+							Name:        "yanked",
+							Provider:    "aws",
+							Versions: []module.Version{
+								{Version: "1.0.0", Status: artifact.StatusYanked},
+							},
+						},
+					}, nil)
+
+				mockAuthorityService.
+					On("GetByID", authorityID).
+					Return(&authority.Authority{Name: "team-a"}, nil).
+					Once()
+
+				Convey("When the service is queried", func() {
+					items, read, err := moduleService.List(filter)
+
+					Convey("Modules with installable versions should be returned with their latest stable version", func() {
+						So(err, ShouldBeNil)
+						So(read, ShouldEqual, 3)
+						So(items, ShouldHaveLength, 1)
+						So(items[0].ID, ShouldEqual, "team-a/vpc/aws/1.10.0")
+						So(items[0].Namespace, ShouldEqual, "team-a")
+						So(items[0].Version, ShouldEqual, "1.10.0")
+					})
+				})
+			})
+
+			Convey("If the database query fails", func() {
+				mockModuleRepository.
+					On("FindAll", filter).
+					Return(nil, errors.New(""))
+
+				Convey("When the service is queried", func() {
+					items, _, err := moduleService.List(filter)
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+						So(items, ShouldBeNil)
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestGetLatestModule(t *testing.T) {
+	Convey("Subject: Get the latest version of a module", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+		}
+
+		Convey("Given a namespace, name, and provider", func() {
+			namespace, _ := random.String(16)
+			name, _ := random.String(16)
+			provider, _ := random.String(16)
+
+			Convey("If the module has only pre-release versions", func() {
+				mockModuleRepository.
+					On("Find", namespace, name, provider).
+					Return(&module.Module{
+						Name:     name,
+						Provider: provider,
+						Versions: []module.Version{
+							{Version: "1.0.0-alpha"},
+							{Version: "1.0.0-beta"},
+						},
+					}, nil)
+
+				Convey("When the service is queried", func() {
+					dto, err := moduleService.GetLatest(namespace, name, provider, "")
+
+					Convey("The highest pre-release version should be returned", func() {
+						So(err, ShouldBeNil)
+						So(dto.Version, ShouldEqual, "1.0.0-beta")
+						So(dto.Versions, ShouldHaveLength, 2)
+					})
+				})
+			})
+
+			Convey("If the latest version of the module is yanked", func() {
+				mockModuleRepository.
+					On("Find", namespace, name, provider).
+					Return(&module.Module{
+						Name:     name,
+						Provider: provider,
+						Versions: []module.Version{
+							{Version: "1.0.0"},
+							{Version: "1.1.0", Status: artifact.StatusDeprecated},
+							{Version: "1.2.0", Status: artifact.StatusYanked},
+						},
+					}, nil)
+
+				Convey("When the service is queried", func() {
+					dto, err := moduleService.GetLatest(namespace, name, provider, "")
+
+					Convey("The latest version which was not yanked should be returned", func() {
+						So(err, ShouldBeNil)
+						So(dto.Version, ShouldEqual, "1.1.0")
+						So(dto.Versions, ShouldHaveLength, 2)
+					})
+				})
+			})
+
+			Convey("If the module has versions matching a constraint", func() {
+				mockModuleRepository.
+					On("Find", namespace, name, provider).
+					Return(&module.Module{
+						Name:     name,
+						Provider: provider,
+						Versions: []module.Version{
+							{Version: "1.2.0"},
+							{Version: "1.10.0"},
+							{Version: "2.0.0"},
+							{Version: "1.11.0-rc.1"},
+						},
+					}, nil)
+
+				Convey("When the service is queried with the constraint", func() {
+					dto, err := moduleService.GetLatest(namespace, name, provider, "~> 1.2")
+
+					Convey("The latest stable version matching it should be returned", func() {
+						So(err, ShouldBeNil)
+						So(dto.Version, ShouldEqual, "1.10.0")
+						So(dto.Versions, ShouldHaveLength, 4)
+					})
+				})
+
+				Convey("When the service is queried with a constraint no version matches", func() {
+					dto, err := moduleService.GetLatest(namespace, name, provider, ">= 3.0")
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+						So(dto, ShouldBeNil)
+					})
+				})
+
+				Convey("When the service is queried with an invalid constraint", func() {
+					dto, err := moduleService.GetLatest(namespace, name, provider, "=> 1.0")
+
+					Convey("An invalid constraint error should be returned", func() {
+						So(errors.Is(err, version.ErrInvalidConstraint), ShouldBeTrue)
+						So(dto, ShouldBeNil)
+					})
+				})
+			})
+
+			Convey("If the module has no versions", func() {
+				mockModuleRepository.
+					On("Find", namespace, name, provider).
+					Return(&module.Module{
+						Name:     name,
+						Provider: provider,
+					}, nil)
+
+				Convey("When the service is queried", func() {
+					dto, err := moduleService.GetLatest(namespace, name, provider, "")
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+						So(dto, ShouldBeNil)
+					})
+				})
+			})
+		})
+	})
+}
+
 func TestGetModuleDownloadLocation(t *testing.T) {
 	Convey("Subject: Find a module", t, func() {
 		mockModuleRepository := repositories.NewMockModuleRepository(t)
@@ -89,7 +299,11 @@ func TestGetModuleDownloadLocation(t *testing.T) {
 
 				mockModuleRepository.
 					On("FindVersionLocation", namespace, name, provider, version).
-					Return(&locationKey, nil)
+					Return(&module.VersionLocation{Location: locationKey}, nil)
+
+				mockModuleRepository.
+					On("MarkVersionDownloaded", namespace, name, provider, version, mock.AnythingOfType("time.Time")).
+					Return(nil)
 
 				Convey("If the resolver is not set", func() {
 					moduleService.Resolver = nil
@@ -123,6 +337,32 @@ func TestGetModuleDownloadLocation(t *testing.T) {
 					})
 				})
 
+				Convey("If the resolver can resolve the location path of an archive with a checksum", func() {
+					mockModuleRepository.ExpectedCalls = nil
+
+					mockModuleRepository.
+						On("FindVersionLocation", namespace, name, provider, version).
+						Return(&module.VersionLocation{Location: locationKey, Checksum: "abc123"}, nil)
+
+					mockModuleRepository.
+						On("MarkVersionDownloaded", namespace, name, provider, version, mock.AnythingOfType("time.Time")).
+						Return(nil)
+
+					mockResolver.
+						On("Find", locationKey).
+						Return("https://example.com/module.zip?token=xyz", nil)
+
+					Convey("When the service is queried", func() {
+						url, err := moduleService.GetVersionURL(namespace, name, provider, version)
+
+						Convey("The checksum should be appended to the download URL", func() {
+							So(err, ShouldBeNil)
+							So(url, ShouldNotBeNil)
+							So(*url, ShouldEqual, "https://example.com/module.zip?token=xyz&checksum=sha256%3Aabc123")
+						})
+					})
+				})
+
 				Convey("If the resolver cannot resolve the location path", func() {
 					mockResolver.
 						On("Find", locationKey).
@@ -201,6 +441,202 @@ func TestGetSubmoduleDocumentation_ProxyResolverWithArchiveRoot(t *testing.T) {
 	})
 }
 
+func TestGetExample_ProxyResolver(t *testing.T) {
+	Convey("Subject: Get an example in proxy mode", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockFetcher := file.NewMockFetcher(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			Fetcher:          mockFetcher,
+			Resolver:         nil,
+		}
+
+		location := "https://example.invalid/module.zip"
+
+		mockModuleRepository.
+			On("FindVersion", "terraform-aws-modules", "vpc", "aws", "5.0.0").
+			Return(&module.Version{
+				Version:  "5.0.0",
+				Location: location,
+				Examples: []module.Example{
+					{Path: "examples/simple", Files: []module.ExampleFile{{Name: "main.tf"}}},
+				},
+			}, nil)
+
+		Convey("Given an example which is not part of the version", func() {
+			Convey("When the example is requested", func() {
+				_, err := moduleService.GetExample("terraform-aws-modules", "vpc", "aws", "5.0.0", "examples/complete")
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+				})
+			})
+		})
+
+		Convey("Given an example of the version", func() {
+			archive, err := file.Archive("module.zip", []file.File{
+				file.NewInMemoryFile("terraform-aws-vpc-5.0.0/main.tf", []byte(`variable "cidr" {}`)),
+				file.NewInMemoryFile("terraform-aws-vpc-5.0.0/examples/simple/README.md", []byte("# Simple VPC")),
+				file.NewInMemoryFile("terraform-aws-vpc-5.0.0/examples/simple/main.tf", []byte(`module "vpc" { source = "../../" }`)),
+			})
+			So(err, ShouldBeNil)
+
+			mockFetcher.
+				On("Fetch", "5.0.0", location, mock.Anything).
+				Return(archive, func() {}, nil)
+
+			Convey("When the example is requested", func() {
+				example, err := moduleService.GetExample("terraform-aws-modules", "vpc", "aws", "5.0.0", "examples/simple")
+
+				Convey("The documentation and the sources should be read from the archive", func() {
+					So(err, ShouldBeNil)
+					So(example.Documentation, ShouldEqual, "# Simple VPC")
+					So(example.Files, ShouldHaveLength, 1)
+					So(example.Files[0].Name, ShouldEqual, "main.tf")
+					So(example.Files[0].Content, ShouldEqual, `module "vpc" { source = "../../" }`)
+				})
+			})
+		})
+	})
+}
+
+func TestDiffModuleVersions(t *testing.T) {
+	Convey("Subject: Compare the interfaces of two module versions", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockFetcher := file.NewMockFetcher(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			Fetcher:          mockFetcher,
+		}
+
+		mockModuleRepository.
+			On("FindVersion", "acme", "vpc", "aws", "3.2.0").
+			Return(&module.Version{Version: "3.2.0", Location: "https://example.invalid/3.2.0.zip"}, nil).
+			Maybe()
+
+		Convey("Given a version which does not exist", func() {
+			mockModuleRepository.
+				On("FindVersion", "acme", "vpc", "aws", "9.9.9").
+				Return(nil, errors.New("no module version found"))
+
+			from, err := file.Archive("3.2.0.zip", []file.File{
+				file.NewInMemoryFile("main.tf", []byte(`variable "name" {}`)),
+			})
+			So(err, ShouldBeNil)
+
+			mockFetcher.
+				On("Fetch", "3.2.0", "https://example.invalid/3.2.0.zip", mock.Anything).
+				Return(from, func() {}, nil)
+
+			Convey("When the versions are compared", func() {
+				_, err := moduleService.Diff("acme", "vpc", "aws", "3.2.0", "9.9.9")
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(errors.Is(err, ErrArchiveNotInspectable), ShouldBeFalse)
+				})
+			})
+		})
+
+		Convey("Given two versions with a recorded interface", func() {
+			stored := func(v string, variables ...module.Variable) *module.Version {
+				return &module.Version{
+					Version:   v,
+					Location:  "https://example.invalid/" + v + ".zip",
+					Variables: variables,
+					Outputs:   []module.Output{{Name: "id"}},
+				}
+			}
+
+			mockModuleRepository.
+				On("FindVersion", "acme", "vpc", "aws", "5.0.0").
+				Return(stored("5.0.0", module.Variable{Name: "name", Type: "string", Required: true}), nil)
+
+			mockModuleRepository.
+				On("FindVersion", "acme", "vpc", "aws", "5.1.0").
+				Return(stored("5.1.0",
+					module.Variable{Name: "name", Type: "string", Required: true},
+					module.Variable{Name: "region", Type: "string", Required: true},
+				), nil)
+
+			Convey("When the versions are compared", func() {
+				diff, err := moduleService.Diff("acme", "vpc", "aws", "5.0.0", "5.1.0")
+
+				Convey("The changes should be computed without fetching the archives", func() {
+					So(err, ShouldBeNil)
+					So(diff.Breaking, ShouldBeTrue)
+					So(diff.Variables, ShouldHaveLength, 1)
+					So(diff.Variables[0].Name, ShouldEqual, "region")
+					So(diff.Variables[0].Change, ShouldEqual, "added")
+					So(diff.Outputs, ShouldBeEmpty)
+					mockFetcher.AssertNotCalled(t, "Fetch", mock.Anything, mock.Anything, mock.Anything)
+				})
+			})
+		})
+
+		Convey("Given two versions uploaded before their interface was recorded", func() {
+			mockModuleRepository.
+				On("FindVersion", "acme", "vpc", "aws", "4.0.0").
+				Return(&module.Version{Version: "4.0.0", Location: "https://example.invalid/4.0.0.zip"}, nil)
+
+			from, err := file.Archive("3.2.0.zip", []file.File{
+				file.NewInMemoryFile("main.tf", []byte(`
+					variable "name" { type = string }
+					variable "cidr" { default = "10.0.0.0/16" }
+					output "id" { value = "" }
+				`)),
+			})
+			So(err, ShouldBeNil)
+
+			to, err := file.Archive("4.0.0.zip", []file.File{
+				file.NewInMemoryFile("main.tf", []byte(`
+					variable "name" { type = string }
+					variable "tags" { default = {} }
+					output "id" { value = "" }
+					output "arn" { value = "" }
+				`)),
+				file.NewInMemoryFile("modules/endpoints/main.tf", []byte(`variable "services" {}`)),
+			})
+			So(err, ShouldBeNil)
+
+			mockFetcher.
+				On("Fetch", "3.2.0", "https://example.invalid/3.2.0.zip", mock.Anything).
+				Return(from, func() {}, nil)
+
+			mockFetcher.
+				On("Fetch", "4.0.0", "https://example.invalid/4.0.0.zip", mock.Anything).
+				Return(to, func() {}, nil)
+
+			Convey("When the versions are compared", func() {
+				diff, err := moduleService.Diff("acme", "vpc", "aws", "3.2.0", "4.0.0")
+
+				Convey("The changes should be reported and classified", func() {
+					So(err, ShouldBeNil)
+					So(diff.Breaking, ShouldBeTrue)
+
+					So(diff.Variables, ShouldHaveLength, 2)
+					So(diff.Variables[0].Name, ShouldEqual, "cidr")
+					So(diff.Variables[0].Change, ShouldEqual, "removed")
+					So(diff.Variables[0].Breaking, ShouldBeTrue)
+					So(diff.Variables[1].Name, ShouldEqual, "tags")
+					So(diff.Variables[1].Change, ShouldEqual, "added")
+					So(diff.Variables[1].Breaking, ShouldBeFalse)
+
+					So(diff.Outputs, ShouldHaveLength, 1)
+					So(diff.Outputs[0].Name, ShouldEqual, "arn")
+					So(diff.Outputs[0].Change, ShouldEqual, "added")
+
+					So(diff.Submodules, ShouldHaveLength, 1)
+					So(diff.Submodules[0].Name, ShouldEqual, "modules/endpoints")
+					So(diff.Submodules[0].Change, ShouldEqual, "added")
+				})
+			})
+		})
+	})
+}
+
 func TestUploadModule(t *testing.T) {
 	Convey("Subject: Upload a new module version", t, func() {
 		mockModuleRepository := repositories.NewMockModuleRepository(t)
@@ -303,7 +739,7 @@ func TestUploadModule(t *testing.T) {
 								expectedErr := errors.New("some reason")
 
 								mockFetcher.
-									On("Fetch", dto.Version, url, mock.AnythingOfType("http.Header")).
+									On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
 									Return(nil, nil, expectedErr)
 
 								Convey("When the service is queried", func() {
@@ -315,19 +751,45 @@ func TestUploadModule(t *testing.T) {
 								})
 							})
 
-							Convey("If the fetcher downloads the module", func() {
-								archive, err := file.Archive("module.zip", []file.File{
-									file.NewInMemoryFile("README.md", []byte("# module\n")),
-									file.NewInMemoryFile("main.tf", []byte("locals {\n  test = true\n}\n")),
+							Convey("If the fetcher downloads a single file", func() {
+								mockFetcher.
+									On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
+									Return(file.NewInMemoryFile("main.tf", []byte("locals {}\n")), func() {}, nil)
+
+								mockAdmissionService := NewMockAdmissionService(t)
+								moduleService.AdmissionService = mockAdmissionService
+
+								Convey("If the admission policy needs the module contents", func() {
+									mockAdmissionService.
+										On("GetPolicy", mock.AnythingOfType("uuid.UUID")).
+										Return(&admission.PolicyDTO{
+											RequireReadme:     true,
+											SemverEnforcement: admission.SemverEnforcementOff,
+										}, nil)
+
+									Convey("When the service is queried", func() {
+										err := moduleService.Upload(&dto, url, nil)
+
+										Convey("The module should be rejected without storing it", func() {
+											So(errors.Is(err, ErrArchiveNotInspectable), ShouldBeTrue)
+											mockResolver.AssertNotCalled(t, "Store", mock.Anything)
+											mockModuleRepository.AssertNotCalled(t, "Upsert", mock.Anything)
+										})
+									})
 								})
-								So(err, ShouldBeNil)
 
-								mockFetcher.
-									On("Fetch", dto.Version, url, mock.AnythingOfType("http.Header")).
-									Return(archive, func() {}, nil)
+								Convey("If the admission policy does not need the module contents", func() {
+									mockAdmissionService.
+										On("GetPolicy", mock.AnythingOfType("uuid.UUID")).
+										Return(&admission.PolicyDTO{
+											SemverEnforcement: admission.SemverEnforcementWarn,
+										}, nil)
 
-								Convey("If the resolver is not set", func() {
-									moduleService.Resolver = nil
+									location, _ := random.String(16)
+
+									mockResolver.
+										On("Store", mock.AnythingOfType("*storage.StoreInput")).
+										Return(location, nil)
 
 									mockModuleRepository.
 										On("Upsert", mock.AnythingOfType("module.Module")).
@@ -336,31 +798,122 @@ func TestUploadModule(t *testing.T) {
 									Convey("When the service is queried", func() {
 										err := moduleService.Upload(&dto, url, nil)
 
-										Convey("No error should be returned", func() {
+										Convey("The module should be stored", func() {
 											So(err, ShouldBeNil)
+											mockAdmissionService.AssertNotCalled(t, "Validate", mock.Anything, mock.Anything)
 										})
 									})
 								})
+							})
 
-								Convey("If the resolver is set", func() {
-									// Set by default
+							Convey("If the fetcher downloads a module without documentation", func() {
+								archive, err := file.Archive("module.zip", []file.File{
+									file.NewInMemoryFile("LICENSE", []byte("MIT\n")),
+								})
+								So(err, ShouldBeNil)
 
-									Convey("If the resolver fails to store the module files", func() {
-										mockResolver.
-											On("Store", mock.AnythingOfType("*storage.StoreInput")).
-											Return("", errors.New(""))
+								mockFetcher.
+									On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
+									Return(archive, func() {}, nil)
 
-										Convey("When the service is queried", func() {
-											err := moduleService.Upload(&dto, url, nil)
+								mockAdmissionService := NewMockAdmissionService(t)
+								moduleService.AdmissionService = mockAdmissionService
 
-											Convey("An error should be returned", func() {
-												So(err, ShouldNotBeNil)
-											})
-										})
-									})
+								mockAdmissionService.
+									On("Validate", mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("*file.FS")).
+									Return(nil)
 
-									Convey("If the resolver successfully stores the module files", func() {
-										location, _ := random.String(16)
+								mockAdmissionService.
+									On("GetPolicy", mock.AnythingOfType("uuid.UUID")).
+									Return(&admission.PolicyDTO{SemverEnforcement: admission.SemverEnforcementOff}, nil).
+									Maybe()
+
+								Convey("When the service is queried", func() {
+									err := moduleService.Upload(&dto, url, nil)
+
+									Convey("The module should be rejected without storing it", func() {
+										var admissionErr *AdmissionError
+										So(errors.As(err, &admissionErr), ShouldBeTrue)
+										So(admissionErr.Violations, ShouldHaveLength, 1)
+										So(admissionErr.Violations[0].Rule, ShouldEqual, docs.RuleValidConfiguration)
+										mockResolver.AssertNotCalled(t, "Store", mock.Anything)
+										mockModuleRepository.AssertNotCalled(t, "Upsert", mock.Anything)
+									})
+								})
+							})
+
+							Convey("If the fetcher downloads the module", func() {
+								archive, err := file.Archive("module.zip", []file.File{
+									file.NewInMemoryFile("README.md", []byte("# module\n")),
+									file.NewInMemoryFile("main.tf", []byte("locals {\n  test = true\n}\n")),
+								})
+								So(err, ShouldBeNil)
+
+								mockFetcher.
+									On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
+									Return(archive, func() {}, nil)
+
+								Convey("If the module is rejected by the admission policy", func() {
+									mockAdmissionService := NewMockAdmissionService(t)
+									moduleService.AdmissionService = mockAdmissionService
+
+									mockAdmissionService.
+										On("Validate", mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("*file.FS")).
+										Return(&AdmissionError{
+											Violations: []docs.Violation{{
+												Rule:    docs.RuleReadme,
+												Message: "the root module has no README.md",
+											}},
+										})
+
+									Convey("When the service is queried", func() {
+										err := moduleService.Upload(&dto, url, nil)
+
+										Convey("The violations should be returned without storing the module", func() {
+											var admissionErr *AdmissionError
+											So(errors.As(err, &admissionErr), ShouldBeTrue)
+											So(admissionErr.Violations, ShouldHaveLength, 1)
+											mockResolver.AssertNotCalled(t, "Store", mock.Anything)
+											mockModuleRepository.AssertNotCalled(t, "Upsert", mock.Anything)
+										})
+									})
+								})
+
+								Convey("If the resolver is not set", func() {
+									moduleService.Resolver = nil
+
+									mockModuleRepository.
+										On("Upsert", mock.AnythingOfType("module.Module")).
+										Return(&module.Module{}, nil)
+
+									Convey("When the service is queried", func() {
+										err := moduleService.Upload(&dto, url, nil)
+
+										Convey("No error should be returned", func() {
+											So(err, ShouldBeNil)
+										})
+									})
+								})
+
+								Convey("If the resolver is set", func() {
+									// Set by default
+
+									Convey("If the resolver fails to store the module files", func() {
+										mockResolver.
+											On("Store", mock.AnythingOfType("*storage.StoreInput")).
+											Return("", errors.New(""))
+
+										Convey("When the service is queried", func() {
+											err := moduleService.Upload(&dto, url, nil)
+
+											Convey("An error should be returned", func() {
+												So(err, ShouldNotBeNil)
+											})
+										})
+									})
+
+									Convey("If the resolver successfully stores the module files", func() {
+										location, _ := random.String(16)
 
 										mockResolver.
 											On("Store", mock.AnythingOfType("*storage.StoreInput")).
@@ -715,7 +1268,7 @@ func TestUploadModuleDocumentation_MultibytePreserved(t *testing.T) {
 		So(err, ShouldBeNil)
 
 		mockFetcher.
-			On("Fetch", dto.Version, url, mock.AnythingOfType("http.Header")).
+			On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
 			Return(arch, func() {}, nil)
 
 		// First store for archive (accept anything but the docs markdown filename)
@@ -759,3 +1312,722 @@ func TestUploadModuleDocumentation_MultibytePreserved(t *testing.T) {
 		})
 	})
 }
+
+func TestUploadModuleInterface(t *testing.T) {
+	Convey("Subject: Upload extracts the module interface", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockFetcher := file.NewMockFetcher(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+			Fetcher:          mockFetcher,
+		}
+
+		dto := module.CreateDTO{VersionCreateDTO: module.VersionCreateDTO{Version: "1.0.0"}}
+		url := "http://example.invalid/archive.zip"
+
+		mockAuthorityService.
+			On("GetByID", mock.AnythingOfType("uuid.UUID")).
+			Return(&authority.Authority{}, nil)
+
+		mockModuleRepository.
+			On("Find", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Return(nil, errors.New("not found"))
+
+		arch, err := file.Archive("module.zip", []file.File{
+			file.NewInMemoryFile("main.tf", []byte(`
+				terraform {
+				  required_providers {
+				    aws = {
+				      source  = "hashicorp/aws"
+				      version = ">= 5.0"
+				    }
+				  }
+				}
+
+				variable "name" {
+				  type        = string
+				  description = "The name of the VPC"
+				}
+
+				variable "cidr" {
+				  type    = string
+				  default = "10.0.0.0/16"
+				}
+
+				module "subnets" {
+				  source = "./modules/subnets"
+				}
+
+				output "vpc_id" {
+				  value = "id"
+				}
+			`)),
+			file.NewInMemoryFile("modules/subnets/main.tf", []byte(`
+				variable "count" {
+				  type = number
+				}
+
+				output "ids" {
+				  value     = []
+				  sensitive = true
+				}
+			`)),
+		})
+		So(err, ShouldBeNil)
+
+		mockFetcher.
+			On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
+			Return(arch, func() {}, nil)
+
+		var uploaded module.Module
+		mockModuleRepository.
+			On("Upsert", mock.AnythingOfType("module.Module")).
+			Run(func(args mock.Arguments) {
+				uploaded = args.Get(0).(module.Module)
+			}).
+			Return(&module.Module{}, nil)
+
+		Convey("When uploading the module", func() {
+			err := moduleService.Upload(&dto, url, nil)
+
+			Convey("The root module interface should be persisted", func() {
+				So(err, ShouldBeNil)
+				So(uploaded.Versions, ShouldHaveLength, 1)
+
+				v := uploaded.Versions[0]
+				So(v.Variables, ShouldHaveLength, 2)
+				So(v.Variables[0].Name, ShouldEqual, "cidr")
+				So(v.Variables[0].Required, ShouldBeFalse)
+				So(*v.Variables[0].Default, ShouldEqual, `"10.0.0.0/16"`)
+				So(v.Variables[1].Name, ShouldEqual, "name")
+				So(v.Variables[1].Required, ShouldBeTrue)
+				So(v.Variables[1].Default, ShouldBeNil)
+
+				So(v.Outputs, ShouldHaveLength, 1)
+				So(v.Outputs[0].Name, ShouldEqual, "vpc_id")
+
+				So(v.Providers, ShouldHaveLength, 1)
+				So(v.Providers[0].Namespace, ShouldEqual, "hashicorp")
+				So(v.Providers[0].Source, ShouldEqual, "hashicorp/aws")
+				So(v.Providers[0].Version, ShouldEqual, ">= 5.0")
+
+				So(v.Dependencies, ShouldHaveLength, 1)
+				So(v.Dependencies[0].Source, ShouldEqual, "./modules/subnets")
+			})
+
+			Convey("The submodule interface should be persisted", func() {
+				So(err, ShouldBeNil)
+				So(uploaded.Versions[0].Submodules, ShouldHaveLength, 1)
+
+				sm := uploaded.Versions[0].Submodules[0]
+				So(sm.Path, ShouldEqual, "modules/subnets")
+				So(sm.Variables, ShouldHaveLength, 1)
+				So(sm.Outputs, ShouldHaveLength, 1)
+				So(sm.Outputs[0].Sensitive, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestUploadModuleExamples(t *testing.T) {
+	Convey("Subject: Upload stores the module examples", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockFetcher := file.NewMockFetcher(t)
+		mockResolver := storage.NewMockResolver(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+			Fetcher:          mockFetcher,
+			Resolver:         mockResolver,
+		}
+
+		dto := module.CreateDTO{
+			Name:             "vpc",
+			Provider:         "aws",
+			VersionCreateDTO: module.VersionCreateDTO{Version: "1.0.0"},
+		}
+		url := "http://example.invalid/archive.zip"
+
+		mockAuthorityService.
+			On("GetByID", mock.AnythingOfType("uuid.UUID")).
+			Return(&authority.Authority{Name: "acme"}, nil)
+
+		mockModuleRepository.
+			On("Find", "acme", "vpc", "aws").
+			Return(nil, errors.New("not found"))
+
+		arch, err := file.Archive("module.zip", []file.File{
+			file.NewInMemoryFile("main.tf", []byte(`variable "cidr" {}`)),
+			file.NewInMemoryFile("examples/complete/README.md", []byte("# Complete VPC")),
+			file.NewInMemoryFile("examples/complete/main.tf", []byte(`module "vpc" { source = "../../" }`)),
+			file.NewInMemoryFile("examples/complete/outputs.tf", []byte(`output "vpc_id" { value = module.vpc.id }`)),
+			file.NewInMemoryFile("examples/complete/versions.tf", []byte{}),
+		})
+		So(err, ShouldBeNil)
+
+		mockFetcher.
+			On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
+			Return(arch, func() {}, nil)
+
+		stored := map[string]string{}
+		mockResolver.
+			On("Store", mock.AnythingOfType("*storage.StoreInput")).
+			Return(func(in *storage.StoreInput) string {
+				key := in.KeyPrefix + "/" + in.FileName
+				content, _ := io.ReadAll(in.Reader)
+				stored[key] = string(content)
+				return key
+			}, nil)
+
+		var uploaded module.Module
+		mockModuleRepository.
+			On("Upsert", mock.AnythingOfType("module.Module")).
+			Run(func(args mock.Arguments) {
+				uploaded = args.Get(0).(module.Module)
+			}).
+			Return(&module.Module{}, nil)
+
+		Convey("When uploading the module", func() {
+			err := moduleService.Upload(&dto, url, nil)
+
+			Convey("The examples should be persisted", func() {
+				So(err, ShouldBeNil)
+				So(uploaded.Versions[0].Examples, ShouldHaveLength, 1)
+
+				e := uploaded.Versions[0].Examples[0]
+				So(e.Path, ShouldEqual, "examples/complete")
+				So(e.Files, ShouldHaveLength, 3)
+				So(e.Files[0].Name, ShouldEqual, "main.tf")
+				So(e.Files[1].Name, ShouldEqual, "outputs.tf")
+				So(e.Files[2].Name, ShouldEqual, "versions.tf")
+			})
+
+			Convey("The documentation and the sources of the examples should be stored", func() {
+				So(err, ShouldBeNil)
+				So(stored["modules/acme/vpc/aws/examples/1.0.0_examples__complete.md"], ShouldEqual, "# Complete VPC")
+				So(stored["modules/acme/vpc/aws/examples/1.0.0_examples__complete/main.tf"], ShouldEqual, `module "vpc" { source = "../../" }`)
+				So(stored, ShouldContainKey, "modules/acme/vpc/aws/examples/1.0.0_examples__complete/outputs.tf")
+				So(stored, ShouldContainKey, "modules/acme/vpc/aws/examples/1.0.0_examples__complete/versions.tf")
+			})
+		})
+	})
+}
+
+func TestUploadModuleDocumentation_ProxyStore(t *testing.T) {
+	Convey("Subject: Module documentation in proxy mode", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockFetcher := file.NewMockFetcher(t)
+		mockDocumentStore := NewMockDocumentStore(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+			Fetcher:          mockFetcher,
+			Resolver:         nil,
+			DocumentStore:    mockDocumentStore,
+		}
+
+		dto := module.CreateDTO{
+			Name:             "vpc",
+			Provider:         "aws",
+			VersionCreateDTO: module.VersionCreateDTO{Version: "1.0.0"},
+		}
+		url := "http://example.invalid/archive.zip"
+
+		mockAuthorityService.
+			On("GetByID", mock.AnythingOfType("uuid.UUID")).
+			Return(&authority.Authority{Name: "acme"}, nil)
+
+		mockModuleRepository.
+			On("Find", "acme", "vpc", "aws").
+			Return(nil, errors.New("not found"))
+
+		arch, err := file.Archive("module.zip", []file.File{
+			file.NewInMemoryFile("README.md", []byte("# VPC")),
+			file.NewInMemoryFile("main.tf", []byte(`variable "cidr" {}`)),
+			file.NewInMemoryFile("modules/subnets/README.md", []byte("# Subnets")),
+			file.NewInMemoryFile("modules/subnets/main.tf", []byte(`variable "cidrs" {}`)),
+		})
+		So(err, ShouldBeNil)
+
+		mockFetcher.
+			On("FetchContext", mock.Anything, dto.Version, url, mock.AnythingOfType("http.Header")).
+			Return(arch, func() {}, nil)
+
+		stored := map[string]string{}
+		mockDocumentStore.
+			On("Put", mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Return(func(key, content string) string {
+				stored[key] = content
+				return key
+			}, nil)
+
+		var uploaded module.Module
+		mockModuleRepository.
+			On("Upsert", mock.AnythingOfType("module.Module")).
+			Run(func(args mock.Arguments) {
+				uploaded = args.Get(0).(module.Module)
+			}).
+			Return(&module.Module{}, nil)
+
+		Convey("When uploading the module", func() {
+			err := moduleService.Upload(&dto, url, nil)
+
+			Convey("The archive should be served from its original location", func() {
+				So(err, ShouldBeNil)
+				So(uploaded.Versions[0].Location, ShouldEqual, url)
+			})
+
+			Convey("The documentation should be stored in the document store", func() {
+				So(err, ShouldBeNil)
+				So(uploaded.Versions[0].Documentation, ShouldNotBeNil)
+				So(*uploaded.Versions[0].Documentation, ShouldEqual, "modules/acme/vpc/aws/1.0.0.md")
+				So(stored["modules/acme/vpc/aws/1.0.0.md"], ShouldEqual, "# VPC")
+				So(stored["modules/acme/vpc/aws/submodules/1.0.0_modules__subnets.md"], ShouldEqual, "# Subnets")
+			})
+
+			Convey("And the documentation is requested", func() {
+				So(err, ShouldBeNil)
+
+				mockModuleRepository.
+					On("FindVersion", "acme", "vpc", "aws", "1.0.0").
+					Return(&uploaded.Versions[0], nil)
+
+				mockDocumentStore.
+					On("Get", mock.AnythingOfType("string")).
+					Return(func(key string) string { return stored[key] }, nil)
+
+				v, err := moduleService.GetVersion("acme", "vpc", "aws", "1.0.0")
+				So(err, ShouldBeNil)
+
+				doc, err := moduleService.GetSubmoduleDocumentation("acme", "vpc", "aws", "1.0.0", "modules/subnets")
+				So(err, ShouldBeNil)
+
+				Convey("It should be read from the document store", func() {
+					So(v.Documentation, ShouldNotBeNil)
+					So(*v.Documentation, ShouldEqual, "# VPC")
+					So(doc, ShouldEqual, "# Subnets")
+				})
+			})
+		})
+
+		Convey("When the documentation is too large to be stored", func() {
+			mockDocumentStore.ExpectedCalls = nil
+			mockDocumentStore.
+				On("Put", mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+				Return(func(key, content string) (string, error) {
+					if content != "" {
+						return "", ErrDocumentTooLarge
+					}
+
+					stored[key] = content
+					return key, nil
+				})
+
+			err := moduleService.Upload(&dto, url, nil)
+
+			Convey("The module should still be uploaded, without documentation", func() {
+				So(err, ShouldBeNil)
+				So(uploaded.Versions[0].Documentation, ShouldBeNil)
+			})
+
+			Convey("An empty submodule documentation should be stored", func() {
+				So(err, ShouldBeNil)
+				So(stored, ShouldContainKey, "modules/acme/vpc/aws/submodules/1.0.0_modules__subnets.md")
+				So(stored["modules/acme/vpc/aws/submodules/1.0.0_modules__subnets.md"], ShouldBeEmpty)
+			})
+
+			Convey("And the submodule documentation is requested", func() {
+				So(err, ShouldBeNil)
+
+				mockModuleRepository.
+					On("FindVersion", "acme", "vpc", "aws", "1.0.0").
+					Return(&uploaded.Versions[0], nil)
+
+				mockDocumentStore.
+					On("Get", mock.AnythingOfType("string")).
+					Return(func(key string) string { return stored[key] }, nil)
+
+				doc, err := moduleService.GetSubmoduleDocumentation("acme", "vpc", "aws", "1.0.0", "modules/subnets")
+
+				Convey("It should not be generated again from the archive", func() {
+					So(err, ShouldBeNil)
+					So(doc, ShouldEqual, submoduleDocsNotAvailable)
+					mockFetcher.AssertNotCalled(t, "Fetch", mock.Anything, mock.Anything, mock.Anything)
+				})
+			})
+		})
+	})
+}
+
+func TestUploadModuleSemverEnforcement(t *testing.T) {
+	Convey("Subject: Upload enforces semantic versioning", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockAdmissionService := NewMockAdmissionService(t)
+		mockFetcher := file.NewMockFetcher(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+			AdmissionService: mockAdmissionService,
+			Fetcher:          mockFetcher,
+		}
+
+		authorityID, _ := uuid.NewRandom()
+
+		mockAuthorityService.
+			On("GetByID", authorityID).
+			Return(&authority.Authority{Entity: entity.Entity{ID: authorityID}, Name: "acme"}, nil)
+
+		mockModuleRepository.
+			On("Find", "acme", "vpc", "aws").
+			Return(&module.Module{
+				Name:     "vpc",
+				Provider: "aws",
+				Versions: []module.Version{
+					{Version: "3.1.0", Location: "https://example.invalid/3.1.0.zip"},
+					{Version: "3.2.0", Location: "https://example.invalid/3.2.0.zip"},
+					{Version: "3.3.0", Location: "https://example.invalid/3.3.0.zip", Status: artifact.StatusYanked},
+				},
+			}, nil)
+
+		mockAdmissionService.
+			On("Validate", authorityID, mock.AnythingOfType("*file.FS")).
+			Return(nil)
+
+		previous, err := file.Archive("3.2.0.zip", []file.File{
+			file.NewInMemoryFile("main.tf", []byte(`
+				variable "name" { type = string }
+				variable "cidr" { default = "10.0.0.0/16" }
+			`)),
+		})
+		So(err, ShouldBeNil)
+
+		mockModuleRepository.
+			On("FindVersion", "acme", "vpc", "aws", "3.2.0").
+			Return(&module.Version{Version: "3.2.0", Location: "https://example.invalid/3.2.0.zip"}, nil).
+			Maybe()
+
+		mockFetcher.
+			On("Fetch", "3.2.0", "https://example.invalid/3.2.0.zip", mock.Anything).
+			Return(previous, func() {}, nil).
+			Maybe()
+
+		url := "http://example.invalid/archive.zip"
+		breaking, err := file.Archive("module.zip", []file.File{
+			file.NewInMemoryFile("main.tf", []byte(`
+				variable "name" { type = string }
+				variable "cidr" { type = string }
+			`)),
+		})
+		So(err, ShouldBeNil)
+
+		upload := func(v string) error {
+			mockFetcher.
+				On("FetchContext", mock.Anything, v, url, mock.AnythingOfType("http.Header")).
+				Return(breaking, func() {}, nil)
+
+			return moduleService.Upload(&module.CreateDTO{
+				AuthorityID:      authorityID,
+				Name:             "vpc",
+				Provider:         "aws",
+				VersionCreateDTO: module.VersionCreateDTO{Version: v},
+			}, url, nil)
+		}
+
+		Convey("Given an authority rejecting breaking changes", func() {
+			mockAdmissionService.
+				On("GetPolicy", authorityID).
+				Return(&admission.PolicyDTO{SemverEnforcement: admission.SemverEnforcementReject}, nil)
+
+			Convey("When a minor version introduces breaking changes", func() {
+				err := upload("3.4.0")
+
+				Convey("The version should be rejected with the breaking changes", func() {
+					var breakingErr *BreakingChangeError
+					So(errors.As(err, &breakingErr), ShouldBeTrue)
+					So(breakingErr.Previous, ShouldEqual, "3.2.0")
+					So(breakingErr.Changes, ShouldHaveLength, 1)
+					So(breakingErr.Changes[0].Element, ShouldEqual, "variable")
+					So(breakingErr.Changes[0].Name, ShouldEqual, "cidr")
+				})
+			})
+
+			Convey("When a major version introduces breaking changes", func() {
+				mockModuleRepository.
+					On("Upsert", mock.AnythingOfType("module.Module")).
+					Return(&module.Module{}, nil)
+
+				err := upload("4.0.0")
+
+				Convey("The version should be published", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given an authority warning about breaking changes", func() {
+			mockAdmissionService.
+				On("GetPolicy", authorityID).
+				Return(&admission.PolicyDTO{SemverEnforcement: admission.SemverEnforcementWarn}, nil)
+
+			mockModuleRepository.
+				On("Upsert", mock.AnythingOfType("module.Module")).
+				Return(&module.Module{}, nil)
+
+			Convey("When a minor version introduces breaking changes", func() {
+				err := upload("3.4.0")
+
+				Convey("The version should be published", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+	})
+}
+
+func TestPreviousInMajorLine(t *testing.T) {
+	versions := []module.Version{
+		{Version: "0.1.0"},
+		{Version: "0.2.0"},
+		{Version: "0.2.1"},
+		{Version: "1.0.0"},
+		{Version: "1.2.0"},
+		{Version: "1.3.0", Status: artifact.StatusYanked},
+		{Version: "2.0.0"},
+	}
+
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"1.4.0", "1.2.0"},
+		{"1.1.0", "1.0.0"},
+		{"2.1.0", "2.0.0"},
+		{"3.0.0", ""},
+		{"0.2.2", "0.2.1"},
+		{"0.3.0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got := previousInMajorLine(versions, tt.version)
+
+			if tt.expected == "" {
+				if got != nil {
+					t.Errorf("expected no previous version, got %s", got.Version)
+				}
+				return
+			}
+
+			if got == nil || got.Version != tt.expected {
+				t.Errorf("expected previous version %s, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetModuleVersionStatus(t *testing.T) {
+	Convey("Subject: Change the status of a module version", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+		}
+
+		Convey("Given an authority ID, a module name and provider", func() {
+			authorityID, _ := uuid.NewRandom()
+			authorityName, _ := random.String(16)
+			name, _ := random.String(16)
+			provider, _ := random.String(16)
+
+			mockAuthorityService.
+				On("GetByID", authorityID).
+				Return(&authority.Authority{Name: authorityName}, nil)
+
+			Convey("If the module does not exist", func() {
+				mockModuleRepository.
+					On("Find", authorityName, name, provider).
+					Return(nil, errors.New(""))
+
+				Convey("When the service is queried", func() {
+					err := moduleService.SetVersionStatus(authorityID, name, provider, "1.0.0", artifact.StatusYanked, artifact.UpdateStatusDTO{})
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If the module exists", func() {
+				mockModuleRepository.
+					On("Find", authorityName, name, provider).
+					Return(&module.Module{
+						Name:     name,
+						Provider: provider,
+						Versions: []module.Version{
+							{Version: "1.0.0"},
+							{Version: "1.1.0", Status: artifact.StatusYanked},
+							{Version: "1.2.0"},
+						},
+					}, nil)
+
+				Convey("If the version does not exist", func() {
+					Convey("When the service is queried", func() {
+						err := moduleService.SetVersionStatus(authorityID, name, provider, "2.0.0", artifact.StatusYanked, artifact.UpdateStatusDTO{})
+
+						Convey("An error should be returned", func() {
+							So(err, ShouldNotBeNil)
+						})
+					})
+				})
+
+				Convey("If the version is yanked in favour of an existing version", func() {
+					mockModuleRepository.
+						On("UpdateVersionStatus", mock.AnythingOfType("*module.Version")).
+						Return(nil)
+
+					Convey("When the service is queried", func() {
+						err := moduleService.SetVersionStatus(authorityID, name, provider, "1.0.0", artifact.StatusYanked, artifact.UpdateStatusDTO{
+							Reason:      "leaks credentials",
+							Replacement: "1.2.0",
+						})
+
+						Convey("The status should be persisted", func() {
+							So(err, ShouldBeNil)
+
+							v := mockModuleRepository.Calls[1].Arguments.Get(0).(*module.Version)
+							So(v.Version, ShouldEqual, "1.0.0")
+							So(v.Status, ShouldEqual, artifact.StatusYanked)
+							So(v.StatusReason, ShouldEqual, "leaks credentials")
+							So(v.Replacement, ShouldEqual, "1.2.0")
+						})
+					})
+				})
+
+				Convey("If the version would replace itself", func() {
+					Convey("When the service is queried", func() {
+						err := moduleService.SetVersionStatus(authorityID, name, provider, "1.0.0", artifact.StatusDeprecated, artifact.UpdateStatusDTO{
+							Replacement: "1.0.0",
+						})
+
+						Convey("An error should be returned", func() {
+							So(err, ShouldNotBeNil)
+						})
+					})
+				})
+
+				Convey("If the replacement version is yanked", func() {
+					Convey("When the service is queried", func() {
+						err := moduleService.SetVersionStatus(authorityID, name, provider, "1.0.0", artifact.StatusDeprecated, artifact.UpdateStatusDTO{
+							Replacement: "1.1.0",
+						})
+
+						Convey("An error should be returned", func() {
+							So(err, ShouldNotBeNil)
+						})
+					})
+				})
+			})
+		})
+	})
+}
+
+// openerResolver is a resolver which can read back the files it stores.
+type openerResolver struct {
+	*storage.MockResolver
+	files map[string]string
+}
+
+func (r *openerResolver) Open(key string) (file.File, func(), error) {
+	content, ok := r.files[key]
+	if !ok {
+		return nil, nil, errors.New("file not found")
+	}
+
+	return file.NewInMemoryFile(key, []byte(content)), func() {}, nil
+}
+
+func TestVerifyModules(t *testing.T) {
+	Convey("Subject: Verify the stored module archives", t, func() {
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+
+		moduleService := &DefaultModuleService{
+			ModuleRepository: mockModuleRepository,
+			AuthorityService: mockAuthorityService,
+		}
+
+		authorityID, _ := uuid.NewRandom()
+
+		Convey("Given modules which are not stored by the registry", func() {
+			Convey("When the archives are verified", func() {
+				_, err := moduleService.Verify(authorityID)
+
+				Convey("An error should be returned", func() {
+					So(errors.Is(err, ErrModulesNotStored), ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("Given stored module archives", func() {
+			moduleService.Resolver = &openerResolver{
+				MockResolver: storage.NewMockResolver(t),
+				files: map[string]string{
+					"modules/team-a/vpc/aws/1.0.0.zip": "vpc 1.0.0",
+					"modules/team-a/vpc/aws/1.1.0.zip": "tampered",
+				},
+			}
+
+			sum := func(content string) string {
+				checksum, _ := file.Checksum(strings.NewReader(content))
+				return checksum
+			}
+
+			mockAuthorityService.
+				On("GetByID", authorityID).
+				Return(&authority.Authority{Name: "team-a"}, nil)
+
+			mockModuleRepository.
+				On("FindAll", module.ListFilter{Namespace: "team-a"}).
+				Return([]*module.Module{
+					{
+						Name:     "vpc",
+						Provider: "aws",
+						Versions: []module.Version{
+							{Version: "0.9.0", Location: "modules/team-a/vpc/aws/0.9.0.zip"},
+							{Version: "1.0.0", Location: "modules/team-a/vpc/aws/1.0.0.zip", Checksum: sum("vpc 1.0.0")},
+							{Version: "1.1.0", Location: "modules/team-a/vpc/aws/1.1.0.zip", Checksum: sum("vpc 1.1.0")},
+							{Version: "1.2.0", Location: "modules/team-a/vpc/aws/1.2.0.zip", Checksum: sum("vpc 1.2.0")},
+						},
+					},
+				}, nil)
+
+			Convey("When the archives are verified", func() {
+				report, err := moduleService.Verify(authorityID)
+
+				Convey("The archives which do not match their checksum should be reported", func() {
+					So(err, ShouldBeNil)
+					So(report.Verified, ShouldEqual, 1)
+					So(report.Unverified, ShouldEqual, 1)
+					So(report.Mismatches, ShouldHaveLength, 2)
+
+					So(report.Mismatches[0].Version, ShouldEqual, "1.1.0")
+					So(report.Mismatches[0].Actual, ShouldEqual, sum("tampered"))
+
+					So(report.Mismatches[1].Version, ShouldEqual, "1.2.0")
+					So(report.Mismatches[1].Error, ShouldNotBeEmpty)
+				})
+			})
+		})
+	})
+}
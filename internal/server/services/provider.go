@@ -1,8 +1,16 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"terralist/internal/server/models/artifact"
+	"terralist/internal/server/models/authority"
 	"terralist/internal/server/models/provider"
 	"terralist/internal/server/repositories"
 	"terralist/pkg/file"
@@ -19,6 +27,11 @@ const (
 	shaSumsSigKey = "shaSumsSig"
 )
 
+var (
+	ErrProvidersNotStored     = errors.New("provider files are not stored by the registry")
+	ErrInvalidProviderRelease = errors.New("invalid provider release")
+)
+
 // ProviderService describes a service that holds the business logic for providers registry.
 type ProviderService interface {
 	// Get returns a specific provider.
@@ -27,13 +40,53 @@ type ProviderService interface {
 	// GetVersion returns a specific installation for a provider.
 	GetVersion(namespace, name, version, system, architecture string) (*provider.DownloadPlatformDTO, error)
 
+	// GetMirrorVersions returns the versions of a provider, as listed by the
+	// provider network mirror protocol.
+	GetMirrorVersions(namespace, name string) (*provider.MirrorVersionsDTO, error)
+
+	// GetMirrorArchives returns the packages of a provider version, as listed
+	// by the provider network mirror protocol.
+	GetMirrorArchives(namespace, name, version string) (*provider.MirrorArchivesDTO, error)
+
+	// GetDocs returns the documentation pages shipped with a provider
+	// version.
+	GetDocs(namespace, name, version string) ([]provider.DocDTO, error)
+
+	// GetDoc returns the content of a documentation page of a provider
+	// version.
+	GetDoc(namespace, name, version, docPath string) (*provider.DocDetailsDTO, error)
+
 	// Upload loads a new provider version into the system.
 	// If the provider does not already exist, it will create a new one.
 	Upload(*provider.CreateProviderDTO) error
 
+	// UploadFiles loads a new provider version into the system from the files
+	// of its release: the platform packages, the SHA256SUMS file, its
+	// signature and, optionally, the registry manifest. The platforms and
+	// the protocols of the version are derived from these files.
+	UploadFiles(d *provider.CreateProviderDTO, files []file.File) error
+
+	// AddPlatforms adds platforms to an existing provider version. Without
+	// a SHA256SUMS file, the new platforms are verified against the one of
+	// the version. Otherwise, the SHA256SUMS file must list all the platforms
+	// of the version and be signed by a key of the authority, it supersedes
+	// the one of the version.
+	AddPlatforms(d *provider.CreateProviderDTO) error
+
+	// Import loads a new provider version into the system from its
+	// GoReleaser release. The platforms, their checksums and the protocols
+	// of the version are discovered from the SHA256SUMS file and the
+	// registry manifest of the release.
+	Import(d *provider.ImportProviderDTO) error
+
 	// Delete removes a provider from the system with all its data (versions).
 	Delete(authorityID uuid.UUID, name string) error
 
+	// SetVersionStatus updates the lifecycle status of a provider version.
+	// Yanked versions are no longer listed, but can still be downloaded when
+	// pinned exactly.
+	SetVersionStatus(authorityID uuid.UUID, name, version string, status artifact.Status, d artifact.UpdateStatusDTO) error
+
 	// DeleteVersion removes a specific version from the system with all its data (installations).
 	// If the removed version is the only version available in the system, the entire
 	// provider will be removed.
@@ -46,6 +99,21 @@ type DefaultProviderService struct {
 	AuthorityService   AuthorityService
 	Resolver           storage.Resolver
 	Fetcher            file.Fetcher
+
+	// DocumentStore stores the documentation of the providers when their
+	// files are not stored by the registry, if set
+	DocumentStore DocumentStore
+}
+
+// documents returns the store holding the documentation of the providers.
+// The documentation is kept next to the provider files, when they are stored
+// by the registry.
+func (s *DefaultProviderService) documents() DocumentStore {
+	if s.Resolver != nil {
+		return &ResolverDocumentStore{Resolver: s.Resolver}
+	}
+
+	return s.DocumentStore
 }
 
 func (s *DefaultProviderService) Get(namespace, name string) (*provider.VersionListProviderDTO, error) {
@@ -78,7 +146,16 @@ func (s *DefaultProviderService) GetVersion(namespace, name, version, system, ar
 
 	keys := []provider.PublicKeyDTO{}
 
+	// Only the keys which signed the version are advertised. The versions
+	// uploaded before their signing key was recorded are not linked to any
+	// key, so all the keys of the authority are advertised for them.
+	legacy := len(p.Version.SigningKeys) == 0 && p.Version.SigningKeyID == ""
+
 	for _, k := range a.Keys {
+		if !legacy && !p.Version.IsSignedBy(k.ID) {
+			continue
+		}
+
 		keys = append(keys, provider.PublicKeyDTO{
 			KeyId:          k.KeyId,
 			AsciiArmor:     k.AsciiArmor,
@@ -96,13 +173,125 @@ func (s *DefaultProviderService) GetVersion(namespace, name, version, system, ar
 		}
 	}
 
+	// Downloads are tracked for the retention policies, so a failure should
+	// not prevent the provider from being downloaded
+	if err := s.ProviderRepository.MarkVersionDownloaded(p.VersionID, time.Now()); err != nil {
+		log.Warn().
+			Str("providerSlug", fmt.Sprintf("%s/%s/%s", namespace, name, version)).
+			Err(err).
+			Msg("could not record provider download")
+	}
+
+	// Record download metrics
+	metrics.RecordRequest(namespace, "download")
+	metrics.RecordArtifactDownload("provider", namespace)
+
+	return &dto, nil
+}
+
+func (s *DefaultProviderService) GetMirrorVersions(namespace, name string) (*provider.MirrorVersionsDTO, error) {
+	p, err := s.ProviderRepository.Find(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("requested provider was not found: %v", err)
+	}
+
+	// Record list operation
+	metrics.RecordRequest(namespace, "list")
+
+	dto := p.ToMirrorVersionsDTO()
+
+	return &dto, nil
+}
+
+func (s *DefaultProviderService) GetMirrorArchives(namespace, name, version string) (*provider.MirrorArchivesDTO, error) {
+	p, err := s.ProviderRepository.Find(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("requested provider was not found: %v", err)
+	}
+
+	v := p.GetVersion(version)
+	if v == nil {
+		return nil, fmt.Errorf("requested version was not found (provider %s/%s, version %s)", namespace, name, version)
+	}
+
+	if s.Resolver != nil {
+		for i, platform := range v.Platforms {
+			v.Platforms[i].Location, err = s.Resolver.Find(platform.Location)
+			if err != nil {
+				return nil, fmt.Errorf("could not resolve %s binary location: %v", platform.String(), err)
+			}
+		}
+	}
+
+	// Downloads are tracked for the retention policies, so a failure should
+	// not prevent the provider from being downloaded
+	if err := s.ProviderRepository.MarkVersionDownloaded(v.ID, time.Now()); err != nil {
+		log.Warn().
+			Str("providerSlug", fmt.Sprintf("%s/%s/%s", namespace, name, version)).
+			Err(err).
+			Msg("could not record provider download")
+	}
+
 	// Record download metrics
 	metrics.RecordRequest(namespace, "download")
 	metrics.RecordArtifactDownload("provider", namespace)
 
+	dto := v.ToMirrorArchivesDTO()
+
 	return &dto, nil
 }
 
+func (s *DefaultProviderService) GetDocs(namespace, name, version string) ([]provider.DocDTO, error) {
+	p, err := s.ProviderRepository.Find(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("requested provider was not found: %v", err)
+	}
+
+	v := p.GetVersion(version)
+	if v == nil {
+		return nil, fmt.Errorf("provider %s/%s does not contain version %s", namespace, name, version)
+	}
+
+	docs := []provider.DocDTO{}
+	for _, d := range v.Docs {
+		docs = append(docs, d.ToDTO())
+	}
+
+	return docs, nil
+}
+
+func (s *DefaultProviderService) GetDoc(namespace, name, version, docPath string) (*provider.DocDetailsDTO, error) {
+	p, err := s.ProviderRepository.Find(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("requested provider was not found: %v", err)
+	}
+
+	v := p.GetVersion(version)
+	if v == nil {
+		return nil, fmt.Errorf("provider %s/%s does not contain version %s", namespace, name, version)
+	}
+
+	i := slices.IndexFunc(v.Docs, func(d provider.Doc) bool { return d.Path == docPath })
+	if i == -1 {
+		return nil, fmt.Errorf("%w: page %s of provider %s/%s/%s", ErrDocumentNotFound, docPath, namespace, name, version)
+	}
+
+	store := s.documents()
+	if store == nil {
+		return nil, fmt.Errorf("%w: the provider documentation is not stored", ErrDocumentNotFound)
+	}
+
+	content, err := store.Get(providerDocKey(namespace, name, v.Version, v.Docs[i].Path))
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.DocDetailsDTO{
+		DocDTO:  v.Docs[i].ToDTO(),
+		Content: content,
+	}, nil
+}
+
 func (s *DefaultProviderService) Upload(d *provider.CreateProviderDTO) error {
 	// Validate version
 	if semVer := version.Version(d.Version); !semVer.Valid() {
@@ -112,51 +301,471 @@ func (s *DefaultProviderService) Upload(d *provider.CreateProviderDTO) error {
 	// Map the DTO
 	p := d.ToProvider()
 
-	// Find the authority
-	a, err := s.AuthorityService.GetByID(p.AuthorityID)
+	a, current, err := s.findCurrent(&p, d.Version)
 	if err != nil {
 		return err
 	}
 
-	// Check if the provider already exists and has this version
-	current, err := s.ProviderRepository.Find(a.Name, p.Name)
-	if err == nil {
-		if current.GetVersion(d.Version) != nil {
-			return fmt.Errorf("version %s already exists", d.Version)
+	if d.ShaSums.URL == "" && d.ShaSums.SignatureURL != "" {
+		return fmt.Errorf("%w: missing %s file", ErrInvalidProviderRelease, shaSumsSuffix)
+	}
+
+	// Download and verify the SHA256SUMS file before the platform packages
+	files, cleanup, err := s.downloadShaSums(d)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if files[shaSumsSigKey] == nil {
+		// The signatures generated by the registry have no URL to be served
+		// from, unless they are stored
+		if s.Resolver == nil {
+			return fmt.Errorf("%w: the versions without a signature cannot be signed", ErrProvidersNotStored)
+		}
+
+		files[shaSumsKey], files[shaSumsSigKey], err = s.signRelease(a, d.Name, d.Version, files[shaSumsKey], d.Platforms)
+		if err != nil {
+			return err
 		}
 	}
 
+	signingKey, err := verifyRelease(a, d.Name, d.Version, files[shaSumsKey], files[shaSumsSigKey], d.Platforms)
+	if err != nil {
+		return err
+	}
+	p.Versions[0].SignedWith(signingKey.ID, signingKey.KeyId, time.Now())
+
+	pages, err := s.fetchDocs(d)
+	if err != nil {
+		return err
+	}
+
+	var keys map[string]string
 	if s.Resolver != nil {
 		// Download provider files
-		files, cleanup, err := s.downloadFiles(d)
+		packages, cleanup, err := s.downloadPlatforms(d)
 		if err != nil {
 			return err
 		}
 		defer cleanup()
 
+		setHashes(&p.Versions[0], packages)
+
+		maps.Copy(files, packages)
+
 		// Upload provider files
-		keys, err := s.uploadFiles(a.Name, p.Name, d.Version, files)
+		keys, err = s.uploadFiles(a.Name, p.Name, d.Version, files)
+		if err != nil {
+			return err
+		}
+
+		setLocations(&p.Versions[0], keys)
+	}
+
+	s.storeDocs(a.Name, d, &p.Versions[0], pages)
+
+	if err := s.save(a, current, &p); err != nil {
+		// The stored files are only indexed by the version
+		s.deleteDocs(a.Name, p.Name, &p.Versions[0])
+		s.purgeFiles(p.Name, d.Version, keys)
+		return err
+	}
+
+	return nil
+}
+
+func (s *DefaultProviderService) UploadFiles(d *provider.CreateProviderDTO, files []file.File) error {
+	// Validate version
+	if semVer := version.Version(d.Version); !semVer.Valid() {
+		return fmt.Errorf("version should respect the semantic versioning standard (semver.org)")
+	}
+
+	if s.Resolver == nil {
+		return ErrProvidersNotStored
+	}
+
+	release, err := newProviderRelease(d.Name, d.Version, files)
+	if err != nil {
+		return err
+	}
+
+	d.Platforms, err = release.Platforms()
+	if err != nil {
+		return err
+	}
+
+	d.Protocols, err = release.Protocols()
+	if err != nil {
+		return err
+	}
+
+	// Map the DTO
+	p := d.ToProvider()
+
+	a, current, err := s.findCurrent(&p, d.Version)
+	if err != nil {
+		return err
+	}
+
+	if release.ShaSumsSig == nil {
+		release.ShaSums, release.ShaSumsSig, err = s.signRelease(a, d.Name, d.Version, release.ShaSums, d.Platforms)
 		if err != nil {
 			return err
 		}
+	}
+
+	signingKey, err := verifyRelease(a, d.Name, d.Version, release.ShaSums, release.ShaSumsSig, d.Platforms)
+	if err != nil {
+		return err
+	}
+	p.Versions[0].SignedWith(signingKey.ID, signingKey.KeyId, time.Now())
+
+	// Store the files under the names used when they are downloaded
+	prefix := releaseFilePrefix(d.Name, d.Version)
+	stored := map[string]file.File{
+		shaSumsKey:    file.RenameStreamingFile(release.ShaSums, fmt.Sprintf("%s_%s", prefix, shaSumsSuffix)),
+		shaSumsSigKey: file.RenameStreamingFile(release.ShaSumsSig, fmt.Sprintf("%s_%s", prefix, shaSumsSigSuffix)),
+	}
+
+	for osArch, pkg := range release.Packages {
+		stored[osArch] = pkg
+	}
+
+	setHashes(&p.Versions[0], release.Packages)
+
+	pages, err := s.fetchDocs(d)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.uploadFiles(a.Name, p.Name, d.Version, stored)
+	if err != nil {
+		return err
+	}
+
+	setLocations(&p.Versions[0], keys)
+
+	s.storeDocs(a.Name, d, &p.Versions[0], pages)
+
+	if err := s.save(a, current, &p); err != nil {
+		// The stored files are only indexed by the version
+		s.deleteDocs(a.Name, p.Name, &p.Versions[0])
+		s.purgeFiles(p.Name, d.Version, keys)
+		return err
+	}
+
+	return nil
+}
+
+func (s *DefaultProviderService) AddPlatforms(d *provider.CreateProviderDTO) error {
+	a, err := s.AuthorityService.GetByID(d.AuthorityID)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.ProviderRepository.Find(a.Name, d.Name)
+	if err != nil {
+		return err
+	}
 
-		// Update provider locations
-		p.Versions[0].ShaSumsUrl = keys[shaSumsKey]
-		p.Versions[0].ShaSumsSignatureUrl = keys[shaSumsSigKey]
+	v := p.GetVersion(d.Version)
+	if v == nil {
+		return fmt.Errorf("version %s does not exist", d.Version)
+	}
+	d.Version = v.Version
+
+	for _, platform := range d.Platforms {
+		added := platform.ToPlatform()
+		if slices.ContainsFunc(v.Platforms, func(p provider.Platform) bool { return p.String() == added.String() }) {
+			return fmt.Errorf("platform %s already exists for version %s", added.String(), v.Version)
+		}
+	}
 
-		for i, platform := range p.Versions[0].Platforms {
-			p.Versions[0].Platforms[i].Location = keys[platform.String()]
+	// Without a new SHA256SUMS file, the one of the version is used
+	supersede := d.ShaSums.URL != "" || d.ShaSums.SignatureURL != ""
+	if !supersede {
+		if d.ShaSums, err = s.versionShaSums(v); err != nil {
+			return err
 		}
 	}
 
-	// Only add the new version if the provider already exists
+	// Download and verify the SHA256SUMS file before the platform packages
+	files, cleanup, err := s.downloadShaSums(d)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if files[shaSumsKey] == nil || files[shaSumsSigKey] == nil {
+		return fmt.Errorf("%w: missing %s or %s file", ErrInvalidProviderRelease, shaSumsSuffix, shaSumsSigSuffix)
+	}
+
+	// A new SHA256SUMS file must still list the existing platforms, since
+	// their packages are verified against it
+	verified := d.Platforms
+	if supersede {
+		for _, platform := range v.Platforms {
+			verified = append(verified, provider.CreatePlatformDTO{
+				System:       platform.System,
+				Architecture: platform.Architecture,
+				ShaSum:       platform.ShaSum,
+			})
+		}
+	}
+
+	signingKey, err := verifyRelease(a, d.Name, v.Version, files[shaSumsKey], files[shaSumsSigKey], verified)
+	if err != nil {
+		return err
+	}
+
+	// The new platforms are mapped as a version, to set their locations
+	added := d.ToProvider().Versions[0]
+
+	if !supersede {
+		// The SHA256SUMS file of the version is already stored
+		delete(files, shaSumsKey)
+		delete(files, shaSumsSigKey)
+	}
+
+	if s.Resolver != nil {
+		packages, cleanup, err := s.downloadPlatforms(d)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		setHashes(&added, packages)
+
+		maps.Copy(files, packages)
+
+		keys, err := s.uploadFiles(a.Name, p.Name, v.Version, files)
+		if err != nil {
+			return err
+		}
+
+		setLocations(&added, keys)
+	}
+
+	superseded := *v
+	if supersede {
+		v.ShaSumsUrl = added.ShaSumsUrl
+		v.ShaSumsSignatureUrl = added.ShaSumsSignatureUrl
+		v.SignedWith(signingKey.ID, signingKey.KeyId, time.Now())
+	}
+
+	if err := s.ProviderRepository.AddVersionPlatforms(v, added.Platforms); err != nil {
+		return err
+	}
+
+	if supersede && s.Resolver != nil {
+		s.purgeShaSums(&superseded, v)
+	}
+
+	return nil
+}
+
+// versionShaSums returns the URLs from which the SHA256SUMS file of a
+// provider version and its signature can be downloaded.
+func (s *DefaultProviderService) versionShaSums(v *provider.Version) (provider.CreateProviderShaSumsDTO, error) {
+	d := provider.CreateProviderShaSumsDTO{
+		URL:          v.ShaSumsUrl,
+		SignatureURL: v.ShaSumsSignatureUrl,
+	}
+
+	if s.Resolver == nil {
+		return d, nil
+	}
+
+	var err error
+
+	d.URL, err = s.Resolver.Find(v.ShaSumsUrl)
+	if err != nil {
+		return d, fmt.Errorf("could not resolve shasums location: %v", err)
+	}
+
+	d.SignatureURL, err = s.Resolver.Find(v.ShaSumsSignatureUrl)
+	if err != nil {
+		return d, fmt.Errorf("could not resolve shasums signature location: %v", err)
+	}
+
+	return d, nil
+}
+
+// purgeShaSums removes the stored SHA256SUMS file of a provider version and
+// its signature once they are superseded, unless they were replaced under
+// the same keys.
+func (s *DefaultProviderService) purgeShaSums(superseded, v *provider.Version) {
+	for _, key := range []string{superseded.ShaSumsUrl, superseded.ShaSumsSignatureUrl} {
+		if key == "" || key == v.ShaSumsUrl || key == v.ShaSumsSignatureUrl {
+			continue
+		}
+
+		if err := s.Resolver.Purge(key); err != nil {
+			log.Warn().
+				AnErr("Error", err).
+				Str("Provider", superseded.Provider.Name).
+				Str("Version", superseded.Version).
+				Str("Key", key).
+				Msg("Could not purge, require manual clean-up")
+		}
+	}
+}
+
+func (s *DefaultProviderService) Import(d *provider.ImportProviderDTO) error {
+	// Validate version
+	if semVer := version.Version(d.Version); !semVer.Valid() {
+		return fmt.Errorf("version should respect the semantic versioning standard (semver.org)")
+	}
+
+	if (d.URL == "") == (d.ArchiveURL == "") {
+		return fmt.Errorf("%w: exactly one of the release URL and the archive URL should be set", ErrInvalidProviderRelease)
+	}
+
+	if d.ArchiveURL != "" {
+		return s.importArchive(d)
+	}
+
+	return s.importRelease(d)
+}
+
+// importRelease imports a provider version from the URL where its release
+// files are published. The files are referenced by their URLs, and
+// downloaded by Upload if the registry stores them.
+func (s *DefaultProviderService) importRelease(d *provider.ImportProviderDTO) error {
+	prefix := releaseFilePrefix(d.Name, d.Version)
+	headers := file.CreateHeader(d.Headers)
+
+	shaSumsName := fmt.Sprintf("%s_%s", prefix, shaSumsSuffix)
+	shaSumsURL := releaseFileURL(d.URL, shaSumsName)
+
+	shaSums, cleanup, err := s.Fetcher.FetchFile(shaSumsName, shaSumsURL, headers)
+	if err != nil {
+		return fmt.Errorf("could not fetch shaSums file: %w", err)
+	}
+	defer cleanup()
+
+	sums, err := parseShaSums(shaSums)
+	if err != nil {
+		return err
+	}
+
+	protocols, err := s.fetchProtocols(d, headers)
+	if err != nil {
+		return err
+	}
+
+	dto := d.ToCreateProviderDTO()
+	dto.ShaSums = provider.CreateProviderShaSumsDTO{
+		URL:          shaSumsURL,
+		SignatureURL: releaseFileURL(d.URL, fmt.Sprintf("%s_%s", prefix, shaSumsSigSuffix)),
+	}
+	dto.Protocols = protocols
+
+	for _, name := range slices.Sorted(maps.Keys(sums)) {
+		osArch, ok := packageOsArch(d.Name, d.Version, name)
+		if !ok {
+			continue
+		}
+
+		os, arch, _ := strings.Cut(osArch, "_")
+		dto.Platforms = append(dto.Platforms, provider.CreatePlatformDTO{
+			System:       os,
+			Architecture: arch,
+			Location:     releaseFileURL(d.URL, name),
+			ShaSum:       sums[name],
+		})
+	}
+
+	if len(dto.Platforms) == 0 {
+		return fmt.Errorf("%w: no platform package is listed in the %s file", ErrInvalidProviderRelease, shaSumsSuffix)
+	}
+
+	return s.Upload(&dto)
+}
+
+// fetchProtocols returns the protocol versions declared by the registry
+// manifest of a release. Releases without a manifest are assumed to support
+// the protocol version 5.
+func (s *DefaultProviderService) fetchProtocols(d *provider.ImportProviderDTO, headers http.Header) ([]string, error) {
+	name := fmt.Sprintf("%s%s", releaseFilePrefix(d.Name, d.Version), manifestSuffix)
+
+	manifest, cleanup, err := s.Fetcher.FetchFile(name, releaseFileURL(d.URL, name), headers)
+	if errors.Is(err, file.ErrLimitExceeded) {
+		return nil, fmt.Errorf("could not fetch the registry manifest: %w", err)
+	} else if err != nil {
+		log.Debug().
+			Err(err).
+			Str("providerSlug", fmt.Sprintf("%s/%s", d.Name, d.Version)).
+			Msg("could not fetch the registry manifest, assuming protocol version 5")
+
+		return []string{defaultProtocol}, nil
+	}
+	defer cleanup()
+
+	return parseManifest(manifest)
+}
+
+// importArchive imports a provider version from an archive of the
+// GoReleaser dist directory of its release.
+func (s *DefaultProviderService) importArchive(d *provider.ImportProviderDTO) error {
+	if s.Resolver == nil {
+		return ErrProvidersNotStored
+	}
+
+	archive, cleanup, err := s.Fetcher.FetchDir(releaseFilePrefix(d.Name, d.Version), d.ArchiveURL, file.CreateHeader(d.Headers))
+	if err != nil {
+		return fmt.Errorf("could not fetch the release archive: %w", err)
+	}
+	defer cleanup()
+
+	archiveFile, ok := archive.(*file.ArchiveFile)
+	if !ok {
+		return fmt.Errorf("%w: the release archive cannot be read", ErrInvalidProviderRelease)
+	}
+
+	files, err := releaseFiles(archiveFile.FS(), d.Name, d.Version)
+	if err != nil {
+		return err
+	}
+
+	dto := d.ToCreateProviderDTO()
+	return s.UploadFiles(&dto, files)
+}
+
+// findCurrent returns the authority of a new provider version and the
+// provider it will be added to, if the provider already exists. It fails if
+// the provider already has this version.
+func (s *DefaultProviderService) findCurrent(p *provider.Provider, version string) (*authority.Authority, *provider.Provider, error) {
+	// Find the authority
+	a, err := s.AuthorityService.GetByID(p.AuthorityID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check if the provider already exists and has this version
+	current, err := s.ProviderRepository.Find(a.Name, p.Name)
+	if err != nil {
+		return a, nil, nil
+	}
+
+	if current.GetVersion(version) != nil {
+		return nil, nil, fmt.Errorf("version %s already exists", version)
+	}
+
+	return a, current, nil
+}
+
+// save persists a new provider version. Only the new version is added if
+// the provider already exists.
+func (s *DefaultProviderService) save(a *authority.Authority, current *provider.Provider, p *provider.Provider) error {
 	var toUpload *provider.Provider
 	if current != nil {
 		current.Versions = append(current.Versions, p.Versions[0])
 
 		toUpload = current
 	} else {
-		toUpload = &p
+		toUpload = p
 	}
 
 	if _, err := s.ProviderRepository.Upsert(*toUpload); err != nil {
@@ -192,6 +801,10 @@ func (s *DefaultProviderService) Delete(authorityID uuid.UUID, name string) erro
 		}
 	}
 
+	for _, ver := range p.Versions {
+		s.deleteDocs(a.Name, p.Name, &ver)
+	}
+
 	if err := s.ProviderRepository.Delete(p); err != nil {
 		return err
 	}
@@ -228,6 +841,10 @@ func (s *DefaultProviderService) DeleteVersion(authorityID uuid.UUID, name strin
 		s.deleteVersion(v)
 	}
 
+	if v := p.GetVersion(version); v != nil {
+		s.deleteDocs(a.Name, p.Name, v)
+	}
+
 	if err := s.ProviderRepository.DeleteVersion(p, version); err != nil {
 		return err
 	}
@@ -238,6 +855,45 @@ func (s *DefaultProviderService) DeleteVersion(authorityID uuid.UUID, name strin
 	return nil
 }
 
+func (s *DefaultProviderService) SetVersionStatus(
+	authorityID uuid.UUID,
+	name, version string,
+	status artifact.Status,
+	d artifact.UpdateStatusDTO,
+) error {
+	a, err := s.AuthorityService.GetByID(authorityID)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.ProviderRepository.Find(a.Name, name)
+	if err != nil {
+		return err
+	}
+
+	v := p.GetVersion(version)
+	if v == nil {
+		return fmt.Errorf("provider %s/%s does not contain version %s", a.Name, name, version)
+	}
+
+	d, err = validateVersionStatus(v.Version, status, d, func(r string) (*artifact.VersionStatus, bool) {
+		rv := p.GetVersion(r)
+		if rv == nil {
+			return nil, false
+		}
+
+		st := rv.GetStatus()
+		return &st, true
+	})
+	if err != nil {
+		return err
+	}
+
+	v.SetStatus(status, d)
+
+	return s.ProviderRepository.UpdateVersionStatus(v)
+}
+
 // resolveLocations resolves the keys for a provider platform.
 func (s *DefaultProviderService) resolveLocations(d *provider.DownloadPlatformDTO) error {
 	var err error
@@ -260,10 +916,11 @@ func (s *DefaultProviderService) resolveLocations(d *provider.DownloadPlatformDT
 	return err
 }
 
-// downloadFiles fetches all provider files and returns a cleanup function
-// that removes all temporary directories created during the download.
-func (s *DefaultProviderService) downloadFiles(d *provider.CreateProviderDTO) (map[string]file.File, func(), error) {
-	prefix := fmt.Sprintf("terraform-provider-%s_%s", d.Name, d.Version)
+// downloadShaSums fetches the SHA256SUMS file of a provider version and its
+// signature, if their URLs are set, and returns a cleanup function that
+// removes all temporary directories created during the download.
+func (s *DefaultProviderService) downloadShaSums(d *provider.CreateProviderDTO) (map[string]file.File, func(), error) {
+	prefix := releaseFilePrefix(d.Name, d.Version)
 
 	headers := file.CreateHeader(d.Headers)
 
@@ -274,26 +931,82 @@ func (s *DefaultProviderService) downloadFiles(d *provider.CreateProviderDTO) (m
 		}
 	}
 
-	// Download provider files
-	shaSums, shaSumsCleanup, err := s.Fetcher.FetchFile(fmt.Sprintf("%s_SHA256SUMS", prefix), d.ShaSums.URL, headers)
-	if err != nil {
-		cleanupAll()
-		return nil, nil, fmt.Errorf("could not fetch shaSums file: %v", err)
+	files := map[string]file.File{}
+
+	if d.ShaSums.URL != "" {
+		shaSums, shaSumsCleanup, err := s.Fetcher.FetchFile(fmt.Sprintf("%s_SHA256SUMS", prefix), d.ShaSums.URL, headers)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not fetch shaSums file: %w", err)
+		}
+		cleanups = append(cleanups, shaSumsCleanup)
+
+		files[shaSumsKey] = shaSums
 	}
-	cleanups = append(cleanups, shaSumsCleanup)
 
-	shaSumsSig, shaSumsSigCleanup, err := s.Fetcher.FetchFile(fmt.Sprintf("%s_SHA256SUMS.sig", prefix), d.ShaSums.SignatureURL, headers)
-	if err != nil {
-		cleanupAll()
-		return nil, nil, fmt.Errorf("could not fetch shaSums sig file: %v", err)
+	if d.ShaSums.SignatureURL != "" {
+		shaSumsSig, shaSumsSigCleanup, err := s.Fetcher.FetchFile(fmt.Sprintf("%s_SHA256SUMS.sig", prefix), d.ShaSums.SignatureURL, headers)
+		if err != nil {
+			cleanupAll()
+			return nil, nil, fmt.Errorf("could not fetch shaSums sig file: %w", err)
+		}
+		cleanups = append(cleanups, shaSumsSigCleanup)
+
+		files[shaSumsSigKey] = shaSumsSig
+	}
+
+	return files, cleanupAll, nil
+}
+
+// signRelease signs the SHA256SUMS file of a release with the signing key
+// of its authority. The SHA256SUMS file is generated from the platforms if
+// the release has none.
+func (s *DefaultProviderService) signRelease(
+	a *authority.Authority,
+	name, version string,
+	shaSums file.File,
+	platforms []provider.CreatePlatformDTO,
+) (file.File, file.File, error) {
+	var content []byte
+	if shaSums != nil {
+		var err error
+		if content, err = readAll(shaSums); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		content = generateShaSums(name, version, platforms)
 	}
-	cleanups = append(cleanups, shaSumsSigCleanup)
 
-	files := map[string]file.File{
-		shaSumsKey:    shaSums,
-		shaSumsSigKey: shaSumsSig,
+	signature, _, err := s.AuthorityService.Sign(a.ID, content)
+	if errors.Is(err, ErrSigningDisabled) || errors.Is(err, ErrSigningKeyNotFound) {
+		return nil, nil, fmt.Errorf("%w: missing %s file, and %v", ErrInvalidProviderRelease, shaSumsSigSuffix, err)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("could not sign the %s file: %v", shaSumsSuffix, err)
 	}
 
+	prefix := releaseFilePrefix(name, version)
+
+	return file.NewInMemoryFile(fmt.Sprintf("%s_%s", prefix, shaSumsSuffix), content),
+		file.NewInMemoryFile(fmt.Sprintf("%s_%s", prefix, shaSumsSigSuffix), signature),
+		nil
+}
+
+// downloadPlatforms fetches the packages of all provider platforms and
+// returns a cleanup function that removes all temporary directories created
+// during the download.
+func (s *DefaultProviderService) downloadPlatforms(d *provider.CreateProviderDTO) (map[string]file.File, func(), error) {
+	prefix := releaseFilePrefix(d.Name, d.Version)
+
+	headers := file.CreateHeader(d.Headers)
+
+	var cleanups []func()
+	cleanupAll := func() {
+		for _, fn := range cleanups {
+			fn()
+		}
+	}
+
+	files := map[string]file.File{}
+
 	for _, platform := range d.Platforms {
 		p := platform.ToPlatform()
 		osArch := p.String()
@@ -301,7 +1014,7 @@ func (s *DefaultProviderService) downloadFiles(d *provider.CreateProviderDTO) (m
 		binary, binaryCleanup, err := s.Fetcher.FetchFileChecksum(fmt.Sprintf("%s_%s.zip", prefix, osArch), p.Location, p.ShaSum, headers)
 		if err != nil {
 			cleanupAll()
-			return nil, nil, fmt.Errorf("could not fetch %s file: %v", osArch, err)
+			return nil, nil, fmt.Errorf("could not fetch %s file: %w", osArch, err)
 		}
 		cleanups = append(cleanups, binaryCleanup)
 
@@ -329,6 +1042,8 @@ func (s *DefaultProviderService) uploadFiles(
 			FileName:    v.Name(),
 		})
 		if err != nil {
+			// Nothing indexes the files stored before the failure
+			s.purgeFiles(name, version, keys)
 			return nil, fmt.Errorf("could not upload %s: %v", v.Name(), err)
 		}
 
@@ -338,6 +1053,32 @@ func (s *DefaultProviderService) uploadFiles(
 	return keys, nil
 }
 
+// purgeFiles removes the stored files of a provider version which could not
+// be saved.
+func (s *DefaultProviderService) purgeFiles(name, version string, keys map[string]string) {
+	for _, key := range keys {
+		if err := s.Resolver.Purge(key); err != nil {
+			log.Warn().
+				AnErr("Error", err).
+				Str("Provider", name).
+				Str("Version", version).
+				Str("Key", key).
+				Msg("Could not purge, require manual clean-up")
+		}
+	}
+}
+
+// setLocations updates the locations of a provider version with the keys
+// of its stored files.
+func setLocations(v *provider.Version, keys map[string]string) {
+	v.ShaSumsUrl = keys[shaSumsKey]
+	v.ShaSumsSignatureUrl = keys[shaSumsSigKey]
+
+	for i, platform := range v.Platforms {
+		v.Platforms[i].Location = keys[platform.String()]
+	}
+}
+
 // deleteVersion removes all provider files for a specific version.
 func (s *DefaultProviderService) deleteVersion(v *provider.Version) {
 	for _, plat := range v.Platforms {
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"terralist/internal/server/models/provider"
+	"terralist/pkg/docs"
+	"terralist/pkg/file"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fetchDocs fetches the documentation archive of a new provider version,
+// if it has one, and returns its pages. It runs before any file of the
+// version is stored, so an invalid archive leaves nothing to clean up.
+func (s *DefaultProviderService) fetchDocs(d *provider.CreateProviderDTO) ([]docs.ProviderDoc, error) {
+	if d.DocsURL == "" {
+		return nil, nil
+	}
+
+	if s.documents() == nil {
+		return nil, fmt.Errorf("%w: the documentation cannot be stored", ErrProvidersNotStored)
+	}
+
+	archive, cleanup, err := s.Fetcher.FetchDir(
+		fmt.Sprintf("%s_docs", releaseFilePrefix(d.Name, d.Version)),
+		d.DocsURL,
+		file.CreateHeader(d.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the documentation archive: %w", err)
+	}
+	defer cleanup()
+
+	archiveFile, ok := archive.(*file.ArchiveFile)
+	if !ok {
+		return nil, fmt.Errorf("%w: the documentation archive cannot be read", ErrInvalidProviderRelease)
+	}
+
+	pages, err := docs.FindProviderDocs(archiveFile.FS())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProviderRelease, err)
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no documentation page found in the archive", ErrInvalidProviderRelease)
+	}
+
+	return pages, nil
+}
+
+// storeDocs stores the documentation pages of a new provider version and
+// indexes them in the version.
+func (s *DefaultProviderService) storeDocs(namespace string, d *provider.CreateProviderDTO, v *provider.Version, pages []docs.ProviderDoc) {
+	if len(pages) == 0 {
+		return
+	}
+
+	store := s.documents()
+
+	for _, page := range pages {
+		key := providerDocKey(namespace, d.Name, d.Version, page.Path)
+
+		// The documentation is not required to use the provider, so the
+		// pages which cannot be stored are only left out of the index
+		if _, err := store.Put(key, page.Content); err != nil {
+			log.Warn().
+				Str("providerSlug", fmt.Sprintf("%s/%s/%s", namespace, d.Name, d.Version)).
+				Str("docPath", page.Path).
+				Str("key", key).
+				Err(err).
+				Msg("failed to store documentation page")
+
+			continue
+		}
+
+		v.Docs = append(v.Docs, provider.Doc{
+			Path:        page.Path,
+			Category:    page.Category,
+			Title:       page.Title,
+			Subcategory: page.Subcategory,
+			Description: page.Description,
+		})
+	}
+}
+
+// deleteDocs removes the stored documentation pages of a provider version.
+func (s *DefaultProviderService) deleteDocs(namespace, name string, v *provider.Version) {
+	store := s.documents()
+	if store == nil {
+		return
+	}
+
+	for _, d := range v.Docs {
+		key := providerDocKey(namespace, name, v.Version, d.Path)
+
+		if err := store.Delete(key); err != nil {
+			log.Warn().
+				AnErr("Error", err).
+				Str("Provider", name).
+				Str("Version", v.Version).
+				Str("DocPath", d.Path).
+				Str("Key", key).
+				Msg("Could not purge provider documentation, require manual clean-up")
+		}
+	}
+}
+
+// providerDocKey returns the key under which a documentation page of a
+// provider version is stored. The namespace and the name are matched
+// case-insensitively, so they are lowercased.
+func providerDocKey(namespace, name, version, docPath string) string {
+	return fmt.Sprintf(
+		"providers/%s/%s/%s/docs/%s.md",
+		strings.ToLower(namespace),
+		strings.ToLower(name),
+		version,
+		docPath,
+	)
+}
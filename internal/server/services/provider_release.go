@@ -0,0 +1,445 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/provider"
+	"terralist/pkg/file"
+	"terralist/pkg/pgp"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+const (
+	shaSumsSuffix      = "SHA256SUMS"
+	shaSumsSigSuffix   = "SHA256SUMS.sig"
+	registryManifest   = "terraform-registry-manifest.json"
+	manifestSuffix     = "_manifest.json"
+	defaultProtocol    = "5.0"
+	providerFilePrefix = "terraform-provider-"
+)
+
+// providerRelease holds the files of a provider release, as published by
+// GoReleaser.
+type providerRelease struct {
+	ShaSums    file.File
+	ShaSumsSig file.File
+	Manifest   file.File
+
+	// Packages maps the os_arch of each platform to its zip package
+	Packages map[string]file.File
+}
+
+// releaseFilePrefix returns the prefix of the names of the files of a
+// provider release.
+func releaseFilePrefix(name, version string) string {
+	return fmt.Sprintf("%s%s_%s", providerFilePrefix, name, version)
+}
+
+// packageOsArch returns the os_arch of a platform package from its name,
+// terraform-provider-<name>_<version>_<os>_<arch>.zip.
+func packageOsArch(name, version, fileName string) (string, bool) {
+	if !strings.HasSuffix(fileName, ".zip") {
+		return "", false
+	}
+
+	osArch, ok := strings.CutPrefix(strings.TrimSuffix(fileName, ".zip"), releaseFilePrefix(name, version)+"_")
+	if !ok {
+		return "", false
+	}
+
+	parts := strings.Split(osArch, "_")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+
+	return osArch, true
+}
+
+// releaseFileURL returns the URL of a release file, given the base URL of
+// the release. The go-getter forced getter and the query of the base URL are
+// kept.
+func releaseFileURL(base, fileName string) string {
+	getter := ""
+	if i := strings.Index(base, "::"); i >= 0 {
+		getter, base = base[:i+2], base[i+2:]
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return getter + strings.TrimSuffix(base, "/") + "/" + fileName
+	}
+
+	return getter + u.JoinPath(fileName).String()
+}
+
+// releaseFiles returns the files of a provider release found in a GoReleaser
+// dist directory. The other files of the directory, such as the binaries or
+// the GoReleaser metadata, are ignored.
+func releaseFiles(fsys *file.FS, name, version string) ([]file.File, error) {
+	prefix := releaseFilePrefix(name, version) + "_"
+
+	var files []file.File
+	err := fsys.Walk("", func(p string, _ fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		base := path.Base(p)
+		if !strings.HasPrefix(base, prefix) && base != registryManifest {
+			return nil
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+
+		rf, ok := f.(file.File)
+		if !ok {
+			return fmt.Errorf("could not read %s", p)
+		}
+
+		files = append(files, file.RenameStreamingFile(rf, base))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// newProviderRelease sorts the files of a provider release by their names.
+func newProviderRelease(name, version string, files []file.File) (*providerRelease, error) {
+	r := &providerRelease{
+		Packages: map[string]file.File{},
+	}
+
+	for _, f := range files {
+		n := f.Name()
+
+		switch {
+		case strings.HasSuffix(n, shaSumsSigSuffix):
+			if r.ShaSumsSig != nil {
+				return nil, fmt.Errorf("%w: multiple %s files", ErrInvalidProviderRelease, shaSumsSigSuffix)
+			}
+			r.ShaSumsSig = f
+		case strings.HasSuffix(n, shaSumsSuffix):
+			if r.ShaSums != nil {
+				return nil, fmt.Errorf("%w: multiple %s files", ErrInvalidProviderRelease, shaSumsSuffix)
+			}
+			r.ShaSums = f
+		case n == registryManifest || strings.HasSuffix(n, manifestSuffix):
+			r.Manifest = f
+		case strings.HasSuffix(n, ".zip"):
+			osArch, ok := packageOsArch(name, version, n)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not a package of %s version %s", ErrInvalidProviderRelease, n, name, version)
+			}
+
+			if _, ok := r.Packages[osArch]; ok {
+				return nil, fmt.Errorf("%w: multiple packages for %s", ErrInvalidProviderRelease, osArch)
+			}
+
+			r.Packages[osArch] = f
+		default:
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidProviderRelease, n)
+		}
+	}
+
+	// Both files can be omitted, for the registry to sign the release
+	if r.ShaSums == nil && r.ShaSumsSig != nil {
+		return nil, fmt.Errorf("%w: missing %s file", ErrInvalidProviderRelease, shaSumsSuffix)
+	}
+
+	if len(r.Packages) == 0 {
+		return nil, fmt.Errorf("%w: no platform package", ErrInvalidProviderRelease)
+	}
+
+	return r, nil
+}
+
+// Platforms checks the packages of the release against the SHA256SUMS file,
+// if the release has one, and returns their platforms.
+func (r *providerRelease) Platforms() ([]provider.CreatePlatformDTO, error) {
+	var sums map[string]string
+	if r.ShaSums != nil {
+		var err error
+		if sums, err = parseShaSums(r.ShaSums); err != nil {
+			return nil, err
+		}
+	}
+
+	var platforms []provider.CreatePlatformDTO
+	for _, osArch := range slices.Sorted(maps.Keys(r.Packages)) {
+		pkg := r.Packages[osArch]
+
+		checksum, err := file.Checksum(pkg)
+		if err != nil {
+			return nil, err
+		}
+
+		if sums != nil {
+			expected, ok := sums[pkg.Name()]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not listed in the %s file", ErrInvalidProviderRelease, pkg.Name(), shaSumsSuffix)
+			}
+
+			if checksum != expected {
+				return nil, fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidProviderRelease, pkg.Name())
+			}
+		}
+
+		os, arch, _ := strings.Cut(osArch, "_")
+		platforms = append(platforms, provider.CreatePlatformDTO{
+			System:       os,
+			Architecture: arch,
+			ShaSum:       checksum,
+		})
+	}
+
+	return platforms, nil
+}
+
+// Protocols returns the Terraform protocol versions supported by the
+// provider, as declared by the registry manifest. Providers without a
+// manifest are assumed to support the protocol version 5.
+func (r *providerRelease) Protocols() ([]string, error) {
+	if r.Manifest == nil {
+		return []string{defaultProtocol}, nil
+	}
+
+	return parseManifest(r.Manifest)
+}
+
+// parseShaSums maps each file name listed in a SHA256SUMS file to its
+// checksum. The reader is rewound after being parsed.
+func parseShaSums(r io.ReadSeeker) (map[string]string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	sums := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: malformed %s line %q", ErrInvalidProviderRelease, shaSumsSuffix, line)
+		}
+
+		// Binary mode entries are prefixed with an asterisk
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return sums, nil
+}
+
+// parseManifest returns the protocol versions declared by a registry
+// manifest. The reader is rewound after being parsed.
+func parseManifest(r io.ReadSeeker) ([]string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Version  int `json:"version"`
+		Metadata struct {
+			ProtocolVersions []string `json:"protocol_versions"`
+		} `json:"metadata"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: malformed registry manifest: %v", ErrInvalidProviderRelease, err)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Metadata.ProtocolVersions) == 0 {
+		return []string{defaultProtocol}, nil
+	}
+
+	return manifest.Metadata.ProtocolVersions, nil
+}
+
+// verifyRelease checks the signature of the SHA256SUMS file of a release
+// against the keys of its authority, and the checksums of its platforms
+// against the SHA256SUMS file. It returns the key which signed the release,
+// the revoked keys are not trusted.
+func verifyRelease(
+	a *authority.Authority,
+	name, version string,
+	shaSums, shaSumsSig io.ReadSeeker,
+	platforms []provider.CreatePlatformDTO,
+) (*authority.Key, error) {
+	sums, err := parseShaSums(shaSums)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := releaseFilePrefix(name, version)
+	for _, p := range platforms {
+		fileName := fmt.Sprintf("%s_%s_%s.zip", prefix, p.System, p.Architecture)
+
+		expected, ok := sums[fileName]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not listed in the %s file", ErrInvalidProviderRelease, fileName, shaSumsSuffix)
+		}
+
+		if !strings.EqualFold(p.ShaSum, expected) {
+			return nil, fmt.Errorf("%w: the checksum of %s does not match the %s file", ErrInvalidProviderRelease, fileName, shaSumsSuffix)
+		}
+	}
+
+	if len(a.Keys) == 0 {
+		return nil, fmt.Errorf("%w: authority %s has no signing key", ErrInvalidProviderRelease, a.Name)
+	}
+
+	content, err := readAll(shaSums)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := readAll(shaSumsSig)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked *authority.Key
+	for _, k := range a.Keys {
+		err := pgp.Verify(k.AsciiArmor, content, signature)
+		if err == nil {
+			// The revoked keys can no longer sign new versions
+			if k.IsRevoked() {
+				revoked = &k
+				continue
+			}
+
+			return &k, nil
+		}
+
+		if errors.Is(err, pgp.ErrInvalidKey) {
+			log.Warn().
+				Err(err).
+				Str("authority", a.Name).
+				Str("keyId", k.KeyId).
+				Msg("could not read the authority key")
+		}
+	}
+
+	if revoked != nil {
+		return nil, fmt.Errorf("%w: the %s file is signed by key %s, which is revoked", ErrInvalidProviderRelease, shaSumsSuffix, revoked.KeyId)
+	}
+
+	return nil, fmt.Errorf("%w: the %s file is not signed by any key of authority %s", ErrInvalidProviderRelease, shaSumsSuffix, a.Name)
+}
+
+// generateShaSums returns the content of a SHA256SUMS file listing the
+// packages of the platforms of a release.
+func generateShaSums(name, version string, platforms []provider.CreatePlatformDTO) []byte {
+	prefix := releaseFilePrefix(name, version)
+
+	sums := map[string]string{}
+	for _, p := range platforms {
+		sums[fmt.Sprintf("%s_%s_%s.zip", prefix, p.System, p.Architecture)] = strings.ToLower(p.ShaSum)
+	}
+
+	var buf strings.Builder
+	for _, fileName := range slices.Sorted(maps.Keys(sums)) {
+		fmt.Fprintf(&buf, "%s  %s\n", sums[fileName], fileName)
+	}
+
+	return []byte(buf.String())
+}
+
+// readAll reads a file from its beginning and rewinds it.
+func readAll(r io.ReadSeeker) ([]byte, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// packageHash returns the h1: hash of a provider package, computed by
+// Terraform from the content of the package.
+func packageHash(f file.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	tmp, err := file.SaveToTemp(f)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = tmp.Remove()
+	}()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return dirhash.HashZip(tmp.Path(), dirhash.Hash1)
+}
+
+// setHashes computes the h1: hashes of the packages of a provider version.
+// The hashes are not required to serve the version, so a package which
+// cannot be hashed is only logged.
+func setHashes(v *provider.Version, packages map[string]file.File) {
+	for i, platform := range v.Platforms {
+		pkg, ok := packages[platform.String()]
+		if !ok {
+			continue
+		}
+
+		hash, err := packageHash(pkg)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("Version", v.Version).
+				Str("Platform", platform.String()).
+				Msg("could not compute the package hash")
+			continue
+		}
+
+		v.Platforms[i].Hash = hash
+	}
+}
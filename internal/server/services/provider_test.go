@@ -1,15 +1,25 @@
 package services
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"terralist/internal/server/models/artifact"
 	"terralist/internal/server/models/authority"
 	"terralist/internal/server/models/provider"
 	"terralist/internal/server/repositories"
 	"terralist/pkg/file"
 	"terralist/pkg/storage"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/google/uuid"
 	"github.com/mazen160/go-random"
 	. "github.com/smartystreets/goconvey/convey"
@@ -118,6 +128,11 @@ func TestGetProviderVersionDownloadInfo(t *testing.T) {
 					On("GetByID", mock.AnythingOfType("uuid.UUID")).
 					Return(&authority.Authority{}, nil)
 
+				mockProviderRepository.
+					On("MarkVersionDownloaded", mockProviderPlatform.VersionID, mock.AnythingOfType("time.Time")).
+					Return(nil).
+					Maybe()
+
 				Convey("If the resolver is not set", func() {
 					providerService.Resolver = nil
 
@@ -231,6 +246,215 @@ func TestGetProviderVersionDownloadInfo(t *testing.T) {
 					})
 				})
 			})
+
+			Convey("If the authority has more keys than the one which signed the version", func() {
+				providerService.Resolver = nil
+
+				signingKey := authority.Key{KeyId: "34365D9472D7468F", AsciiArmor: "signing"}
+				signingKey.ID, _ = uuid.NewRandom()
+
+				otherKey := authority.Key{KeyId: "D7468F34365D9472", AsciiArmor: "other"}
+				otherKey.ID, _ = uuid.NewRandom()
+
+				mockProviderPlatform := provider.Platform{
+					Version: provider.Version{},
+				}
+
+				mockProviderRepository.
+					On("FindVersionPlatform", namespace, name, version, system, architecture).
+					Return(&mockProviderPlatform, nil)
+
+				mockAuthorityService.
+					On("GetByID", mock.AnythingOfType("uuid.UUID")).
+					Return(&authority.Authority{Keys: []authority.Key{signingKey, otherKey}}, nil)
+
+				mockProviderRepository.
+					On("MarkVersionDownloaded", mockProviderPlatform.VersionID, mock.AnythingOfType("time.Time")).
+					Return(nil)
+
+				Convey("If the version is linked to its signing key", func() {
+					mockProviderPlatform.Version.SignedWith(signingKey.ID, signingKey.KeyId, time.Now())
+
+					Convey("When the service is queried", func() {
+						info, err := providerService.GetVersion(namespace, name, version, system, architecture)
+
+						Convey("Only the signing key should be returned", func() {
+							So(err, ShouldBeNil)
+							So(info.SigningKeys.Keys, ShouldHaveLength, 1)
+							So(info.SigningKeys.Keys[0].KeyId, ShouldEqual, signingKey.KeyId)
+						})
+					})
+				})
+
+				Convey("If the signing key of the version was not recorded", func() {
+					Convey("When the service is queried", func() {
+						info, err := providerService.GetVersion(namespace, name, version, system, architecture)
+
+						Convey("All the keys of the authority should be returned", func() {
+							So(err, ShouldBeNil)
+							So(info.SigningKeys.Keys, ShouldHaveLength, 2)
+						})
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestGetProviderMirror(t *testing.T) {
+	Convey("Subject: Find a provider with the network mirror protocol", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockResolver := storage.NewMockResolver(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+			Resolver:           mockResolver,
+		}
+
+		Convey("Given a provider with an active and a yanked version", func() {
+			mockProviderRepository.
+				On("Find", "hashicorp", "acme").
+				Return(&provider.Provider{
+					Name: "acme",
+					Versions: []provider.Version{
+						{
+							Version: "1.0.0",
+							Platforms: []provider.Platform{
+								{
+									System:       "linux",
+									Architecture: "amd64",
+									Location:     "providers/hashicorp/acme/1.0.0/linux_amd64.zip",
+									ShaSum:       "ABCDEF",
+									Hash:         "h1:abcdef=",
+								},
+								{
+									System:       "darwin",
+									Architecture: "arm64",
+									Location:     "providers/hashicorp/acme/1.0.0/darwin_arm64.zip",
+									ShaSum:       "012345",
+								},
+							},
+						},
+						{
+							Version: "1.1.0",
+							Status:  artifact.StatusYanked,
+						},
+					},
+				}, nil)
+
+			Convey("When the versions are queried", func() {
+				dto, err := providerService.GetMirrorVersions("hashicorp", "acme")
+
+				Convey("The yanked version should not be listed", func() {
+					So(err, ShouldBeNil)
+					So(dto.Versions, ShouldHaveLength, 1)
+					So(dto.Versions, ShouldContainKey, "1.0.0")
+				})
+			})
+
+			Convey("When the packages of an unknown version are queried", func() {
+				dto, err := providerService.GetMirrorArchives("hashicorp", "acme", "2.0.0")
+
+				Convey("An error should be returned", func() {
+					So(dto, ShouldBeNil)
+					So(err, ShouldNotBeNil)
+				})
+			})
+
+			Convey("When the packages of a version are queried", func() {
+				mockResolver.
+					On("Find", mock.AnythingOfType("string")).
+					Return(func(key string) (string, error) {
+						return "https://example.com/" + key, nil
+					})
+
+				mockProviderRepository.
+					On("MarkVersionDownloaded", mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("time.Time")).
+					Return(nil)
+
+				dto, err := providerService.GetMirrorArchives("hashicorp", "acme", "1.0.0")
+
+				Convey("The packages should be listed with their resolved URLs and their hashes", func() {
+					So(err, ShouldBeNil)
+					So(dto.Archives, ShouldHaveLength, 2)
+
+					linux := dto.Archives["linux_amd64"]
+					So(linux.URL, ShouldEqual, "https://example.com/providers/hashicorp/acme/1.0.0/linux_amd64.zip")
+					So(linux.Hashes, ShouldResemble, []string{"h1:abcdef=", "zh:abcdef"})
+
+					darwin := dto.Archives["darwin_arm64"]
+					So(darwin.Hashes, ShouldResemble, []string{"zh:012345"})
+				})
+			})
+		})
+	})
+}
+
+func TestGetProviderDocs(t *testing.T) {
+	Convey("Subject: Read the documentation of a provider version", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockDocumentStore := NewMockDocumentStore(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+			DocumentStore:      mockDocumentStore,
+		}
+
+		Convey("Given a provider version with documentation", func() {
+			mockProviderRepository.
+				On("Find", "Acme", "server").
+				Return(&provider.Provider{
+					Name: "server",
+					Versions: []provider.Version{
+						{
+							Version: "1.0.0",
+							Docs: []provider.Doc{
+								{Path: "index", Category: "index", Title: "Acme Provider"},
+								{Path: "resources/server", Category: "resources", Title: "acme_server"},
+							},
+						},
+					},
+				}, nil)
+
+			Convey("When the documentation is listed", func() {
+				docs, err := providerService.GetDocs("Acme", "server", "1.0.0")
+
+				Convey("The indexed pages should be returned", func() {
+					So(err, ShouldBeNil)
+					So(docs, ShouldHaveLength, 2)
+					So(docs[1].Path, ShouldEqual, "resources/server")
+				})
+			})
+
+			Convey("When an indexed page is requested", func() {
+				mockDocumentStore.
+					On("Get", "providers/acme/server/1.0.0/docs/resources/server.md").
+					Return("# acme_server", nil)
+
+				doc, err := providerService.GetDoc("Acme", "server", "1.0.0", "resources/server")
+
+				Convey("The page content should be returned", func() {
+					So(err, ShouldBeNil)
+					So(doc.Title, ShouldEqual, "acme_server")
+					So(doc.Content, ShouldEqual, "# acme_server")
+				})
+			})
+
+			Convey("When a page which is not indexed is requested", func() {
+				_, err := providerService.GetDoc("Acme", "server", "1.0.0", "guides/missing")
+
+				Convey("A not found error should be returned", func() {
+					So(errors.Is(err, ErrDocumentNotFound), ShouldBeTrue)
+				})
+			})
+
+			Convey("When the documentation of an unknown version is listed", func() {
+				_, err := providerService.GetDocs("Acme", "server", "2.0.0")
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+				})
+			})
 		})
 	})
 }
@@ -250,7 +474,9 @@ func TestUploadProvider(t *testing.T) {
 		}
 
 		Convey("Given a provider DTO", func() {
-			dto := provider.CreateProviderDTO{}
+			dto := provider.CreateProviderDTO{
+				Name: "acme",
+			}
 
 			Convey("If the version is not respecting the semantic format", func() {
 				dto.Version = "100%-not-sem-ver-valid"
@@ -282,9 +508,13 @@ func TestUploadProvider(t *testing.T) {
 				})
 
 				Convey("If the authority exists", func() {
+					key, sign := newTestSigningKey()
+
+					a := &authority.Authority{Keys: []authority.Key{key}}
+
 					mockAuthorityService.
 						On("GetByID", mock.AnythingOfType("uuid.UUID")).
-						Return(&authority.Authority{}, nil)
+						Return(a, nil)
 
 					Convey("If the provider exists and already has the given version", func() {
 						mockProviderRepository.
@@ -332,11 +562,99 @@ func TestUploadProvider(t *testing.T) {
 						Convey(td.Desc, func() {
 							td.Func()
 
+							dto.ShaSums.URL, _ = random.String(16)
+							dto.ShaSums.SignatureURL, _ = random.String(16)
+
+							binaryURL, _ := random.String(16)
+							dto.Platforms = append(dto.Platforms, provider.CreatePlatformDTO{
+								System:       "linux",
+								Architecture: "amd64",
+								Location:     binaryURL,
+								ShaSum:       strings.Repeat("a", 64),
+							})
+
+							shaSums := []byte(strings.Repeat("a", 64) + "  terraform-provider-acme_1.0.0_linux_amd64.zip\n")
+
+							mockShaSums := func(shaSums, signature []byte) {
+								mockFetcher.
+									On("FetchFile", "terraform-provider-acme_1.0.0_SHA256SUMS", dto.ShaSums.URL, mock.AnythingOfType("http.Header")).
+									Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS", shaSums), func() {}, nil)
+
+								mockFetcher.
+									On("FetchFile", "terraform-provider-acme_1.0.0_SHA256SUMS.sig", dto.ShaSums.SignatureURL, mock.AnythingOfType("http.Header")).
+									Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS.sig", signature), func() {}, nil)
+							}
+
+							Convey("If the SHA256SUMS file is not signed by a key of the authority", func() {
+								_, signWithOtherKey := newTestSigningKey()
+								mockShaSums(shaSums, signWithOtherKey(shaSums))
+
+								Convey("When the service is queried", func() {
+									err := providerService.Upload(&dto)
+
+									Convey("An error should be returned", func() {
+										So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+									})
+								})
+							})
+
+							Convey("If the SHA256SUMS file is signed by a revoked key of the authority", func() {
+								revokedAt := time.Now()
+								a.Keys[0].RevokedAt = &revokedAt
+								mockShaSums(shaSums, sign(shaSums))
+
+								Convey("When the service is queried", func() {
+									err := providerService.Upload(&dto)
+
+									Convey("An error should be returned", func() {
+										So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+										So(err.Error(), ShouldContainSubstring, "revoked")
+									})
+								})
+							})
+
+							Convey("If the SHA256SUMS file is signed by a key of the authority revoked from a future date", func() {
+								revokedAt := time.Now().Add(time.Hour)
+								a.Keys[0].RevokedAt = &revokedAt
+								providerService.Resolver = nil
+								mockShaSums(shaSums, sign(shaSums))
+
+								mockProviderRepository.
+									On("Upsert", mock.AnythingOfType("provider.Provider")).
+									Return(&provider.Provider{}, nil)
+
+								Convey("When the service is queried", func() {
+									err := providerService.Upload(&dto)
+
+									Convey("No error should be returned", func() {
+										So(err, ShouldBeNil)
+									})
+								})
+							})
+
+							Convey("If a platform checksum does not match the SHA256SUMS file", func() {
+								dto.Platforms[0].ShaSum = strings.Repeat("b", 64)
+								mockShaSums(shaSums, sign(shaSums))
+
+								Convey("When the service is queried", func() {
+									err := providerService.Upload(&dto)
+
+									Convey("An error should be returned", func() {
+										So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+									})
+								})
+							})
+
 							Convey("If the resolver is not set", func() {
 								providerService.Resolver = nil
+								mockShaSums(shaSums, sign(shaSums))
 
+								var saved provider.Provider
 								mockProviderRepository.
 									On("Upsert", mock.AnythingOfType("provider.Provider")).
+									Run(func(args mock.Arguments) {
+										saved = args.Get(0).(provider.Provider)
+									}).
 									Return(&provider.Provider{}, nil)
 
 								Convey("When the service is queried", func() {
@@ -345,18 +663,66 @@ func TestUploadProvider(t *testing.T) {
 									Convey("No error should be returned", func() {
 										So(err, ShouldBeNil)
 									})
+
+									Convey("The signing key should be recorded", func() {
+										So(saved.Versions, ShouldHaveLength, 1)
+										So(saved.Versions[0].SigningKeyID, ShouldEqual, key.KeyId)
+										So(saved.Versions[0].IsSignedBy(key.ID), ShouldBeTrue)
+									})
 								})
 							})
 
-							Convey("If the resolver is set", func() {
-								dto.ShaSums.URL, _ = random.String(16)
-								dto.ShaSums.SignatureURL, _ = random.String(16)
+							Convey("If the documentation is stored but the version cannot be saved", func() {
+								providerService.Resolver = nil
+								mockShaSums(shaSums, sign(shaSums))
+
+								mockDocumentStore := NewMockDocumentStore(t)
+								providerService.DocumentStore = mockDocumentStore
+
+								dto.DocsURL = "https://releases.example.com/docs.tar.gz"
+								archive, err := file.Archive("docs", []file.File{
+									file.NewInMemoryFile("docs/index.md", []byte("# Acme Provider")),
+								})
+								So(err, ShouldBeNil)
+
+								mockFetcher.
+									On("FetchDir", "terraform-provider-acme_1.0.0_docs", dto.DocsURL, mock.Anything).
+									Return(archive, func() {}, nil)
+
+								var stored, deleted []string
+								mockDocumentStore.
+									On("Put", mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+									Run(func(args mock.Arguments) {
+										stored = append(stored, args.String(0))
+									}).
+									Return("", nil)
+
+								mockDocumentStore.
+									On("Delete", mock.AnythingOfType("string")).
+									Run(func(args mock.Arguments) {
+										deleted = append(deleted, args.String(0))
+									}).
+									Return(nil)
 
-								binaryURL, _ := random.String(16)
-								dto.Platforms = append(dto.Platforms, provider.CreatePlatformDTO{
-									Location: binaryURL,
+								mockProviderRepository.
+									On("Upsert", mock.AnythingOfType("provider.Provider")).
+									Return(nil, errors.New("database is locked"))
+
+								Convey("When the service is queried", func() {
+									err := providerService.Upload(&dto)
+
+									Convey("An error should be returned", func() {
+										So(err, ShouldNotBeNil)
+									})
+
+									Convey("The stored documentation should be purged", func() {
+										So(stored, ShouldNotBeEmpty)
+										So(deleted, ShouldResemble, stored)
+									})
 								})
+							})
 
+							Convey("If the resolver is set", func() {
 								Convey("If the provider files cannot be downloaded", func() {
 									mockFetcher.
 										On(
@@ -376,15 +742,31 @@ func TestUploadProvider(t *testing.T) {
 									})
 								})
 
-								Convey("If the provider files can be downloaded", func() {
+								Convey("If the documentation archive has no page", func() {
+									mockShaSums(shaSums, sign(shaSums))
+
+									dto.DocsURL = "https://releases.example.com/docs.tar.gz"
+									archive, err := file.Archive("docs", []file.File{
+										file.NewInMemoryFile("LICENSE", []byte("MIT")),
+									})
+									So(err, ShouldBeNil)
+
 									mockFetcher.
-										On(
-											"FetchFile",
-											mock.AnythingOfType("string"),
-											mock.AnythingOfType("string"),
-											mock.AnythingOfType("http.Header"),
-										).
-										Return(file.NewEmptyFile("test.txt"), func() {}, nil)
+										On("FetchDir", "terraform-provider-acme_1.0.0_docs", dto.DocsURL, mock.Anything).
+										Return(archive, func() {}, nil)
+
+									Convey("When the service is queried", func() {
+										err := providerService.Upload(&dto)
+
+										Convey("The release should be rejected without storing any file", func() {
+											So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+											mockResolver.AssertNotCalled(t, "Store", mock.Anything)
+										})
+									})
+								})
+
+								Convey("If the provider files can be downloaded", func() {
+									mockShaSums(shaSums, sign(shaSums))
 
 									mockFetcher.
 										On(
@@ -410,6 +792,55 @@ func TestUploadProvider(t *testing.T) {
 										})
 									})
 
+									Convey("If the provider files are stored but the version cannot be saved", func() {
+										a.Name = "hashicorp"
+										dto.DocsURL = "https://releases.example.com/docs.tar.gz"
+										archive, err := file.Archive("docs", []file.File{
+											file.NewInMemoryFile("docs/index.md", []byte("# Acme Provider")),
+										})
+										So(err, ShouldBeNil)
+
+										mockFetcher.
+											On("FetchDir", "terraform-provider-acme_1.0.0_docs", dto.DocsURL, mock.Anything).
+											Return(archive, func() {}, nil)
+
+										var stored, purged []string
+										mockResolver.
+											On("Store", mock.AnythingOfType("*storage.StoreInput")).
+											Return(func(in *storage.StoreInput) (string, error) {
+												key := fmt.Sprintf("%s/%s", in.KeyPrefix, in.FileName)
+												stored = append(stored, key)
+												return key, nil
+											})
+
+										mockResolver.
+											On("Purge", mock.AnythingOfType("string")).
+											Run(func(args mock.Arguments) {
+												purged = append(purged, args.String(0))
+											}).
+											Return(nil)
+
+										mockProviderRepository.
+											On("Upsert", mock.AnythingOfType("provider.Provider")).
+											Return(nil, errors.New("database is locked"))
+
+										Convey("When the service is queried", func() {
+											err := providerService.Upload(&dto)
+
+											Convey("An error should be returned", func() {
+												So(err, ShouldNotBeNil)
+											})
+
+											Convey("Every stored file should be purged", func() {
+												So(stored, ShouldHaveLength, 4)
+												So(purged, ShouldHaveLength, len(stored))
+												for _, key := range stored {
+													So(purged, ShouldContain, key)
+												}
+											})
+										})
+									})
+
 									Convey("If the resolver successfully stores the provider files", func() {
 										mockResolver.
 											On("Store", mock.AnythingOfType("*storage.StoreInput")).
@@ -437,8 +868,8 @@ func TestUploadProvider(t *testing.T) {
 	})
 }
 
-func TestDeleteProvider(t *testing.T) {
-	Convey("Subject: Delete a provider", t, func() {
+func TestUploadProviderFiles(t *testing.T) {
+	Convey("Subject: Upload a provider version from its release files", t, func() {
 		mockProviderRepository := repositories.NewMockProviderRepository(t)
 		mockAuthorityService := NewMockAuthorityService(t)
 		mockResolver := storage.NewMockResolver(t)
@@ -449,46 +880,618 @@ func TestDeleteProvider(t *testing.T) {
 			Resolver:           mockResolver,
 		}
 
-		Convey("Given an authority ID and a provider name", func() {
-			authorityID, _ := uuid.NewRandom()
-			name, _ := random.String(16)
+		Convey("Given the files of a provider release", func() {
+			dto := provider.CreateProviderDTO{
+				Name:    "acme",
+				Version: "1.0.0",
+			}
 
-			Convey("If the authority does not exist", func() {
-				mockAuthorityService.
-					On("GetByID", authorityID).
-					Return(nil, errors.New(""))
+			pkg := newTestPackage("terraform-provider-acme")
+			checksum := fmt.Sprintf("%x", sha256.Sum256(pkg))
+
+			key, sign := newTestSigningKey()
+
+			newFiles := func(shaSums string) []file.File {
+				return []file.File{
+					file.NewInMemoryFile("terraform-provider-acme_1.0.0_linux_amd64.zip", pkg),
+					file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS", []byte(shaSums)),
+					file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS.sig", sign([]byte(shaSums))),
+					file.NewInMemoryFile("terraform-provider-acme_1.0.0_manifest.json", []byte(`{"version":1,"metadata":{"protocol_versions":["6.0"]}}`)),
+				}
+			}
+
+			Convey("If the providers are not stored by the registry", func() {
+				providerService.Resolver = nil
 
 				Convey("When the service is queried", func() {
-					err := providerService.Delete(authorityID, name)
+					err := providerService.UploadFiles(&dto, newFiles(""))
 
 					Convey("An error should be returned", func() {
-						So(err, ShouldNotBeNil)
+						So(errors.Is(err, ErrProvidersNotStored), ShouldBeTrue)
 					})
 				})
 			})
 
-			Convey("If the authority exists", func() {
+			Convey("If the SHA256SUMS file is missing", func() {
+				files := newFiles("")
+				files = append(files[:1], files[2:]...)
+
+				Convey("When the service is queried", func() {
+					err := providerService.UploadFiles(&dto, files)
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("If the release is not signed", func() {
+				files := newFiles("")
+				files = files[:1]
+
 				mockAuthorityService.
-					On("GetByID", authorityID).
-					Return(&authority.Authority{}, nil)
+					On("GetByID", mock.AnythingOfType("uuid.UUID")).
+					Return(&authority.Authority{Name: "hashicorp", Keys: []authority.Key{key}}, nil)
 
-				Convey("If the provider does not exist", func() {
-					mockProviderRepository.
-						On("Find", mock.AnythingOfType("string"), name).
-						Return(nil, errors.New(""))
+				mockProviderRepository.
+					On("Find", "hashicorp", "acme").
+					Return(nil, errors.New(""))
+
+				Convey("If the authority has no signing key", func() {
+					mockAuthorityService.
+						On("Sign", mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("[]uint8")).
+						Return(nil, "", ErrSigningKeyNotFound)
 
 					Convey("When the service is queried", func() {
-						err := providerService.Delete(authorityID, name)
+						err := providerService.UploadFiles(&dto, files)
 
 						Convey("An error should be returned", func() {
-							So(err, ShouldNotBeNil)
+							So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
 						})
 					})
 				})
 
-				Convey("If the provider exists", func() {
-					mockProvider := provider.Provider{
-						AuthorityID: authorityID,
+				Convey("If the authority has a signing key", func() {
+					var signed []byte
+					mockAuthorityService.
+						On("Sign", mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("[]uint8")).
+						Return(func(_ uuid.UUID, content []byte) ([]byte, string, error) {
+							signed = content
+							return sign(content), key.KeyId, nil
+						})
+
+					var stored []string
+					mockResolver.
+						On("Store", mock.AnythingOfType("*storage.StoreInput")).
+						Return(func(in *storage.StoreInput) (string, error) {
+							stored = append(stored, in.FileName)
+							return in.KeyPrefix + "/" + in.FileName, nil
+						})
+
+					var saved provider.Provider
+					mockProviderRepository.
+						On("Upsert", mock.AnythingOfType("provider.Provider")).
+						Run(func(args mock.Arguments) {
+							saved = args.Get(0).(provider.Provider)
+						}).
+						Return(&provider.Provider{}, nil)
+
+					Convey("When the service is queried", func() {
+						err := providerService.UploadFiles(&dto, files)
+
+						Convey("The SHA256SUMS file should be generated and signed by the registry", func() {
+							So(err, ShouldBeNil)
+							So(string(signed), ShouldEqual, fmt.Sprintf("%s  terraform-provider-acme_1.0.0_linux_amd64.zip\n", checksum))
+							So(stored, ShouldHaveLength, 3)
+							So(saved.Versions, ShouldHaveLength, 1)
+							So(saved.Versions[0].SigningKeyID, ShouldEqual, key.KeyId)
+							So(saved.Versions[0].Protocols, ShouldEqual, "5.0")
+						})
+					})
+				})
+			})
+
+			Convey("If a package does not match its checksum", func() {
+				files := newFiles(fmt.Sprintf("%x  terraform-provider-acme_1.0.0_linux_amd64.zip\n", sha256.Sum256([]byte("other"))))
+
+				Convey("When the service is queried", func() {
+					err := providerService.UploadFiles(&dto, files)
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("If the packages match their checksums", func() {
+				files := newFiles(fmt.Sprintf("%s  terraform-provider-acme_1.0.0_linux_amd64.zip\n", checksum))
+
+				mockAuthorityService.
+					On("GetByID", mock.AnythingOfType("uuid.UUID")).
+					Return(&authority.Authority{Name: "hashicorp", Keys: []authority.Key{key}}, nil)
+
+				mockProviderRepository.
+					On("Find", "hashicorp", "acme").
+					Return(nil, errors.New(""))
+
+				var stored []string
+				mockResolver.
+					On("Store", mock.AnythingOfType("*storage.StoreInput")).
+					Return(func(in *storage.StoreInput) (string, error) {
+						stored = append(stored, in.FileName)
+						return in.KeyPrefix + "/" + in.FileName, nil
+					})
+
+				var saved provider.Provider
+				mockProviderRepository.
+					On("Upsert", mock.AnythingOfType("provider.Provider")).
+					Run(func(args mock.Arguments) {
+						saved = args.Get(0).(provider.Provider)
+					}).
+					Return(&provider.Provider{}, nil)
+
+				Convey("When the service is queried", func() {
+					err := providerService.UploadFiles(&dto, files)
+
+					Convey("The version should be derived from the files", func() {
+						So(err, ShouldBeNil)
+						So(stored, ShouldHaveLength, 3)
+						So(saved.Versions, ShouldHaveLength, 1)
+
+						v := saved.Versions[0]
+						So(v.Protocols, ShouldEqual, "6.0")
+						So(v.SigningKeyID, ShouldEqual, key.KeyId)
+						So(v.ShaSumsUrl, ShouldEqual, "providers/hashicorp/acme/1.0.0/terraform-provider-acme_1.0.0_SHA256SUMS")
+						So(v.ShaSumsSignatureUrl, ShouldEqual, "providers/hashicorp/acme/1.0.0/terraform-provider-acme_1.0.0_SHA256SUMS.sig")
+						So(v.Platforms, ShouldHaveLength, 1)
+						So(v.Platforms[0].System, ShouldEqual, "linux")
+						So(v.Platforms[0].Architecture, ShouldEqual, "amd64")
+						So(v.Platforms[0].ShaSum, ShouldEqual, checksum)
+						So(v.Platforms[0].Hash, ShouldStartWith, "h1:")
+						So(v.Platforms[0].Location, ShouldEqual, "providers/hashicorp/acme/1.0.0/terraform-provider-acme_1.0.0_linux_amd64.zip")
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestImportProvider(t *testing.T) {
+	Convey("Subject: Import a provider version from its release", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockFetcher := file.NewMockFetcher(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+			AuthorityService:   mockAuthorityService,
+			Fetcher:            mockFetcher,
+		}
+
+		Convey("Given an import DTO", func() {
+			dto := provider.ImportProviderDTO{
+				Name:    "acme",
+				Version: "1.0.0",
+			}
+
+			key, sign := newTestSigningKey()
+
+			Convey("If neither the release URL nor the archive URL is set", func() {
+				Convey("When the service is queried", func() {
+					err := providerService.Import(&dto)
+
+					Convey("An error should be returned", func() {
+						So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("If the release URL is set", func() {
+				dto.URL = "https://github.com/acme/terraform-provider-acme/releases/download/v1.0.0"
+
+				shaSums := "" +
+					"1111111111111111111111111111111111111111111111111111111111111111  terraform-provider-acme_1.0.0_linux_amd64.zip\n" +
+					"2222222222222222222222222222222222222222222222222222222222222222  terraform-provider-acme_1.0.0_darwin_arm64.zip\n" +
+					"3333333333333333333333333333333333333333333333333333333333333333  terraform-provider-acme_1.0.0_manifest.json\n"
+
+				mockFetcher.
+					On("FetchFile", "terraform-provider-acme_1.0.0_SHA256SUMS", dto.URL+"/terraform-provider-acme_1.0.0_SHA256SUMS", mock.Anything).
+					Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS", []byte(shaSums)), func() {}, nil)
+
+				mockFetcher.
+					On("FetchFile", "terraform-provider-acme_1.0.0_SHA256SUMS.sig", dto.URL+"/terraform-provider-acme_1.0.0_SHA256SUMS.sig", mock.Anything).
+					Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS.sig", sign([]byte(shaSums))), func() {}, nil)
+
+				mockFetcher.
+					On("FetchFile", "terraform-provider-acme_1.0.0_manifest.json", dto.URL+"/terraform-provider-acme_1.0.0_manifest.json", mock.Anything).
+					Return(nil, nil, errors.New("bad response code: 404"))
+
+				mockAuthorityService.
+					On("GetByID", mock.AnythingOfType("uuid.UUID")).
+					Return(&authority.Authority{Name: "hashicorp", Keys: []authority.Key{key}}, nil)
+
+				mockProviderRepository.
+					On("Find", "hashicorp", "acme").
+					Return(nil, errors.New(""))
+
+				var saved provider.Provider
+				mockProviderRepository.
+					On("Upsert", mock.AnythingOfType("provider.Provider")).
+					Run(func(args mock.Arguments) {
+						saved = args.Get(0).(provider.Provider)
+					}).
+					Return(&provider.Provider{}, nil)
+
+				Convey("When the service is queried", func() {
+					err := providerService.Import(&dto)
+
+					Convey("The platforms should be discovered from the SHA256SUMS file", func() {
+						So(err, ShouldBeNil)
+						So(saved.Versions, ShouldHaveLength, 1)
+
+						v := saved.Versions[0]
+						So(v.Protocols, ShouldEqual, "5.0")
+						So(v.ShaSumsUrl, ShouldEqual, dto.URL+"/terraform-provider-acme_1.0.0_SHA256SUMS")
+						So(v.ShaSumsSignatureUrl, ShouldEqual, dto.URL+"/terraform-provider-acme_1.0.0_SHA256SUMS.sig")
+						So(v.Platforms, ShouldHaveLength, 2)
+						So(v.Platforms[0].String(), ShouldEqual, "darwin_arm64")
+						So(v.Platforms[0].ShaSum, ShouldEqual, strings.Repeat("2", 64))
+						So(v.Platforms[1].String(), ShouldEqual, "linux_amd64")
+						So(v.Platforms[1].Location, ShouldEqual, dto.URL+"/terraform-provider-acme_1.0.0_linux_amd64.zip")
+					})
+				})
+			})
+
+			Convey("If the archive URL is set", func() {
+				dto.ArchiveURL = "https://ci.example.com/artifacts/dist.tar.gz"
+
+				Convey("If the providers are not stored by the registry", func() {
+					Convey("When the service is queried", func() {
+						err := providerService.Import(&dto)
+
+						Convey("An error should be returned", func() {
+							So(errors.Is(err, ErrProvidersNotStored), ShouldBeTrue)
+						})
+					})
+				})
+
+				Convey("If the providers are stored by the registry", func() {
+					mockResolver := storage.NewMockResolver(t)
+					providerService.Resolver = mockResolver
+
+					pkg := []byte("linux package")
+					shaSums := []byte(fmt.Sprintf("%x  terraform-provider-acme_1.0.0_linux_amd64.zip\n", sha256.Sum256(pkg)))
+					archive, err := file.Archive("dist", []file.File{
+						file.NewInMemoryFile("dist/terraform-provider-acme_1.0.0_linux_amd64.zip", pkg),
+						file.NewInMemoryFile("dist/terraform-provider-acme_1.0.0_SHA256SUMS", shaSums),
+						file.NewInMemoryFile("dist/terraform-provider-acme_1.0.0_SHA256SUMS.sig", sign(shaSums)),
+						file.NewInMemoryFile("dist/metadata.json", []byte("{}")),
+						file.NewInMemoryFile("dist/terraform-provider-acme_linux_amd64_v1/terraform-provider-acme_v1.0.0", []byte("binary")),
+					})
+					So(err, ShouldBeNil)
+
+					mockFetcher.
+						On("FetchDir", "terraform-provider-acme_1.0.0", dto.ArchiveURL, mock.Anything).
+						Return(archive, func() {}, nil)
+
+					mockAuthorityService.
+						On("GetByID", mock.AnythingOfType("uuid.UUID")).
+						Return(&authority.Authority{Name: "hashicorp", Keys: []authority.Key{key}}, nil)
+
+					mockProviderRepository.
+						On("Find", "hashicorp", "acme").
+						Return(nil, errors.New(""))
+
+					var stored []string
+					mockResolver.
+						On("Store", mock.AnythingOfType("*storage.StoreInput")).
+						Return(func(in *storage.StoreInput) (string, error) {
+							stored = append(stored, in.FileName)
+							return in.KeyPrefix + "/" + in.FileName, nil
+						})
+
+					mockProviderRepository.
+						On("Upsert", mock.AnythingOfType("provider.Provider")).
+						Return(&provider.Provider{}, nil)
+
+					Convey("When the service is queried", func() {
+						err := providerService.Import(&dto)
+
+						Convey("Only the release files should be stored", func() {
+							So(err, ShouldBeNil)
+							So(stored, ShouldHaveLength, 3)
+							So(stored, ShouldContain, "terraform-provider-acme_1.0.0_linux_amd64.zip")
+						})
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestAddProviderPlatforms(t *testing.T) {
+	Convey("Subject: Add platforms to a provider version", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockResolver := storage.NewMockResolver(t)
+		mockFetcher := file.NewMockFetcher(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+			AuthorityService:   mockAuthorityService,
+			Fetcher:            mockFetcher,
+		}
+
+		key, sign := newTestSigningKey()
+
+		mockAuthorityService.
+			On("GetByID", mock.AnythingOfType("uuid.UUID")).
+			Return(&authority.Authority{Name: "hashicorp", Keys: []authority.Key{key}}, nil)
+
+		linuxShaSum := strings.Repeat("a", 64)
+		darwinShaSum := strings.Repeat("b", 64)
+
+		version := provider.Version{
+			Version:             "1.0.0",
+			ShaSumsUrl:          "https://example.com/SHA256SUMS",
+			ShaSumsSignatureUrl: "https://example.com/SHA256SUMS.sig",
+			SigningKeyID:        key.KeyId,
+			Platforms: []provider.Platform{
+				{System: "linux", Architecture: "amd64", ShaSum: linuxShaSum},
+			},
+		}
+
+		mockProviderRepository.
+			On("Find", "hashicorp", "acme").
+			Return(func(_, _ string) (*provider.Provider, error) {
+				return &provider.Provider{
+					Name:     "acme",
+					Versions: []provider.Version{version},
+				}, nil
+			})
+
+		mockShaSums := func(url, signatureURL string, shaSums []byte) {
+			mockFetcher.
+				On("FetchFile", "terraform-provider-acme_1.0.0_SHA256SUMS", url, mock.AnythingOfType("http.Header")).
+				Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS", shaSums), func() {}, nil)
+
+			mockFetcher.
+				On("FetchFile", "terraform-provider-acme_1.0.0_SHA256SUMS.sig", signatureURL, mock.AnythingOfType("http.Header")).
+				Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_SHA256SUMS.sig", sign(shaSums)), func() {}, nil)
+		}
+
+		var saved *provider.Version
+		var added []provider.Platform
+		mockAddVersionPlatforms := func() {
+			mockProviderRepository.
+				On("AddVersionPlatforms", mock.AnythingOfType("*provider.Version"), mock.AnythingOfType("[]provider.Platform")).
+				Run(func(args mock.Arguments) {
+					saved = args.Get(0).(*provider.Version)
+					added = args.Get(1).([]provider.Platform)
+				}).
+				Return(nil)
+		}
+
+		Convey("Given a new platform", func() {
+			dto := provider.CreateProviderDTO{
+				Name:    "acme",
+				Version: "1.0.0",
+				Platforms: []provider.CreatePlatformDTO{
+					{
+						System:       "darwin",
+						Architecture: "arm64",
+						Location:     "https://example.com/terraform-provider-acme_1.0.0_darwin_arm64.zip",
+						ShaSum:       darwinShaSum,
+					},
+				},
+			}
+
+			Convey("If the version does not exist", func() {
+				dto.Version = "2.0.0"
+
+				Convey("When the service is queried", func() {
+					err := providerService.AddPlatforms(&dto)
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If the version already has the platform", func() {
+				dto.Platforms[0].System = "linux"
+				dto.Platforms[0].Architecture = "amd64"
+
+				Convey("When the service is queried", func() {
+					err := providerService.AddPlatforms(&dto)
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If no SHA256SUMS file is given", func() {
+				Convey("If the SHA256SUMS file of the version lists the platform", func() {
+					mockShaSums("https://example.com/SHA256SUMS", "https://example.com/SHA256SUMS.sig", []byte(
+						linuxShaSum+"  terraform-provider-acme_1.0.0_linux_amd64.zip\n"+
+							darwinShaSum+"  terraform-provider-acme_1.0.0_darwin_arm64.zip\n",
+					))
+					mockAddVersionPlatforms()
+
+					Convey("When the service is queried", func() {
+						err := providerService.AddPlatforms(&dto)
+
+						Convey("The platform should be added to the version", func() {
+							So(err, ShouldBeNil)
+							So(added, ShouldHaveLength, 1)
+							So(added[0].String(), ShouldEqual, "darwin_arm64")
+						})
+
+						Convey("The SHA256SUMS file of the version should be kept", func() {
+							So(saved.ShaSumsUrl, ShouldEqual, "https://example.com/SHA256SUMS")
+							So(saved.ShaSumsSignatureUrl, ShouldEqual, "https://example.com/SHA256SUMS.sig")
+						})
+					})
+				})
+
+				Convey("If the SHA256SUMS file of the version does not list the platform", func() {
+					mockShaSums("https://example.com/SHA256SUMS", "https://example.com/SHA256SUMS.sig", []byte(
+						linuxShaSum+"  terraform-provider-acme_1.0.0_linux_amd64.zip\n",
+					))
+
+					Convey("When the service is queried", func() {
+						err := providerService.AddPlatforms(&dto)
+
+						Convey("An error should be returned", func() {
+							So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+						})
+					})
+				})
+
+				Convey("If the SHA256SUMS file of the version is stored by the registry", func() {
+					providerService.Resolver = mockResolver
+
+					version.ShaSumsUrl = "providers/hashicorp/acme/1.0.0/SHA256SUMS"
+					version.ShaSumsSignatureUrl = "providers/hashicorp/acme/1.0.0/SHA256SUMS.sig"
+
+					mockResolver.
+						On("Find", "providers/hashicorp/acme/1.0.0/SHA256SUMS").
+						Return("https://storage.example.com/SHA256SUMS", nil)
+
+					mockResolver.
+						On("Find", "providers/hashicorp/acme/1.0.0/SHA256SUMS.sig").
+						Return("https://storage.example.com/SHA256SUMS.sig", nil)
+
+					mockShaSums("https://storage.example.com/SHA256SUMS", "https://storage.example.com/SHA256SUMS.sig", []byte(
+						linuxShaSum+"  terraform-provider-acme_1.0.0_linux_amd64.zip\n"+
+							darwinShaSum+"  terraform-provider-acme_1.0.0_darwin_arm64.zip\n",
+					))
+
+					mockFetcher.
+						On(
+							"FetchFileChecksum",
+							mock.AnythingOfType("string"),
+							dto.Platforms[0].Location,
+							darwinShaSum,
+							mock.AnythingOfType("http.Header"),
+						).
+						Return(file.NewInMemoryFile("terraform-provider-acme_1.0.0_darwin_arm64.zip", newTestPackage("terraform-provider-acme")), func() {}, nil)
+
+					// Only the package of the new platform is stored
+					mockResolver.
+						On("Store", mock.AnythingOfType("*storage.StoreInput")).
+						Return("providers/hashicorp/acme/1.0.0/terraform-provider-acme_1.0.0_darwin_arm64.zip", nil).
+						Once()
+
+					mockAddVersionPlatforms()
+
+					Convey("When the service is queried", func() {
+						err := providerService.AddPlatforms(&dto)
+
+						Convey("The stored package should be added to the version", func() {
+							So(err, ShouldBeNil)
+							So(added, ShouldHaveLength, 1)
+							So(added[0].Location, ShouldEqual, "providers/hashicorp/acme/1.0.0/terraform-provider-acme_1.0.0_darwin_arm64.zip")
+							So(saved.ShaSumsUrl, ShouldEqual, "providers/hashicorp/acme/1.0.0/SHA256SUMS")
+						})
+					})
+				})
+			})
+
+			Convey("If a new SHA256SUMS file is given", func() {
+				dto.ShaSums.URL = "https://example.com/v2/SHA256SUMS"
+				dto.ShaSums.SignatureURL = "https://example.com/v2/SHA256SUMS.sig"
+
+				Convey("If it does not list the existing platforms", func() {
+					mockShaSums(dto.ShaSums.URL, dto.ShaSums.SignatureURL, []byte(
+						darwinShaSum+"  terraform-provider-acme_1.0.0_darwin_arm64.zip\n",
+					))
+
+					Convey("When the service is queried", func() {
+						err := providerService.AddPlatforms(&dto)
+
+						Convey("An error should be returned", func() {
+							So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+						})
+					})
+				})
+
+				Convey("If it lists all the platforms", func() {
+					mockShaSums(dto.ShaSums.URL, dto.ShaSums.SignatureURL, []byte(
+						linuxShaSum+"  terraform-provider-acme_1.0.0_linux_amd64.zip\n"+
+							darwinShaSum+"  terraform-provider-acme_1.0.0_darwin_arm64.zip\n",
+					))
+					mockAddVersionPlatforms()
+
+					Convey("When the service is queried", func() {
+						err := providerService.AddPlatforms(&dto)
+
+						Convey("It should supersede the SHA256SUMS file of the version", func() {
+							So(err, ShouldBeNil)
+							So(saved.ShaSumsUrl, ShouldEqual, dto.ShaSums.URL)
+							So(saved.ShaSumsSignatureUrl, ShouldEqual, dto.ShaSums.SignatureURL)
+							So(saved.SigningKeyID, ShouldEqual, key.KeyId)
+						})
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestDeleteProvider(t *testing.T) {
+	Convey("Subject: Delete a provider", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockResolver := storage.NewMockResolver(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+			AuthorityService:   mockAuthorityService,
+			Resolver:           mockResolver,
+		}
+
+		Convey("Given an authority ID and a provider name", func() {
+			authorityID, _ := uuid.NewRandom()
+			name, _ := random.String(16)
+
+			Convey("If the authority does not exist", func() {
+				mockAuthorityService.
+					On("GetByID", authorityID).
+					Return(nil, errors.New(""))
+
+				Convey("When the service is queried", func() {
+					err := providerService.Delete(authorityID, name)
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If the authority exists", func() {
+				mockAuthorityService.
+					On("GetByID", authorityID).
+					Return(&authority.Authority{}, nil)
+
+				Convey("If the provider does not exist", func() {
+					mockProviderRepository.
+						On("Find", mock.AnythingOfType("string"), name).
+						Return(nil, errors.New(""))
+
+					Convey("When the service is queried", func() {
+						err := providerService.Delete(authorityID, name)
+
+						Convey("An error should be returned", func() {
+							So(err, ShouldNotBeNil)
+						})
+					})
+				})
+
+				Convey("If the provider exists", func() {
+					mockProvider := provider.Provider{
+						AuthorityID: authorityID,
 						Name:        name,
 						Versions: []provider.Version{
 							{
@@ -655,3 +1658,226 @@ func TestDeleteProviderVersion(t *testing.T) {
 		})
 	})
 }
+
+func TestGetProviderWithVersionStatuses(t *testing.T) {
+	Convey("Subject: Find a provider with deprecated and yanked versions", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+		}
+
+		Convey("Given a provider with a deprecated and a yanked version", func() {
+			namespace, _ := random.String(16)
+			name, _ := random.String(16)
+
+			mockProviderRepository.
+				On("Find", namespace, name).
+				Return(&provider.Provider{
+					Name: name,
+					Versions: []provider.Version{
+						{Version: "1.0.0", Status: artifact.StatusDeprecated, StatusReason: "Broken state upgrade.", Replacement: "1.1.0"},
+						{Version: "1.0.1", Status: artifact.StatusYanked},
+						{Version: "1.1.0"},
+					},
+				}, nil)
+
+			Convey("When the service is queried", func() {
+				resp, err := providerService.Get(namespace, name)
+
+				Convey("The yanked version should not be listed", func() {
+					So(err, ShouldBeNil)
+					So(resp.Versions, ShouldHaveLength, 2)
+					So(resp.Versions[0].Version, ShouldEqual, "1.0.0")
+					So(resp.Versions[1].Version, ShouldEqual, "1.1.0")
+				})
+
+				Convey("A warning should be returned for the deprecated version", func() {
+					So(resp.Warnings, ShouldResemble, []string{
+						"Version 1.0.0 is deprecated: Broken state upgrade. Use version 1.1.0 instead.",
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestSetProviderVersionStatus(t *testing.T) {
+	Convey("Subject: Change the status of a provider version", t, func() {
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+
+		providerService := &DefaultProviderService{
+			ProviderRepository: mockProviderRepository,
+			AuthorityService:   mockAuthorityService,
+		}
+
+		Convey("Given an authority ID and a provider name", func() {
+			authorityID, _ := uuid.NewRandom()
+			authorityName, _ := random.String(16)
+			name, _ := random.String(16)
+
+			mockAuthorityService.
+				On("GetByID", authorityID).
+				Return(&authority.Authority{Name: authorityName}, nil)
+
+			mockProviderRepository.
+				On("Find", authorityName, name).
+				Return(&provider.Provider{
+					Name: name,
+					Versions: []provider.Version{
+						{Version: "1.0.0"},
+						{Version: "1.1.0", Status: artifact.StatusYanked},
+						{Version: "1.2.0", Status: artifact.StatusDeprecated, StatusReason: "old"},
+					},
+				}, nil)
+
+			Convey("If the version does not exist", func() {
+				Convey("When the service is queried", func() {
+					err := providerService.SetVersionStatus(authorityID, name, "2.0.0", artifact.StatusYanked, artifact.UpdateStatusDTO{})
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If the version is deprecated in favour of an existing version", func() {
+				mockProviderRepository.
+					On("UpdateVersionStatus", mock.AnythingOfType("*provider.Version")).
+					Return(nil)
+
+				Convey("When the service is queried", func() {
+					err := providerService.SetVersionStatus(authorityID, name, "1.0.0", artifact.StatusDeprecated, artifact.UpdateStatusDTO{
+						Reason:      "security fix",
+						Replacement: "1.2.0",
+					})
+
+					Convey("The status should be persisted", func() {
+						So(err, ShouldBeNil)
+
+						v := mockProviderRepository.Calls[1].Arguments.Get(0).(*provider.Version)
+						So(v.Version, ShouldEqual, "1.0.0")
+						So(v.Status, ShouldEqual, artifact.StatusDeprecated)
+						So(v.StatusReason, ShouldEqual, "security fix")
+						So(v.Replacement, ShouldEqual, "1.2.0")
+					})
+				})
+			})
+
+			Convey("If the replacement version is yanked", func() {
+				Convey("When the service is queried", func() {
+					err := providerService.SetVersionStatus(authorityID, name, "1.0.0", artifact.StatusDeprecated, artifact.UpdateStatusDTO{
+						Replacement: "1.1.0",
+					})
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If the replacement version does not exist", func() {
+				Convey("When the service is queried", func() {
+					err := providerService.SetVersionStatus(authorityID, name, "1.0.0", artifact.StatusYanked, artifact.UpdateStatusDTO{
+						Replacement: "3.0.0",
+					})
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+
+			Convey("If the version is restored", func() {
+				mockProviderRepository.
+					On("UpdateVersionStatus", mock.AnythingOfType("*provider.Version")).
+					Return(nil)
+
+				Convey("When the service is queried", func() {
+					err := providerService.SetVersionStatus(authorityID, name, "1.2.0", artifact.StatusActive, artifact.UpdateStatusDTO{
+						Reason: "ignored",
+					})
+
+					Convey("The status details should be cleared", func() {
+						So(err, ShouldBeNil)
+
+						v := mockProviderRepository.Calls[1].Arguments.Get(0).(*provider.Version)
+						So(v.Status, ShouldEqual, artifact.StatusActive)
+						So(v.StatusReason, ShouldBeEmpty)
+						So(v.Replacement, ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("If the status is unknown", func() {
+				Convey("When the service is queried", func() {
+					err := providerService.SetVersionStatus(authorityID, name, "1.0.0", artifact.Status("archived"), artifact.UpdateStatusDTO{})
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+		})
+	})
+}
+
+// newTestSigningKey generates an authority key, and returns it with a
+// function producing detached signatures with its private half.
+func newTestSigningKey() (authority.Key, func([]byte) []byte) {
+	entity, err := openpgp.NewEntity("Terralist", "test", "test@terralist.io", &packet.Config{RSABits: 1024})
+	if err != nil {
+		panic(err)
+	}
+
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := entity.Serialize(w); err != nil {
+		panic(err)
+	}
+
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+
+	key := authority.Key{
+		KeyId:      entity.PrimaryKey.KeyIdString(),
+		AsciiArmor: armored.String(),
+	}
+
+	return key, func(content []byte) []byte {
+		var signature bytes.Buffer
+		if err := openpgp.DetachSign(&signature, entity, bytes.NewReader(content), nil); err != nil {
+			panic(err)
+		}
+
+		return signature.Bytes()
+	}
+}
+
+func newTestPackage(files ...string) []byte {
+	var buf bytes.Buffer
+
+	w := zip.NewWriter(&buf)
+	for _, name := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			panic(err)
+		}
+
+		if _, err := f.Write([]byte(name)); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+
+	return buf.Bytes()
+}
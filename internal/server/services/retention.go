@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"terralist/internal/server/models/artifact"
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/models/provider"
+	"terralist/internal/server/models/retention"
+	"terralist/internal/server/repositories"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+)
+
+var (
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+)
+
+// RetentionService describes a service that manages and applies the version
+// retention policies of the authorities.
+type RetentionService interface {
+	// GetPolicies returns the retention policies of an authority.
+	GetPolicies(authorityID uuid.UUID) ([]retention.PolicyDTO, error)
+
+	// SetPolicy creates or replaces the retention policy of an authority for
+	// the artifact targeted by the given policy.
+	SetPolicy(authorityID uuid.UUID, d retention.PolicyDTO) (*retention.PolicyDTO, error)
+
+	// DeletePolicy removes a retention policy of an authority.
+	DeletePolicy(authorityID uuid.UUID, id uuid.UUID) error
+
+	// Apply removes the versions of the authority artifacts which are not
+	// retained by its policies. If dryRun is set, the versions are only
+	// reported, without being removed.
+	Apply(authorityID uuid.UUID, dryRun bool) (*retention.ReportDTO, error)
+
+	// ApplyAll applies the retention policies of every authority.
+	ApplyAll() error
+}
+
+// DefaultRetentionService is a concrete implementation of RetentionService.
+type DefaultRetentionService struct {
+	RetentionPolicyRepository repositories.RetentionPolicyRepository
+	ModuleRepository          repositories.ModuleRepository
+	ProviderRepository        repositories.ProviderRepository
+	AuthorityService          AuthorityService
+	ModuleService             ModuleService
+	ProviderService           ProviderService
+
+	// Now returns the current time, it defaults to time.Now
+	Now func() time.Time
+}
+
+func (s *DefaultRetentionService) GetPolicies(authorityID uuid.UUID) ([]retention.PolicyDTO, error) {
+	policies, err := s.RetentionPolicyRepository.FindAll(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(policies, func(p *retention.Policy, _ int) retention.PolicyDTO {
+		return p.ToDTO()
+	}), nil
+}
+
+func (s *DefaultRetentionService) SetPolicy(authorityID uuid.UUID, d retention.PolicyDTO) (*retention.PolicyDTO, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	p, err := s.RetentionPolicyRepository.Upsert(d.ToPolicy(authorityID))
+	if err != nil {
+		return nil, err
+	}
+
+	dto := p.ToDTO()
+
+	return &dto, nil
+}
+
+func (s *DefaultRetentionService) DeletePolicy(authorityID uuid.UUID, id uuid.UUID) error {
+	if err := s.RetentionPolicyRepository.Delete(authorityID, id); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrRetentionPolicyNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *DefaultRetentionService) Apply(authorityID uuid.UUID, dryRun bool) (*retention.ReportDTO, error) {
+	a, err := s.AuthorityService.GetByID(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &retention.ReportDTO{
+		DryRun:   dryRun,
+		Removals: []retention.RemovalDTO{},
+	}
+
+	policies, err := s.RetentionPolicyRepository.FindAll(authorityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policies) == 0 {
+		return report, nil
+	}
+
+	defaultPolicy := retention.Policy{}
+	overrides := map[string]retention.Policy{}
+	for _, p := range policies {
+		if p.IsDefault() {
+			defaultPolicy = *p
+		} else {
+			overrides[policyKey(p.ArtifactType, p.ArtifactName)] = *p
+		}
+	}
+
+	policyFor := func(artifactType, name string) retention.Policy {
+		if override, ok := overrides[policyKey(artifactType, name)]; ok {
+			return defaultPolicy.Merge(override)
+		}
+
+		return defaultPolicy
+	}
+
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	modules, err := s.ModuleRepository.FindAll(module.ListFilter{Namespace: a.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range modules {
+		policy := policyFor(artifact.TypeModule, fmt.Sprintf("%s/%s", m.Name, m.Provider))
+		candidates := lo.Map(m.Versions, func(v module.Version, _ int) retention.Candidate {
+			return retention.Candidate{
+				Version:          v.Version,
+				CreatedAt:        v.CreatedAt,
+				LastDownloadedAt: v.LastDownloadedAt,
+				Yanked:           v.IsYanked(),
+			}
+		})
+
+		for _, r := range policy.Evaluate(candidates, now) {
+			removal := retention.RemovalDTO{
+				Type:      artifact.TypeModule,
+				Namespace: a.Name,
+				Name:      m.Name,
+				Provider:  m.Provider,
+				Version:   r.Version,
+				Reason:    r.Reason,
+			}
+
+			if !dryRun {
+				if err := s.ModuleService.DeleteVersion(a.ID, m.Name, m.Provider, r.Version); err != nil {
+					log.Warn().
+						Str("moduleSlug", fmt.Sprintf("%s/%s/%s/%s", a.Name, m.Name, m.Provider, r.Version)).
+						Err(err).
+						Msg("could not remove module version")
+
+					removal.Error = err.Error()
+				}
+			}
+
+			report.Removals = append(report.Removals, removal)
+		}
+	}
+
+	providers, err := s.ProviderRepository.FindAll(a.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range providers {
+		policy := policyFor(artifact.TypeProvider, p.Name)
+		candidates := lo.Map(p.Versions, func(v provider.Version, _ int) retention.Candidate {
+			return retention.Candidate{
+				Version:          v.Version,
+				CreatedAt:        v.CreatedAt,
+				LastDownloadedAt: v.LastDownloadedAt,
+				Yanked:           v.IsYanked(),
+			}
+		})
+
+		for _, r := range policy.Evaluate(candidates, now) {
+			removal := retention.RemovalDTO{
+				Type:      artifact.TypeProvider,
+				Namespace: a.Name,
+				Name:      p.Name,
+				Version:   r.Version,
+				Reason:    r.Reason,
+			}
+
+			if !dryRun {
+				if err := s.ProviderService.DeleteVersion(a.ID, p.Name, r.Version); err != nil {
+					log.Warn().
+						Str("providerSlug", fmt.Sprintf("%s/%s/%s", a.Name, p.Name, r.Version)).
+						Err(err).
+						Msg("could not remove provider version")
+
+					removal.Error = err.Error()
+				}
+			}
+
+			report.Removals = append(report.Removals, removal)
+		}
+	}
+
+	return report, nil
+}
+
+func (s *DefaultRetentionService) ApplyAll() error {
+	authorities, err := s.AuthorityService.GetAll()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, a := range authorities {
+		report, err := s.Apply(a.ID, false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("authority %s: %w", a.Name, err))
+			continue
+		}
+
+		if len(report.Removals) > 0 {
+			log.Info().
+				Str("authority", a.Name).
+				Int("removals", len(report.Removals)).
+				Msg("retention policies applied")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// policyKey returns the key of the policy of an artifact. Artifact names
+// are case-insensitive.
+func policyKey(artifactType, name string) string {
+	return artifactType + "/" + strings.ToLower(name)
+}
+
+// RetentionJanitor periodically applies the retention policies of all
+// authorities.
+type RetentionJanitor struct {
+	service  RetentionService
+	interval time.Duration
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewRetentionJanitor creates a new janitor which applies the retention
+// policies at the given interval, starting after the first interval.
+func NewRetentionJanitor(service RetentionService, interval time.Duration) *RetentionJanitor {
+	j := &RetentionJanitor{
+		service:  service,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	go j.run()
+
+	return j
+}
+
+func (j *RetentionJanitor) run() {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.service.ApplyAll(); err != nil {
+				log.Error().Err(err).Msg("could not apply retention policies")
+			}
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the janitor. Safe to call multiple times.
+func (j *RetentionJanitor) Stop() {
+	j.once.Do(func() {
+		close(j.stop)
+	})
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"terralist/internal/server/models/artifact"
+	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/models/provider"
+	"terralist/internal/server/models/retention"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/database/entity"
+
+	"github.com/google/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApplyRetentionPolicies(t *testing.T) {
+	Convey("Subject: Apply the retention policies of an authority", t, func() {
+		mockRetentionPolicyRepository := repositories.NewMockRetentionPolicyRepository(t)
+		mockModuleRepository := repositories.NewMockModuleRepository(t)
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockModuleService := NewMockModuleService(t)
+		mockProviderService := NewMockProviderService(t)
+
+		now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		daysAgo := func(days int) time.Time {
+			return now.AddDate(0, 0, -days)
+		}
+		intPtr := func(v int) *int {
+			return &v
+		}
+		moduleVersion := func(v string, createdAt time.Time, lastDownloadedAt *time.Time) module.Version {
+			return module.Version{
+				Entity:           entity.Entity{CreatedAt: createdAt},
+				Version:          v,
+				LastDownloadedAt: lastDownloadedAt,
+			}
+		}
+
+		retentionService := &DefaultRetentionService{
+			RetentionPolicyRepository: mockRetentionPolicyRepository,
+			ModuleRepository:          mockModuleRepository,
+			ProviderRepository:        mockProviderRepository,
+			AuthorityService:          mockAuthorityService,
+			ModuleService:             mockModuleService,
+			ProviderService:           mockProviderService,
+			Now:                       func() time.Time { return now },
+		}
+
+		Convey("Given an authority", func() {
+			authorityID, _ := uuid.NewRandom()
+
+			mockAuthorityService.
+				On("GetByID", authorityID).
+				Return(&authority.Authority{
+					Entity: entity.Entity{ID: authorityID},
+					Name:   "acme",
+				}, nil)
+
+			Convey("If the authority has no retention policy", func() {
+				mockRetentionPolicyRepository.
+					On("FindAll", authorityID).
+					Return([]*retention.Policy{}, nil)
+
+				Convey("When the policies are applied", func() {
+					report, err := retentionService.Apply(authorityID, false)
+
+					Convey("No version should be removed", func() {
+						So(err, ShouldBeNil)
+						So(report.Removals, ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("If the most recent stable version is yanked", func() {
+				yanked := moduleVersion("2.0.0", daysAgo(1), nil)
+				yanked.Status = artifact.StatusYanked
+
+				mockRetentionPolicyRepository.
+					On("FindAll", authorityID).
+					Return([]*retention.Policy{
+						{
+							AuthorityID:    authorityID,
+							KeepLastStable: intPtr(1),
+						},
+					}, nil)
+
+				mockModuleRepository.
+					On("FindAll", module.ListFilter{Namespace: "acme"}).
+					Return([]*module.Module{
+						{
+							Name:     "vpc",
+							Provider: "aws",
+							Versions: []module.Version{
+								yanked,
+								moduleVersion("1.1.0", daysAgo(10), nil),
+								moduleVersion("1.0.0", daysAgo(100), nil),
+							},
+						},
+					}, nil)
+
+				mockProviderRepository.
+					On("FindAll", "acme").
+					Return([]*provider.Provider{}, nil)
+
+				Convey("When a dry run is requested", func() {
+					report, err := retentionService.Apply(authorityID, true)
+
+					Convey("The latest installable version should be retained", func() {
+						So(err, ShouldBeNil)
+						So(report.Removals, ShouldHaveLength, 1)
+						So(report.Removals[0].Version, ShouldEqual, "1.0.0")
+					})
+				})
+			})
+
+			Convey("If the authority has a default policy and an override", func() {
+				recently := daysAgo(2)
+
+				mockRetentionPolicyRepository.
+					On("FindAll", authorityID).
+					Return([]*retention.Policy{
+						{
+							AuthorityID:          authorityID,
+							KeepLastStable:       intPtr(2),
+							PreReleaseMaxAgeDays: intPtr(30),
+							KeepDownloadedDays:   intPtr(7),
+						},
+						{
+							AuthorityID:    authorityID,
+							ArtifactType:   "provider",
+							ArtifactName:   "Internal",
+							KeepLastStable: intPtr(0),
+						},
+					}, nil)
+
+				mockModuleRepository.
+					On("FindAll", module.ListFilter{Namespace: "acme"}).
+					Return([]*module.Module{
+						{
+							Name:     "vpc",
+							Provider: "aws",
+							Versions: []module.Version{
+								moduleVersion("1.10.0", daysAgo(1), nil),
+								moduleVersion("1.9.0", daysAgo(10), nil),
+								moduleVersion("1.2.0", daysAgo(100), nil),
+								moduleVersion("1.1.0", daysAgo(200), &recently),
+								moduleVersion("1.0.0", daysAgo(300), nil),
+								moduleVersion("1.11.0-rc.1", daysAgo(40), nil),
+								moduleVersion("1.11.0-rc.2", daysAgo(5), nil),
+							},
+						},
+					}, nil)
+
+				mockProviderRepository.
+					On("FindAll", "acme").
+					Return([]*provider.Provider{
+						{
+							Name: "internal",
+							Versions: []provider.Version{
+								{Version: "1.0.0", Entity: entity.Entity{CreatedAt: daysAgo(300)}},
+								{Version: "1.1.0", Entity: entity.Entity{CreatedAt: daysAgo(200)}},
+								{Version: "1.2.0", Entity: entity.Entity{CreatedAt: daysAgo(100)}},
+								{Version: "1.3.0-beta", Entity: entity.Entity{CreatedAt: daysAgo(60)}},
+							},
+						},
+					}, nil)
+
+				Convey("When a dry run is requested", func() {
+					report, err := retentionService.Apply(authorityID, true)
+
+					Convey("The versions not retained should be reported", func() {
+						So(err, ShouldBeNil)
+						So(report.DryRun, ShouldBeTrue)
+						So(report.Removals, ShouldResemble, []retention.RemovalDTO{
+							{
+								Type:      "module",
+								Namespace: "acme",
+								Name:      "vpc",
+								Provider:  "aws",
+								Version:   "1.11.0-rc.1",
+								Reason:    "pre-release older than 30 days",
+							},
+							{
+								Type:      "module",
+								Namespace: "acme",
+								Name:      "vpc",
+								Provider:  "aws",
+								Version:   "1.2.0",
+								Reason:    "not one of the last 2 stable versions",
+							},
+							{
+								Type:      "module",
+								Namespace: "acme",
+								Name:      "vpc",
+								Provider:  "aws",
+								Version:   "1.0.0",
+								Reason:    "not one of the last 2 stable versions",
+							},
+							{
+								Type:      "provider",
+								Namespace: "acme",
+								Name:      "internal",
+								Version:   "1.3.0-beta",
+								Reason:    "pre-release older than 30 days",
+							},
+						})
+					})
+
+					Convey("No version should be removed", func() {
+						mockModuleService.AssertNotCalled(t, "DeleteVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+						mockProviderService.AssertNotCalled(t, "DeleteVersion", mock.Anything, mock.Anything, mock.Anything)
+					})
+				})
+
+				Convey("When the policies are applied", func() {
+					mockModuleService.
+						On("DeleteVersion", authorityID, "vpc", "aws", mock.AnythingOfType("string")).
+						Return(nil)
+
+					mockProviderService.
+						On("DeleteVersion", authorityID, "internal", "1.3.0-beta").
+						Return(errors.New("storage failure"))
+
+					report, err := retentionService.Apply(authorityID, false)
+
+					Convey("The versions not retained should be removed", func() {
+						So(err, ShouldBeNil)
+						So(report.DryRun, ShouldBeFalse)
+						So(report.Removals, ShouldHaveLength, 4)
+
+						mockModuleService.AssertNumberOfCalls(t, "DeleteVersion", 3)
+						mockModuleService.AssertCalled(t, "DeleteVersion", authorityID, "vpc", "aws", "1.11.0-rc.1")
+						mockModuleService.AssertCalled(t, "DeleteVersion", authorityID, "vpc", "aws", "1.2.0")
+						mockModuleService.AssertCalled(t, "DeleteVersion", authorityID, "vpc", "aws", "1.0.0")
+					})
+
+					Convey("The versions which could not be removed should be reported", func() {
+						So(report.Removals[3].Error, ShouldEqual, "storage failure")
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestSetRetentionPolicy(t *testing.T) {
+	Convey("Subject: Set a retention policy", t, func() {
+		mockRetentionPolicyRepository := repositories.NewMockRetentionPolicyRepository(t)
+
+		retentionService := &DefaultRetentionService{
+			RetentionPolicyRepository: mockRetentionPolicyRepository,
+		}
+
+		authorityID, _ := uuid.NewRandom()
+		keep := 5
+
+		Convey("Given a valid policy", func() {
+			dto := retention.PolicyDTO{
+				ArtifactType:   "module",
+				ArtifactName:   "vpc/aws",
+				KeepLastStable: &keep,
+			}
+
+			mockRetentionPolicyRepository.
+				On("Upsert", dto.ToPolicy(authorityID)).
+				Return(&retention.Policy{
+					AuthorityID:    authorityID,
+					ArtifactType:   "module",
+					ArtifactName:   "vpc/aws",
+					KeepLastStable: &keep,
+				}, nil)
+
+			Convey("When the service is queried", func() {
+				resp, err := retentionService.SetPolicy(authorityID, dto)
+
+				Convey("The policy should be saved", func() {
+					So(err, ShouldBeNil)
+					So(resp.ArtifactName, ShouldEqual, "vpc/aws")
+					So(*resp.KeepLastStable, ShouldEqual, 5)
+				})
+			})
+		})
+
+		Convey("Given a policy with a negative rule", func() {
+			negative := -1
+			dto := retention.PolicyDTO{
+				PreReleaseMaxAgeDays: &negative,
+			}
+
+			Convey("When the service is queried", func() {
+				resp, err := retentionService.SetPolicy(authorityID, dto)
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(resp, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given a policy with an unknown artifact type", func() {
+			dto := retention.PolicyDTO{
+				ArtifactType: "image",
+				ArtifactName: "nginx",
+			}
+
+			Convey("When the service is queried", func() {
+				resp, err := retentionService.SetPolicy(authorityID, dto)
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(resp, ShouldBeNil)
+				})
+			})
+		})
+	})
+}
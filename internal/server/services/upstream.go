@@ -0,0 +1,444 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/models/provider"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/registry"
+
+	"github.com/rs/zerolog/log"
+)
+
+// anyNamespace matches all the namespaces in the upstream allow list.
+const anyNamespace = "*"
+
+// maxUpstreamEntries is the number of upstream version lists kept in memory.
+const maxUpstreamEntries = 10000
+
+var (
+	ErrUpstreamNamespaceTaken = errors.New("the namespace is owned by a local authority")
+)
+
+// UpstreamService describes a service that resolves the modules and the
+// providers of some namespaces against an upstream registry. The versions
+// are cached in the registry on their first download.
+type UpstreamService interface {
+	// Proxies returns true if a namespace is resolved against the upstream
+	// registry.
+	Proxies(namespace string) bool
+
+	// GetProvider returns the versions of a provider, as listed by the
+	// upstream registry.
+	GetProvider(namespace, name string) (*provider.VersionListProviderDTO, error)
+
+	// GetProviderVersion returns a specific installation for a provider. It
+	// is downloaded from the upstream registry if it is not cached yet.
+	GetProviderVersion(namespace, name, version, system, architecture string) (*provider.DownloadPlatformDTO, error)
+
+	// GetModule returns the versions of a module, as listed by the upstream
+	// registry.
+	GetModule(namespace, name, provider string) (*module.ListResponseDTO, error)
+
+	// GetModuleVersionURL returns a public URL from which a module version
+	// can be downloaded. It is downloaded from the upstream registry if it is
+	// not cached yet.
+	GetModuleVersionURL(namespace, name, provider, version string) (*string, error)
+}
+
+// DefaultUpstreamService is a concrete implementation of UpstreamService.
+type DefaultUpstreamService struct {
+	Registry           registry.Client
+	ProviderRepository repositories.ProviderRepository
+	AuthorityService   AuthorityService
+	ModuleService      ModuleService
+	ProviderService    ProviderService
+
+	// AllowedNamespaces are the namespaces resolved against the upstream
+	// registry, "*" allows all of them
+	AllowedNamespaces []string
+
+	// DeniedNamespaces are never resolved against the upstream registry,
+	// even if they are allowed
+	DeniedNamespaces []string
+
+	// TTL is how long the versions listed by the upstream registry are
+	// cached
+	TTL time.Duration
+
+	mu       sync.Mutex
+	versions map[string]upstreamEntry
+	locks    map[string]*upstreamLock
+}
+
+type upstreamLock struct {
+	sync.Mutex
+
+	// waiters is the number of callers holding or waiting for the lock
+	waiters int
+}
+
+type upstreamEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+func (s *DefaultUpstreamService) Proxies(namespace string) bool {
+	matches := func(namespaces []string) bool {
+		return slices.ContainsFunc(namespaces, func(ns string) bool {
+			return strings.EqualFold(ns, namespace)
+		})
+	}
+
+	if matches(s.DeniedNamespaces) {
+		return false
+	}
+
+	if !slices.Contains(s.AllowedNamespaces, anyNamespace) && !matches(s.AllowedNamespaces) {
+		return false
+	}
+
+	// A namespace published to the registry is never resolved against the
+	// upstream registry, so its artifacts cannot be shadowed by upstream ones
+	if a, err := s.AuthorityService.GetByName(namespace); err == nil && !a.Upstream {
+		return false
+	}
+
+	return true
+}
+
+func (s *DefaultUpstreamService) GetProvider(namespace, name string) (*provider.VersionListProviderDTO, error) {
+	dto, err := cachedVersions(s, fmt.Sprintf("providers/%s/%s", namespace, name), func() (*provider.VersionListProviderDTO, error) {
+		versions, err := s.Registry.ProviderVersions(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		dto := &provider.VersionListProviderDTO{}
+		for _, v := range versions.Versions {
+			var platforms []provider.VersionListPlatformDTO
+			for _, p := range v.Platforms {
+				platforms = append(platforms, provider.VersionListPlatformDTO{
+					System:       p.OS,
+					Architecture: p.Arch,
+				})
+			}
+
+			dto.Versions = append(dto.Versions, provider.VersionListVersionDTO{
+				Version:   v.Version,
+				Protocols: v.Protocols,
+				Platforms: platforms,
+			})
+		}
+
+		return dto, nil
+	})
+	if err != nil {
+		// The cached versions are still available if the upstream registry
+		// cannot be reached
+		if local, localErr := s.ProviderService.Get(namespace, name); localErr == nil {
+			return local, nil
+		}
+
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (s *DefaultUpstreamService) GetProviderVersion(namespace, name, version, system, architecture string) (*provider.DownloadPlatformDTO, error) {
+	if dto, err := s.ProviderService.GetVersion(namespace, name, version, system, architecture); err == nil {
+		return dto, nil
+	}
+
+	unlock := s.lock(fmt.Sprintf("providers/%s/%s/%s", namespace, name, version))
+	defer unlock()
+
+	// The version may have been cached while waiting for the lock
+	if dto, err := s.ProviderService.GetVersion(namespace, name, version, system, architecture); err == nil {
+		return dto, nil
+	}
+
+	if err := s.cacheProvider(namespace, name, version, system, architecture); err != nil {
+		return nil, err
+	}
+
+	return s.ProviderService.GetVersion(namespace, name, version, system, architecture)
+}
+
+func (s *DefaultUpstreamService) GetModule(namespace, name, provider string) (*module.ListResponseDTO, error) {
+	dto, err := cachedVersions(s, fmt.Sprintf("modules/%s/%s/%s", namespace, name, provider), func() (*module.ListResponseDTO, error) {
+		versions, err := s.Registry.ModuleVersions(namespace, name, provider)
+		if err != nil {
+			return nil, err
+		}
+
+		dto := &module.ListResponseDTO{}
+		for _, m := range versions.Modules {
+			var list []module.VersionListDTO
+			for _, v := range m.Versions {
+				list = append(list, module.VersionListDTO{Version: v.Version})
+			}
+
+			dto.Modules = append(dto.Modules, module.ModuleDTO{Versions: list})
+		}
+
+		return dto, nil
+	})
+	if err != nil {
+		// The cached versions are still available if the upstream registry
+		// cannot be reached
+		if local, localErr := s.ModuleService.Get(namespace, name, provider); localErr == nil {
+			return local, nil
+		}
+
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (s *DefaultUpstreamService) GetModuleVersionURL(namespace, name, provider, version string) (*string, error) {
+	if location, err := s.ModuleService.GetVersionURL(namespace, name, provider, version); err == nil {
+		return location, nil
+	}
+
+	unlock := s.lock(fmt.Sprintf("modules/%s/%s/%s/%s", namespace, name, provider, version))
+	defer unlock()
+
+	// The version may have been cached while waiting for the lock
+	if location, err := s.ModuleService.GetVersionURL(namespace, name, provider, version); err == nil {
+		return location, nil
+	}
+
+	location, err := s.Registry.ModuleLocation(namespace, name, provider, version)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the module in the upstream registry: %w", err)
+	}
+
+	a, err := s.upstreamAuthority(namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := &module.CreateDTO{
+		AuthorityID: a.ID,
+		Name:        name,
+		Provider:    provider,
+		VersionCreateDTO: module.VersionCreateDTO{
+			Version: version,
+		},
+	}
+	dto.Provenance.SetSource(location)
+
+	if err := s.ModuleService.Upload(dto, location, nil); err != nil {
+		return nil, fmt.Errorf("could not cache the module: %w", err)
+	}
+
+	return s.ModuleService.GetVersionURL(namespace, name, provider, version)
+}
+
+// cacheProvider downloads the package of a provider version for a platform
+// from the upstream registry, and adds it to the provider version, which
+// is created if needed. Its signature is verified against the keys
+// advertised by the upstream registry.
+func (s *DefaultUpstreamService) cacheProvider(namespace, name, version, system, architecture string) error {
+	pkg, err := s.Registry.ProviderPackage(namespace, name, version, system, architecture)
+	if err != nil {
+		return fmt.Errorf("could not find the provider in the upstream registry: %w", err)
+	}
+
+	a, err := s.upstreamAuthority(namespace, pkg.SigningKeys.GPGPublicKeys)
+	if err != nil {
+		return err
+	}
+
+	dto := &provider.CreateProviderDTO{
+		AuthorityID: a.ID,
+		Name:        name,
+		Version:     version,
+		ShaSums: provider.CreateProviderShaSumsDTO{
+			URL:          pkg.ShaSumsURL,
+			SignatureURL: pkg.ShaSumsSignatureURL,
+		},
+		Protocols: pkg.Protocols,
+		Platforms: []provider.CreatePlatformDTO{
+			{
+				System:       pkg.OS,
+				Architecture: pkg.Arch,
+				Location:     pkg.DownloadURL,
+				ShaSum:       pkg.ShaSum,
+			},
+		},
+	}
+	dto.Provenance.SetSource(pkg.ShaSumsURL)
+
+	// The other platforms of the version may already be cached
+	if p, findErr := s.ProviderRepository.Find(namespace, name); findErr == nil && p.GetVersion(version) != nil {
+		err = s.ProviderService.AddPlatforms(dto)
+	} else {
+		err = s.ProviderService.Upload(dto)
+	}
+	if err != nil {
+		return fmt.Errorf("could not cache the provider: %w", err)
+	}
+
+	return nil
+}
+
+// upstreamAuthority returns the authority the artifacts of an upstream
+// namespace are cached in, and creates it if needed. The keys advertised by
+// the upstream registry are added to the authority. The authorities which
+// were not created by the upstream cache are never written to.
+func (s *DefaultUpstreamService) upstreamAuthority(namespace string, keys []registry.GPGPublicKey) (*authority.Authority, error) {
+	a, err := s.AuthorityService.GetByName(namespace)
+	if err == nil && !a.Upstream {
+		return nil, fmt.Errorf("%w: %s", ErrUpstreamNamespaceTaken, namespace)
+	}
+
+	if err != nil {
+		// The artifacts of the upstream registry are public
+		if _, err := s.AuthorityService.Create(authority.AuthorityCreateDTO{
+			Name:     namespace,
+			Public:   true,
+			Upstream: true,
+		}); err != nil {
+			return nil, fmt.Errorf("could not create the %s authority: %w", namespace, err)
+		}
+
+		log.Info().
+			Str("Authority", namespace).
+			Str("Upstream", s.Registry.Host()).
+			Msg("created the authority of an upstream namespace")
+
+		if a, err = s.AuthorityService.GetByName(namespace); err != nil {
+			return nil, err
+		}
+
+		if !a.Upstream {
+			return nil, fmt.Errorf("%w: %s", ErrUpstreamNamespaceTaken, namespace)
+		}
+	}
+
+	for _, k := range keys {
+		if slices.ContainsFunc(a.Keys, func(key authority.Key) bool { return strings.EqualFold(key.KeyId, k.KeyID) }) {
+			continue
+		}
+
+		key := authority.KeyDTO{
+			KeyId:          k.KeyID,
+			AsciiArmor:     k.ASCIIArmor,
+			TrustSignature: k.TrustSignature,
+		}
+
+		if _, err := s.AuthorityService.AddKey(a.ID, key); err != nil {
+			return nil, fmt.Errorf("could not add the upstream key %s: %w", k.KeyID, err)
+		}
+
+		a.Keys = append(a.Keys, key.ToKey())
+	}
+
+	return a, nil
+}
+
+// lock acquires the lock of an artifact version, so it is only cached once,
+// and returns the function releasing it. The lock is forgotten once it is
+// released by all its callers.
+func (s *DefaultUpstreamService) lock(key string) func() {
+	s.mu.Lock()
+	if s.locks == nil {
+		s.locks = map[string]*upstreamLock{}
+	}
+
+	l, ok := s.locks[key]
+	if !ok {
+		l = &upstreamLock{}
+		s.locks[key] = l
+	}
+	l.waiters++
+	s.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		s.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(s.locks, key)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// cachedVersions returns the versions of an artifact listed by the upstream
+// registry, fetching them if they are not cached or if they expired. The
+// expired versions are returned if they cannot be fetched again.
+func cachedVersions[T any](s *DefaultUpstreamService, key string, fetch func() (T, error)) (T, error) {
+	s.mu.Lock()
+	entry, ok := s.versions[key]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value.(T), nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		if ok {
+			log.Warn().
+				Str("Key", key).
+				Str("Upstream", s.Registry.Host()).
+				Err(err).
+				Msg("could not refresh the upstream versions, serving the expired ones")
+
+			return entry.value.(T), nil
+		}
+
+		return value, err
+	}
+
+	s.mu.Lock()
+	if s.versions == nil {
+		s.versions = map[string]upstreamEntry{}
+	}
+	if _, ok := s.versions[key]; !ok && len(s.versions) >= maxUpstreamEntries {
+		s.evictVersions()
+	}
+	s.versions[key] = upstreamEntry{
+		value:     value,
+		expiresAt: time.Now().Add(s.TTL),
+	}
+	s.mu.Unlock()
+
+	return value, nil
+}
+
+// evictVersions makes room in the cached versions, by removing the expired
+// entries or, if none expired, an arbitrary one. It must be called while
+// holding the lock of the service.
+func (s *DefaultUpstreamService) evictVersions() {
+	now := time.Now()
+	for key, entry := range s.versions {
+		if now.After(entry.expiresAt) {
+			delete(s.versions, key)
+		}
+	}
+
+	if len(s.versions) < maxUpstreamEntries {
+		return
+	}
+
+	for key := range s.versions {
+		delete(s.versions, key)
+		return
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"terralist/internal/server/models/authority"
+	"terralist/internal/server/models/provider"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/database/entity"
+	"terralist/pkg/registry"
+
+	"github.com/google/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpstreamProxies(t *testing.T) {
+	Convey("Subject: Select the namespaces resolved against the upstream registry", t, func() {
+		mockAuthorityService := NewMockAuthorityService(t)
+
+		mockAuthorityService.
+			On("GetByName", mock.AnythingOfType("string")).
+			Return(nil, errors.New("not found")).
+			Maybe()
+
+		Convey("Given an allow list and a deny list", func() {
+			upstreamService := &DefaultUpstreamService{
+				AuthorityService:  mockAuthorityService,
+				AllowedNamespaces: []string{"hashicorp", "Terraform-AWS-Modules"},
+				DeniedNamespaces:  []string{"internal"},
+			}
+
+			Convey("The allowed namespaces should be proxied, whatever their case", func() {
+				So(upstreamService.Proxies("hashicorp"), ShouldBeTrue)
+				So(upstreamService.Proxies("terraform-aws-modules"), ShouldBeTrue)
+			})
+
+			Convey("The other namespaces should not be proxied", func() {
+				So(upstreamService.Proxies("acme"), ShouldBeFalse)
+				So(upstreamService.Proxies("internal"), ShouldBeFalse)
+			})
+		})
+
+		Convey("Given an allow list matching all the namespaces", func() {
+			upstreamService := &DefaultUpstreamService{
+				AuthorityService:  mockAuthorityService,
+				AllowedNamespaces: []string{anyNamespace},
+				DeniedNamespaces:  []string{"internal"},
+			}
+
+			Convey("Only the denied namespaces should not be proxied", func() {
+				So(upstreamService.Proxies("acme"), ShouldBeTrue)
+				So(upstreamService.Proxies("internal"), ShouldBeFalse)
+			})
+		})
+
+		Convey("Given an allowed namespace with a local authority", func() {
+			authorityService := NewMockAuthorityService(t)
+
+			upstreamService := &DefaultUpstreamService{
+				AuthorityService:  authorityService,
+				AllowedNamespaces: []string{anyNamespace},
+			}
+
+			authorityService.
+				On("GetByName", "acme").
+				Return(&authority.Authority{Name: "acme"}, nil).
+				Maybe()
+
+			authorityService.
+				On("GetByName", "hashicorp").
+				Return(&authority.Authority{Name: "hashicorp", Upstream: true}, nil).
+				Maybe()
+
+			Convey("The namespace should not be proxied", func() {
+				So(upstreamService.Proxies("acme"), ShouldBeFalse)
+			})
+
+			Convey("The namespaces cached from the upstream registry should still be proxied", func() {
+				So(upstreamService.Proxies("hashicorp"), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestUpstreamGetProvider(t *testing.T) {
+	Convey("Subject: List the versions of an upstream provider", t, func() {
+		mockRegistry := registry.NewMockClient(t)
+		mockProviderService := NewMockProviderService(t)
+
+		upstreamService := &DefaultUpstreamService{
+			Registry:        mockRegistry,
+			ProviderService: mockProviderService,
+			TTL:             time.Hour,
+		}
+
+		Convey("Given a provider listed by the upstream registry", func() {
+			mockRegistry.
+				On("ProviderVersions", "hashicorp", "random").
+				Return(&registry.ProviderVersions{
+					Versions: []registry.ProviderVersion{
+						{
+							Version:   "3.6.0",
+							Protocols: []string{"5.0"},
+							Platforms: []registry.Platform{{OS: "linux", Arch: "amd64"}},
+						},
+					},
+				}, nil).
+				Once()
+
+			Convey("When the versions are listed twice", func() {
+				first, err := upstreamService.GetProvider("hashicorp", "random")
+				So(err, ShouldBeNil)
+
+				second, err := upstreamService.GetProvider("hashicorp", "random")
+
+				Convey("The upstream versions should be returned", func() {
+					So(err, ShouldBeNil)
+					So(first.Versions, ShouldHaveLength, 1)
+					So(first.Versions[0].Version, ShouldEqual, "3.6.0")
+					So(first.Versions[0].Platforms[0].System, ShouldEqual, "linux")
+				})
+
+				Convey("The upstream registry should only be queried once", func() {
+					So(second, ShouldEqual, first)
+				})
+			})
+		})
+
+		Convey("Given a full cache of upstream versions", func() {
+			upstreamService.versions = map[string]upstreamEntry{}
+			for i := range maxUpstreamEntries {
+				upstreamService.versions[fmt.Sprintf("providers/acme/p%d", i)] = upstreamEntry{
+					value:     &provider.VersionListProviderDTO{},
+					expiresAt: time.Now().Add(-time.Minute),
+				}
+			}
+
+			mockRegistry.
+				On("ProviderVersions", "hashicorp", "random").
+				Return(&registry.ProviderVersions{}, nil)
+
+			Convey("When the versions of another provider are listed", func() {
+				_, err := upstreamService.GetProvider("hashicorp", "random")
+
+				Convey("The expired versions should be evicted", func() {
+					So(err, ShouldBeNil)
+					So(upstreamService.versions, ShouldHaveLength, 1)
+					So(upstreamService.versions, ShouldContainKey, "providers/hashicorp/random")
+				})
+			})
+		})
+
+		Convey("Given an upstream registry which cannot be reached", func() {
+			mockRegistry.
+				On("ProviderVersions", "hashicorp", "random").
+				Return(nil, errors.New("connection refused"))
+
+			Convey("If the provider is cached", func() {
+				mockProviderService.
+					On("Get", "hashicorp", "random").
+					Return(&provider.VersionListProviderDTO{
+						Versions: []provider.VersionListVersionDTO{{Version: "3.5.0"}},
+					}, nil)
+
+				Convey("When the versions are listed", func() {
+					resp, err := upstreamService.GetProvider("hashicorp", "random")
+
+					Convey("The cached versions should be returned", func() {
+						So(err, ShouldBeNil)
+						So(resp.Versions, ShouldHaveLength, 1)
+						So(resp.Versions[0].Version, ShouldEqual, "3.5.0")
+					})
+				})
+			})
+
+			Convey("If the provider is not cached", func() {
+				mockProviderService.
+					On("Get", "hashicorp", "random").
+					Return(nil, errors.New("not found"))
+
+				Convey("When the versions are listed", func() {
+					_, err := upstreamService.GetProvider("hashicorp", "random")
+
+					Convey("An error should be returned", func() {
+						So(err, ShouldNotBeNil)
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestUpstreamGetProviderVersion(t *testing.T) {
+	Convey("Subject: Download an upstream provider", t, func() {
+		mockRegistry := registry.NewMockClient(t)
+		mockProviderRepository := repositories.NewMockProviderRepository(t)
+		mockAuthorityService := NewMockAuthorityService(t)
+		mockProviderService := NewMockProviderService(t)
+
+		upstreamService := &DefaultUpstreamService{
+			Registry:           mockRegistry,
+			ProviderRepository: mockProviderRepository,
+			AuthorityService:   mockAuthorityService,
+			ProviderService:    mockProviderService,
+		}
+
+		dto := &provider.DownloadPlatformDTO{
+			System:       "linux",
+			Architecture: "amd64",
+		}
+
+		Convey("If the version is cached", func() {
+			mockProviderService.
+				On("GetVersion", "hashicorp", "random", "3.6.0", "linux", "amd64").
+				Return(dto, nil)
+
+			Convey("When the version is downloaded", func() {
+				resp, err := upstreamService.GetProviderVersion("hashicorp", "random", "3.6.0", "linux", "amd64")
+
+				Convey("The cached version should be returned without querying the upstream registry", func() {
+					So(err, ShouldBeNil)
+					So(resp, ShouldEqual, dto)
+				})
+			})
+		})
+
+		Convey("If the version is not cached", func() {
+			cached := false
+
+			mockProviderService.
+				On("GetVersion", "hashicorp", "random", "3.6.0", "linux", "amd64").
+				Return(func(_, _, _, _, _ string) (*provider.DownloadPlatformDTO, error) {
+					if !cached {
+						return nil, errors.New("not found")
+					}
+
+					return dto, nil
+				})
+
+			mockRegistry.
+				On("ProviderPackage", "hashicorp", "random", "3.6.0", "linux", "amd64").
+				Return(&registry.ProviderPackage{
+					Protocols:           []string{"5.0"},
+					OS:                  "linux",
+					Arch:                "amd64",
+					DownloadURL:         "https://releases.example.com/random_linux_amd64.zip",
+					ShaSumsURL:          "https://releases.example.com/SHA256SUMS",
+					ShaSumsSignatureURL: "https://releases.example.com/SHA256SUMS.sig",
+					ShaSum:              "abcdef",
+					SigningKeys: registry.SigningKeys{
+						GPGPublicKeys: []registry.GPGPublicKey{
+							{KeyID: "34365D9472D7468F", ASCIIArmor: "armor"},
+						},
+					},
+				}, nil)
+
+			authorityID, _ := uuid.NewRandom()
+
+			Convey("And the upstream namespace has no authority", func() {
+				mockAuthorityService.
+					On("GetByName", "hashicorp").
+					Return(nil, errors.New("not found")).
+					Once()
+
+				mockAuthorityService.
+					On("Create", authority.AuthorityCreateDTO{Name: "hashicorp", Public: true, Upstream: true}).
+					Return(nil, nil)
+
+				mockRegistry.
+					On("Host").
+					Return("registry.terraform.io")
+
+				mockAuthorityService.
+					On("GetByName", "hashicorp").
+					Return(&authority.Authority{
+						Entity:   entity.Entity{ID: authorityID},
+						Name:     "hashicorp",
+						Public:   true,
+						Upstream: true,
+					}, nil)
+
+				mockAuthorityService.
+					On("AddKey", authorityID, mock.MatchedBy(func(k authority.KeyDTO) bool {
+						return k.KeyId == "34365D9472D7468F"
+					})).
+					Return(nil, nil)
+
+				mockProviderRepository.
+					On("Find", "hashicorp", "random").
+					Return(nil, errors.New("not found"))
+
+				mockProviderService.
+					On("Upload", mock.MatchedBy(func(d *provider.CreateProviderDTO) bool {
+						return d.AuthorityID == authorityID &&
+							d.Version == "3.6.0" &&
+							d.ShaSums.SignatureURL == "https://releases.example.com/SHA256SUMS.sig" &&
+							len(d.Platforms) == 1 &&
+							d.Platforms[0].Location == "https://releases.example.com/random_linux_amd64.zip"
+					})).
+					Run(func(_ mock.Arguments) { cached = true }).
+					Return(nil)
+
+				Convey("When the version is downloaded", func() {
+					resp, err := upstreamService.GetProviderVersion("hashicorp", "random", "3.6.0", "linux", "amd64")
+
+					Convey("The version should be cached in a new public authority", func() {
+						So(err, ShouldBeNil)
+						So(resp, ShouldEqual, dto)
+					})
+
+					Convey("The lock of the version should be released", func() {
+						So(upstreamService.locks, ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("And the upstream namespace has a local authority", func() {
+				mockAuthorityService.
+					On("GetByName", "hashicorp").
+					Return(&authority.Authority{
+						Entity: entity.Entity{ID: authorityID},
+						Name:   "hashicorp",
+					}, nil)
+
+				Convey("When the version is downloaded", func() {
+					_, err := upstreamService.GetProviderVersion("hashicorp", "random", "3.6.0", "linux", "amd64")
+
+					Convey("The version should not be cached in the local authority", func() {
+						So(errors.Is(err, ErrUpstreamNamespaceTaken), ShouldBeTrue)
+					})
+				})
+			})
+
+			Convey("And another platform of the version is cached", func() {
+				mockAuthorityService.
+					On("GetByName", "hashicorp").
+					Return(&authority.Authority{
+						Entity:   entity.Entity{ID: authorityID},
+						Name:     "hashicorp",
+						Upstream: true,
+						Keys:     []authority.Key{{KeyId: "34365d9472d7468f"}},
+					}, nil)
+
+				mockProviderRepository.
+					On("Find", "hashicorp", "random").
+					Return(&provider.Provider{
+						Name:     "random",
+						Versions: []provider.Version{{Version: "3.6.0"}},
+					}, nil)
+
+				Convey("If the platform cannot be verified", func() {
+					mockProviderService.
+						On("AddPlatforms", mock.AnythingOfType("*provider.CreateProviderDTO")).
+						Return(ErrInvalidProviderRelease)
+
+					Convey("When the version is downloaded", func() {
+						_, err := upstreamService.GetProviderVersion("hashicorp", "random", "3.6.0", "linux", "amd64")
+
+						Convey("An error should be returned", func() {
+							So(errors.Is(err, ErrInvalidProviderRelease), ShouldBeTrue)
+						})
+					})
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+
+	"terralist/internal/server/models/artifact"
+)
+
+// validateVersionStatus checks a version status change and returns the
+// normalized status details. The replacement version, if any, must be
+// another version of the same artifact that was not yanked.
+func validateVersionStatus(
+	version string,
+	status artifact.Status,
+	d artifact.UpdateStatusDTO,
+	findStatus func(version string) (*artifact.VersionStatus, bool),
+) (artifact.UpdateStatusDTO, error) {
+	switch status {
+	case artifact.StatusActive:
+		// Restoring a version clears its status details
+		return artifact.UpdateStatusDTO{}, nil
+	case artifact.StatusDeprecated, artifact.StatusYanked:
+	default:
+		return d, fmt.Errorf("unknown version status %q", status)
+	}
+
+	if d.Replacement == "" {
+		return d, nil
+	}
+
+	if d.Replacement == version {
+		return d, fmt.Errorf("version %s cannot be replaced by itself", version)
+	}
+
+	replacement, ok := findStatus(d.Replacement)
+	if !ok {
+		return d, fmt.Errorf("replacement version %s does not exist", d.Replacement)
+	}
+
+	if replacement.Status == artifact.StatusYanked {
+		return d, fmt.Errorf("replacement version %s is yanked", d.Replacement)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"terralist/internal/server/models/artifact"
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/models/webhook"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/auth"
+	"terralist/pkg/vcs"
+
+	"github.com/google/uuid"
+	"github.com/mazen160/go-random"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+)
+
+const (
+	webhookSecretLength    = 32
+	webhookDeliveriesLimit = 100
+
+	// The most recent deliveries of each registration are kept, so the
+	// deliveries table does not grow with every push
+	webhookDeliveriesRetained = 1000
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook registration not found")
+	ErrWebhookQueueFull        = errors.New("too many webhook deliveries are waiting to be published")
+	ErrWebhookPublisherStopped = errors.New("the server is shutting down")
+	ErrWebhookPublishTimeout   = errors.New("publishing the module version took too long")
+)
+
+// WebhookService describes a service that publishes module versions from
+// the tags pushed to git repositories.
+type WebhookService interface {
+	// GetRegistrations returns the webhook registrations of a module.
+	GetRegistrations(authorityID uuid.UUID, name, provider string) ([]webhook.RegistrationDTO, error)
+
+	// CreateRegistration registers a new webhook for a module. The returned
+	// registration is the only one holding the secret.
+	CreateRegistration(authorityID uuid.UUID, name, provider string, d webhook.CreateRegistrationDTO) (*webhook.RegistrationDTO, error)
+
+	// DeleteRegistration removes a webhook registration of a module.
+	DeleteRegistration(authorityID uuid.UUID, name, provider string, id uuid.UUID) error
+
+	// GetDeliveries returns the most recent deliveries of a webhook
+	// registration, optionally filtered by their status.
+	GetDeliveries(authorityID uuid.UUID, name, provider string, id uuid.UUID, status webhook.DeliveryStatus) ([]webhook.DeliveryDTO, error)
+
+	// Receive verifies a webhook request and records a delivery for each tag
+	// created by the event. The deliveries of tags which map to a module
+	// version are pending until they are published. A redelivery retries a
+	// failed delivery, while the deliveries which are pending or succeeded
+	// are left out.
+	Receive(id uuid.UUID, header http.Header, body []byte) ([]*webhook.Delivery, error)
+
+	// Publish uploads the module version of a pending delivery and records
+	// the outcome. The upload is canceled when the given context is done.
+	Publish(ctx context.Context, d *webhook.Delivery)
+}
+
+// DefaultWebhookService is a concrete implementation of WebhookService.
+type DefaultWebhookService struct {
+	WebhookRepository repositories.WebhookRepository
+	ModuleService     ModuleService
+}
+
+func (s *DefaultWebhookService) GetRegistrations(authorityID uuid.UUID, name, provider string) ([]webhook.RegistrationDTO, error) {
+	regs, err := s.WebhookRepository.FindRegistrations(authorityID, name, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(regs, func(r *webhook.Registration, _ int) webhook.RegistrationDTO {
+		return r.ToDTO()
+	}), nil
+}
+
+func (s *DefaultWebhookService) CreateRegistration(
+	authorityID uuid.UUID,
+	name, provider string,
+	d webhook.CreateRegistrationDTO,
+) (*webhook.RegistrationDTO, error) {
+	if !d.Kind.Valid() {
+		return nil, fmt.Errorf("kind should be one of: %s", strings.Join(lo.Map(vcs.Kinds(), func(k vcs.Kind, _ int) string {
+			return string(k)
+		}), ", "))
+	}
+
+	if strings.TrimSpace(d.Repository) == "" {
+		return nil, fmt.Errorf("repository is required")
+	}
+
+	// The tags are cloned by the server, so the local paths cannot be used
+	if d.CloneURL != "" && !strings.HasPrefix(d.CloneURL, "https://") && !strings.HasPrefix(d.CloneURL, "ssh://") {
+		return nil, fmt.Errorf("clone_url should be an https:// or ssh:// URL")
+	}
+
+	if d.Secret == "" {
+		secret, err := random.String(webhookSecretLength)
+		if err != nil {
+			return nil, fmt.Errorf("could not generate a secret: %v", err)
+		}
+
+		d.Secret = secret
+	}
+
+	reg, err := s.WebhookRepository.CreateRegistration(d.ToRegistration(authorityID, name, provider))
+	if err != nil {
+		return nil, err
+	}
+
+	dto := reg.ToDTO()
+	dto.Secret = reg.Secret
+
+	return &dto, nil
+}
+
+func (s *DefaultWebhookService) DeleteRegistration(authorityID uuid.UUID, name, provider string, id uuid.UUID) error {
+	reg, err := s.findModuleRegistration(authorityID, name, provider, id)
+	if err != nil {
+		return err
+	}
+
+	return s.WebhookRepository.DeleteRegistration(reg)
+}
+
+func (s *DefaultWebhookService) GetDeliveries(
+	authorityID uuid.UUID,
+	name, provider string,
+	id uuid.UUID,
+	status webhook.DeliveryStatus,
+) ([]webhook.DeliveryDTO, error) {
+	switch status {
+	case "", webhook.DeliveryPending, webhook.DeliverySucceeded, webhook.DeliveryFailed, webhook.DeliveryIgnored:
+	default:
+		return nil, fmt.Errorf("unknown delivery status %q", status)
+	}
+
+	reg, err := s.findModuleRegistration(authorityID, name, provider, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.WebhookRepository.FindDeliveries(reg.ID, status, webhookDeliveriesLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(deliveries, func(d *webhook.Delivery, _ int) webhook.DeliveryDTO {
+		return d.ToDTO()
+	}), nil
+}
+
+func (s *DefaultWebhookService) Receive(id uuid.UUID, header http.Header, body []byte) ([]*webhook.Delivery, error) {
+	reg, err := s.WebhookRepository.FindRegistration(id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+
+		return nil, err
+	}
+
+	if err := vcs.Verify(reg.Kind, reg.Secret, header, body); err != nil {
+		return nil, err
+	}
+
+	tags, err := vcs.ParseTagEvents(reg.Kind, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryID := vcs.DeliveryID(reg.Kind, header)
+
+	var deliveries []*webhook.Delivery
+	for _, tag := range tags {
+		d := webhook.Delivery{
+			RegistrationID: reg.ID,
+			DeliveryID:     deliveryID,
+			Event:          vcs.Event(reg.Kind, header),
+			Tag:            tag.Tag,
+			Commit:         tag.Commit,
+			Status:         webhook.DeliveryPending,
+		}
+
+		existing, err := s.WebhookRepository.FindDelivery(reg.ID, deliveryID, tag.Tag)
+		if err == nil {
+			if existing.Status != webhook.DeliveryFailed {
+				continue
+			}
+
+			d.Entity = existing.Entity
+		} else if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+
+		if v, ok := reg.VersionFromTag(tag.Tag); !ok {
+			d.Status = webhook.DeliveryIgnored
+			d.Error = fmt.Sprintf("tag %s is not a semantic version prefixed by %q", tag.Tag, reg.TagPrefix)
+		} else if !strings.EqualFold(tag.Repository, reg.Repository) {
+			d.Status = webhook.DeliveryFailed
+			d.Error = fmt.Sprintf("repository %s does not match the registered repository %s", tag.Repository, reg.Repository)
+		} else {
+			d.Version = v
+			d.Source = reg.SourceURL(tag.Tag)
+		}
+
+		delivery, err := s.WebhookRepository.UpsertDelivery(d)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := s.WebhookRepository.PruneDeliveries(reg.ID, webhookDeliveriesRetained); err != nil {
+		log.Warn().
+			Str("registrationID", reg.ID.String()).
+			Err(err).
+			Msg("could not prune webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+func (s *DefaultWebhookService) Publish(ctx context.Context, d *webhook.Delivery) {
+	if d.Status != webhook.DeliveryPending {
+		return
+	}
+
+	if err := s.publish(ctx, d); err != nil {
+		// Report why the upload was canceled, rather than how it was
+		// interrupted
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+
+		log.Warn().
+			Str("registrationID", d.RegistrationID.String()).
+			Str("tag", d.Tag).
+			Err(err).
+			Msg("could not publish module version from webhook")
+
+		d.Status = webhook.DeliveryFailed
+		d.Error = err.Error()
+	} else {
+		d.Status = webhook.DeliverySucceeded
+	}
+
+	if _, err := s.WebhookRepository.UpsertDelivery(*d); err != nil {
+		log.Error().
+			Str("deliveryID", d.ID.String()).
+			Err(err).
+			Msg("could not record webhook delivery")
+	}
+}
+
+func (s *DefaultWebhookService) publish(ctx context.Context, d *webhook.Delivery) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	reg, err := s.WebhookRepository.FindRegistration(d.RegistrationID)
+	if err != nil {
+		return err
+	}
+
+	dto := module.CreateDTO{
+		AuthorityID: reg.AuthorityID,
+		Name:        reg.Name,
+		Provider:    reg.Provider,
+		VersionCreateDTO: module.VersionCreateDTO{
+			Version: d.Version,
+		},
+		Provenance: artifact.Provenance{
+			PublishedBy: fmt.Sprintf("webhook:%s", reg.ID.String()),
+			AuthMethod:  string(auth.MethodWebhook),
+		},
+	}
+
+	dto.Provenance.SetSource(d.Source)
+
+	// The commit of the tag is known even if it cannot be derived from the
+	// source
+	if d.Commit != "" {
+		dto.Provenance.CommitSHA = strings.ToLower(d.Commit)
+	}
+
+	return s.ModuleService.UploadContext(ctx, &dto, d.Source, http.Header{})
+}
+
+// findModuleRegistration returns a webhook registration, if it belongs to
+// the given module.
+func (s *DefaultWebhookService) findModuleRegistration(
+	authorityID uuid.UUID,
+	name, provider string,
+	id uuid.UUID,
+) (*webhook.Registration, error) {
+	reg, err := s.WebhookRepository.FindRegistration(id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+
+		return nil, err
+	}
+
+	if reg.AuthorityID != authorityID || !strings.EqualFold(reg.Name, name) || !strings.EqualFold(reg.Provider, provider) {
+		return nil, ErrWebhookNotFound
+	}
+
+	return reg, nil
+}
+
+// WebhookPublisher publishes the pending webhook deliveries in background,
+// using a bounded number of workers. Each publication is canceled after a
+// timeout, or when the publisher is stopped.
+type WebhookPublisher struct {
+	service WebhookService
+	timeout time.Duration
+	queue   chan *webhook.Delivery
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+	once    sync.Once
+}
+
+// NewWebhookPublisher creates a new publisher, running the given number of
+// workers. At most queueSize deliveries wait for a worker, the next ones
+// fail right away.
+func NewWebhookPublisher(service WebhookService, workers, queueSize int, timeout time.Duration) *WebhookPublisher {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	p := &WebhookPublisher{
+		service: service,
+		timeout: timeout,
+		queue:   make(chan *webhook.Delivery, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for range workers {
+		p.wg.Add(1)
+		go p.run()
+	}
+
+	return p
+}
+
+func (p *WebhookPublisher) run() {
+	defer p.wg.Done()
+
+	for d := range p.queue {
+		ctx, cancel := context.WithTimeoutCause(p.ctx, p.timeout, ErrWebhookPublishTimeout)
+		p.service.Publish(ctx, d)
+		cancel()
+	}
+}
+
+// Enqueue schedules the publication of a delivery. The workers publish a
+// copy of the delivery, so the given one is only updated if the delivery
+// cannot be scheduled; it is then recorded as failed and the reason is
+// returned.
+func (p *WebhookPublisher) Enqueue(d *webhook.Delivery) error {
+	delivery := *d
+
+	err := p.enqueue(&delivery)
+	if err != nil {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(err)
+
+		p.service.Publish(ctx, d)
+	}
+
+	return err
+}
+
+func (p *WebhookPublisher) enqueue(d *webhook.Delivery) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrWebhookPublisherStopped
+	}
+
+	select {
+	case p.queue <- d:
+		return nil
+	default:
+		return ErrWebhookQueueFull
+	}
+}
+
+// Stop stops accepting deliveries, cancels the publications in progress and
+// waits for the workers to record their outcome. Safe to call multiple
+// times.
+func (p *WebhookPublisher) Stop() {
+	p.once.Do(func() {
+		p.mu.Lock()
+		p.stopped = true
+		close(p.queue)
+		p.mu.Unlock()
+
+		p.cancel(ErrWebhookPublisherStopped)
+		p.wg.Wait()
+	})
+}
@@ -0,0 +1,521 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"terralist/internal/server/models/module"
+	"terralist/internal/server/models/webhook"
+	"terralist/internal/server/repositories"
+	"terralist/pkg/database/entity"
+	"terralist/pkg/vcs"
+
+	"github.com/google/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateWebhookRegistration(t *testing.T) {
+	Convey("Subject: Register a webhook for a module", t, func() {
+		mockWebhookRepository := repositories.NewMockWebhookRepository(t)
+		mockModuleService := NewMockModuleService(t)
+
+		webhookService := &DefaultWebhookService{
+			WebhookRepository: mockWebhookRepository,
+			ModuleService:     mockModuleService,
+		}
+
+		authorityID, _ := uuid.NewRandom()
+
+		Convey("Given a registration with an unknown kind", func() {
+			dto := webhook.CreateRegistrationDTO{
+				Kind:       vcs.Kind("gitea"),
+				Repository: "acme/terraform-aws-vpc",
+			}
+
+			Convey("When the registration is created", func() {
+				reg, err := webhookService.CreateRegistration(authorityID, "vpc", "aws", dto)
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(reg, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given a registration without a repository", func() {
+			dto := webhook.CreateRegistrationDTO{
+				Kind: vcs.GitHub,
+			}
+
+			Convey("When the registration is created", func() {
+				reg, err := webhookService.CreateRegistration(authorityID, "vpc", "aws", dto)
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(reg, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given a registration with a local clone URL", func() {
+			dto := webhook.CreateRegistrationDTO{
+				Kind:       vcs.GitHub,
+				Repository: "acme/terraform-aws-vpc",
+				CloneURL:   "file:///srv/git/terraform-aws-vpc",
+			}
+
+			Convey("When the registration is created", func() {
+				reg, err := webhookService.CreateRegistration(authorityID, "vpc", "aws", dto)
+
+				Convey("An error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(reg, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given a valid registration without a secret", func() {
+			dto := webhook.CreateRegistrationDTO{
+				Kind:       vcs.GitHub,
+				Repository: "acme/terraform-aws-vpc",
+				TagPrefix:  "vpc/",
+			}
+
+			mockWebhookRepository.
+				On("CreateRegistration", mock.AnythingOfType("webhook.Registration")).
+				Return(func(r webhook.Registration) *webhook.Registration {
+					r.ID, _ = uuid.NewRandom()
+					return &r
+				}, nil)
+
+			Convey("When the registration is created", func() {
+				reg, err := webhookService.CreateRegistration(authorityID, "vpc", "aws", dto)
+
+				Convey("A secret should be generated and returned", func() {
+					So(err, ShouldBeNil)
+					So(reg, ShouldNotBeNil)
+					So(reg.Secret, ShouldHaveLength, webhookSecretLength)
+					So(reg.TagPrefix, ShouldEqual, "vpc/")
+				})
+			})
+		})
+	})
+}
+
+func TestReceiveWebhook(t *testing.T) {
+	Convey("Subject: Receive a git webhook", t, func() {
+		mockWebhookRepository := repositories.NewMockWebhookRepository(t)
+		mockModuleService := NewMockModuleService(t)
+
+		webhookService := &DefaultWebhookService{
+			WebhookRepository: mockWebhookRepository,
+			ModuleService:     mockModuleService,
+		}
+
+		sign := func(secret string, body []byte) string {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+
+		pushTag := func(repository, tag string) []byte {
+			return []byte(`{
+				"ref": "refs/tags/` + tag + `",
+				"after": "abc123",
+				"repository": {
+					"full_name": "` + repository + `",
+					"clone_url": "https://github.com/` + repository + `.git"
+				}
+			}`)
+		}
+
+		Convey("Given a GitHub webhook registration", func() {
+			id, _ := uuid.NewRandom()
+			reg := &webhook.Registration{
+				Entity:     entity.Entity{ID: id},
+				Name:       "vpc",
+				Provider:   "aws",
+				Kind:       vcs.GitHub,
+				Repository: "acme/terraform-aws-vpc",
+				Secret:     "s3cr3t",
+			}
+
+			mockWebhookRepository.
+				On("FindRegistration", id).
+				Return(reg, nil)
+
+			mockWebhookRepository.
+				On("UpsertDelivery", mock.AnythingOfType("webhook.Delivery")).
+				Return(func(d webhook.Delivery) *webhook.Delivery {
+					return &d
+				}, nil).
+				Maybe()
+
+			var recorded *webhook.Delivery
+			mockWebhookRepository.
+				On("FindDelivery", id, "72d3162e", mock.AnythingOfType("string")).
+				Return(func(uuid.UUID, string, string) (*webhook.Delivery, error) {
+					if recorded == nil {
+						return nil, repositories.ErrNotFound
+					}
+
+					return recorded, nil
+				}).
+				Maybe()
+
+			mockWebhookRepository.
+				On("PruneDeliveries", id, webhookDeliveriesRetained).
+				Return(nil).
+				Maybe()
+
+			header := func(body []byte) http.Header {
+				return http.Header{
+					"X-Github-Event":      {"push"},
+					"X-Github-Delivery":   {"72d3162e"},
+					"X-Hub-Signature-256": {sign(reg.Secret, body)},
+				}
+			}
+
+			Convey("If a semantic version tag is pushed", func() {
+				body := pushTag("acme/terraform-aws-vpc", "v1.2.0")
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, header(body), body)
+
+					Convey("A pending delivery should be recorded", func() {
+						So(err, ShouldBeNil)
+						So(deliveries, ShouldHaveLength, 1)
+						So(deliveries[0].Status, ShouldEqual, webhook.DeliveryPending)
+						So(deliveries[0].Version, ShouldEqual, "1.2.0")
+						So(deliveries[0].DeliveryID, ShouldEqual, "72d3162e")
+						So(deliveries[0].Source, ShouldEqual, "git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.2.0")
+					})
+				})
+			})
+
+			Convey("If the event holds another clone URL", func() {
+				body := []byte(`{
+					"ref": "refs/tags/v1.2.0",
+					"after": "abc123",
+					"repository": {
+						"full_name": "acme/terraform-aws-vpc",
+						"clone_url": "file:///etc"
+					}
+				}`)
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, header(body), body)
+
+					Convey("The tag should be cloned from the registered repository", func() {
+						So(err, ShouldBeNil)
+						So(deliveries, ShouldHaveLength, 1)
+						So(deliveries[0].Source, ShouldEqual, "git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.2.0")
+					})
+				})
+			})
+
+			Convey("If a published tag is delivered again", func() {
+				body := pushTag("acme/terraform-aws-vpc", "v1.2.0")
+				recorded = &webhook.Delivery{
+					RegistrationID: id,
+					DeliveryID:     "72d3162e",
+					Tag:            "v1.2.0",
+					Status:         webhook.DeliverySucceeded,
+				}
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, header(body), body)
+
+					Convey("The tag should not be published again", func() {
+						So(err, ShouldBeNil)
+						So(deliveries, ShouldBeEmpty)
+						mockWebhookRepository.AssertNotCalled(t, "UpsertDelivery", mock.Anything)
+					})
+				})
+			})
+
+			Convey("If a failed tag is delivered again", func() {
+				body := pushTag("acme/terraform-aws-vpc", "v1.2.0")
+				deliveryID, _ := uuid.NewRandom()
+				recorded = &webhook.Delivery{
+					Entity:         entity.Entity{ID: deliveryID},
+					RegistrationID: id,
+					DeliveryID:     "72d3162e",
+					Tag:            "v1.2.0",
+					Status:         webhook.DeliveryFailed,
+					Error:          "version already exists",
+				}
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, header(body), body)
+
+					Convey("The recorded delivery should be retried", func() {
+						So(err, ShouldBeNil)
+						So(deliveries, ShouldHaveLength, 1)
+						So(deliveries[0].ID, ShouldEqual, deliveryID)
+						So(deliveries[0].Status, ShouldEqual, webhook.DeliveryPending)
+						So(deliveries[0].Error, ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("If a tag which is not a semantic version is pushed", func() {
+				body := pushTag("acme/terraform-aws-vpc", "latest")
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, header(body), body)
+
+					Convey("The delivery should be ignored", func() {
+						So(err, ShouldBeNil)
+						So(deliveries, ShouldHaveLength, 1)
+						So(deliveries[0].Status, ShouldEqual, webhook.DeliveryIgnored)
+						So(deliveries[0].Version, ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("If a tag is pushed to another repository", func() {
+				body := pushTag("evil/terraform-aws-vpc", "v1.2.0")
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, header(body), body)
+
+					Convey("The delivery should fail", func() {
+						So(err, ShouldBeNil)
+						So(deliveries, ShouldHaveLength, 1)
+						So(deliveries[0].Status, ShouldEqual, webhook.DeliveryFailed)
+						So(deliveries[0].Source, ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("If the request is signed with another secret", func() {
+				body := pushTag("acme/terraform-aws-vpc", "v1.2.0")
+				h := header(body)
+				h.Set("X-Hub-Signature-256", sign("other", body))
+
+				Convey("When the webhook is received", func() {
+					deliveries, err := webhookService.Receive(id, h, body)
+
+					Convey("The request should be rejected without recording a delivery", func() {
+						So(errors.Is(err, vcs.ErrInvalidSignature), ShouldBeTrue)
+						So(deliveries, ShouldBeNil)
+						mockWebhookRepository.AssertNotCalled(t, "UpsertDelivery", mock.Anything)
+					})
+				})
+			})
+		})
+
+		Convey("Given an unknown webhook registration", func() {
+			id, _ := uuid.NewRandom()
+
+			mockWebhookRepository.
+				On("FindRegistration", id).
+				Return(nil, repositories.ErrNotFound)
+
+			Convey("When the webhook is received", func() {
+				_, err := webhookService.Receive(id, http.Header{}, []byte(`{}`))
+
+				Convey("The registration should not be found", func() {
+					So(errors.Is(err, ErrWebhookNotFound), ShouldBeTrue)
+				})
+			})
+		})
+	})
+}
+
+func TestPublishWebhookDelivery(t *testing.T) {
+	Convey("Subject: Publish a module version from a webhook delivery", t, func() {
+		mockWebhookRepository := repositories.NewMockWebhookRepository(t)
+		mockModuleService := NewMockModuleService(t)
+
+		webhookService := &DefaultWebhookService{
+			WebhookRepository: mockWebhookRepository,
+			ModuleService:     mockModuleService,
+		}
+
+		Convey("Given a pending delivery", func() {
+			registrationID, _ := uuid.NewRandom()
+			authorityID, _ := uuid.NewRandom()
+
+			mockWebhookRepository.
+				On("FindRegistration", registrationID).
+				Return(&webhook.Registration{
+					Entity:      entity.Entity{ID: registrationID},
+					AuthorityID: authorityID,
+					Name:        "vpc",
+					Provider:    "aws",
+				}, nil).
+				Maybe()
+
+			delivery := &webhook.Delivery{
+				RegistrationID: registrationID,
+				Tag:            "v1.2.0",
+				Version:        "1.2.0",
+				Commit:         "6DCB09B5B57875F334F61AEBED695E2E4193DB5E",
+				Source:         "git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.2.0",
+				Status:         webhook.DeliveryPending,
+			}
+
+			isModuleVersion := mock.MatchedBy(func(d *module.CreateDTO) bool {
+				return d.AuthorityID == authorityID && d.Name == "vpc" && d.Provider == "aws" && d.Version == "1.2.0"
+			})
+
+			Convey("If the module version is uploaded", func() {
+				var uploaded *module.CreateDTO
+				mockModuleService.
+					On("UploadContext", mock.Anything, isModuleVersion, delivery.Source, http.Header{}).
+					Run(func(args mock.Arguments) {
+						uploaded = args.Get(1).(*module.CreateDTO)
+					}).
+					Return(nil)
+
+				mockWebhookRepository.
+					On("UpsertDelivery", mock.MatchedBy(func(d webhook.Delivery) bool {
+						return d.Status == webhook.DeliverySucceeded
+					})).
+					Return(delivery, nil)
+
+				Convey("When the delivery is published", func() {
+					webhookService.Publish(context.Background(), delivery)
+
+					Convey("The delivery should succeed", func() {
+						So(delivery.Status, ShouldEqual, webhook.DeliverySucceeded)
+						So(delivery.Error, ShouldBeEmpty)
+					})
+
+					Convey("The provenance of the version should identify the webhook", func() {
+						So(uploaded.Provenance.AuthMethod, ShouldEqual, "webhook")
+						So(uploaded.Provenance.PublishedBy, ShouldEqual, "webhook:"+registrationID.String())
+						So(uploaded.Provenance.Source, ShouldEqual, "git::https://github.com/acme/terraform-aws-vpc.git?ref=v1.2.0")
+						So(uploaded.Provenance.CommitSHA, ShouldEqual, "6dcb09b5b57875f334f61aebed695e2e4193db5e")
+					})
+				})
+			})
+
+			Convey("If the module version cannot be uploaded", func() {
+				mockModuleService.
+					On("UploadContext", mock.Anything, isModuleVersion, delivery.Source, http.Header{}).
+					Return(errors.New("version already exists"))
+
+				mockWebhookRepository.
+					On("UpsertDelivery", mock.MatchedBy(func(d webhook.Delivery) bool {
+						return d.Status == webhook.DeliveryFailed
+					})).
+					Return(delivery, nil)
+
+				Convey("When the delivery is published", func() {
+					webhookService.Publish(context.Background(), delivery)
+
+					Convey("The delivery should fail with the upload error", func() {
+						So(delivery.Status, ShouldEqual, webhook.DeliveryFailed)
+						So(delivery.Error, ShouldEqual, "version already exists")
+					})
+				})
+			})
+
+			Convey("If the publication is canceled", func() {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(ErrWebhookPublisherStopped)
+
+				mockWebhookRepository.
+					On("UpsertDelivery", mock.MatchedBy(func(d webhook.Delivery) bool {
+						return d.Status == webhook.DeliveryFailed
+					})).
+					Return(delivery, nil)
+
+				Convey("When the delivery is published", func() {
+					webhookService.Publish(ctx, delivery)
+
+					Convey("The delivery should fail with the cancellation cause", func() {
+						So(delivery.Status, ShouldEqual, webhook.DeliveryFailed)
+						So(delivery.Error, ShouldEqual, ErrWebhookPublisherStopped.Error())
+						mockModuleService.AssertNotCalled(t, "UploadContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	Convey("Subject: Publish webhook deliveries in background", t, func() {
+		mockWebhookService := NewMockWebhookService(t)
+
+		delivery := &webhook.Delivery{
+			Tag:     "v1.2.0",
+			Version: "1.2.0",
+			Status:  webhook.DeliveryPending,
+		}
+
+		Convey("Given a running publisher", func() {
+			publisher := NewWebhookPublisher(mockWebhookService, 1, 1, time.Minute)
+			defer publisher.Stop()
+
+			published := make(chan error, 1)
+			mockWebhookService.
+				On("Publish", mock.Anything, mock.AnythingOfType("*webhook.Delivery")).
+				Run(func(args mock.Arguments) {
+					published <- args.Get(0).(context.Context).Err()
+				}).
+				Once()
+
+			Convey("When a delivery is enqueued", func() {
+				err := publisher.Enqueue(delivery)
+
+				Convey("It should be published by a worker", func() {
+					So(err, ShouldBeNil)
+					So(<-published, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("Given a publisher whose queue is full", func() {
+			publisher := NewWebhookPublisher(mockWebhookService, 0, 1, time.Minute)
+			defer publisher.Stop()
+
+			mockWebhookService.
+				On("Publish", mock.Anything, mock.AnythingOfType("*webhook.Delivery")).
+				Maybe()
+
+			So(publisher.Enqueue(&webhook.Delivery{Status: webhook.DeliveryPending}), ShouldBeNil)
+
+			Convey("When a delivery is enqueued", func() {
+				err := publisher.Enqueue(delivery)
+
+				Convey("It should be rejected", func() {
+					So(err, ShouldEqual, ErrWebhookQueueFull)
+					mockWebhookService.AssertCalled(t, "Publish", mock.MatchedBy(func(ctx context.Context) bool {
+						return errors.Is(context.Cause(ctx), ErrWebhookQueueFull)
+					}), delivery)
+				})
+			})
+		})
+
+		Convey("Given a stopped publisher", func() {
+			publisher := NewWebhookPublisher(mockWebhookService, 1, 1, time.Minute)
+			publisher.Stop()
+
+			mockWebhookService.
+				On("Publish", mock.MatchedBy(func(ctx context.Context) bool {
+					return errors.Is(context.Cause(ctx), ErrWebhookPublisherStopped)
+				}), delivery).
+				Once()
+
+			Convey("When a delivery is enqueued", func() {
+				err := publisher.Enqueue(delivery)
+
+				Convey("It should be rejected", func() {
+					So(err, ShouldEqual, ErrWebhookPublisherStopped)
+				})
+			})
+		})
+	})
+}
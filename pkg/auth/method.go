@@ -0,0 +1,19 @@
+package auth
+
+// Method is the way a user authenticated a request.
+type Method string
+
+const (
+	// MethodSession is used by the users logged in the web interface.
+	MethodSession Method = "session"
+
+	// MethodCLIToken is used by the users logged in with `terraform login`.
+	MethodCLIToken Method = "cli_token"
+
+	// MethodApiKey is used by the API keys.
+	MethodApiKey Method = "api_key"
+
+	// MethodWebhook is used by the versions published by a webhook, which
+	// is authenticated by its secret.
+	MethodWebhook Method = "webhook"
+)
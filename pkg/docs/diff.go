@@ -0,0 +1,422 @@
+package docs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"terralist/pkg/file"
+	"terralist/pkg/version"
+)
+
+// ChangeType describes how an element of a module interface changed between
+// two versions.
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "changed"
+)
+
+// Change is a change of a single element (variable, output, provider or
+// submodule) of a module interface.
+type Change struct {
+	Name string
+	Type ChangeType
+
+	// Breaking is true if the callers of the module may have to be updated
+	// to keep working with the new version
+	Breaking bool
+
+	// Details lists what changed, for modified elements
+	Details []string
+}
+
+// SubmoduleChange is a change of a submodule, along with the changes of its
+// interface when the submodule exists in both versions.
+type SubmoduleChange struct {
+	Change
+	Diff *InterfaceDiff
+}
+
+// InterfaceDiff holds the changes between the interfaces of two versions of a
+// module.
+type InterfaceDiff struct {
+	Variables  []Change
+	Outputs    []Change
+	Providers  []Change
+	Submodules []SubmoduleChange
+}
+
+// Breaking returns true if any of the changes is breaking.
+func (d *InterfaceDiff) Breaking() bool {
+	for _, changes := range [][]Change{d.Variables, d.Outputs, d.Providers} {
+		if slices.ContainsFunc(changes, func(c Change) bool { return c.Breaking }) {
+			return true
+		}
+	}
+
+	return slices.ContainsFunc(d.Submodules, func(c SubmoduleChange) bool { return c.Breaking })
+}
+
+// Empty returns true if the interfaces are the same.
+func (d *InterfaceDiff) Empty() bool {
+	return len(d.Variables) == 0 && len(d.Outputs) == 0 && len(d.Providers) == 0 && len(d.Submodules) == 0
+}
+
+// BreakingChange is a breaking change of a module interface, as reported to
+// the publishers of the module.
+type BreakingChange struct {
+	// Element is the kind of the changed element: variable, output,
+	// provider or submodule
+	Element string `json:"element"`
+
+	// Submodule is the path of the submodule declaring the element, empty
+	// for the root module
+	Submodule string     `json:"submodule,omitempty"`
+	Name      string     `json:"name"`
+	Change    ChangeType `json:"change"`
+	Details   []string   `json:"details,omitempty"`
+}
+
+// BreakingChanges lists the breaking changes, including the ones of the
+// submodules interfaces.
+func (d *InterfaceDiff) BreakingChanges() []BreakingChange {
+	return d.breakingChanges("")
+}
+
+func (d *InterfaceDiff) breakingChanges(submodule string) []BreakingChange {
+	var out []BreakingChange
+
+	collect := func(element string, changes []Change) {
+		for _, c := range changes {
+			if !c.Breaking {
+				continue
+			}
+
+			out = append(out, BreakingChange{
+				Element:   element,
+				Submodule: submodule,
+				Name:      c.Name,
+				Change:    c.Type,
+				Details:   c.Details,
+			})
+		}
+	}
+
+	collect("variable", d.Variables)
+	collect("output", d.Outputs)
+	collect("provider", d.Providers)
+
+	for _, sm := range d.Submodules {
+		if !sm.Breaking {
+			continue
+		}
+
+		// The changes of a submodule present in both versions are reported
+		// instead of the submodule itself
+		if sm.Diff != nil {
+			out = append(out, sm.Diff.breakingChanges(sm.Name)...)
+			continue
+		}
+
+		out = append(out, BreakingChange{
+			Element: "submodule",
+			Name:    sm.Name,
+			Change:  sm.Type,
+		})
+	}
+
+	return out
+}
+
+// ModuleDefinition holds the interfaces of a module and of its submodules.
+type ModuleDefinition struct {
+	Root *ModuleInterface
+
+	// Submodules maps the path of each submodule to its interface
+	Submodules map[string]*ModuleInterface
+}
+
+// InspectArchive extracts the interfaces of the root module and of the
+// submodules of a module archive.
+func InspectArchive(moduleFS *file.FS) (*ModuleDefinition, error) {
+	root, err := InspectModule(moduleFS, "")
+	if err != nil {
+		return nil, err
+	}
+
+	submodules, err := FindSubmodules(moduleFS)
+	if err != nil {
+		return nil, err
+	}
+
+	def := &ModuleDefinition{
+		Root:       root,
+		Submodules: make(map[string]*ModuleInterface, len(submodules)),
+	}
+
+	for _, sm := range submodules {
+		iface, err := InspectModule(moduleFS, sm.Path)
+		if err != nil {
+			// Submodules without Terraform files have an empty interface
+			iface = &ModuleInterface{}
+		}
+
+		def.Submodules[sm.Path] = iface
+	}
+
+	return def, nil
+}
+
+// DiffModules compares the interfaces of two versions of a module.
+func DiffModules(from, to *ModuleDefinition) *InterfaceDiff {
+	d := DiffInterfaces(from.Root, to.Root)
+
+	for _, p := range sortedKeys(from.Submodules, to.Submodules) {
+		lhs, inFrom := from.Submodules[p]
+		rhs, inTo := to.Submodules[p]
+
+		switch {
+		case !inTo:
+			// Callers referencing the submodule can no longer use it
+			d.Submodules = append(d.Submodules, SubmoduleChange{
+				Change: Change{Name: p, Type: ChangeRemoved, Breaking: true},
+			})
+		case !inFrom:
+			d.Submodules = append(d.Submodules, SubmoduleChange{
+				Change: Change{Name: p, Type: ChangeAdded},
+			})
+		default:
+			sd := DiffInterfaces(lhs, rhs)
+			if sd.Empty() {
+				continue
+			}
+
+			d.Submodules = append(d.Submodules, SubmoduleChange{
+				Change: Change{Name: p, Type: ChangeModified, Breaking: sd.Breaking()},
+				Diff:   sd,
+			})
+		}
+	}
+
+	return d
+}
+
+// DiffInterfaces compares the variables, outputs and required providers of
+// two module interfaces.
+func DiffInterfaces(from, to *ModuleInterface) *InterfaceDiff {
+	d := &InterfaceDiff{}
+
+	d.Variables = diffElements(
+		from.Variables,
+		to.Variables,
+		func(v Variable) string { return v.Name },
+		func(v Variable) bool {
+			// New variables must be set by the callers, unless they have a
+			// default value
+			return v.Required
+		},
+		diffVariable,
+	)
+
+	d.Outputs = diffElements(
+		from.Outputs,
+		to.Outputs,
+		func(o Output) string { return o.Name },
+		func(Output) bool { return false },
+		diffOutput,
+	)
+
+	d.Providers = diffElements(
+		from.RequiredProviders,
+		to.RequiredProviders,
+		func(p RequiredProvider) string { return p.Name },
+		func(RequiredProvider) bool { return false },
+		diffProvider,
+	)
+
+	return d
+}
+
+// diffElements matches the elements of two lists by name and reports the
+// added, removed and changed ones. Removed elements are always breaking.
+func diffElements[T any](
+	from, to []T,
+	name func(T) string,
+	breakingAddition func(T) bool,
+	diff func(lhs, rhs T) ([]string, bool),
+) []Change {
+	lhs := make(map[string]T, len(from))
+	for _, e := range from {
+		lhs[name(e)] = e
+	}
+
+	rhs := make(map[string]T, len(to))
+	for _, e := range to {
+		rhs[name(e)] = e
+	}
+
+	var changes []Change
+	for _, n := range sortedKeys(lhs, rhs) {
+		l, inFrom := lhs[n]
+		r, inTo := rhs[n]
+
+		switch {
+		case !inTo:
+			changes = append(changes, Change{Name: n, Type: ChangeRemoved, Breaking: true})
+		case !inFrom:
+			changes = append(changes, Change{Name: n, Type: ChangeAdded, Breaking: breakingAddition(r)})
+		default:
+			details, breaking := diff(l, r)
+			if len(details) == 0 {
+				continue
+			}
+
+			changes = append(changes, Change{Name: n, Type: ChangeModified, Breaking: breaking, Details: details})
+		}
+	}
+
+	return changes
+}
+
+func diffVariable(lhs, rhs Variable) ([]string, bool) {
+	var details []string
+	breaking := false
+
+	if lhs.Type != rhs.Type {
+		details = append(details, fmt.Sprintf("type changed from %s to %s", orNone(lhs.Type), orNone(rhs.Type)))
+		breaking = true
+	}
+
+	switch {
+	case !lhs.Required && rhs.Required:
+		details = append(details, "the variable is now required")
+		breaking = true
+	case lhs.Required && !rhs.Required:
+		details = append(details, "the variable is no longer required")
+	}
+
+	// The default values of required variables are always empty
+	if !lhs.Required && !rhs.Required && deref(lhs.Default) != deref(rhs.Default) {
+		details = append(details, fmt.Sprintf("default changed from %s to %s", orNone(deref(lhs.Default)), orNone(deref(rhs.Default))))
+	}
+
+	return details, breaking
+}
+
+func diffOutput(lhs, rhs Output) ([]string, bool) {
+	switch {
+	case !lhs.Sensitive && rhs.Sensitive:
+		// Callers using the output in non-sensitive contexts will fail
+		return []string{"the output is now sensitive"}, true
+	case lhs.Sensitive && !rhs.Sensitive:
+		return []string{"the output is no longer sensitive"}, false
+	}
+
+	return nil, false
+}
+
+func diffProvider(lhs, rhs RequiredProvider) ([]string, bool) {
+	var details []string
+
+	if lhs.Source != rhs.Source {
+		details = append(details, fmt.Sprintf("source changed from %s to %s", orNone(lhs.Source), orNone(rhs.Source)))
+	}
+
+	// A new source may conflict with the providers used by the callers
+	breaking := len(details) > 0
+
+	if detail, narrowed := diffConstraints(lhs.VersionConstraints, rhs.VersionConstraints); detail != "" {
+		details = append(details, detail)
+		breaking = breaking || narrowed
+	}
+
+	return details, breaking
+}
+
+// diffConstraints compares the version constraints of a provider. The change
+// is breaking if the new constraints exclude versions the callers may use,
+// i.e. ones allowed by the previous constraints.
+func diffConstraints(lhs, rhs []string) (string, bool) {
+	lc := strings.Join(lhs, ", ")
+	rc := strings.Join(rhs, ", ")
+	if lc == rc {
+		return "", false
+	}
+
+	lcs, lerr := parseConstraints(lc)
+	rcs, rerr := parseConstraints(rc)
+	if lerr != nil || rerr != nil {
+		return fmt.Sprintf("version constraints changed from %s to %s", orNone(lc), orNone(rc)), true
+	}
+
+	// The same constraints in a different order or notation
+	if normalizeConstraints(lcs) == normalizeConstraints(rcs) {
+		return "", false
+	}
+
+	if !rcs.Includes(lcs) {
+		return fmt.Sprintf("version constraints changed from %s to %s", orNone(lc), orNone(rc)), true
+	}
+
+	if !lcs.Includes(rcs) {
+		return fmt.Sprintf("version constraints widened from %s to %s", orNone(lc), orNone(rc)), false
+	}
+
+	return fmt.Sprintf("version constraints rewritten from %s to %s", orNone(lc), orNone(rc)), false
+}
+
+// parseConstraints parses the version constraints of a provider. A provider
+// without constraints allows any version.
+func parseConstraints(s string) (version.Constraints, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	return version.ParseConstraints(s)
+}
+
+// normalizeConstraints returns the sorted normalized constraints, without
+// duplicates.
+func normalizeConstraints(cs version.Constraints) string {
+	raw := strings.Split(cs.String(), ", ")
+	slices.Sort(raw)
+
+	return strings.Join(slices.Compact(raw), ", ")
+}
+
+// sortedKeys returns the union of the keys of two maps, sorted.
+func sortedKeys[T any](lhs, rhs map[string]T) []string {
+	keys := make([]string, 0, len(lhs)+len(rhs))
+	for k := range lhs {
+		keys = append(keys, k)
+	}
+
+	for k := range rhs {
+		if _, ok := lhs[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
@@ -0,0 +1,315 @@
+package docs
+
+import (
+	"testing"
+
+	"terralist/pkg/file"
+)
+
+func TestDiffInterfaces(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		from     *ModuleInterface
+		to       *ModuleInterface
+		expected []Change
+	}{
+		{
+			name: "Same interface",
+			from: &ModuleInterface{
+				Variables: []Variable{{Name: "cidr", Type: "string", Default: str(`"10.0.0.0/16"`)}},
+				Outputs:   []Output{{Name: "vpc_id"}},
+			},
+			to: &ModuleInterface{
+				Variables: []Variable{{Name: "cidr", Type: "string", Default: str(`"10.0.0.0/16"`)}},
+				Outputs:   []Output{{Name: "vpc_id"}},
+			},
+			expected: nil,
+		},
+		{
+			name: "Optional variable added",
+			from: &ModuleInterface{},
+			to: &ModuleInterface{
+				Variables: []Variable{{Name: "tags", Type: "map(string)", Default: str(`{}`)}},
+			},
+			expected: []Change{{Name: "tags", Type: ChangeAdded, Breaking: false}},
+		},
+		{
+			name: "Required variable added",
+			from: &ModuleInterface{},
+			to: &ModuleInterface{
+				Variables: []Variable{{Name: "name", Type: "string", Required: true}},
+			},
+			expected: []Change{{Name: "name", Type: ChangeAdded, Breaking: true}},
+		},
+		{
+			name: "Variable removed",
+			from: &ModuleInterface{
+				Variables: []Variable{{Name: "name", Type: "string", Required: true}},
+			},
+			to:       &ModuleInterface{},
+			expected: []Change{{Name: "name", Type: ChangeRemoved, Breaking: true}},
+		},
+		{
+			name: "Variable type changed",
+			from: &ModuleInterface{
+				Variables: []Variable{{Name: "azs", Type: "string", Required: true}},
+			},
+			to: &ModuleInterface{
+				Variables: []Variable{{Name: "azs", Type: "list(string)", Required: true}},
+			},
+			expected: []Change{{
+				Name:     "azs",
+				Type:     ChangeModified,
+				Breaking: true,
+				Details:  []string{"type changed from string to list(string)"},
+			}},
+		},
+		{
+			name: "Variable default changed",
+			from: &ModuleInterface{
+				Variables: []Variable{{Name: "cidr", Type: "string", Default: str(`"10.0.0.0/16"`)}},
+			},
+			to: &ModuleInterface{
+				Variables: []Variable{{Name: "cidr", Type: "string", Default: str(`"10.1.0.0/16"`)}},
+			},
+			expected: []Change{{
+				Name:     "cidr",
+				Type:     ChangeModified,
+				Breaking: false,
+				Details:  []string{`default changed from "10.0.0.0/16" to "10.1.0.0/16"`},
+			}},
+		},
+		{
+			name: "Variable became required",
+			from: &ModuleInterface{
+				Variables: []Variable{{Name: "cidr", Type: "string", Default: str(`"10.0.0.0/16"`)}},
+			},
+			to: &ModuleInterface{
+				Variables: []Variable{{Name: "cidr", Type: "string", Required: true}},
+			},
+			expected: []Change{{
+				Name:     "cidr",
+				Type:     ChangeModified,
+				Breaking: true,
+				Details:  []string{"the variable is now required"},
+			}},
+		},
+		{
+			name: "Output removed and added",
+			from: &ModuleInterface{
+				Outputs: []Output{{Name: "id"}},
+			},
+			to: &ModuleInterface{
+				Outputs: []Output{{Name: "vpc_id"}},
+			},
+			expected: []Change{
+				{Name: "id", Type: ChangeRemoved, Breaking: true},
+				{Name: "vpc_id", Type: ChangeAdded, Breaking: false},
+			},
+		},
+		{
+			name: "Output became sensitive",
+			from: &ModuleInterface{
+				Outputs: []Output{{Name: "password"}},
+			},
+			to: &ModuleInterface{
+				Outputs: []Output{{Name: "password", Sensitive: true}},
+			},
+			expected: []Change{{
+				Name:     "password",
+				Type:     ChangeModified,
+				Breaking: true,
+				Details:  []string{"the output is now sensitive"},
+			}},
+		},
+		{
+			name: "Provider constraints changed",
+			from: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 4.0"}}},
+			},
+			to: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 5.0"}}},
+			},
+			expected: []Change{{
+				Name:     "aws",
+				Type:     ChangeModified,
+				Breaking: true,
+				Details:  []string{"version constraints changed from >= 4.0 to >= 5.0"},
+			}},
+		},
+		{
+			name: "Provider constraints widened",
+			from: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 4.0"}}},
+			},
+			to: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 3.0"}}},
+			},
+			expected: []Change{{
+				Name:     "aws",
+				Type:     ChangeModified,
+				Breaking: false,
+				Details:  []string{"version constraints widened from >= 4.0 to >= 3.0"},
+			}},
+		},
+		{
+			name: "Provider constraints upper bound raised",
+			from: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{"~> 4.0"}}},
+			},
+			to: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 4.0", "< 6.0"}}},
+			},
+			expected: []Change{{
+				Name:     "aws",
+				Type:     ChangeModified,
+				Breaking: false,
+				Details:  []string{"version constraints widened from ~> 4.0 to >= 4.0, < 6.0"},
+			}},
+		},
+		{
+			name: "Provider constraints reordered",
+			from: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 4.0", "< 6.0"}}},
+			},
+			to: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{"< 6.0", ">=4.0"}}},
+			},
+			expected: nil,
+		},
+		{
+			name: "Provider constraints rewritten",
+			from: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{"~> 4.0"}}},
+			},
+			to: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 4.0, < 5.0"}}},
+			},
+			expected: []Change{{
+				Name:     "aws",
+				Type:     ChangeModified,
+				Breaking: false,
+				Details:  []string{"version constraints rewritten from ~> 4.0 to >= 4.0, < 5.0"},
+			}},
+		},
+		{
+			name: "Provider constraints added",
+			from: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws"}},
+			},
+			to: &ModuleInterface{
+				RequiredProviders: []RequiredProvider{{Name: "aws", Source: "hashicorp/aws", VersionConstraints: []string{">= 5.0"}}},
+			},
+			expected: []Change{{
+				Name:     "aws",
+				Type:     ChangeModified,
+				Breaking: true,
+				Details:  []string{"version constraints changed from none to >= 5.0"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffInterfaces(tt.from, tt.to)
+
+			var changes []Change
+			changes = append(changes, d.Variables...)
+			changes = append(changes, d.Outputs...)
+			changes = append(changes, d.Providers...)
+
+			if len(changes) != len(tt.expected) {
+				t.Fatalf("Expected %d changes, got %d: %+v", len(tt.expected), len(changes), changes)
+			}
+
+			for i, expected := range tt.expected {
+				got := changes[i]
+
+				if got.Name != expected.Name || got.Type != expected.Type || got.Breaking != expected.Breaking {
+					t.Errorf("Expected change %+v, got %+v", expected, got)
+				}
+
+				if len(got.Details) != len(expected.Details) {
+					t.Errorf("Expected details %v, got %v", expected.Details, got.Details)
+					continue
+				}
+
+				for j := range expected.Details {
+					if got.Details[j] != expected.Details[j] {
+						t.Errorf("Expected details %v, got %v", expected.Details, got.Details)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDiffModules(t *testing.T) {
+	from, err := InspectArchive(file.MustNewFS([]file.File{
+		file.NewInMemoryFile("main.tf", []byte(`variable "name" { type = string }`)),
+		file.NewInMemoryFile("modules/subnets/main.tf", []byte(`variable "cidrs" { type = list(string) }`)),
+		file.NewInMemoryFile("modules/endpoints/main.tf", []byte(`variable "services" { type = list(string) }`)),
+	}))
+	if err != nil {
+		t.Fatalf("InspectArchive() returned unexpected error: %v", err)
+	}
+
+	to, err := InspectArchive(file.MustNewFS([]file.File{
+		file.NewInMemoryFile("main.tf", []byte(`variable "name" { type = string }`)),
+		file.NewInMemoryFile("modules/subnets/main.tf", []byte(`variable "cidrs" { type = set(string) }`)),
+		file.NewInMemoryFile("modules/flow-logs/main.tf", []byte(`variable "bucket" { default = "" }`)),
+	}))
+	if err != nil {
+		t.Fatalf("InspectArchive() returned unexpected error: %v", err)
+	}
+
+	d := DiffModules(from, to)
+
+	if len(d.Variables) != 0 {
+		t.Errorf("Expected no root variable change, got %+v", d.Variables)
+	}
+
+	if !d.Breaking() {
+		t.Error("Expected the diff to be breaking")
+	}
+
+	expected := []struct {
+		name     string
+		typ      ChangeType
+		breaking bool
+	}{
+		{"modules/endpoints", ChangeRemoved, true},
+		{"modules/flow-logs", ChangeAdded, false},
+		{"modules/subnets", ChangeModified, true},
+	}
+
+	if len(d.Submodules) != len(expected) {
+		t.Fatalf("Expected %d submodule changes, got %d: %+v", len(expected), len(d.Submodules), d.Submodules)
+	}
+
+	for i, e := range expected {
+		got := d.Submodules[i]
+		if got.Name != e.name || got.Type != e.typ || got.Breaking != e.breaking {
+			t.Errorf("Expected submodule change %s %s (breaking: %v), got %+v", e.name, e.typ, e.breaking, got.Change)
+		}
+	}
+
+	if d.Submodules[2].Diff == nil || len(d.Submodules[2].Diff.Variables) != 1 {
+		t.Errorf("Expected the interface changes of the submodule, got %+v", d.Submodules[2].Diff)
+	}
+
+	breaking := d.BreakingChanges()
+	if len(breaking) != 2 {
+		t.Fatalf("Expected 2 breaking changes, got %d: %+v", len(breaking), breaking)
+	}
+
+	if breaking[0].Element != "submodule" || breaking[0].Name != "modules/endpoints" {
+		t.Errorf("Expected the removed submodule to be reported, got %+v", breaking[0])
+	}
+
+	if breaking[1].Element != "variable" || breaking[1].Submodule != "modules/subnets" || breaking[1].Name != "cidrs" {
+		t.Errorf("Expected the changed submodule variable to be reported, got %+v", breaking[1])
+	}
+}
@@ -0,0 +1,153 @@
+package docs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"terralist/pkg/file"
+)
+
+const (
+	// examplesDir is the conventional directory name used by the Terraform
+	// community for usage samples of a module.
+	// See: https://developer.hashicorp.com/terraform/language/modules/develop/structure
+	examplesDir = "examples"
+
+	// tfSourceExt is the extension of the Terraform source files kept for
+	// each example.
+	tfSourceExt = ".tf"
+)
+
+// ExampleInfo contains information about a discovered example.
+type ExampleInfo struct {
+	Path          string
+	Documentation string
+	Files         []ExampleFile
+}
+
+// ExampleFile is a Terraform source file of an example.
+type ExampleFile struct {
+	Name    string
+	Content string
+}
+
+// FindExamples scans the module filesystem for usage examples. Each directory
+// within an "examples" directory which contains Terraform source files is an
+// example. The path of an example starts at its "examples" directory, so
+// archives wrapping the module in a top-level folder are handled the same way.
+func FindExamples(moduleFS *file.FS) ([]ExampleInfo, error) {
+	// Archives may contain the same example more than once (e.g. vendored
+	// modules), so the source files are grouped by directory first
+	dirs := make(map[string]map[string][]string)
+
+	if err := moduleFS.Walk("./", func(p string, fi fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() || path.Ext(p) != tfSourceExt {
+			return nil
+		}
+
+		normalizedPath := strings.TrimPrefix(p, "./")
+		parts := strings.Split(normalizedPath, "/")
+
+		exampleRootIndex := slices.Index(parts, examplesDir)
+		if exampleRootIndex == -1 {
+			return nil
+		}
+
+		// Files placed directly in the "examples" directory do not belong
+		// to any example
+		relativeParts := parts[exampleRootIndex:]
+		if len(relativeParts) < 3 {
+			return nil
+		}
+
+		examplePath := strings.Join(relativeParts[:len(relativeParts)-1], "/")
+		if dirs[examplePath] == nil {
+			dirs[examplePath] = make(map[string][]string)
+		}
+
+		dir := path.Dir(normalizedPath)
+		dirs[examplePath][dir] = append(dirs[examplePath][dir], normalizedPath)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	examplePaths := make([]string, 0, len(dirs))
+	for examplePath := range dirs {
+		examplePaths = append(examplePaths, examplePath)
+	}
+	slices.Sort(examplePaths)
+
+	examples := make([]ExampleInfo, 0, len(examplePaths))
+	for _, examplePath := range examplePaths {
+		// Only the shallowest copy of the example is kept
+		var exampleDir string
+		for dir := range dirs[examplePath] {
+			if exampleDir == "" || strings.Count(dir, "/") < strings.Count(exampleDir, "/") ||
+				(strings.Count(dir, "/") == strings.Count(exampleDir, "/") && dir < exampleDir) {
+				exampleDir = dir
+			}
+		}
+
+		doc, err := GetModuleDocumentation(moduleFS, exampleDir)
+		if err != nil {
+			// If we can't generate docs, provide a helpful message instead of empty string
+			if errors.Is(err, ErrNoEntrypointFound) {
+				doc = "# Documentation Not Available\n\nNo README.md or main.tf file found in this example."
+			} else {
+				doc = fmt.Sprintf("# Documentation Not Available\n\nFailed to generate documentation: %s", err.Error())
+			}
+		}
+
+		files := dirs[examplePath][exampleDir]
+		slices.Sort(files)
+
+		example := ExampleInfo{
+			Path:          examplePath,
+			Documentation: doc,
+		}
+
+		for _, f := range files {
+			content, err := readFile(moduleFS, f)
+			if err != nil {
+				return nil, fmt.Errorf("could not read example file %s: %w", f, err)
+			}
+
+			example.Files = append(example.Files, ExampleFile{
+				Name:    path.Base(f),
+				Content: content,
+			})
+		}
+
+		examples = append(examples, example)
+	}
+
+	return examples, nil
+}
+
+// readFile reads a file of the module filesystem as UTF-8.
+func readFile(moduleFS *file.FS, name string) (string, error) {
+	f, err := moduleFS.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, f); err != nil {
+		return "", err
+	}
+
+	return normalizeToUTF8(buf.Bytes())
+}
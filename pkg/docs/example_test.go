@@ -0,0 +1,153 @@
+package docs
+
+import (
+	"strings"
+	"testing"
+
+	"terralist/pkg/file"
+)
+
+func TestFindExamples(t *testing.T) {
+	tests := []struct {
+		name          string
+		fs            *file.FS
+		expectedPaths []string
+		expectedFiles map[string][]string
+	}{
+		{
+			name: "No examples directory",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`variable "test" {}`)),
+				file.NewInMemoryFile("modules/vpc/main.tf", []byte(`variable "cidr" {}`)),
+			}),
+			expectedPaths: []string{},
+		},
+		{
+			name: "Examples without Terraform files",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`variable "test" {}`)),
+				file.NewInMemoryFile("examples/README.md", []byte(`# Examples`)),
+				file.NewInMemoryFile("examples/main.tf", []byte(`module "root" {}`)),
+				file.NewInMemoryFile("examples/draft/.gitkeep", []byte(``)),
+			}),
+			expectedPaths: []string{},
+		},
+		{
+			name: "Multiple examples",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`variable "test" {}`)),
+				file.NewInMemoryFile("examples/simple/main.tf", []byte(`module "vpc" { source = "../../" }`)),
+				file.NewInMemoryFile("examples/complete/main.tf", []byte(`module "vpc" { source = "../../" }`)),
+				file.NewInMemoryFile("examples/complete/outputs.tf", []byte(`output "vpc_id" {}`)),
+				file.NewInMemoryFile("examples/complete/terraform.tfvars", []byte(`cidr = "10.0.0.0/16"`)),
+			}),
+			expectedPaths: []string{"examples/complete", "examples/simple"},
+			expectedFiles: map[string][]string{
+				"examples/complete": {"main.tf", "outputs.tf"},
+				"examples/simple":   {"main.tf"},
+			},
+		},
+		{
+			name: "Nested examples",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`variable "test" {}`)),
+				file.NewInMemoryFile("examples/networking/private/main.tf", []byte(`module "vpc" {}`)),
+				file.NewInMemoryFile("examples/networking/public/main.tf", []byte(`module "vpc" {}`)),
+			}),
+			expectedPaths: []string{"examples/networking/private", "examples/networking/public"},
+		},
+		{
+			name: "Examples discovered under archive root directory",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("terraform-aws-vpc-5.0.0/main.tf", []byte(`variable "test" {}`)),
+				file.NewInMemoryFile("terraform-aws-vpc-5.0.0/examples/simple/main.tf", []byte(`module "vpc" {}`)),
+			}),
+			expectedPaths: []string{"examples/simple"},
+			expectedFiles: map[string][]string{
+				"examples/simple": {"main.tf"},
+			},
+		},
+		{
+			name: "Only the shallowest copy of an example is kept",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`variable "test" {}`)),
+				file.NewInMemoryFile("examples/simple/main.tf", []byte(`module "vpc" {}`)),
+				file.NewInMemoryFile("modules/vpc/examples/simple/main.tf", []byte(`module "vpc" {}`)),
+				file.NewInMemoryFile("modules/vpc/examples/simple/versions.tf", []byte(`terraform {}`)),
+			}),
+			expectedPaths: []string{"examples/simple"},
+			expectedFiles: map[string][]string{
+				"examples/simple": {"main.tf"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			examples, err := FindExamples(tt.fs)
+			if err != nil {
+				t.Fatalf("FindExamples() returned unexpected error: %v", err)
+			}
+
+			if len(examples) != len(tt.expectedPaths) {
+				t.Fatalf("FindExamples() returned %d examples, expected %d", len(examples), len(tt.expectedPaths))
+			}
+
+			for i, expectedPath := range tt.expectedPaths {
+				if examples[i].Path != expectedPath {
+					t.Errorf("Expected example path %s, got %s", expectedPath, examples[i].Path)
+				}
+
+				expectedFiles, ok := tt.expectedFiles[expectedPath]
+				if !ok {
+					continue
+				}
+
+				var names []string
+				for _, f := range examples[i].Files {
+					names = append(names, f.Name)
+				}
+
+				if strings.Join(names, ",") != strings.Join(expectedFiles, ",") {
+					t.Errorf("Expected example %s files %v, got %v", expectedPath, expectedFiles, names)
+				}
+			}
+		})
+	}
+}
+
+func TestFindExamples_WithDocumentation(t *testing.T) {
+	fs := file.MustNewFS([]file.File{
+		file.NewInMemoryFile("main.tf", []byte(`variable "root_var" {}`)),
+		file.NewInMemoryFile("examples/documented/main.tf", []byte(`module "vpc" { source = "../../" }`)),
+		file.NewInMemoryFile("examples/documented/README.md", []byte("# Documented Example\n")),
+		file.NewInMemoryFile("examples/generated/main.tf", []byte(`
+			variable "region" {
+				description = "The region to deploy to"
+			}
+		`)),
+	})
+
+	examples, err := FindExamples(fs)
+	if err != nil {
+		t.Fatalf("FindExamples() returned unexpected error: %v", err)
+	}
+
+	if len(examples) != 2 {
+		t.Fatalf("Expected 2 examples, got %d", len(examples))
+	}
+
+	// Should use the README.md content
+	if examples[0].Documentation != "# Documented Example\n" {
+		t.Errorf("Expected documentation to match README.md content, got: %q", examples[0].Documentation)
+	}
+
+	// Should be generated from the Terraform files
+	if !strings.Contains(examples[1].Documentation, "Input Variables") {
+		t.Errorf("Expected generated documentation, got: %q", examples[1].Documentation)
+	}
+
+	if len(examples[0].Files) != 1 || examples[0].Files[0].Content != `module "vpc" { source = "../../" }` {
+		t.Errorf("Expected example sources to be kept as is, got: %v", examples[0].Files)
+	}
+}
@@ -0,0 +1,163 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+
+	"terralist/pkg/file"
+)
+
+// ModuleInterface describes the public interface of a Terraform module.
+type ModuleInterface struct {
+	Variables         []Variable
+	Outputs           []Output
+	RequiredProviders []RequiredProvider
+	ModuleCalls       []ModuleCall
+}
+
+// Variable is an input variable declared by a module.
+type Variable struct {
+	Name        string
+	Type        string
+	Description string
+	// Default holds the JSON encoded default value, or nil if the variable
+	// has no default.
+	Default   *string
+	Required  bool
+	Sensitive bool
+}
+
+// Output is an output value declared by a module.
+type Output struct {
+	Name        string
+	Description string
+	Sensitive   bool
+}
+
+// RequiredProvider is a provider requirement declared in the module's
+// required_providers block.
+type RequiredProvider struct {
+	Name               string
+	Namespace          string
+	Source             string
+	VersionConstraints []string
+}
+
+// ModuleCall is a module block declared by a module.
+type ModuleCall struct {
+	Name    string
+	Source  string
+	Version string
+}
+
+// findModuleRoot returns the directory holding the root module, relative to
+// the archive root. Archives are allowed to wrap the module in a top-level
+// folder, so the root is the directory of the shallowest main.tf, unless that
+// file is part of a submodule.
+func findModuleRoot(moduleFS *file.FS) string {
+	mainTfPath, err := findTopLevelFile(moduleFS, tfEntrypointFile)
+	if err != nil {
+		return "."
+	}
+
+	parts := strings.Split(path.Dir(path.Clean(mainTfPath)), "/")
+	if idx := findSubmoduleRootIndex(parts); idx != -1 {
+		parts = parts[:idx]
+	}
+
+	if len(parts) == 0 {
+		return "."
+	}
+
+	return path.Join(parts...)
+}
+
+// InspectModule loads the module located at relativePath (relative to the
+// module root, empty for the root module) and extracts its interface.
+func InspectModule(moduleFS *file.FS, relativePath string) (*ModuleInterface, error) {
+	dir := path.Join(findModuleRoot(moduleFS), relativePath)
+
+	if entries, err := moduleFS.ReadDir(dir); err != nil || len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoEntrypointFound, dir)
+	}
+
+	m, diags := tfconfig.LoadModuleFromFilesystem(tfconfig.WrapFS(moduleFS), dir)
+	if diags.HasErrors() {
+		return nil, diags.Err()
+	}
+
+	out := &ModuleInterface{}
+
+	for _, v := range m.Variables {
+		variable := Variable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Description: v.Description,
+			Required:    v.Required,
+			Sensitive:   v.Sensitive,
+		}
+
+		if v.Default != nil {
+			raw, err := json.Marshal(v.Default)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode default value of variable %s: %w", v.Name, err)
+			}
+
+			def := string(raw)
+			variable.Default = &def
+		}
+
+		out.Variables = append(out.Variables, variable)
+	}
+
+	for _, o := range m.Outputs {
+		out.Outputs = append(out.Outputs, Output{
+			Name:        o.Name,
+			Description: o.Description,
+			Sensitive:   o.Sensitive,
+		})
+	}
+
+	for name, p := range m.RequiredProviders {
+		out.RequiredProviders = append(out.RequiredProviders, RequiredProvider{
+			Name:               name,
+			Namespace:          providerNamespace(p.Source),
+			Source:             p.Source,
+			VersionConstraints: p.VersionConstraints,
+		})
+	}
+
+	for _, c := range m.ModuleCalls {
+		out.ModuleCalls = append(out.ModuleCalls, ModuleCall{
+			Name:    c.Name,
+			Source:  c.Source,
+			Version: c.Version,
+		})
+	}
+
+	// tfconfig exposes everything as maps, sort the results to keep the
+	// output deterministic
+	slices.SortFunc(out.Variables, func(lhs, rhs Variable) int { return strings.Compare(lhs.Name, rhs.Name) })
+	slices.SortFunc(out.Outputs, func(lhs, rhs Output) int { return strings.Compare(lhs.Name, rhs.Name) })
+	slices.SortFunc(out.RequiredProviders, func(lhs, rhs RequiredProvider) int { return strings.Compare(lhs.Name, rhs.Name) })
+	slices.SortFunc(out.ModuleCalls, func(lhs, rhs ModuleCall) int { return strings.Compare(lhs.Name, rhs.Name) })
+
+	return out, nil
+}
+
+// providerNamespace extracts the namespace from a provider source address
+// (e.g. hashicorp from registry.terraform.io/hashicorp/aws). Sources without
+// a namespace default to hashicorp, as Terraform does.
+func providerNamespace(source string) string {
+	parts := strings.Split(source, "/")
+	if len(parts) < 2 {
+		return "hashicorp"
+	}
+
+	return parts[len(parts)-2]
+}
@@ -0,0 +1,172 @@
+package docs
+
+import (
+	"errors"
+	"testing"
+
+	"terralist/pkg/file"
+)
+
+func TestInspectModule(t *testing.T) {
+	tests := []struct {
+		name              string
+		fs                *file.FS
+		relativePath      string
+		expectedVariables []string
+		expectedOutputs   []string
+		expectedProviders []string
+		expectedCalls     []string
+	}{
+		{
+			name: "Root module",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`
+					terraform {
+					  required_providers {
+					    aws = {
+					      source  = "hashicorp/aws"
+					      version = ">= 5.0"
+					    }
+					  }
+					}
+
+					variable "b" {}
+					variable "a" {}
+					output "id" { value = "" }
+					module "child" { source = "./modules/child" }
+				`)),
+			}),
+			relativePath:      "",
+			expectedVariables: []string{"a", "b"},
+			expectedOutputs:   []string{"id"},
+			expectedProviders: []string{"aws"},
+			expectedCalls:     []string{"child"},
+		},
+		{
+			name: "Root module wrapped in a top-level folder",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("vpc-1.0.0/main.tf", []byte(`
+					variable "cidr" {}
+				`)),
+				file.NewInMemoryFile("vpc-1.0.0/modules/subnet/main.tf", []byte(`
+					variable "zone" {}
+				`)),
+			}),
+			relativePath:      "",
+			expectedVariables: []string{"cidr"},
+		},
+		{
+			name: "Submodule wrapped in a top-level folder",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("vpc-1.0.0/main.tf", []byte(`
+					variable "cidr" {}
+				`)),
+				file.NewInMemoryFile("vpc-1.0.0/modules/subnet/main.tf", []byte(`
+					variable "zone" {}
+					output "subnet_id" { value = "" }
+				`)),
+			}),
+			relativePath:      "modules/subnet",
+			expectedVariables: []string{"zone"},
+			expectedOutputs:   []string{"subnet_id"},
+		},
+		{
+			name: "Only submodules",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("modules/subnet/main.tf", []byte(`
+					variable "zone" {}
+				`)),
+			}),
+			relativePath:      "modules/subnet",
+			expectedVariables: []string{"zone"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iface, err := InspectModule(tt.fs, tt.relativePath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertNames(t, "variables", tt.expectedVariables, iface.Variables, func(v Variable) string { return v.Name })
+			assertNames(t, "outputs", tt.expectedOutputs, iface.Outputs, func(o Output) string { return o.Name })
+			assertNames(t, "providers", tt.expectedProviders, iface.RequiredProviders, func(p RequiredProvider) string { return p.Name })
+			assertNames(t, "module calls", tt.expectedCalls, iface.ModuleCalls, func(c ModuleCall) string { return c.Name })
+		})
+	}
+}
+
+func TestInspectModule_Attributes(t *testing.T) {
+	fs := file.MustNewFS([]file.File{
+		file.NewInMemoryFile("main.tf", []byte(`
+			terraform {
+			  required_providers {
+			    google = {
+			      source  = "registry.terraform.io/hashicorp/google"
+			      version = "~> 5.0"
+			    }
+			  }
+			}
+
+			variable "tags" {
+			  type        = map(string)
+			  description = "Tags to apply"
+			  default     = { env = "dev" }
+			  sensitive   = true
+			}
+
+			module "network" {
+			  source  = "terraform-google-modules/network/google"
+			  version = "9.0.0"
+			}
+		`)),
+	})
+
+	iface, err := InspectModule(fs, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v := iface.Variables[0]
+	if v.Type != "map(string)" || v.Description != "Tags to apply" || !v.Sensitive || v.Required {
+		t.Errorf("unexpected variable: %+v", v)
+	}
+	if v.Default == nil || *v.Default != `{"env":"dev"}` {
+		t.Errorf("expected JSON encoded default, got %v", v.Default)
+	}
+
+	p := iface.RequiredProviders[0]
+	if p.Namespace != "hashicorp" || len(p.VersionConstraints) != 1 || p.VersionConstraints[0] != "~> 5.0" {
+		t.Errorf("unexpected provider requirement: %+v", p)
+	}
+
+	c := iface.ModuleCalls[0]
+	if c.Source != "terraform-google-modules/network/google" || c.Version != "9.0.0" {
+		t.Errorf("unexpected module call: %+v", c)
+	}
+}
+
+func TestInspectModule_MissingDirectory(t *testing.T) {
+	fs := file.MustNewFS([]file.File{
+		file.NewInMemoryFile("main.tf", []byte(`variable "a" {}`)),
+	})
+
+	if _, err := InspectModule(fs, "modules/missing"); !errors.Is(err, ErrNoEntrypointFound) {
+		t.Errorf("expected ErrNoEntrypointFound, got %v", err)
+	}
+}
+
+func assertNames[T any](t *testing.T, kind string, expected []string, items []T, name func(T) string) {
+	t.Helper()
+
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d %s, got %d", len(expected), kind, len(items))
+	}
+
+	for i, item := range items {
+		if name(item) != expected[i] {
+			t.Errorf("expected %s[%d] to be %q, got %q", kind, i, expected[i], name(item))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package docs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"terralist/pkg/file"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// providerDocsDir is the directory in which tfplugindocs generates the
+	// documentation of a provider.
+	// See: https://github.com/hashicorp/terraform-plugin-docs
+	providerDocsDir = "docs"
+
+	// ProviderDocIndex is the path of the provider overview page.
+	ProviderDocIndex = "index"
+
+	markdownExt = ".md"
+
+	frontMatterDelimiter = "---"
+)
+
+// providerDocCategories are the directories of the provider documentation
+// recognized by the Terraform registry.
+// See: https://developer.hashicorp.com/terraform/registry/providers/docs
+var providerDocCategories = []string{
+	"resources",
+	"data-sources",
+	"ephemeral-resources",
+	"functions",
+	"guides",
+}
+
+// ProviderDoc is a page of the documentation of a provider.
+type ProviderDoc struct {
+	// Path identifies the page, e.g. "index" or "resources/example"
+	Path        string
+	Category    string
+	Title       string
+	Subcategory string
+	Description string
+	Content     string
+}
+
+// providerDocFrontMatter holds the metadata set by tfplugindocs at the top of
+// each page.
+type providerDocFrontMatter struct {
+	PageTitle   string `yaml:"page_title"`
+	Subcategory string `yaml:"subcategory"`
+	Description string `yaml:"description"`
+}
+
+// FindProviderDocs scans a filesystem for the documentation pages of a
+// provider, in the layout generated by tfplugindocs: an "index.md" overview
+// and a markdown file per resource, data source or guide in their category
+// directory. The pages are looked up in the "docs" directory if there is
+// one, at the root of the filesystem otherwise.
+func FindProviderDocs(docsFS *file.FS) ([]ProviderDoc, error) {
+	// Archives may contain more than one "docs" directory, the shallowest
+	// one is kept
+	var docsRoot string
+	var files []string
+
+	if err := docsFS.Walk("./", func(p string, fi fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() || path.Ext(p) != markdownExt {
+			return nil
+		}
+
+		normalizedPath := strings.TrimPrefix(p, "./")
+		files = append(files, normalizedPath)
+
+		parts := strings.Split(normalizedPath, "/")
+		if i := slices.Index(parts, providerDocsDir); i != -1 {
+			root := strings.Join(parts[:i+1], "/")
+			if docsRoot == "" || strings.Count(root, "/") < strings.Count(docsRoot, "/") {
+				docsRoot = root
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(files)
+
+	var pages []ProviderDoc
+	for _, f := range files {
+		relativePath := f
+		if docsRoot != "" {
+			if !strings.HasPrefix(f, docsRoot+"/") {
+				continue
+			}
+
+			relativePath = strings.TrimPrefix(f, docsRoot+"/")
+		}
+
+		docPath, category, ok := providerDocPath(relativePath)
+		if !ok {
+			continue
+		}
+
+		content, err := readFile(docsFS, f)
+		if err != nil {
+			return nil, fmt.Errorf("could not read documentation page %s: %w", f, err)
+		}
+
+		page, err := parseProviderDoc(content)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse documentation page %s: %w", f, err)
+		}
+
+		page.Path = docPath
+		page.Category = category
+		if page.Title == "" {
+			page.Title = path.Base(docPath)
+		}
+
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+// providerDocPath returns the path and the category of a documentation
+// page, given its path relative to the documentation root. The files which
+// are not pages of a known category are skipped.
+func providerDocPath(relativePath string) (string, string, bool) {
+	docPath := strings.TrimSuffix(relativePath, markdownExt)
+	parts := strings.Split(docPath, "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == ProviderDocIndex:
+		return ProviderDocIndex, ProviderDocIndex, true
+	case len(parts) == 2 && slices.Contains(providerDocCategories, parts[0]) && parts[1] != "":
+		return docPath, parts[0], true
+	default:
+		return "", "", false
+	}
+}
+
+// parseProviderDoc separates the front matter of a documentation page from
+// its content.
+func parseProviderDoc(content string) (ProviderDoc, error) {
+	body, ok := strings.CutPrefix(content, frontMatterDelimiter+"\n")
+	if !ok {
+		return ProviderDoc{Content: content}, nil
+	}
+
+	// The front matter may be empty, so its closing delimiter may directly
+	// follow the opening one
+	rawFrontMatter, rest, ok := strings.Cut("\n"+body, "\n"+frontMatterDelimiter)
+	if !ok {
+		return ProviderDoc{Content: content}, nil
+	}
+
+	var fm providerDocFrontMatter
+	if err := yaml.Unmarshal([]byte(rawFrontMatter), &fm); err != nil {
+		return ProviderDoc{}, err
+	}
+
+	return ProviderDoc{
+		Title:       fm.PageTitle,
+		Subcategory: fm.Subcategory,
+		Description: strings.TrimSpace(fm.Description),
+		Content:     strings.TrimLeft(rest, "\n"),
+	}, nil
+}
@@ -0,0 +1,154 @@
+package docs
+
+import (
+	"testing"
+
+	"terralist/pkg/file"
+)
+
+func TestFindProviderDocs(t *testing.T) {
+	resource := `---
+page_title: "acme_server Resource - terraform-provider-acme"
+subcategory: "Compute"
+description: |-
+  Manages a server.
+---
+
+# acme_server (Resource)
+
+Manages a server.
+`
+
+	tests := []struct {
+		name          string
+		fs            *file.FS
+		expectedPaths []string
+	}{
+		{
+			name: "Docs directory",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("README.md", []byte(`# Provider`)),
+				file.NewInMemoryFile("docs/index.md", []byte(`# Acme Provider`)),
+				file.NewInMemoryFile("docs/resources/server.md", []byte(resource)),
+				file.NewInMemoryFile("docs/data-sources/server.md", []byte(resource)),
+				file.NewInMemoryFile("docs/guides/getting-started.md", []byte(`# Getting started`)),
+			}),
+			expectedPaths: []string{"data-sources/server", "guides/getting-started", "index", "resources/server"},
+		},
+		{
+			name: "Docs at the archive root",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("index.md", []byte(`# Acme Provider`)),
+				file.NewInMemoryFile("resources/server.md", []byte(resource)),
+			}),
+			expectedPaths: []string{"index", "resources/server"},
+		},
+		{
+			name: "Docs under an archive root directory",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("terraform-provider-acme-1.0.0/docs/index.md", []byte(`# Acme Provider`)),
+				file.NewInMemoryFile("terraform-provider-acme-1.0.0/docs/resources/server.md", []byte(resource)),
+				file.NewInMemoryFile("terraform-provider-acme-1.0.0/vendor/docs/index.md", []byte(`# Vendored`)),
+			}),
+			expectedPaths: []string{"index", "resources/server"},
+		},
+		{
+			name: "Unknown categories and nested pages",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("docs/index.md", []byte(`# Acme Provider`)),
+				file.NewInMemoryFile("docs/templates/server.md", []byte(`# Template`)),
+				file.NewInMemoryFile("docs/resources/nested/server.md", []byte(resource)),
+				file.NewInMemoryFile("docs/resources/server.html.markdown", []byte(resource)),
+			}),
+			expectedPaths: []string{"index"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := FindProviderDocs(tt.fs)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if len(pages) != len(tt.expectedPaths) {
+				t.Fatalf("expected %d pages, got %d", len(tt.expectedPaths), len(pages))
+			}
+
+			for i, page := range pages {
+				if page.Path != tt.expectedPaths[i] {
+					t.Errorf("expected page %s, got %s", tt.expectedPaths[i], page.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProviderDoc(t *testing.T) {
+	tests := []struct {
+		name                string
+		content             string
+		expectedTitle       string
+		expectedSubcategory string
+		expectedDescription string
+		expectedContent     string
+	}{
+		{
+			name: "Front matter",
+			content: `---
+page_title: "acme_server Resource - terraform-provider-acme"
+subcategory: "Compute"
+description: |-
+  Manages a server.
+---
+
+# acme_server (Resource)
+`,
+			expectedTitle:       "acme_server Resource - terraform-provider-acme",
+			expectedSubcategory: "Compute",
+			expectedDescription: "Manages a server.",
+			expectedContent:     "# acme_server (Resource)\n",
+		},
+		{
+			name:            "Empty front matter",
+			content:         "---\n---\n# Guide\n",
+			expectedContent: "# Guide\n",
+		},
+		{
+			name:            "No front matter",
+			content:         "# Guide\n\n---\n",
+			expectedContent: "# Guide\n\n---\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := parseProviderDoc(tt.content)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if page.Title != tt.expectedTitle {
+				t.Errorf("expected title %q, got %q", tt.expectedTitle, page.Title)
+			}
+
+			if page.Subcategory != tt.expectedSubcategory {
+				t.Errorf("expected subcategory %q, got %q", tt.expectedSubcategory, page.Subcategory)
+			}
+
+			if page.Description != tt.expectedDescription {
+				t.Errorf("expected description %q, got %q", tt.expectedDescription, page.Description)
+			}
+
+			if page.Content != tt.expectedContent {
+				t.Errorf("expected content %q, got %q", tt.expectedContent, page.Content)
+			}
+		})
+	}
+
+	t.Run("Invalid front matter", func(t *testing.T) {
+		if _, err := parseProviderDoc("---\npage_title: [\n---\n"); err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package docs
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+
+	"terralist/pkg/file"
+)
+
+// Rules checked by ValidateModule.
+const (
+	RuleValidConfiguration   = "valid_configuration"
+	RuleReadme               = "readme"
+	RuleVariableDescriptions = "variable_descriptions"
+	RuleRequiredVersion      = "required_version"
+)
+
+// ValidationRules holds the optional rules a module must follow. The
+// configuration of the module is always validated.
+type ValidationRules struct {
+	RequireReadme               bool
+	RequireVariableDescriptions bool
+	RequireRequiredVersion      bool
+}
+
+// Violation describes a rule that a module does not follow.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Path    string `json:"path,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidateModule checks the root module and the submodules of a module
+// against the given rules. It returns the violations found, sorted by path.
+func ValidateModule(moduleFS *file.FS, rules ValidationRules) []Violation {
+	root := findModuleRoot(moduleFS)
+
+	dirs := []string{root}
+	if submodules, err := FindSubmodules(moduleFS); err == nil {
+		for _, sm := range submodules {
+			dirs = append(dirs, path.Join(root, sm.Path))
+		}
+	}
+
+	var violations []Violation
+
+	for _, dir := range dirs {
+		// Violations of the archive root are reported without a path
+		dirPath := dir
+		if dirPath == "." {
+			dirPath = ""
+		}
+
+		if entries, err := moduleFS.ReadDir(dir); err != nil || len(entries) == 0 {
+			continue
+		}
+
+		m, diags := tfconfig.LoadModuleFromFilesystem(tfconfig.WrapFS(moduleFS), dir)
+		for _, diag := range diags {
+			if diag.Severity != tfconfig.DiagError {
+				continue
+			}
+
+			v := Violation{
+				Rule:    RuleValidConfiguration,
+				Path:    dirPath,
+				Message: diag.Summary,
+			}
+
+			if diag.Detail != "" {
+				v.Message = fmt.Sprintf("%s: %s", diag.Summary, diag.Detail)
+			}
+
+			if diag.Pos != nil {
+				v.Path = diag.Pos.Filename
+				v.Line = diag.Pos.Line
+			}
+
+			violations = append(violations, v)
+		}
+
+		if rules.RequireVariableDescriptions {
+			for _, variable := range m.Variables {
+				if strings.TrimSpace(variable.Description) != "" {
+					continue
+				}
+
+				violations = append(violations, Violation{
+					Rule:    RuleVariableDescriptions,
+					Path:    variable.Pos.Filename,
+					Line:    variable.Pos.Line,
+					Message: fmt.Sprintf("variable %q has no description", variable.Name),
+				})
+			}
+		}
+
+		// The remaining rules only apply to the root module
+		if dir != root {
+			continue
+		}
+
+		if rules.RequireRequiredVersion && len(m.RequiredCore) == 0 {
+			violations = append(violations, Violation{
+				Rule:    RuleRequiredVersion,
+				Path:    dirPath,
+				Message: "the root module does not declare a required_version",
+			})
+		}
+
+		if rules.RequireReadme {
+			if f, err := moduleFS.Open(path.Join(dir, docsEntrypointFile)); err != nil {
+				violations = append(violations, Violation{
+					Rule:    RuleReadme,
+					Path:    dirPath,
+					Message: fmt.Sprintf("the root module has no %s", docsEntrypointFile),
+				})
+			} else {
+				f.Close()
+			}
+		}
+	}
+
+	slices.SortStableFunc(violations, func(lhs, rhs Violation) int {
+		return strings.Compare(lhs.Path, rhs.Path)
+	})
+
+	return violations
+}
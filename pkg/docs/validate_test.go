@@ -0,0 +1,107 @@
+package docs
+
+import (
+	"reflect"
+	"testing"
+
+	"terralist/pkg/file"
+
+	"github.com/samber/lo"
+)
+
+func TestValidateModule(t *testing.T) {
+	allRules := ValidationRules{
+		RequireReadme:               true,
+		RequireVariableDescriptions: true,
+		RequireRequiredVersion:      true,
+	}
+
+	tests := []struct {
+		name          string
+		fs            *file.FS
+		rules         ValidationRules
+		expectedRules []string
+	}{
+		{
+			name: "Valid module without rules",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`
+					variable "name" {}
+				`)),
+			}),
+		},
+		{
+			name: "Invalid configuration",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`
+					variable "name" {
+				`)),
+			}),
+			expectedRules: []string{RuleValidConfiguration},
+		},
+		{
+			name: "Invalid configuration in a submodule",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("vpc-1.0.0/main.tf", []byte(`
+					variable "name" {}
+				`)),
+				file.NewInMemoryFile("vpc-1.0.0/modules/subnet/main.tf", []byte(`
+					variable "zone" = {}
+				`)),
+			}),
+			expectedRules: []string{RuleValidConfiguration},
+		},
+		{
+			name: "Module following all rules",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("README.md", []byte("# vpc")),
+				file.NewInMemoryFile("main.tf", []byte(`
+					terraform {
+					  required_version = ">= 1.5"
+					}
+
+					variable "name" {
+					  description = "The name of the VPC"
+					}
+				`)),
+			}),
+			rules: allRules,
+		},
+		{
+			name: "Module breaking all rules",
+			fs: file.MustNewFS([]file.File{
+				file.NewInMemoryFile("main.tf", []byte(`
+					variable "name" {}
+				`)),
+				file.NewInMemoryFile("modules/subnet/main.tf", []byte(`
+					variable "zone" {}
+				`)),
+			}),
+			rules: allRules,
+			expectedRules: []string{
+				RuleRequiredVersion,
+				RuleReadme,
+				RuleVariableDescriptions,
+				RuleVariableDescriptions,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := ValidateModule(tt.fs, tt.rules)
+
+			rules := lo.Map(violations, func(v Violation, _ int) string {
+				return v.Rule
+			})
+
+			if len(rules) == 0 && len(tt.expectedRules) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(rules, tt.expectedRules) {
+				t.Fatalf("expected violations of %v, got %+v", tt.expectedRules, violations)
+			}
+		})
+	}
+}
@@ -3,13 +3,19 @@ package file
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
 	"strings"
 	"time"
 )
 
+const (
+	tempArchivePattern = "tl-archive-*.zip"
+)
+
 // BufferFileInfo implements fs.FileInfo for a bytes.Buffer.
 type BufferFileInfo struct {
 	name    string
@@ -79,51 +85,126 @@ func NewBufferReadSeekCloser(buffer *bytes.Buffer) io.ReadSeekCloser {
 	}
 }
 
+// limitedWriter is an io.Writer which accounts the bytes written against
+// the decompressed size limit.
+type limitedWriter struct {
+	w       io.Writer
+	tracker *limitTracker
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if err := lw.tracker.addSize(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	return lw.w.Write(p)
+}
+
 // Archive archives a slice of Files and returns the
 // archive as an ArchiveFile.
 func Archive(name string, files []File) (File, error) {
-	buffer := new(bytes.Buffer)
+	return ArchiveWithLimits(name, files, Limits{})
+}
 
-	writer := zip.NewWriter(buffer)
+// tempArchive is an archive written to a temporary file, which is removed
+// once it is closed.
+type tempArchive struct {
+	*os.File
+}
 
-	for _, f := range files {
-		// fetch file info and set file path to relative one to preserve directories
-		hdr, err := zip.FileInfoHeader(f.Metadata())
-		hdr.Name = f.Name()
+func (t *tempArchive) Close() error {
+	err := t.File.Close()
+	if rerr := os.Remove(t.File.Name()); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+		return rerr
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrArchiveFailure, err)
-		}
+	return err
+}
 
-		w, err := writer.CreateHeader(hdr)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrArchiveFailure, err)
-		}
+// ArchiveWithLimits archives a slice of Files and returns the archive as an
+// ArchiveFile. The archiving stops as soon as the files exceed the limits.
+// The archive is written to a temporary file, so its size is not bounded by
+// the memory, and the file is removed when the archive is closed.
+func ArchiveWithLimits(name string, files []File, limits Limits) (File, error) {
+	tracker := newLimitTracker(limits)
 
-		if _, err := io.Copy(w, f); err != nil {
-			return nil, err
-		}
+	tmp, err := os.CreateTemp("", tempArchivePattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not create temp file: %v", ErrSystemFailure, err)
 	}
 
-	if !strings.HasSuffix(name, ".zip") {
-		name = fmt.Sprintf("%s.zip", name)
+	archive := &tempArchive{File: tmp}
+	if err := writeArchive(archive, files, tracker); err != nil {
+		_ = archive.Close()
+		return nil, err
 	}
 
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrArchiveFailure, err)
+	info, err := archive.Stat()
+	if err != nil {
+		_ = archive.Close()
+		return nil, fmt.Errorf("%w: %v", ErrSystemFailure, err)
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		_ = archive.Close()
+		return nil, fmt.Errorf("%w: %v", ErrSystemFailure, err)
 	}
 
 	fs, err := NewFS(files)
 	if err != nil {
+		_ = archive.Close()
 		return nil, err
 	}
 
+	if !strings.HasSuffix(name, ".zip") {
+		name = fmt.Sprintf("%s.zip", name)
+	}
+
 	return &ArchiveFile{
 		archive: &StreamingFile{
-			name:     name,
-			fileInfo: NewBufferFileInfo(buffer, name),
-			reader:   NewBufferReadSeekCloser(buffer),
+			name: name,
+			fileInfo: &BufferFileInfo{
+				name:    name,
+				size:    info.Size(),
+				mode:    0644,
+				modTime: info.ModTime(),
+			},
+			reader: archive,
 		},
 		fs: fs,
 	}, nil
 }
+
+// writeArchive writes a zip archive of a slice of Files.
+func writeArchive(dst io.Writer, files []File, tracker *limitTracker) error {
+	writer := zip.NewWriter(dst)
+
+	for _, f := range files {
+		// The size is accounted while copying, the declared one can lie
+		if err := tracker.addFile(f.Name(), 0); err != nil {
+			return err
+		}
+
+		// fetch file info and set file path to relative one to preserve directories
+		hdr, err := zip.FileInfoHeader(f.Metadata())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrArchiveFailure, err)
+		}
+		hdr.Name = f.Name()
+
+		w, err := writer.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrArchiveFailure, err)
+		}
+
+		if _, err := io.Copy(&limitedWriter{w: w, tracker: tracker}, f); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveFailure, err)
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package file
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrSystemFailure = errors.New("system failure")
@@ -10,5 +13,14 @@ var (
 	ErrDownloadFailure   = errors.New("download failure")
 	ErrDownloadInterrupt = errors.New("download interrupt")
 
+	ErrNotFound = fmt.Errorf("%w: not found", ErrDownloadFailure)
+
 	ErrArchiveFailure = errors.New("archive failure")
+
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	ErrDownloadTooLarge     = fmt.Errorf("%w: download too large", ErrLimitExceeded)
+	ErrDecompressedTooLarge = fmt.Errorf("%w: decompressed content too large", ErrLimitExceeded)
+	ErrTooManyFiles         = fmt.Errorf("%w: too many files", ErrLimitExceeded)
+	ErrPathTooDeep          = fmt.Errorf("%w: path too deep", ErrLimitExceeded)
 )
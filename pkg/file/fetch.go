@@ -2,6 +2,7 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,11 +23,21 @@ const (
 
 // generateGetters returns the map of getters.
 // Modified version of https://github.com/hashicorp/go-getter/blob/f7836fb97529673f24dac0aaa140762ee05c847f/get.go#L65
-// to add support for custom http headers.
-func generateGetters(header http.Header) map[string]getter.Getter {
+// to add support for custom http headers, download limits and not found
+// responses.
+func generateGetters(header http.Header, tracker *limitTracker, status *statusTransport) map[string]getter.Getter {
+	status.base = http.DefaultTransport.(*http.Transport).Clone()
+	if tracker.limits.MaxDownloadSize > 0 {
+		status.base = &limitedTransport{
+			base:    status.base,
+			tracker: tracker,
+		}
+	}
+
 	httpGetter := &getter.HttpGetter{
 		Netrc:  true,
 		Header: header,
+		Client: &http.Client{Transport: status},
 	}
 
 	return map[string]getter.Getter{
@@ -44,7 +55,9 @@ func generateGetters(header http.Header) map[string]getter.Getter {
 // It returns the downloaded file and a cleanup function that removes the temporary
 // directory used during the download. The caller must invoke the cleanup function
 // when the file is no longer needed.
-func fetch(name string, url string, checksum string, kind int, header http.Header) (File, func(), error) {
+// The limits are enforced while downloading, decompressing and archiving.
+// The download is canceled when the parent context is done.
+func fetch(parent context.Context, name string, url string, checksum string, kind int, header http.Header, limits Limits) (File, func(), error) {
 	tempDir, err := os.MkdirTemp("", tempDirPattern)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%w: could not create temp dir: %v", ErrSystemFailure, err)
@@ -75,13 +88,17 @@ func fetch(name string, url string, checksum string, kind int, header http.Heade
 	}
 	u.RawQuery = q.Encode()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	tracker := newLimitTracker(limits)
+	status := &statusTransport{}
+
+	ctx, cancel := context.WithCancel(parent)
 	client := &getter.Client{
-		Ctx:     ctx,
-		Src:     u.String(),
-		Dst:     dst,
-		Pwd:     tempDir,
-		Getters: generateGetters(header),
+		Ctx:           ctx,
+		Src:           u.String(),
+		Dst:           dst,
+		Pwd:           tempDir,
+		Getters:       generateGetters(header, tracker, status),
+		Decompressors: limitedDecompressors(tracker),
 	}
 
 	switch kind {
@@ -122,13 +139,33 @@ func fetch(name string, url string, checksum string, kind int, header http.Heade
 		wg.Wait()
 
 		cleanup()
+
+		if perr := parent.Err(); perr != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrDownloadInterrupt, perr)
+		}
+
+		// go-getter does not wrap the errors, the tracker tells if the
+		// download failed because of a limit
+		if lerr := tracker.Err(); lerr != nil {
+			return nil, nil, lerr
+		}
+
+		if status.NotFound() {
+			return nil, nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+
 		return nil, nil, fmt.Errorf("%w: %v", ErrDownloadFailure, err)
 	case <-ctx.Done():
 		wg.Wait()
 
+		if err := parent.Err(); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("%w: %v", ErrDownloadInterrupt, err)
+		}
+
 		// If we know the type, just parse it
 		if kind == file || kind == dir {
-			f, err := parseResult(name, dst, kind)
+			f, err := parseResult(name, dst, kind, tracker)
 			if err != nil {
 				cleanup()
 				return nil, nil, err
@@ -148,7 +185,7 @@ func fetch(name string, url string, checksum string, kind int, header http.Heade
 			resultKind = dir
 		}
 
-		f, err := parseResult(name, dst, resultKind)
+		f, err := parseResult(name, dst, resultKind, tracker)
 		if err != nil {
 			cleanup()
 			return nil, nil, err
@@ -159,12 +196,22 @@ func fetch(name string, url string, checksum string, kind int, header http.Heade
 
 // parseResult parses the download result and returns an
 // File.
-func parseResult(name, src string, kind int) (File, error) {
+func parseResult(name, src string, kind int, tracker *limitTracker) (File, error) {
 	switch kind {
 	case file:
+		// The getters other than http cannot be limited while downloading
+		inf, err := os.Stat(src)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSystemFailure, err)
+		}
+
+		if err := tracker.addDownloaded(inf.Size()); err != nil {
+			return nil, err
+		}
+
 		return readFile(name, src)
 	case dir:
-		return archiveDir(name, src)
+		return archiveDir(name, src, tracker)
 	}
 
 	return nil, fmt.Errorf("%w: unknown file type", ErrSystemFailure)
@@ -178,7 +225,7 @@ func readFile(name, src string) (File, error) {
 
 // archiveDir reads a directory from the disk, archives it
 // and returns the archive file.
-func archiveDir(name, src string) (File, error) {
+func archiveDir(name, src string, tracker *limitTracker) (File, error) {
 	dirFiles := []File{}
 	var rootDir string
 
@@ -198,6 +245,11 @@ func archiveDir(name, src string) (File, error) {
 		relPath = filepath.Clean(relPath)
 		relPath = strings.ReplaceAll(relPath, "\\", "/")
 
+		// Check the limits before opening the file
+		if err := tracker.addFile(relPath, info.Size()); err != nil {
+			return err
+		}
+
 		// Detect if all files are under a single root directory
 		// (common in GitHub archive downloads like terraform-aws-eks-21.15.1/)
 		if rootDir == "" {
@@ -216,6 +268,12 @@ func archiveDir(name, src string) (File, error) {
 
 		return nil
 	}); err != nil {
+		closeAll(dirFiles)
+
+		if errors.Is(err, ErrLimitExceeded) {
+			return nil, err
+		}
+
 		return nil, fmt.Errorf("%w: could not parse downloaded dir: %v", ErrSystemFailure, err)
 	}
 
@@ -248,10 +306,45 @@ func archiveDir(name, src string) (File, error) {
 		}
 	}
 
-	archive, err := Archive(name, dirFiles)
+	archive, err := ArchiveWithLimits(name, dirFiles, tracker.limits)
 	if err != nil {
 		return nil, err
 	}
 
 	return archive, nil
 }
+
+// closeAll closes a slice of Files, ignoring the errors.
+func closeAll(files []File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// statusTransport is an http.RoundTripper which remembers whether the last
+// response was not found, since go-getter does not wrap the status codes in
+// its errors.
+type statusTransport struct {
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	notFound bool
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+
+	t.mu.Lock()
+	t.notFound = err == nil && resp.StatusCode == http.StatusNotFound
+	t.mu.Unlock()
+
+	return resp, err
+}
+
+// NotFound returns true if the last response was not found.
+func (t *statusTransport) NotFound() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.notFound
+}
@@ -1,6 +1,10 @@
 package file
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,7 +25,7 @@ func TestFetch_CleanupRemovesTempDir(t *testing.T) {
 	tempFile.Close()
 
 	// Fetch the file
-	result, cleanup, err := fetch("test.txt", "file://"+tempFile.Name(), "", file, nil)
+	result, cleanup, err := fetch(context.Background(), "test.txt", "file://"+tempFile.Name(), "", file, nil, Limits{})
 	if err != nil {
 		t.Fatalf("fetch failed: %v", err)
 	}
@@ -80,7 +84,7 @@ func TestArchiveDir_StripRootFolder(t *testing.T) {
 	}
 
 	// Archive the directory
-	archive, err := archiveDir("test.zip", tempDir)
+	archive, err := archiveDir("test.zip", tempDir, newLimitTracker(Limits{}))
 	if err != nil {
 		t.Fatalf("archiveDir failed: %v", err)
 	}
@@ -155,7 +159,7 @@ func TestArchiveDir_NoStripWhenMultipleRoots(t *testing.T) {
 	}
 
 	// Archive the directory
-	archive, err := archiveDir("test.zip", tempDir)
+	archive, err := archiveDir("test.zip", tempDir, newLimitTracker(Limits{}))
 	if err != nil {
 		t.Fatalf("archiveDir failed: %v", err)
 	}
@@ -193,3 +197,19 @@ func TestArchiveDir_NoStripWhenMultipleRoots(t *testing.T) {
 		t.Error("Archive should contain dir1/file2.txt")
 	}
 }
+
+func TestFetch_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	result, cleanup, err := fetch(context.Background(), "README.md", server.URL+"/README.md", "", file, nil, Limits{})
+	if err == nil {
+		result.Close()
+		cleanup()
+		t.Fatal("Expected the download to fail")
+	}
+
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrDownloadFailure) {
+		t.Fatalf("Expected error %v, got %v", ErrNotFound, err)
+	}
+}
@@ -1,6 +1,9 @@
 package file
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 type Fetcher interface {
 	// Fetch downloads a file or a directory from a given URL
@@ -12,6 +15,10 @@ type Fetcher interface {
 	// is no longer needed to remove the temporary directory.
 	Fetch(name string, url string, header http.Header) (File, func(), error)
 
+	// FetchContext is like Fetch, but the download is canceled when the
+	// given context is done.
+	FetchContext(ctx context.Context, name string, url string, header http.Header) (File, func(), error)
+
 	// FetchFile downloads a file from a given URL and returns it
 	// along with a cleanup function.
 	FetchFile(name string, url string, header http.Header) (File, func(), error)
@@ -36,30 +43,42 @@ const (
 	unknown
 )
 
-type defaultFetcher struct{}
+type defaultFetcher struct {
+	limits Limits
+}
 
 func NewFetcher() Fetcher {
 	return &defaultFetcher{}
 }
 
-func (*defaultFetcher) Fetch(name string, url string, header http.Header) (File, func(), error) {
-	return fetch(name, url, "", unknown, header)
+// NewLimitedFetcher creates a Fetcher which enforces the given limits while
+// downloading and archiving the files.
+func NewLimitedFetcher(limits Limits) Fetcher {
+	return &defaultFetcher{limits: limits}
+}
+
+func (f *defaultFetcher) Fetch(name string, url string, header http.Header) (File, func(), error) {
+	return fetch(context.Background(), name, url, "", unknown, header, f.limits)
+}
+
+func (f *defaultFetcher) FetchContext(ctx context.Context, name string, url string, header http.Header) (File, func(), error) {
+	return fetch(ctx, name, url, "", unknown, header, f.limits)
 }
 
-func (*defaultFetcher) FetchFile(name string, url string, header http.Header) (File, func(), error) {
-	return fetch(name, url, "", file, header)
+func (f *defaultFetcher) FetchFile(name string, url string, header http.Header) (File, func(), error) {
+	return fetch(context.Background(), name, url, "", file, header, f.limits)
 }
 
-func (*defaultFetcher) FetchFileChecksum(name string, url string, checksum string, header http.Header) (File, func(), error) {
-	return fetch(name, url, checksum, file, header)
+func (f *defaultFetcher) FetchFileChecksum(name string, url string, checksum string, header http.Header) (File, func(), error) {
+	return fetch(context.Background(), name, url, checksum, file, header, f.limits)
 }
 
-func (*defaultFetcher) FetchDir(name string, url string, header http.Header) (File, func(), error) {
-	return fetch(name, url, "", dir, header)
+func (f *defaultFetcher) FetchDir(name string, url string, header http.Header) (File, func(), error) {
+	return fetch(context.Background(), name, url, "", dir, header, f.limits)
 }
 
-func (*defaultFetcher) FetchDirChecksum(name string, url string, checksum string, header http.Header) (File, func(), error) {
-	return fetch(name, url, checksum, dir, header)
+func (f *defaultFetcher) FetchDirChecksum(name string, url string, checksum string, header http.Header) (File, func(), error) {
+	return fetch(context.Background(), name, url, checksum, dir, header, f.limits)
 }
 
 // CreateHeader creates an http.Header from a map of key-value strings.
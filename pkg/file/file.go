@@ -3,6 +3,8 @@ package file
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/fs"
@@ -229,6 +231,25 @@ func ContentType(f File) string {
 	return http.DetectContentType(data)
 }
 
+// Checksum returns the hex-encoded SHA-256 checksum of a reader content.
+// The reader is rewound before and after computing the checksum.
+func Checksum(r io.ReadSeeker) (string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("could not rewind the reader: %w", err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", fmt.Errorf("could not compute the checksum: %w", err)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("could not rewind the reader: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // SaveToTemp writes a file to the disk, in a temp file.
 func SaveToTemp(f File) (*OnDiskFile, error) {
 	file, err := os.CreateTemp("", "terralist.tmp.*")
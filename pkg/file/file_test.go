@@ -21,3 +21,25 @@ func TestContentTypeRewindsSmallFiles(t *testing.T) {
 		t.Fatalf("expected file content to remain readable after ContentType, got %q", string(body))
 	}
 }
+
+func TestChecksumRewindsFiles(t *testing.T) {
+	f := NewInMemoryFile("hello.txt", []byte("hello"))
+
+	got, err := Checksum(f)
+	if err != nil {
+		t.Fatalf("Checksum returned error: %v", err)
+	}
+
+	if want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Fatalf("expected checksum %s, got %s", want, got)
+	}
+
+	body, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll returned error: %v", err)
+	}
+
+	if string(body) != "hello" {
+		t.Fatalf("expected file content to remain readable after Checksum, got %q", string(body))
+	}
+}
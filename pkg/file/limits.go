@@ -0,0 +1,277 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	getter "github.com/hashicorp/go-getter"
+)
+
+// Limits bounds the resources used to fetch and archive files. A zero
+// value disables the corresponding limit.
+type Limits struct {
+	// MaxDownloadSize is the maximum number of bytes downloaded
+	MaxDownloadSize int64
+
+	// MaxDecompressedSize is the maximum number of bytes of all files, once
+	// decompressed
+	MaxDecompressedSize int64
+
+	// MaxFiles is the maximum number of files
+	MaxFiles int
+
+	// MaxPathDepth is the maximum number of elements in the path of a file
+	MaxPathDepth int
+}
+
+// limitTracker accounts the bytes and files processed while fetching or
+// archiving, and remembers the first limit exceeded.
+type limitTracker struct {
+	limits Limits
+
+	mu         sync.Mutex
+	downloaded int64
+	size       int64
+	files      int
+	err        error
+}
+
+func newLimitTracker(limits Limits) *limitTracker {
+	return &limitTracker{limits: limits}
+}
+
+// Err returns the first limit exceeded, if any.
+func (t *limitTracker) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// record records err as the limit exceeded, unless another one was
+// already recorded, and returns it.
+func (t *limitTracker) record(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.fail(err)
+}
+
+// fail is the same as record, for callers which hold the lock.
+func (t *limitTracker) fail(err error) error {
+	if t.err == nil {
+		t.err = err
+	}
+
+	return err
+}
+
+// addDownloaded accounts n downloaded bytes.
+func (t *limitTracker) addDownloaded(n int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.downloaded += n
+	if t.limits.MaxDownloadSize > 0 && t.downloaded > t.limits.MaxDownloadSize {
+		return t.fail(fmt.Errorf("%w: more than %d bytes", ErrDownloadTooLarge, t.limits.MaxDownloadSize))
+	}
+
+	return nil
+}
+
+// addFile accounts a file of the given size.
+func (t *limitTracker) addFile(name string, size int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.files++
+	if t.limits.MaxFiles > 0 && t.files > t.limits.MaxFiles {
+		return t.fail(fmt.Errorf("%w: more than %d files", ErrTooManyFiles, t.limits.MaxFiles))
+	}
+
+	if depth := pathDepth(name); t.limits.MaxPathDepth > 0 && depth > t.limits.MaxPathDepth {
+		return t.fail(fmt.Errorf("%w: %s has more than %d elements", ErrPathTooDeep, name, t.limits.MaxPathDepth))
+	}
+
+	return t.addSizeLocked(size)
+}
+
+// addSize accounts n decompressed bytes.
+func (t *limitTracker) addSize(n int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.addSizeLocked(n)
+}
+
+func (t *limitTracker) addSizeLocked(n int64) error {
+	t.size += n
+	if t.limits.MaxDecompressedSize > 0 && t.size > t.limits.MaxDecompressedSize {
+		return t.fail(fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, t.limits.MaxDecompressedSize))
+	}
+
+	return nil
+}
+
+// pathDepth returns the number of elements of a slash-separated path.
+func pathDepth(name string) int {
+	name = strings.Trim(strings.ReplaceAll(name, "\\", "/"), "/")
+	if name == "" || name == "." {
+		return 0
+	}
+
+	return len(strings.Split(name, "/"))
+}
+
+// limitedTransport is an http.RoundTripper which stops the downloads once
+// they exceed the download size limit.
+type limitedTransport struct {
+	base    http.RoundTripper
+	tracker *limitTracker
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	// Fail early if the server announces a download over the limit
+	if max := t.tracker.limits.MaxDownloadSize; max > 0 && resp.ContentLength > max {
+		resp.Body.Close()
+
+		return nil, t.tracker.record(fmt.Errorf("%w: %d bytes announced, more than %d", ErrDownloadTooLarge, resp.ContentLength, max))
+	}
+
+	resp.Body = &limitedBody{ReadCloser: resp.Body, tracker: t.tracker}
+
+	return resp, nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	tracker *limitTracker
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if lerr := b.tracker.addDownloaded(int64(n)); lerr != nil {
+		return n, lerr
+	}
+
+	return n, err
+}
+
+// limitedDecompressor inspects the archives before they are decompressed,
+// so the archives exceeding the limits are rejected before they are written
+// to the disk.
+type limitedDecompressor struct {
+	getter.Decompressor
+
+	format  string
+	tracker *limitTracker
+}
+
+// limitedDecompressors returns the go-getter decompressors, enforcing the
+// limits of a tracker. The go-getter limits are kept as a safeguard for the
+// formats which cannot be inspected.
+func limitedDecompressors(tracker *limitTracker) map[string]getter.Decompressor {
+	decompressors := getter.LimitedDecompressors(tracker.limits.MaxFiles, tracker.limits.MaxDecompressedSize)
+
+	for format, d := range decompressors {
+		decompressors[format] = &limitedDecompressor{
+			Decompressor: d,
+			format:       format,
+			tracker:      tracker,
+		}
+	}
+
+	return decompressors
+}
+
+func (d *limitedDecompressor) Decompress(dst, src string, dir bool, umask os.FileMode) error {
+	if err := d.inspect(src); err != nil {
+		return err
+	}
+
+	return d.Decompressor.Decompress(dst, src, dir, umask)
+}
+
+// inspect checks the entries of an archive against the limits, without
+// decompressing it to the disk. The entries are accounted again once
+// decompressed, so the tracker only records the limit exceeded.
+func (d *limitedDecompressor) inspect(src string) error {
+	entries := newLimitTracker(d.tracker.limits)
+
+	switch d.format {
+	case "zip":
+		r, err := zip.OpenReader(src)
+		if err != nil {
+			// Let go-getter report the invalid archive
+			return nil
+		}
+		defer r.Close()
+
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+
+			// The declared size is trusted, since the zip reader fails when
+			// an entry is larger than its declared size
+			if err := entries.addFile(f.Name, int64(f.UncompressedSize64)); err != nil {
+				return d.tracker.record(err)
+			}
+		}
+
+		return nil
+	case "tar", "tar.gz", "tgz", "tar.bz2", "tbz2":
+		f, err := os.Open(src)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		var r io.Reader = f
+		switch d.format {
+		case "tar.gz", "tgz":
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return nil
+			}
+			defer gz.Close()
+
+			r = gz
+		case "tar.bz2", "tbz2":
+			r = bzip2.NewReader(f)
+		}
+
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				// Either the end of the archive, or an invalid archive which
+				// is reported by go-getter
+				return nil
+			}
+
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			if err := entries.addFile(hdr.Name, hdr.Size); err != nil {
+				return d.tracker.record(err)
+			}
+		}
+	}
+
+	return nil
+}
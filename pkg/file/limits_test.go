@@ -0,0 +1,202 @@
+package file
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeZip creates a zip archive on the disk holding the given files.
+func writeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), "module.zip")
+
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("Failed to create zip file: %v", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s to zip: %v", name, err)
+		}
+
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s to zip: %v", name, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+
+	return p
+}
+
+func TestFetch_Limits(t *testing.T) {
+	manyFiles := map[string]string{}
+	for i := 0; i < 5; i++ {
+		manyFiles[fmt.Sprintf("file%d.tf", i)] = "locals {}"
+	}
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		limits Limits
+		err    error
+	}{
+		{
+			name:   "Within limits",
+			files:  map[string]string{"main.tf": "locals {}"},
+			limits: Limits{MaxFiles: 5, MaxDecompressedSize: 1024, MaxPathDepth: 2},
+		},
+		{
+			name:   "Too many files",
+			files:  manyFiles,
+			limits: Limits{MaxFiles: 4},
+			err:    ErrTooManyFiles,
+		},
+		{
+			name:   "Decompressed content too large",
+			files:  map[string]string{"main.tf": strings.Repeat("#", 4096)},
+			limits: Limits{MaxDecompressedSize: 1024},
+			err:    ErrDecompressedTooLarge,
+		},
+		{
+			name:   "Path too deep",
+			files:  map[string]string{"a/b/c/d/main.tf": "locals {}"},
+			limits: Limits{MaxPathDepth: 4},
+			err:    ErrPathTooDeep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := writeZip(t, tt.files)
+
+			result, cleanup, err := fetch(context.Background(), "module", "file://"+src, "", unknown, nil, tt.limits)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+
+				result.Close()
+				cleanup()
+				return
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("Expected %v to be a limit error", err)
+			}
+		})
+	}
+}
+
+func TestFetch_DownloadSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	defer server.Close()
+
+	result, cleanup, err := fetch(context.Background(), "provider.zip", server.URL+"/provider.zip", "", file, nil, Limits{MaxDownloadSize: 1024})
+	if err == nil {
+		result.Close()
+		cleanup()
+		t.Fatal("Expected the download to fail")
+	}
+
+	if !errors.Is(err, ErrDownloadTooLarge) {
+		t.Fatalf("Expected error %v, got %v", ErrDownloadTooLarge, err)
+	}
+
+	result, cleanup, err = fetch(context.Background(), "provider.zip", server.URL+"/provider.zip", "", file, nil, Limits{MaxDownloadSize: 8192})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result.Close()
+	cleanup()
+}
+
+func TestArchiveWithLimits(t *testing.T) {
+	files := func() []File {
+		return []File{
+			NewInMemoryFile("main.tf", []byte("locals {}")),
+			NewInMemoryFile("modules/vpc/main.tf", []byte(strings.Repeat("#", 2048))),
+		}
+	}
+
+	if _, err := ArchiveWithLimits("module", files(), Limits{MaxFiles: 2, MaxDecompressedSize: 4096, MaxPathDepth: 3}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := ArchiveWithLimits("module", files(), Limits{MaxFiles: 1}); !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("Expected error %v, got %v", ErrTooManyFiles, err)
+	}
+
+	if _, err := ArchiveWithLimits("module", files(), Limits{MaxDecompressedSize: 1024}); !errors.Is(err, ErrDecompressedTooLarge) {
+		t.Fatalf("Expected error %v, got %v", ErrDecompressedTooLarge, err)
+	}
+
+	if _, err := ArchiveWithLimits("module", files(), Limits{MaxPathDepth: 2}); !errors.Is(err, ErrPathTooDeep) {
+		t.Fatalf("Expected error %v, got %v", ErrPathTooDeep, err)
+	}
+}
+
+func TestFetch_CanceledContext(t *testing.T) {
+	// The server holds the download until the client gives up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, cleanup, err := fetch(ctx, "provider.zip", server.URL+"/provider.zip", "", file, nil, Limits{})
+	if !errors.Is(err, ErrDownloadInterrupt) {
+		if cleanup != nil {
+			cleanup()
+		}
+		t.Fatalf("expected ErrDownloadInterrupt, got %v (result %v)", err, result)
+	}
+}
+
+func TestArchiveWithLimits_RemovesTempFile(t *testing.T) {
+	archive, err := ArchiveWithLimits("module", []File{NewInMemoryFile("main.tf", []byte("locals {}"))}, Limits{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	path := archive.(*ArchiveFile).archive.reader.(*tempArchive).Name()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the archive to be written to %s, got %v", path, err)
+	}
+
+	if size := archive.Metadata().Size(); size == 0 {
+		t.Fatal("Expected the archive to have a size")
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected the archive to be removed, got %v", err)
+	}
+}
@@ -46,7 +46,10 @@ func NewRegistry(cfg *RegistryConfig) *prometheus.Registry {
 		RequestsByAuthorityTotal,
 		ApiKeysTotal, StorageOperationsTotal,
 		StorageBytesTotal,
-		StorageOperationDuration)
+		StorageOperationDuration,
+		StorageUploadPartsTotal,
+		StorageUploadPartSize,
+		StorageUploadInProgressBytes)
 
 	// Register database metrics if SQL DB is provided
 	if cfg != nil && cfg.SqlDB != nil {
@@ -32,6 +32,36 @@ var (
 		},
 		[]string{"operation", "backend"},
 	)
+
+	// StorageUploadPartsTotal counts the parts uploaded by the storage
+	// backends.
+	StorageUploadPartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terralist_storage_upload_parts_total",
+			Help: "Total number of parts uploaded to the storage",
+		},
+		[]string{"backend"},
+	)
+
+	// StorageUploadPartSize tracks the size of the uploaded parts.
+	StorageUploadPartSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "terralist_storage_upload_part_size_bytes",
+			Help:    "Size of the parts uploaded to the storage in bytes",
+			Buckets: prometheus.ExponentialBuckets(64<<10, 4, 8),
+		},
+		[]string{"backend"},
+	)
+
+	// StorageUploadInProgressBytes tracks the bytes already uploaded by the
+	// uploads which are not completed yet.
+	StorageUploadInProgressBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terralist_storage_upload_in_progress_bytes",
+			Help: "Bytes uploaded so far by the storage uploads in progress",
+		},
+		[]string{"backend"},
+	)
 )
 
 // RecordStorageOperation records a completed storage operation with its metrics.
@@ -48,3 +78,17 @@ func RecordStorageOperation(operation, backend, status string, durationSeconds f
 		StorageBytesTotal.WithLabelValues(operation, backend).Add(float64(bytes))
 	}
 }
+
+// RecordStorageUploadPart records a part uploaded by an upload in progress.
+func RecordStorageUploadPart(backend string, bytes int64) {
+	StorageUploadPartsTotal.WithLabelValues(backend).Inc()
+	StorageUploadPartSize.WithLabelValues(backend).Observe(float64(bytes))
+	StorageUploadInProgressBytes.WithLabelValues(backend).Add(float64(bytes))
+}
+
+// RecordStorageUploadDone removes the parts of a completed upload from the
+// uploads in progress.
+// bytes: the total size of the parts recorded for the upload
+func RecordStorageUploadDone(backend string, bytes int64) {
+	StorageUploadInProgressBytes.WithLabelValues(backend).Sub(float64(bytes))
+}
@@ -105,6 +105,40 @@ func TestStorageOperationZeroBytes(t *testing.T) {
 	}
 }
 
+func TestRecordStorageUploadPart(t *testing.T) {
+	StorageUploadPartsTotal.Reset()
+	StorageUploadPartSize.Reset()
+	StorageUploadInProgressBytes.Reset()
+
+	// Record two parts of an upload in progress
+	RecordStorageUploadPart("s3", 5<<20)
+	RecordStorageUploadPart("s3", 2<<20)
+
+	if count := testutil.ToFloat64(StorageUploadPartsTotal.WithLabelValues("s3")); count != 2 {
+		t.Errorf("Expected 2 parts, got %f", count)
+	}
+
+	if count := testutil.CollectAndCount(StorageUploadPartSize); count != 1 {
+		t.Errorf("Expected 1 part size series, got %d", count)
+	}
+
+	if bytes := testutil.ToFloat64(StorageUploadInProgressBytes.WithLabelValues("s3")); bytes != 7<<20 {
+		t.Errorf("Expected %d bytes in progress, got %f", 7<<20, bytes)
+	}
+
+	// Complete the upload
+	RecordStorageUploadDone("s3", 7<<20)
+
+	if bytes := testutil.ToFloat64(StorageUploadInProgressBytes.WithLabelValues("s3")); bytes != 0 {
+		t.Errorf("Expected 0 bytes in progress, got %f", bytes)
+	}
+
+	// Parts are still counted after the upload completes
+	if count := testutil.ToFloat64(StorageUploadPartsTotal.WithLabelValues("s3")); count != 2 {
+		t.Errorf("Expected 2 parts, got %f", count)
+	}
+}
+
 func TestStorageMetricsRegistration(t *testing.T) {
 	// Create a new registry
 	reg := prometheus.NewRegistry()
@@ -124,4 +158,19 @@ func TestStorageMetricsRegistration(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to register StorageOperationDuration: %v", err)
 	}
+
+	err = reg.Register(StorageUploadPartsTotal)
+	if err != nil {
+		t.Errorf("Failed to register StorageUploadPartsTotal: %v", err)
+	}
+
+	err = reg.Register(StorageUploadPartSize)
+	if err != nil {
+		t.Errorf("Failed to register StorageUploadPartSize: %v", err)
+	}
+
+	err = reg.Register(StorageUploadInProgressBytes)
+	if err != nil {
+		t.Errorf("Failed to register StorageUploadInProgressBytes: %v", err)
+	}
 }
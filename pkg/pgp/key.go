@@ -0,0 +1,211 @@
+package pgp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+var (
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrSealedKey         = errors.New("could not open the sealed private key")
+)
+
+const (
+	// keyBits is the size of the RSA keys generated by the registry. RSA is
+	// the algorithm Terraform recommends for the provider signing keys, and
+	// 3072 bits keep their generation short enough to be done on request.
+	keyBits = 3072
+
+	// saltSize is the size of the random salt the sealing key of a private
+	// key is derived with. It is stored in front of the sealed key.
+	saltSize = 32
+
+	// sealInfo binds the keys derived from a secret to their purpose.
+	sealInfo = "terralist signing key"
+)
+
+// PrivateKey is an OpenPGP private key, used by the registry to sign the
+// SHA256SUMS files of the providers.
+type PrivateKey struct {
+	entity *openpgp.Entity
+}
+
+// GenerateKey creates a new RSA private key for an identity.
+func GenerateKey(name, comment, email string) (*PrivateKey, error) {
+	entity, err := openpgp.NewEntity(name, comment, email, &packet.Config{RSABits: keyBits})
+	if err != nil {
+		return nil, fmt.Errorf("could not generate the key: %v", err)
+	}
+
+	return &PrivateKey{entity: entity}, nil
+}
+
+// ReadPrivateKey reads an ASCII armored private key. If the key is protected
+// by a passphrase, it is decrypted with the given one.
+func ReadPrivateKey(asciiArmor, passphrase string) (*PrivateKey, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(asciiArmor))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+
+	if len(keyring) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one key, found %d", ErrInvalidPrivateKey, len(keyring))
+	}
+
+	entity := keyring[0]
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: the key has no private half", ErrInvalidPrivateKey)
+	}
+
+	keys := []*packet.PrivateKey{entity.PrivateKey}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil {
+			keys = append(keys, subkey.PrivateKey)
+		}
+	}
+
+	for _, k := range keys {
+		if !k.Encrypted {
+			continue
+		}
+
+		if err := k.Decrypt([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("%w: could not decrypt the key: %v", ErrInvalidPrivateKey, err)
+		}
+	}
+
+	return &PrivateKey{entity: entity}, nil
+}
+
+// KeyID returns the ID of the key, as advertised to Terraform.
+func (k *PrivateKey) KeyID() string {
+	return k.entity.PrimaryKey.KeyIdString()
+}
+
+// PublicKey returns the ASCII armored public half of the key.
+func (k *PrivateKey) PublicKey() (string, error) {
+	var buf bytes.Buffer
+
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if err := k.entity.Serialize(w); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Sign returns a binary detached signature of a content, the format
+// expected by Terraform for the SHA256SUMS signatures.
+func (k *PrivateKey) Sign(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := openpgp.DetachSign(&buf, k.entity, bytes.NewReader(content), nil); err != nil {
+		return nil, fmt.Errorf("could not sign the content: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Seal encrypts the private key with a secret, so it can be stored.
+func (k *PrivateKey) Seal(secret string) (string, error) {
+	var buf bytes.Buffer
+
+	// The self-signatures are kept as they are, the key does not have to be
+	// signed again to be serialized
+	if err := k.entity.SerializePrivateWithoutSigning(&buf, nil); err != nil {
+		return "", fmt.Errorf("could not serialize the private key: %v", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	gcm, err := newCipher(secret, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := append(salt, nonce...)
+	sealed = gcm.Seal(sealed, nonce, buf.Bytes(), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenPrivateKey decrypts a private key sealed with a secret.
+func OpenPrivateKey(sealed, secret string) (*PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSealedKey, err)
+	}
+
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("%w: the key is truncated", ErrSealedKey)
+	}
+
+	salt, data := data[:saltSize], data[saltSize:]
+
+	gcm, err := newCipher(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: the key is truncated", ErrSealedKey)
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSealedKey, err)
+	}
+
+	entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(plaintext)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSealedKey, err)
+	}
+
+	return &PrivateKey{entity: entity}, nil
+}
+
+// newCipher returns an AES-256-GCM cipher with a key derived from a secret
+// and a salt, using HKDF.
+func newCipher(secret string, salt []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, []byte(secret), salt, sealInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,111 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestSealPrivateKey(t *testing.T) {
+	entity, publicKey := newTestEntity(t)
+	key := &PrivateKey{entity: entity}
+
+	sealed, err := key.Seal("secret")
+	if err != nil {
+		t.Fatalf("could not seal the key: %v", err)
+	}
+
+	t.Run("same secret", func(t *testing.T) {
+		opened, err := OpenPrivateKey(sealed, "secret")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if opened.KeyID() != key.KeyID() {
+			t.Fatalf("expected key %s, got %s", key.KeyID(), opened.KeyID())
+		}
+
+		content := []byte("0123456789abcdef  terraform-provider-acme_1.0.0_linux_amd64.zip\n")
+
+		signature, err := opened.Sign(content)
+		if err != nil {
+			t.Fatalf("could not sign the content: %v", err)
+		}
+
+		if err := Verify(publicKey, content, signature); err != nil {
+			t.Fatalf("expected a valid signature, got %v", err)
+		}
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		if _, err := OpenPrivateKey(sealed, "other"); !errors.Is(err, ErrSealedKey) {
+			t.Fatalf("expected %v, got %v", ErrSealedKey, err)
+		}
+	})
+
+	t.Run("salted secret", func(t *testing.T) {
+		again, err := key.Seal("secret")
+		if err != nil {
+			t.Fatalf("could not seal the key: %v", err)
+		}
+
+		if again[:saltSize] == sealed[:saltSize] {
+			t.Fatalf("expected each sealed key to have its own salt")
+		}
+	})
+
+	t.Run("truncated key", func(t *testing.T) {
+		if _, err := OpenPrivateKey(sealed[:saltSize], "secret"); !errors.Is(err, ErrSealedKey) {
+			t.Fatalf("expected %v, got %v", ErrSealedKey, err)
+		}
+	})
+}
+
+func TestReadPrivateKey(t *testing.T) {
+	entity, publicKey := newTestEntity(t)
+
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("could not armor the key: %v", err)
+	}
+
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("could not serialize the key: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not armor the key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		expect error
+	}{
+		{"private key", armored.String(), nil},
+		{"public key", publicKey, ErrInvalidPrivateKey},
+		{"invalid key", "not a key", ErrInvalidPrivateKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ReadPrivateKey(tt.key, "")
+
+			if tt.expect == nil && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if tt.expect != nil && !errors.Is(err, tt.expect) {
+				t.Fatalf("expected %v, got %v", tt.expect, err)
+			}
+
+			if tt.expect == nil && key.KeyID() != entity.PrimaryKey.KeyIdString() {
+				t.Fatalf("expected key %s, got %s", entity.PrimaryKey.KeyIdString(), key.KeyID())
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+var (
+	ErrInvalidKey       = errors.New("invalid public key")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+const armoredSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+
+// Verify checks a detached signature of a content against an ASCII armored
+// public key. The signature can be either binary, as the ones produced by
+// GoReleaser, or ASCII armored.
+func Verify(asciiArmor string, content, signature []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(asciiArmor))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	check := openpgp.CheckDetachedSignature
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(armoredSignatureHeader)) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+
+	if _, err := check(keyring, bytes.NewReader(content), bytes.NewReader(signature), nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
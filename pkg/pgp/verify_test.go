@@ -0,0 +1,80 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func newTestEntity(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Terralist", "test", "test@terralist.io", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("could not generate the key: %v", err)
+	}
+
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("could not armor the key: %v", err)
+	}
+
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("could not serialize the key: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not armor the key: %v", err)
+	}
+
+	return entity, armored.String()
+}
+
+func TestVerify(t *testing.T) {
+	signer, signerKey := newTestEntity(t)
+	_, otherKey := newTestEntity(t)
+
+	content := []byte("0123456789abcdef  terraform-provider-acme_1.0.0_linux_amd64.zip\n")
+
+	var binary, armored bytes.Buffer
+	if err := openpgp.DetachSign(&binary, signer, bytes.NewReader(content), nil); err != nil {
+		t.Fatalf("could not sign the content: %v", err)
+	}
+
+	if err := openpgp.ArmoredDetachSign(&armored, signer, bytes.NewReader(content), nil); err != nil {
+		t.Fatalf("could not sign the content: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		content   []byte
+		signature []byte
+		expect    error
+	}{
+		{"binary signature", signerKey, content, binary.Bytes(), nil},
+		{"armored signature", signerKey, content, armored.Bytes(), nil},
+		{"other key", otherKey, content, binary.Bytes(), ErrInvalidSignature},
+		{"altered content", signerKey, append([]byte("x"), content...), binary.Bytes(), ErrInvalidSignature},
+		{"invalid key", "not a key", content, binary.Bytes(), ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.key, tt.content, tt.signature)
+
+			if tt.expect == nil && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if tt.expect != nil && !errors.Is(err, tt.expect) {
+				t.Fatalf("expected %v, got %v", tt.expect, err)
+			}
+		})
+	}
+}
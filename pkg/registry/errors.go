@@ -0,0 +1,10 @@
+package registry
+
+import "errors"
+
+var (
+	ErrDiscovery       = errors.New("could not discover the registry services")
+	ErrNotFound        = errors.New("not found in the upstream registry")
+	ErrInvalidRequest  = errors.New("invalid upstream registry request")
+	ErrInvalidLocation = errors.New("unsupported upstream location")
+)
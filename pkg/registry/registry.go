@@ -0,0 +1,273 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoveryPath = "/.well-known/terraform.json"
+
+	modulesService   = "modules.v1"
+	providersService = "providers.v1"
+
+	// terraformGetHeader holds the location of a module version
+	terraformGetHeader = "X-Terraform-Get"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// allowedGetters are the go-getter prefixes accepted in the locations
+// returned by an upstream registry, the other getters (e.g. file, s3) could
+// make the registry read its own files or credentials.
+var allowedGetters = []string{"", "git"}
+
+// Client queries an upstream registry, such as registry.terraform.io, using
+// the module and provider registry protocols.
+type Client interface {
+	// Host returns the host of the upstream registry.
+	Host() string
+
+	// ProviderVersions lists the versions of a provider.
+	ProviderVersions(namespace, name string) (*ProviderVersions, error)
+
+	// ProviderPackage returns the package of a provider version for a
+	// platform.
+	ProviderPackage(namespace, name, version, system, architecture string) (*ProviderPackage, error)
+
+	// ModuleVersions lists the versions of a module.
+	ModuleVersions(namespace, name, provider string) (*ModuleVersions, error)
+
+	// ModuleLocation returns the location from which a module version can
+	// be downloaded, in the format accepted by go-getter. Only the HTTP(S)
+	// locations, which may be forced to the git getter, are returned.
+	ModuleLocation(namespace, name, provider, version string) (string, error)
+}
+
+type defaultClient struct {
+	host       string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	services map[string]*url.URL
+}
+
+// NewClient creates a client for the registry served on a host. The
+// registry services are discovered on the first request.
+func NewClient(host string) Client {
+	return &defaultClient{
+		host:       host,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+func (c *defaultClient) Host() string {
+	return c.host
+}
+
+// Docs: https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#list-available-versions
+func (c *defaultClient) ProviderVersions(namespace, name string) (*ProviderVersions, error) {
+	u, err := c.serviceURL(providersService, namespace, name, "versions")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions ProviderVersions
+	if _, err := c.get(u, &versions); err != nil {
+		return nil, err
+	}
+
+	return &versions, nil
+}
+
+// Docs: https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#find-a-provider-package
+func (c *defaultClient) ProviderPackage(namespace, name, version, system, architecture string) (*ProviderPackage, error) {
+	u, err := c.serviceURL(providersService, namespace, name, version, "download", system, architecture)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg ProviderPackage
+	if _, err := c.get(u, &pkg); err != nil {
+		return nil, err
+	}
+
+	// The URLs may be relative to the one of the package
+	for _, location := range []*string{&pkg.DownloadURL, &pkg.ShaSumsURL, &pkg.ShaSumsSignatureURL} {
+		*location = resolve(u, *location)
+
+		if err := checkLocation(*location, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return &pkg, nil
+}
+
+// Docs: https://developer.hashicorp.com/terraform/internals/module-registry-protocol#list-available-versions-for-a-specific-module
+func (c *defaultClient) ModuleVersions(namespace, name, provider string) (*ModuleVersions, error) {
+	u, err := c.serviceURL(modulesService, namespace, name, provider, "versions")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions ModuleVersions
+	if _, err := c.get(u, &versions); err != nil {
+		return nil, err
+	}
+
+	return &versions, nil
+}
+
+// Docs: https://developer.hashicorp.com/terraform/internals/module-registry-protocol#download-source-code-for-a-specific-module-version
+func (c *defaultClient) ModuleLocation(namespace, name, provider, version string) (string, error) {
+	u, err := c.serviceURL(modulesService, namespace, name, provider, version, "download")
+	if err != nil {
+		return "", err
+	}
+
+	header, err := c.get(u, nil)
+	if err != nil {
+		return "", err
+	}
+
+	location := header.Get(terraformGetHeader)
+	if location == "" {
+		return "", fmt.Errorf("%w: the registry did not return the location of the module", ErrInvalidRequest)
+	}
+
+	location = resolve(u, location)
+	if err := checkLocation(location, true); err != nil {
+		return "", err
+	}
+
+	return location, nil
+}
+
+// serviceURL returns the URL of a path within a registry service.
+func (c *defaultClient) serviceURL(service string, elem ...string) (*url.URL, error) {
+	base, err := c.service(service)
+	if err != nil {
+		return nil, err
+	}
+
+	return base.JoinPath(elem...), nil
+}
+
+// service returns the base URL of a registry service, discovering the
+// services of the registry if needed.
+// Docs: https://developer.hashicorp.com/terraform/internals/remote-service-discovery
+func (c *defaultClient) service(name string) (*url.URL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.services == nil {
+		u := &url.URL{Scheme: "https", Host: c.host, Path: discoveryPath}
+
+		var document map[string]any
+		if _, err := c.get(u, &document); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+		}
+
+		services := map[string]*url.URL{}
+		for _, s := range []string{modulesService, providersService} {
+			location, ok := document[s].(string)
+			if !ok {
+				continue
+			}
+
+			// The service URLs may be relative to the discovery document
+			base, err := u.Parse(location)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s URL: %v", ErrDiscovery, s, err)
+			}
+
+			services[s] = base
+		}
+
+		c.services = services
+	}
+
+	base, ok := c.services[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: the registry does not support %s", ErrDiscovery, name)
+	}
+
+	return base, nil
+}
+
+// get sends a GET request and decodes its JSON response, if out is set. It
+// returns the headers of the response.
+func (c *defaultClient) get(u *url.URL, out any) (http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", u.Redacted(), err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, u.Path)
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return nil, fmt.Errorf("request to %s responded with status %d", u.Redacted(), res.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("could not decode the response of %s: %w", u.Redacted(), err)
+		}
+	}
+
+	return res.Header, nil
+}
+
+// resolve resolves a location relative to the URL it was returned by. The
+// locations with a go-getter prefix (e.g. git::https://...) are kept as is.
+func resolve(base *url.URL, location string) string {
+	// Absolute URLs and go-getter locations (which may not be valid URLs)
+	// are returned as is
+	u, err := url.Parse(location)
+	if location == "" || err != nil || u.Scheme != "" {
+		return location
+	}
+
+	return base.ResolveReference(u).String()
+}
+
+// checkLocation returns an error if a location returned by the registry is
+// not an HTTP(S) URL. If getters is true, the location may be forced to one
+// of the allowed go-getter getters (e.g. git::https://...).
+func checkLocation(location string, getters bool) error {
+	getter, rest := "", location
+	if i := strings.Index(location, "::"); i > 0 {
+		getter, rest = location[:i], location[i+2:]
+	}
+
+	if getter != "" && (!getters || !slices.Contains(allowedGetters, getter)) {
+		return fmt.Errorf("%w: the %s getter is not allowed", ErrInvalidLocation, getter)
+	}
+
+	u, err := url.Parse(rest)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLocation, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s is not an HTTP(S) URL", ErrInvalidLocation, u.Redacted())
+	}
+
+	return nil
+}
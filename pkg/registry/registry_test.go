@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newTestClient(t *testing.T, discovery string) (*defaultClient, *http.ServeMux) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(discovery))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+
+	c := NewClient(u.Host).(*defaultClient)
+	c.httpClient = server.Client()
+
+	return c, mux
+}
+
+func TestProviderPackage(t *testing.T) {
+	c, mux := newTestClient(t, `{"providers.v1": "/v1/providers/"}`)
+
+	mux.HandleFunc("/v1/providers/hashicorp/random/3.6.0/download/linux/amd64", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"os": "linux",
+			"arch": "amd64",
+			"download_url": "https://releases.example.com/random_linux_amd64.zip",
+			"shasums_url": "SHA256SUMS",
+			"shasums_signature_url": "/files/SHA256SUMS.sig",
+			"shasum": "abcdef",
+			"signing_keys": {"gpg_public_keys": [{"key_id": "34365D9472D7468F"}]}
+		}`))
+	})
+
+	pkg, err := c.ProviderPackage("hashicorp", "random", "3.6.0", "linux", "amd64")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	base := "https://" + c.Host()
+
+	tests := []struct {
+		name   string
+		got    string
+		expect string
+	}{
+		{"absolute URL", pkg.DownloadURL, "https://releases.example.com/random_linux_amd64.zip"},
+		{"relative URL", pkg.ShaSumsURL, base + "/v1/providers/hashicorp/random/3.6.0/download/linux/SHA256SUMS"},
+		{"absolute path", pkg.ShaSumsSignatureURL, base + "/files/SHA256SUMS.sig"},
+		{"signing key", pkg.SigningKeys.GPGPublicKeys[0].KeyID, "34365D9472D7468F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expect {
+				t.Fatalf("expected %s, got %s", tt.expect, tt.got)
+			}
+		})
+	}
+
+	mux.HandleFunc("/v1/providers/hashicorp/random/3.6.0/download/linux/arm64", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"os": "linux",
+			"arch": "arm64",
+			"download_url": "file:///var/lib/terralist/random_linux_arm64.zip",
+			"shasums_url": "SHA256SUMS",
+			"shasums_signature_url": "SHA256SUMS.sig"
+		}`))
+	})
+
+	t.Run("unsupported location", func(t *testing.T) {
+		if _, err := c.ProviderPackage("hashicorp", "random", "3.6.0", "linux", "arm64"); !errors.Is(err, ErrInvalidLocation) {
+			t.Fatalf("expected %v, got %v", ErrInvalidLocation, err)
+		}
+	})
+}
+
+func TestModuleLocation(t *testing.T) {
+	c, mux := newTestClient(t, `{"modules.v1": "/v1/modules/"}`)
+
+	mux.HandleFunc("/v1/modules/hashicorp/consul/aws/0.1.0/download", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Terraform-Get", "git::https://github.com/hashicorp/terraform-aws-consul?ref=v0.1.0")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	t.Run("existing version", func(t *testing.T) {
+		location, err := c.ModuleLocation("hashicorp", "consul", "aws", "0.1.0")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if expect := "git::https://github.com/hashicorp/terraform-aws-consul?ref=v0.1.0"; location != expect {
+			t.Fatalf("expected %s, got %s", expect, location)
+		}
+	})
+
+	for version, location := range map[string]string{
+		"1.0.0": "file:///etc/passwd",
+		"1.1.0": "s3::https://s3.amazonaws.com/bucket/module.zip",
+		"1.2.0": "git::ssh://git@internal.example.com/module.git",
+		"1.3.0": "git::file:///etc/passwd",
+	} {
+		mux.HandleFunc("/v1/modules/hashicorp/consul/aws/"+version+"/download", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Terraform-Get", location)
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		t.Run("unsupported location "+location, func(t *testing.T) {
+			if _, err := c.ModuleLocation("hashicorp", "consul", "aws", version); !errors.Is(err, ErrInvalidLocation) {
+				t.Fatalf("expected %v, got %v", ErrInvalidLocation, err)
+			}
+		})
+	}
+
+	t.Run("unknown version", func(t *testing.T) {
+		if _, err := c.ModuleLocation("hashicorp", "consul", "aws", "0.2.0"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected %v, got %v", ErrNotFound, err)
+		}
+	})
+
+	t.Run("unsupported service", func(t *testing.T) {
+		if _, err := c.ProviderVersions("hashicorp", "random"); !errors.Is(err, ErrDiscovery) {
+			t.Fatalf("expected %v, got %v", ErrDiscovery, err)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package registry
+
+// ProviderVersions lists the versions of a provider.
+type ProviderVersions struct {
+	Versions []ProviderVersion `json:"versions"`
+}
+
+// ProviderVersion describes a provider version and the platforms it is
+// available for.
+type ProviderVersion struct {
+	Version   string     `json:"version"`
+	Protocols []string   `json:"protocols"`
+	Platforms []Platform `json:"platforms"`
+}
+
+// Platform is a system and an architecture a provider is available for.
+type Platform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// ProviderPackage describes the package of a provider version for a
+// platform.
+type ProviderPackage struct {
+	Protocols           []string    `json:"protocols"`
+	OS                  string      `json:"os"`
+	Arch                string      `json:"arch"`
+	FileName            string      `json:"filename"`
+	DownloadURL         string      `json:"download_url"`
+	ShaSumsURL          string      `json:"shasums_url"`
+	ShaSumsSignatureURL string      `json:"shasums_signature_url"`
+	ShaSum              string      `json:"shasum"`
+	SigningKeys         SigningKeys `json:"signing_keys"`
+}
+
+// SigningKeys lists the keys which may have signed a provider package.
+type SigningKeys struct {
+	GPGPublicKeys []GPGPublicKey `json:"gpg_public_keys"`
+}
+
+// GPGPublicKey is an ASCII armored public key.
+type GPGPublicKey struct {
+	KeyID          string `json:"key_id"`
+	ASCIIArmor     string `json:"ascii_armor"`
+	TrustSignature string `json:"trust_signature"`
+	Source         string `json:"source"`
+	SourceURL      string `json:"source_url"`
+}
+
+// ModuleVersions lists the versions of a module.
+type ModuleVersions struct {
+	Modules []struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
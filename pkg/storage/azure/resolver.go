@@ -17,6 +17,10 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 )
 
+// blockSize is the size of the blocks the files are uploaded in. The SDK
+// default of 1 MiB requires too many requests for the large providers.
+const blockSize = 8 << 20
+
 type Resolver struct {
 	ContainerName string
 	AccountName   string
@@ -40,12 +44,25 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 
 	ctx := context.Background()
 
-	_, err := r.Client.UploadStream(ctx, r.ContainerName, key, in.Reader, nil)
+	// The content is uploaded in blocks, only the blocks being uploaded are
+	// buffered
+	_, err := r.Client.UploadStream(ctx, r.ContainerName, key, in.Reader, &azblob.UploadStreamOptions{
+		BlockSize: blockSize,
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: to.Ptr(in.ContentType),
+		},
+	})
 	if err != nil {
 		return "", fmt.Errorf("could not upload archive: %v", err)
 	}
-	return key, nil
 
+	// The blocks are not reported as they are uploaded, so the content is
+	// reported as a single part
+	if written, err := in.Reader.Seek(0, io.SeekCurrent); err == nil {
+		in.ReportPart(written)
+	}
+
+	return key, nil
 }
 
 func (r *Resolver) GetSASURL(blobName string) (string, error) {
@@ -0,0 +1,154 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"terralist/pkg/storage"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testContainer is a fake Azure container, which stores the blobs uploaded
+// in a single request or in blocks.
+type testContainer struct {
+	mu sync.Mutex
+
+	blocks       map[string][]byte
+	blobs        map[string][]byte
+	contentTypes map[string]string
+
+	// stagedBlocks is the number of blocks staged
+	stagedBlocks int
+}
+
+func (c *testContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.Method != http.MethodPut {
+		http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/test-container/")
+
+	switch r.URL.Query().Get("comp") {
+	case "block":
+		c.blocks[r.URL.Query().Get("blockid")] = body
+		c.stagedBlocks++
+	case "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var content []byte
+		for _, id := range list.Latest {
+			content = append(content, c.blocks[id]...)
+		}
+
+		c.blobs[name] = content
+		c.contentTypes[name] = r.Header.Get("x-ms-blob-content-type")
+	default:
+		c.blobs[name] = body
+		c.contentTypes[name] = r.Header.Get("x-ms-blob-content-type")
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newTestResolver(t *testing.T) (*Resolver, *testContainer) {
+	container := &testContainer{
+		blocks:       map[string][]byte{},
+		blobs:        map[string][]byte{},
+		contentTypes: map[string]string{},
+	}
+
+	srv := httptest.NewServer(container)
+	t.Cleanup(srv.Close)
+
+	client, err := azblob.NewClientWithNoCredential(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create the Azure client: %v", err)
+	}
+
+	return &Resolver{
+		ContainerName: "test-container",
+		Client:        client,
+	}, container
+}
+
+func TestStore(t *testing.T) {
+	Convey("Subject: Store files in Azure", t, func() {
+		resolver, container := newTestResolver(t)
+
+		var parts []int64
+		storeInput := &storage.StoreInput{
+			KeyPrefix:   "test",
+			FileName:    "test.txt",
+			ContentType: "text/plain",
+			OnPart: func(size int64) {
+				parts = append(parts, size)
+			},
+		}
+
+		Convey("When the file is smaller than a block", func() {
+			content := []byte("test content")
+			storeInput.Reader = bytes.NewReader(content)
+			storeInput.Size = int64(len(content))
+
+			// The reader is not expected to be rewound by the caller
+			_, _ = storeInput.Reader.Seek(4, io.SeekStart)
+
+			key, err := resolver.Store(storeInput)
+
+			Convey("The file should be stored under its key", func() {
+				So(err, ShouldBeNil)
+				So(key, ShouldEqual, "test/test.txt")
+				So(container.blobs["test/test.txt"], ShouldResemble, content)
+				So(container.contentTypes["test/test.txt"], ShouldEqual, "text/plain")
+			})
+
+			Convey("The file should be reported as a single part", func() {
+				So(parts, ShouldResemble, []int64{int64(len(content))})
+			})
+		})
+
+		Convey("When the file is larger than a block", func() {
+			content := bytes.Repeat([]byte("a"), blockSize+blockSize/2)
+			storeInput.Reader = bytes.NewReader(content)
+			storeInput.Size = int64(len(content))
+
+			key, err := resolver.Store(storeInput)
+
+			Convey("The file should be stored in blocks of the configured size", func() {
+				So(err, ShouldBeNil)
+				So(key, ShouldEqual, "test/test.txt")
+				So(container.stagedBlocks, ShouldEqual, 2)
+				So(container.blobs["test/test.txt"], ShouldResemble, content)
+				So(container.contentTypes["test/test.txt"], ShouldEqual, "text/plain")
+			})
+
+			Convey("The file should be reported once uploaded", func() {
+				So(parts, ShouldResemble, []int64{int64(len(content))})
+			})
+		})
+	})
+}
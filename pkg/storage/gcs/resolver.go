@@ -14,6 +14,20 @@ import (
 // The GCS resolver will download files from the given URL then
 // uploads them to an GCS bucket, generating a public download URL.
 
+const (
+	// chunkSize is the size of the chunks the files are uploaded in, only
+	// one chunk is buffered at a time.
+	chunkSize = 8 << 20
+
+	// uploadTimeout is how long the upload of an empty file may take, the
+	// files are given more time according to their size.
+	uploadTimeout = 2 * time.Minute
+
+	// minUploadRate is the slowest rate, in bytes per second, at which the
+	// files are expected to be uploaded.
+	minUploadRate = 1 << 20
+)
+
 type Resolver struct {
 	BucketName   string
 	BucketPrefix string
@@ -31,14 +45,26 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 		return "", fmt.Errorf("could not upload archive, file can't be rewinded: %w", err)
 	}
 
-	ctx := context.Background()
-
-	ctx, cancel := context.WithTimeout(ctx, time.Minute*2)
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout(in.Size))
 	defer cancel()
 
 	wc := r.Client.Bucket(r.BucketName).Object(key).NewWriter(ctx)
+	wc.ContentType = in.ContentType
+	wc.ChunkSize = chunkSize
+
+	// A chunk whose upload fails is retried until this deadline passes
+	wc.ChunkRetryDeadline = time.Minute * 2
 
-	if _, err := io.Copy(wc, in.Reader); err != nil {
+	// The writer reports the bytes uploaded after each chunk
+	var reported int64
+	wc.ProgressFunc = func(uploaded int64) {
+		in.ReportPart(uploaded - reported)
+		reported = uploaded
+	}
+
+	written, err := io.Copy(wc, in.Reader)
+	if err != nil {
+		// Cancelling the context aborts the upload
 		return "", fmt.Errorf("could not upload archive: %v", err)
 	}
 
@@ -46,9 +72,21 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 		return "", fmt.Errorf("could not close the archive: %v", err)
 	}
 
+	// The last chunk, or the whole file if it fits in a single chunk, is
+	// uploaded on close
+	if written > reported {
+		in.ReportPart(written - reported)
+	}
+
 	return key, nil
 }
 
+// storeTimeout returns how long the upload of a file of the given size may
+// take.
+func storeTimeout(size int64) time.Duration {
+	return uploadTimeout + time.Duration(size/minUploadRate)*time.Second
+}
+
 func (r *Resolver) Find(key string) (string, error) {
 	opts := &gcs.SignedURLOptions{
 		Scheme:  gcs.SigningSchemeV4,
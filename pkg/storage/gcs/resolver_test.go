@@ -0,0 +1,171 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"terralist/pkg/storage"
+
+	gcs "cloud.google.com/go/storage"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// uploadedObject is an object received by the fake GCS server.
+type uploadedObject struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// newTestServer starts a fake GCS server accepting the single request
+// uploads, and returns a client connected to it.
+func newTestServer(t *testing.T, status int) (*gcs.Client, *[]uploadedObject) {
+	var uploaded []uploadedObject
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, `{"error":{"code":403,"message":"forbidden"}}`, status)
+			return
+		}
+
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Errorf("unexpected upload content type: %v", err)
+			return
+		}
+
+		// The metadata is sent first, followed by the content
+		reader := multipart.NewReader(r.Body, params["boundary"])
+
+		var object uploadedObject
+		metadata, err := reader.NextPart()
+		if err != nil {
+			t.Errorf("missing metadata part: %v", err)
+			return
+		}
+		if err := json.NewDecoder(metadata).Decode(&object); err != nil {
+			t.Errorf("invalid metadata part: %v", err)
+			return
+		}
+
+		media, err := reader.NextPart()
+		if err != nil {
+			t.Errorf("missing media part: %v", err)
+			return
+		}
+		if object.Content, err = io.ReadAll(media); err != nil {
+			t.Errorf("invalid media part: %v", err)
+			return
+		}
+
+		uploaded = append(uploaded, object)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"bucket":      "test-bucket",
+			"name":        object.Name,
+			"contentType": object.ContentType,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("STORAGE_EMULATOR_HOST", srv.URL)
+
+	client, err := gcs.NewClient(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create the GCS client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client, &uploaded
+}
+
+func TestStore(t *testing.T) {
+	Convey("Subject: Store files in GCS", t, func() {
+		content := []byte("test content")
+
+		var parts []int64
+		storeInput := &storage.StoreInput{
+			KeyPrefix:   "test",
+			FileName:    "test.txt",
+			Reader:      bytes.NewReader(content),
+			Size:        int64(len(content)),
+			ContentType: "text/plain",
+			OnPart: func(size int64) {
+				parts = append(parts, size)
+			},
+		}
+
+		Convey("When the bucket accepts the upload", func() {
+			client, uploaded := newTestServer(t, http.StatusOK)
+
+			resolver := &Resolver{
+				BucketName: "test-bucket",
+				Client:     client,
+			}
+
+			// The reader is not expected to be rewound by the caller
+			_, _ = storeInput.Reader.Seek(4, io.SeekStart)
+
+			key, err := resolver.Store(storeInput)
+
+			Convey("The file should be stored under its key", func() {
+				So(err, ShouldBeNil)
+				So(key, ShouldEqual, "test/test.txt")
+				So(*uploaded, ShouldHaveLength, 1)
+				So((*uploaded)[0].Name, ShouldEqual, "test/test.txt")
+				So((*uploaded)[0].ContentType, ShouldEqual, "text/plain")
+				So((*uploaded)[0].Content, ShouldResemble, content)
+			})
+
+			Convey("The file should be reported as a single part", func() {
+				So(parts, ShouldResemble, []int64{int64(len(content))})
+			})
+		})
+
+		Convey("When the bucket rejects the upload", func() {
+			client, _ := newTestServer(t, http.StatusForbidden)
+
+			resolver := &Resolver{
+				BucketName: "test-bucket",
+				Client:     client,
+			}
+
+			key, err := resolver.Store(storeInput)
+
+			Convey("An error should be returned", func() {
+				So(err, ShouldNotBeNil)
+				So(key, ShouldBeEmpty)
+				So(parts, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestStoreTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		want time.Duration
+	}{
+		{name: "empty file", size: 0, want: 2 * time.Minute},
+		{name: "small file", size: 512 << 10, want: 2 * time.Minute},
+		{name: "large file", size: 600 << 20, want: 12 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := storeTimeout(tt.size); got != tt.want {
+				t.Fatalf("storeTimeout(%d) = %s, want %s", tt.size, got, tt.want)
+			}
+		})
+	}
+}
@@ -36,17 +36,6 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 	fileKey := path.Join(in.KeyPrefix, in.FileName)
 	filePath := path.Join(r.RegistryDir, fileKey)
 
-	_, err := os.Stat(filePath)
-
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return "", err
-	}
-
-	// If the file already exists, remove it first so it can be overwritten
-	if err == nil {
-		os.Remove(filePath)
-	}
-
 	if err := os.MkdirAll(path.Dir(filePath), 0700); err != nil {
 		return "", fmt.Errorf("could not create parent directories: %w", err)
 	}
@@ -55,15 +44,35 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 		return "", fmt.Errorf("could not rewind input reader: %w", err)
 	}
 
-	content, err := io.ReadAll(in.Reader)
+	// The content is written next to its final path, then renamed, so an
+	// existing file is replaced atomically and is never read while partially
+	// written
+	tmp, err := os.CreateTemp(path.Dir(filePath), fmt.Sprintf(".%s.*.tmp", path.Base(filePath)))
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, in.Reader)
 	if err != nil {
-		return "", fmt.Errorf("could not read input content: %w", err)
+		tmp.Close()
+		return "", fmt.Errorf("could not store file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("could not store file: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, content, 0700); err != nil {
+	if err := os.Chmod(tmp.Name(), 0700); err != nil {
 		return "", fmt.Errorf("could not store file: %w", err)
 	}
 
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return "", fmt.Errorf("could not store file: %w", err)
+	}
+
+	in.ReportPart(written)
+
 	return fileKey, nil
 }
 
@@ -126,6 +135,8 @@ func UnwrapResolver(resolver storage.Resolver) *Resolver {
 		return r
 	case *storage.MetricsResolver:
 		return UnwrapResolver(r.Resolver)
+	case storage.Wrapper:
+		return UnwrapResolver(r.Unwrap())
 	default:
 		return nil
 	}
@@ -0,0 +1,132 @@
+package local
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"terralist/pkg/storage"
+)
+
+// failingReader returns an error after its content is read.
+type failingReader struct {
+	reader *bytes.Reader
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("connection reset")
+	}
+
+	return n, err
+}
+
+func (r failingReader) Seek(offset int64, whence int) (int64, error) {
+	return r.reader.Seek(offset, whence)
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	resolver := &Resolver{RegistryDir: dir}
+
+	store := func(content []byte) (string, []int64, error) {
+		var parts []int64
+
+		reader := bytes.NewReader(content)
+
+		// The reader is not expected to be rewound by the caller
+		_, _ = reader.Seek(int64(len(content)/2), io.SeekStart)
+
+		key, err := resolver.Store(&storage.StoreInput{
+			Reader:    reader,
+			Size:      int64(len(content)),
+			KeyPrefix: "providers/hashicorp/random",
+			FileName:  "terraform-provider-random_3.6.0_SHA256SUMS",
+			OnPart: func(size int64) {
+				parts = append(parts, size)
+			},
+		})
+
+		return key, parts, err
+	}
+
+	key, parts, err := store([]byte("first content"))
+	if err != nil {
+		t.Fatalf("failed to store the file: %v", err)
+	}
+
+	if want := "providers/hashicorp/random/terraform-provider-random_3.6.0_SHA256SUMS"; key != want {
+		t.Fatalf("expected the key %q, got %q", want, key)
+	}
+
+	if len(parts) != 1 || parts[0] != int64(len("first content")) {
+		t.Fatalf("expected the file to be reported as a single part, got %v", parts)
+	}
+
+	// A stored file is replaced
+	if _, _, err := store([]byte("second content")); err != nil {
+		t.Fatalf("failed to replace the file: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, key))
+	if err != nil {
+		t.Fatalf("failed to read the stored file: %v", err)
+	}
+
+	if string(content) != "second content" {
+		t.Fatalf("expected the file to be replaced, got %q", content)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(filepath.Join(dir, key)))
+	if err != nil {
+		t.Fatalf("failed to list the stored files: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary file to be left, got %d files", len(entries))
+	}
+}
+
+func TestStoreFailure(t *testing.T) {
+	dir := t.TempDir()
+	resolver := &Resolver{RegistryDir: dir}
+
+	in := &storage.StoreInput{
+		KeyPrefix: "modules/hashicorp/consul/aws",
+		FileName:  "0.1.0.tar.gz",
+	}
+
+	in.Reader = bytes.NewReader([]byte("first content"))
+	key, err := resolver.Store(in)
+	if err != nil {
+		t.Fatalf("failed to store the file: %v", err)
+	}
+
+	// A failed upload must not alter the stored file
+	in.Reader = failingReader{bytes.NewReader([]byte("partial"))}
+	if _, err := resolver.Store(in); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, key))
+	if err != nil {
+		t.Fatalf("failed to read the stored file: %v", err)
+	}
+
+	if string(content) != "first content" {
+		t.Fatalf("expected the stored file to be kept, got %q", content)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(filepath.Join(dir, key)))
+	if err != nil {
+		t.Fatalf("failed to list the stored files: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary file to be left, got %d files", len(entries))
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"sync/atomic"
 	"time"
 
 	"terralist/pkg/metrics"
@@ -14,11 +15,23 @@ type MetricsResolver struct {
 	Backend  string
 }
 
-// Store uploads a file and records metrics.
+// Store uploads a file and records metrics, along with the progress of the
+// upload as its parts are uploaded.
 func (m *MetricsResolver) Store(in *StoreInput) (string, error) {
 	start := time.Now()
 
-	key, err := m.Resolver.Store(in)
+	var uploaded atomic.Int64
+	observed := *in
+	observed.OnPart = func(size int64) {
+		uploaded.Add(size)
+		metrics.RecordStorageUploadPart(m.Backend, size)
+
+		in.ReportPart(size)
+	}
+
+	key, err := m.Resolver.Store(&observed)
+
+	metrics.RecordStorageUploadDone(m.Backend, uploaded.Load())
 
 	duration := time.Since(start).Seconds()
 	status := "success"
@@ -22,12 +22,25 @@ type StoreInput struct {
 	// applied to the resulted key.
 	// Also represents the basename of the datastore path.
 	FileName string
+
+	// OnPart is called with the size of each part of the data once it is
+	// uploaded. The resolvers which do not track the parts of the data
+	// report it as a single part. It may be called concurrently.
+	OnPart func(size int64)
+}
+
+// ReportPart notifies that a part of the data was uploaded.
+func (in *StoreInput) ReportPart(size int64) {
+	if in.OnPart != nil {
+		in.OnPart(size)
+	}
 }
 
 // Resolver handles the storage and resolve operations.
 type Resolver interface {
 	// Store uploads a file to the resolver datastore and returns
-	// a unique key.
+	// a unique key. The content is streamed from the reader, so the
+	// implementations should not buffer it entirely.
 	Store(*StoreInput) (string, error)
 
 	// Find receives a key and returns a URL from where the
@@ -38,3 +51,10 @@ type Resolver interface {
 	// If the given key does not exist, it will not return an error.
 	Purge(string) error
 }
+
+// Wrapper is implemented by the resolvers which add a behaviour on top of
+// another resolver.
+type Wrapper interface {
+	// Unwrap returns the wrapped resolver.
+	Unwrap() Resolver
+}
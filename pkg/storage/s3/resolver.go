@@ -10,14 +10,20 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // S3Client is a wrapper interface around the S3 client methods used by the Resolver.
+// The multipart methods are used by the transfer manager.
 type S3Client interface {
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
 }
 
 // PresignClient is a wrapper interface around the S3 Presign client methods used by the Resolver.
@@ -52,7 +58,6 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 		Bucket:             aws.String(r.BucketName),
 		Key:                r.withPrefix(key),
 		Body:               in.Reader,
-		ContentLength:      aws.Int64(in.Size),
 		ContentType:        aws.String(in.ContentType),
 		ContentDisposition: aws.String("attachment"),
 	}
@@ -66,7 +71,11 @@ func (r *Resolver) Store(in *storage.StoreInput) (string, error) {
 		putObjectInput.ServerSideEncryption = types.ServerSideEncryption(r.ServerSideEncryption)
 	}
 
-	if _, err := r.Client.PutObject(context.TODO(), putObjectInput); err != nil {
+	// The transfer manager sends the files larger than a part in multiple
+	// parts, read from the input as they are sent
+	uploader := manager.NewUploader(&partsClient{S3Client: r.Client, in: in})
+
+	if _, err := uploader.Upload(context.TODO(), putObjectInput); err != nil {
 		return "", fmt.Errorf("could not upload archive: %v", err)
 	}
 
@@ -101,3 +110,57 @@ func (r *Resolver) Purge(key string) error {
 func (r *Resolver) withPrefix(key string) *string {
 	return aws.String(fmt.Sprintf("%s%s", r.BucketPrefix, key))
 }
+
+// partsClient reports the parts uploaded by the transfer manager.
+type partsClient struct {
+	S3Client
+
+	in *storage.StoreInput
+}
+
+func (c *partsClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	size := bodySize(params.Body)
+
+	out, err := c.S3Client.PutObject(ctx, params, optFns...)
+	if err == nil {
+		c.in.ReportPart(size)
+	}
+
+	return out, err
+}
+
+func (c *partsClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	size := bodySize(params.Body)
+
+	out, err := c.S3Client.UploadPart(ctx, params, optFns...)
+	if err == nil {
+		c.in.ReportPart(size)
+	}
+
+	return out, err
+}
+
+// bodySize returns the number of bytes left to be read from a request body,
+// or 0 if it cannot be known.
+func bodySize(body io.Reader) int64 {
+	s, ok := body.(io.Seeker)
+	if !ok {
+		return 0
+	}
+
+	current, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0
+	}
+
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0
+	}
+
+	if _, err := s.Seek(current, io.SeekStart); err != nil {
+		return 0
+	}
+
+	return end - current
+}
@@ -3,10 +3,12 @@ package s3
 import (
 	"bytes"
 	"fmt"
+	"sync"
 	"testing"
 
 	"terralist/pkg/storage"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -48,7 +50,7 @@ func TestStore(t *testing.T) {
 						So(input.ACL, ShouldEqual, types.ObjectCannedACLPrivate)
 						So(input.ServerSideEncryption, ShouldEqual, types.ServerSideEncryptionAes256)
 						return true
-					})).
+					}), mock.Anything, mock.Anything).
 					Return(&s3.PutObjectOutput{}, nil).
 					Once()
 
@@ -60,7 +62,7 @@ func TestStore(t *testing.T) {
 
 			Convey("Should fail and return error from S3", func() {
 				client.
-					On("PutObject", mock.Anything, mock.AnythingOfType("*s3.PutObjectInput")).
+					On("PutObject", mock.Anything, mock.AnythingOfType("*s3.PutObjectInput"), mock.Anything, mock.Anything).
 					Return(nil, fmt.Errorf("s3 error")).
 					Once()
 
@@ -88,7 +90,7 @@ func TestStore(t *testing.T) {
 						So(input.ACL, ShouldBeEmpty)
 						So(input.ServerSideEncryption, ShouldEqual, types.ServerSideEncryptionAes256)
 						return true
-					})).
+					}), mock.Anything, mock.Anything).
 					Return(&s3.PutObjectOutput{}, nil).
 					Once()
 
@@ -100,7 +102,7 @@ func TestStore(t *testing.T) {
 
 			Convey("Should fail and return error from S3", func() {
 				client.
-					On("PutObject", mock.Anything, mock.AnythingOfType("*s3.PutObjectInput")).
+					On("PutObject", mock.Anything, mock.AnythingOfType("*s3.PutObjectInput"), mock.Anything, mock.Anything).
 					Return(nil, fmt.Errorf("s3 error")).
 					Once()
 
@@ -111,6 +113,69 @@ func TestStore(t *testing.T) {
 			})
 		})
 
+		Convey("When the file is larger than a part", func() {
+			resolver := &Resolver{
+				BucketName:           "test-bucket",
+				BucketPrefix:         "test-prefix/",
+				ServerSideEncryption: "none",
+				Client:               client,
+				Presigner:            presigner,
+			}
+
+			content := bytes.Repeat([]byte("a"), 12<<20)
+			storeInput.Reader = bytes.NewReader(content)
+			storeInput.Size = int64(len(content))
+
+			var parts []int64
+			var m sync.Mutex
+			storeInput.OnPart = func(size int64) {
+				m.Lock()
+				defer m.Unlock()
+
+				parts = append(parts, size)
+			}
+
+			Convey("Should upload it in multiple parts", func() {
+				client.
+					On("CreateMultipartUpload", mock.Anything, mock.MatchedBy(func(input *s3.CreateMultipartUploadInput) bool {
+						So(*input.Key, ShouldEqual, expectedPrefixedKey)
+						return true
+					}), mock.Anything, mock.Anything).
+					Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}, nil).
+					Once()
+
+				client.
+					On("UploadPart", mock.Anything, mock.MatchedBy(func(input *s3.UploadPartInput) bool {
+						return *input.UploadId == "upload-id"
+					}), mock.Anything, mock.Anything).
+					Return(&s3.UploadPartOutput{ETag: aws.String("etag")}, nil).
+					Times(3)
+
+				client.
+					On("CompleteMultipartUpload", mock.Anything, mock.MatchedBy(func(input *s3.CompleteMultipartUploadInput) bool {
+						So(input.MultipartUpload.Parts, ShouldHaveLength, 3)
+						return true
+					}), mock.Anything, mock.Anything).
+					Return(&s3.CompleteMultipartUploadOutput{}, nil).
+					Once()
+
+				key, err := resolver.Store(storeInput)
+				So(err, ShouldBeNil)
+				So(key, ShouldEqual, expectedKey)
+				client.AssertExpectations(t)
+
+				Convey("Each part should be reported", func() {
+					So(parts, ShouldHaveLength, 3)
+
+					var total int64
+					for _, p := range parts {
+						total += p
+					}
+					So(total, ShouldEqual, len(content))
+				})
+			})
+		})
+
 		Convey("When ServerSideEncryption is 'none'", func() {
 			resolver := &Resolver{
 				BucketName:           "test-bucket",
@@ -126,7 +191,7 @@ func TestStore(t *testing.T) {
 					On("PutObject", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
 						So(input.ServerSideEncryption, ShouldBeEmpty)
 						return true
-					})).
+					}), mock.Anything, mock.Anything).
 					Return(&s3.PutObjectOutput{}, nil).
 					Once()
 
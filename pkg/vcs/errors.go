@@ -0,0 +1,13 @@
+package vcs
+
+import "errors"
+
+var (
+	ErrUnsupportedKind  = errors.New("unsupported version control system")
+	ErrUnsupportedEvent = errors.New("unsupported event")
+
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+)
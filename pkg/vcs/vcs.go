@@ -0,0 +1,273 @@
+package vcs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kind is the kind of version control system sending webhook events.
+type Kind string
+
+const (
+	GitHub    Kind = "github"
+	GitLab    Kind = "gitlab"
+	Bitbucket Kind = "bitbucket"
+)
+
+const (
+	tagRefPrefix = "refs/tags/"
+
+	// zeroCommit is the commit hash sent by GitLab when a tag is deleted
+	zeroCommit = "0000000000000000000000000000000000000000"
+)
+
+// Kinds returns all the supported kinds of version control systems.
+func Kinds() []Kind {
+	return []Kind{GitHub, GitLab, Bitbucket}
+}
+
+// Valid returns true if the kind is supported.
+func (k Kind) Valid() bool {
+	switch k {
+	case GitHub, GitLab, Bitbucket:
+		return true
+	}
+
+	return false
+}
+
+// CloneURL returns the HTTPS URL from which a repository hosted by the
+// public instance of the kind can be cloned.
+func (k Kind) CloneURL(repository string) string {
+	switch k {
+	case GitHub:
+		return fmt.Sprintf("https://github.com/%s.git", repository)
+	case GitLab:
+		return fmt.Sprintf("https://gitlab.com/%s.git", repository)
+	case Bitbucket:
+		return fmt.Sprintf("https://bitbucket.org/%s.git", repository)
+	}
+
+	return ""
+}
+
+// TagEvent is a tag pushed to a repository.
+type TagEvent struct {
+	// Repository is the full name of the repository (e.g. acme/terraform-aws-vpc)
+	Repository string
+
+	// Tag is the name of the tag, without the refs/tags/ prefix
+	Tag string
+
+	// Commit is the hash of the commit the tag points to
+	Commit string
+}
+
+// Event returns the name of the event sent in a webhook request.
+func Event(kind Kind, header http.Header) string {
+	switch kind {
+	case GitHub:
+		return header.Get("X-GitHub-Event")
+	case GitLab:
+		return header.Get("X-Gitlab-Event")
+	case Bitbucket:
+		return header.Get("X-Event-Key")
+	}
+
+	return ""
+}
+
+// DeliveryID returns the identifier assigned by the sender to a webhook
+// request, if any.
+func DeliveryID(kind Kind, header http.Header) string {
+	switch kind {
+	case GitHub:
+		return header.Get("X-GitHub-Delivery")
+	case GitLab:
+		return header.Get("X-Gitlab-Event-UUID")
+	case Bitbucket:
+		return header.Get("X-Request-UUID")
+	}
+
+	return ""
+}
+
+// Verify checks that a webhook request was sent by a party knowing the
+// secret. GitHub and Bitbucket sign the body with an HMAC-SHA256, while
+// GitLab sends the secret as a token.
+func Verify(kind Kind, secret string, header http.Header, body []byte) error {
+	switch kind {
+	case GitHub:
+		return verifyHMAC(secret, header.Get("X-Hub-Signature-256"), body)
+	case Bitbucket:
+		return verifyHMAC(secret, header.Get("X-Hub-Signature"), body)
+	case GitLab:
+		token := header.Get("X-Gitlab-Token")
+		if token == "" {
+			return ErrMissingSignature
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnsupportedKind, kind)
+}
+
+func verifyHMAC(secret string, signature string, body []byte) error {
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ParseTagEvents returns the tags created by a push event. Events which are
+// not push events are reported with ErrUnsupportedEvent, while push events
+// without created tags (e.g. branch pushes or tag deletions) have no tags.
+func ParseTagEvents(kind Kind, header http.Header, body []byte) ([]TagEvent, error) {
+	switch kind {
+	case GitHub:
+		if event := Event(kind, header); event != "push" {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedEvent, event)
+		}
+
+		return parseGitHub(body)
+	case GitLab:
+		if event := Event(kind, header); event != "Tag Push Hook" {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedEvent, event)
+		}
+
+		return parseGitLab(body)
+	case Bitbucket:
+		if event := Event(kind, header); event != "repo:push" {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedEvent, event)
+		}
+
+		return parseBitbucket(body)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedKind, kind)
+}
+
+// Docs: https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type gitHubPushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func parseGitHub(body []byte) ([]TagEvent, error) {
+	var e gitHubPushEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	tag, ok := strings.CutPrefix(e.Ref, tagRefPrefix)
+	if !ok || e.Deleted {
+		return nil, nil
+	}
+
+	return []TagEvent{{
+		Repository: e.Repository.FullName,
+		Tag:        tag,
+		Commit:     e.After,
+	}}, nil
+}
+
+// Docs: https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#tag-events
+type gitLabTagPushEvent struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+}
+
+func parseGitLab(body []byte) ([]TagEvent, error) {
+	var e gitLabTagPushEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	tag, ok := strings.CutPrefix(e.Ref, tagRefPrefix)
+	if !ok || e.After == zeroCommit {
+		return nil, nil
+	}
+
+	return []TagEvent{{
+		Repository: e.Project.PathWithNamespace,
+		Tag:        tag,
+		Commit:     e.After,
+	}}, nil
+}
+
+// Docs: https://support.atlassian.com/bitbucket-cloud/docs/event-payloads/#Push
+type bitbucketPushEvent struct {
+	Push struct {
+		Changes []struct {
+			New *struct {
+				Type   string `json:"type"`
+				Name   string `json:"name"`
+				Target struct {
+					Hash string `json:"hash"`
+				} `json:"target"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func parseBitbucket(body []byte) ([]TagEvent, error) {
+	var e bitbucketPushEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	var tags []TagEvent
+	for _, change := range e.Push.Changes {
+		// Deleted references have no new state
+		if change.New == nil || change.New.Type != "tag" {
+			continue
+		}
+
+		tags = append(tags, TagEvent{
+			Repository: e.Repository.FullName,
+			Tag:        change.New.Name,
+			Commit:     change.New.Target.Hash,
+		})
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,236 @@
+package vcs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"ref":"refs/tags/v1.0.0"}`)
+	secret := "s3cr3t"
+
+	tests := []struct {
+		name   string
+		kind   Kind
+		header http.Header
+		err    error
+	}{
+		{
+			name:   "GitHub valid signature",
+			kind:   GitHub,
+			header: http.Header{"X-Hub-Signature-256": {sign(secret, body)}},
+		},
+		{
+			name:   "GitHub signature with another secret",
+			kind:   GitHub,
+			header: http.Header{"X-Hub-Signature-256": {sign("other", body)}},
+			err:    ErrInvalidSignature,
+		},
+		{
+			name:   "GitHub malformed signature",
+			kind:   GitHub,
+			header: http.Header{"X-Hub-Signature-256": {"sha1=abcd"}},
+			err:    ErrInvalidSignature,
+		},
+		{
+			name:   "GitHub missing signature",
+			kind:   GitHub,
+			header: http.Header{},
+			err:    ErrMissingSignature,
+		},
+		{
+			name:   "Bitbucket valid signature",
+			kind:   Bitbucket,
+			header: http.Header{"X-Hub-Signature": {sign(secret, body)}},
+		},
+		{
+			name:   "Bitbucket signature sent in the GitHub header",
+			kind:   Bitbucket,
+			header: http.Header{"X-Hub-Signature-256": {sign(secret, body)}},
+			err:    ErrMissingSignature,
+		},
+		{
+			name:   "GitLab valid token",
+			kind:   GitLab,
+			header: http.Header{"X-Gitlab-Token": {secret}},
+		},
+		{
+			name:   "GitLab invalid token",
+			kind:   GitLab,
+			header: http.Header{"X-Gitlab-Token": {"other"}},
+			err:    ErrInvalidSignature,
+		},
+		{
+			name:   "Unknown kind",
+			kind:   Kind("gitea"),
+			header: http.Header{},
+			err:    ErrUnsupportedKind,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.kind, secret, tt.header, body)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseTagEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   Kind
+		header http.Header
+		body   string
+		tags   []TagEvent
+		err    error
+	}{
+		{
+			name:   "GitHub tag push",
+			kind:   GitHub,
+			header: http.Header{"X-Github-Event": {"push"}},
+			body: `{
+				"ref": "refs/tags/v1.2.0",
+				"after": "abc123",
+				"deleted": false,
+				"repository": {
+					"full_name": "acme/terraform-aws-vpc",
+					"clone_url": "https://github.com/acme/terraform-aws-vpc.git"
+				}
+			}`,
+			tags: []TagEvent{{
+				Repository: "acme/terraform-aws-vpc",
+				Tag:        "v1.2.0",
+				Commit:     "abc123",
+			}},
+		},
+		{
+			name:   "GitHub branch push",
+			kind:   GitHub,
+			header: http.Header{"X-Github-Event": {"push"}},
+			body:   `{"ref": "refs/heads/main", "after": "abc123"}`,
+		},
+		{
+			name:   "GitHub tag deletion",
+			kind:   GitHub,
+			header: http.Header{"X-Github-Event": {"push"}},
+			body:   `{"ref": "refs/tags/v1.2.0", "deleted": true}`,
+		},
+		{
+			name:   "GitHub ping",
+			kind:   GitHub,
+			header: http.Header{"X-Github-Event": {"ping"}},
+			body:   `{"zen": "Keep it logically awesome."}`,
+			err:    ErrUnsupportedEvent,
+		},
+		{
+			name:   "GitHub invalid payload",
+			kind:   GitHub,
+			header: http.Header{"X-Github-Event": {"push"}},
+			body:   `{`,
+			err:    ErrInvalidPayload,
+		},
+		{
+			name:   "GitLab tag push",
+			kind:   GitLab,
+			header: http.Header{"X-Gitlab-Event": {"Tag Push Hook"}},
+			body: `{
+				"object_kind": "tag_push",
+				"ref": "refs/tags/2.0.0",
+				"after": "def456",
+				"project": {
+					"path_with_namespace": "acme/vpc",
+					"git_http_url": "https://gitlab.com/acme/vpc.git"
+				}
+			}`,
+			tags: []TagEvent{{
+				Repository: "acme/vpc",
+				Tag:        "2.0.0",
+				Commit:     "def456",
+			}},
+		},
+		{
+			name:   "GitLab tag deletion",
+			kind:   GitLab,
+			header: http.Header{"X-Gitlab-Event": {"Tag Push Hook"}},
+			body:   `{"ref": "refs/tags/2.0.0", "after": "0000000000000000000000000000000000000000"}`,
+		},
+		{
+			name:   "GitLab push",
+			kind:   GitLab,
+			header: http.Header{"X-Gitlab-Event": {"Push Hook"}},
+			body:   `{}`,
+			err:    ErrUnsupportedEvent,
+		},
+		{
+			name:   "Bitbucket tag and branch push",
+			kind:   Bitbucket,
+			header: http.Header{"X-Event-Key": {"repo:push"}},
+			body: `{
+				"push": {
+					"changes": [
+						{"new": {"type": "branch", "name": "main", "target": {"hash": "aaa"}}},
+						{"new": {"type": "tag", "name": "v0.3.1", "target": {"hash": "bbb"}}},
+						{"new": null}
+					]
+				},
+				"repository": {
+					"full_name": "acme/vpc",
+					"links": {"html": {"href": "https://bitbucket.org/acme/vpc"}}
+				}
+			}`,
+			tags: []TagEvent{{
+				Repository: "acme/vpc",
+				Tag:        "v0.3.1",
+				Commit:     "bbb",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := ParseTagEvents(tt.kind, tt.header, []byte(tt.body))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Fatalf("expected tags %+v, got %+v", tt.tags, tags)
+			}
+		})
+	}
+}
+
+func TestCloneURL(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		want string
+	}{
+		{GitHub, "https://github.com/acme/vpc.git"},
+		{GitLab, "https://gitlab.com/acme/vpc.git"},
+		{Bitbucket, "https://bitbucket.org/acme/vpc.git"},
+		{Kind("gitea"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			if got := tt.kind.CloneURL("acme/vpc"); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,37 +1,128 @@
 package version
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
-// Compare compares two versions and returns:
+// Compare compares two versions according to the semantic versioning
+// precedence rules (https://semver.org/#spec-item-11) and returns:
 //
 //   - -1  if lhs < rhs
 //   - 0   if lhs = rhs
 //   - 1   if lhs > rhs
+//
+// Build metadata is ignored when determining precedence. Invalid versions
+// are considered lower than any valid version and are compared as strings
+// between themselves.
 func Compare(lhs Version, rhs Version) int {
-	major := strings.Compare(lhs.Major(), rhs.Major())
-	if major != 0 {
-		return major
+	lhsValid, rhsValid := lhs.Valid(), rhs.Valid()
+	switch {
+	case !lhsValid && !rhsValid:
+		return strings.Compare(string(lhs), string(rhs))
+	case !lhsValid:
+		return -1
+	case !rhsValid:
+		return 1
 	}
 
-	minor := strings.Compare(lhs.Minor(), rhs.Minor())
-	if minor != 0 {
-		return minor
+	lhsParts, rhsParts := lhs.parts(), rhs.parts()
+
+	// Major, minor and patch
+	for i := range 3 {
+		if c := compareNumeric(lhsParts[i], rhsParts[i]); c != 0 {
+			return c
+		}
 	}
 
-	patch := strings.Compare(lhs.Patch(), rhs.Patch())
-	if patch != 0 {
-		return patch
+	return comparePreRelease(lhsParts[3], rhsParts[3])
+}
+
+// Sort sorts a list of items in ascending order of the precedence of their
+// versions. The sort is stable, so items with versions of equal precedence
+// keep their original order.
+func Sort[T any](items []T, v func(T) Version) {
+	slices.SortStableFunc(items, func(lhs, rhs T) int {
+		return Compare(v(lhs), v(rhs))
+	})
+}
+
+// comparePreRelease compares two pre-release strings. A version without a
+// pre-release has a higher precedence than one with a pre-release.
+func comparePreRelease(lhs, rhs string) int {
+	switch {
+	case lhs == rhs:
+		return 0
+	case lhs == "":
+		return 1
+	case rhs == "":
+		return -1
 	}
 
-	preRelease := strings.Compare(lhs.PreRelease(), rhs.PreRelease())
-	if preRelease != 0 {
-		return preRelease
+	lhsIdentifiers := strings.Split(lhs, ".")
+	rhsIdentifiers := strings.Split(rhs, ".")
+
+	for i := range min(len(lhsIdentifiers), len(rhsIdentifiers)) {
+		if c := compareIdentifier(lhsIdentifiers[i], rhsIdentifiers[i]); c != 0 {
+			return c
+		}
 	}
 
-	buildMetadata := strings.Compare(lhs.BuildMetadata(), rhs.BuildMetadata())
-	if buildMetadata != 0 {
-		return buildMetadata
+	// A larger set of pre-release fields has a higher precedence, if all of
+	// the preceding identifiers are equal.
+	switch {
+	case len(lhsIdentifiers) < len(rhsIdentifiers):
+		return -1
+	case len(lhsIdentifiers) > len(rhsIdentifiers):
+		return 1
 	}
 
 	return 0
 }
+
+// compareIdentifier compares two pre-release identifiers. Numeric identifiers
+// are compared numerically and always have a lower precedence than
+// alphanumeric identifiers, which are compared lexically in ASCII sort order.
+func compareIdentifier(lhs, rhs string) int {
+	lhsNumeric, rhsNumeric := isNumeric(lhs), isNumeric(rhs)
+
+	switch {
+	case lhsNumeric && rhsNumeric:
+		return compareNumeric(lhs, rhs)
+	case lhsNumeric:
+		return -1
+	case rhsNumeric:
+		return 1
+	}
+
+	return strings.Compare(lhs, rhs)
+}
+
+// compareNumeric compares two numeric strings without leading zeroes.
+// Numbers are not parsed, since semantic versions do not impose a limit
+// on their size.
+func compareNumeric(lhs, rhs string) int {
+	if len(lhs) != len(rhs) {
+		if len(lhs) < len(rhs) {
+			return -1
+		}
+
+		return 1
+	}
+
+	return strings.Compare(lhs, rhs)
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
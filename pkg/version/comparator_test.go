@@ -22,8 +22,34 @@ var (
 		{"2.0.1", "2.0.0", 1},
 		{"2.0.0-alpha", "2.0.0-beta", -1},
 		{"2.0.0-beta", "2.0.0-alpha", 1},
-		{"2.0.0-pre+alpha", "2.0.0-pre+beta", -1},
-		{"2.0.0-pre+beta", "2.0.0-pre+alpha", 1},
+		{"2.0.0-pre+alpha", "2.0.0-pre+beta", 0},
+		{"2.0.0-pre+beta", "2.0.0-pre+alpha", 0},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.9.0", "1.10.0", -1},
+		{"10.0.0", "9.0.0", 1},
+		{"1.0.10", "1.0.2", 1},
+		{"99999999999999999999999.0.0", "9999999999999999999999.0.0", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha", 1},
+		{"invalid", "1.0.0", -1},
+		{"1.0.0", "invalid", 1},
+	}
+
+	// https://semver.org/#spec-item-11
+	precedenceTests = []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
 	}
 )
 
@@ -37,3 +63,30 @@ func TestCompare(t *testing.T) {
 		}
 	}
 }
+
+func TestCompare_Precedence(t *testing.T) {
+	for i := 0; i < len(precedenceTests)-1; i++ {
+		lhs, rhs := Version(precedenceTests[i]), Version(precedenceTests[i+1])
+
+		if got := Compare(lhs, rhs); got != -1 {
+			t.Errorf("Comparing %v with %v: got %v, expecting -1.", lhs, rhs, got)
+		}
+
+		if got := Compare(rhs, lhs); got != 1 {
+			t.Errorf("Comparing %v with %v: got %v, expecting 1.", rhs, lhs, got)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	versions := []Version{"1.10.0", "1.0.0", "1.0.0-rc.1", "1.9.0", "1.2.0"}
+
+	Sort(versions, func(v Version) Version { return v })
+
+	want := []Version{"1.0.0-rc.1", "1.0.0", "1.2.0", "1.9.0", "1.10.0"}
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Fatalf("Sort: got %v, expecting %v.", versions, want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyConstraint   = errors.New("empty version constraint")
+	ErrInvalidConstraint = errors.New("invalid version constraint")
+
+	constraintRegEx = regexp.MustCompile(`^\s*(=|!=|>=|<=|>|<|~>)?\s*(\d+(?:\.\d+){0,2})(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?\s*$`)
+)
+
+type operator string
+
+const (
+	opEqual          operator = "="
+	opNotEqual       operator = "!="
+	opGreater        operator = ">"
+	opGreaterOrEqual operator = ">="
+	opLess           operator = "<"
+	opLessOrEqual    operator = "<="
+	opPessimistic    operator = "~>"
+)
+
+// constraint is a single version constraint (e.g. >= 1.2).
+type constraint struct {
+	op      operator
+	version Version
+	// segments is the number of version segments specified in the
+	// constraint (e.g. 2 for ~> 1.2)
+	segments int
+	raw      string
+}
+
+// Constraints is a set of version constraints, as written in Terraform
+// configurations (e.g. ">= 1.2.0, < 2.0.0"). A version satisfies the set
+// only if it satisfies every constraint in it.
+type Constraints []constraint
+
+// ParseConstraints parses a comma separated list of Terraform version
+// constraints. The supported operators are =, !=, >, >=, <, <= and ~>. A
+// constraint without an operator is an exact match. Versions in constraints
+// may omit the minor and patch segments, which default to zero. As in
+// Terraform, pre-release versions only satisfy exact constraints.
+func ParseConstraints(s string) (Constraints, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, ErrEmptyConstraint
+	}
+
+	var out Constraints
+	for _, raw := range strings.Split(s, ",") {
+		c, err := parseConstraint(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, c)
+	}
+
+	return out, nil
+}
+
+func parseConstraint(raw string) (constraint, error) {
+	matches := constraintRegEx.FindStringSubmatch(raw)
+	if matches == nil {
+		return constraint{}, fmt.Errorf("%w: %q", ErrInvalidConstraint, strings.TrimSpace(raw))
+	}
+
+	op := operator(matches[1])
+	if op == "" {
+		op = opEqual
+	}
+
+	segments := strings.Split(matches[2], ".")
+	count := len(segments)
+	for len(segments) < 3 {
+		segments = append(segments, "0")
+	}
+
+	v := Version(strings.Join(segments, ".") + matches[3] + matches[4])
+	if !v.Valid() {
+		return constraint{}, fmt.Errorf("%w: %q", ErrInvalidConstraint, strings.TrimSpace(raw))
+	}
+
+	return constraint{
+		op:       op,
+		version:  v,
+		segments: count,
+		raw:      fmt.Sprintf("%s %s%s%s", op, matches[2], matches[3], matches[4]),
+	}, nil
+}
+
+// Check returns true if the given version satisfies all the constraints.
+// Invalid versions never satisfy a set of constraints.
+func (cs Constraints) Check(v Version) bool {
+	if !v.Valid() {
+		return false
+	}
+
+	for _, c := range cs {
+		if !c.check(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the normalized representation of the constraints.
+func (cs Constraints) String() string {
+	raw := make([]string, 0, len(cs))
+	for _, c := range cs {
+		raw = append(raw, c.raw)
+	}
+
+	return strings.Join(raw, ", ")
+}
+
+// Includes returns true if every version satisfying the other constraints
+// also satisfies these ones. The versions satisfying a set of constraints
+// only change at the versions written in them, at the versions right after
+// and at the next minor and major versions, for the pessimistic
+// constraints. Checking the versions at and right before each of them is
+// therefore enough.
+func (cs Constraints) Includes(other Constraints) bool {
+	candidates := []Version{"0.0.0"}
+
+	for _, c := range append(slices.Clone(cs), other...) {
+		bounds, ok := c.bounds()
+		if !ok {
+			// The versions too large to be bumped cannot be compared
+			return false
+		}
+
+		candidates = append(candidates, bounds...)
+	}
+
+	for _, v := range candidates {
+		if other.Check(v) && !cs.Check(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bounds returns the versions at and right before the ones where the
+// constraint may start or stop being satisfied. The version right before
+// the next minor or major one uses the largest segments compared.
+func (c constraint) bounds() ([]Version, bool) {
+	parts := c.version.parts()
+
+	var segments [3]uint64
+	for i := range segments {
+		n, err := strconv.ParseUint(parts[i], 10, 32)
+		if err != nil {
+			return nil, false
+		}
+
+		segments[i] = n
+	}
+
+	major, minor, patch := segments[0], segments[1], segments[2]
+	last := uint64(math.MaxUint32)
+
+	bounds := []Version{
+		c.version,
+		newVersion(major, minor, patch),
+		newVersion(major, minor, patch+1),
+		newVersion(major, minor, last),
+		newVersion(major, minor+1, 0),
+		newVersion(major, last, last),
+		newVersion(major+1, 0, 0),
+	}
+
+	switch {
+	case patch > 0:
+		bounds = append(bounds, newVersion(major, minor, patch-1))
+	case minor > 0:
+		bounds = append(bounds, newVersion(major, minor-1, last))
+	case major > 0:
+		bounds = append(bounds, newVersion(major-1, last, last))
+	}
+
+	return bounds, true
+}
+
+func newVersion(major, minor, patch uint64) Version {
+	return Version(fmt.Sprintf("%d.%d.%d", major, minor, patch))
+}
+
+func (c constraint) check(v Version) bool {
+	// As in Terraform, pre-release versions can only be selected by an
+	// exact constraint
+	if v.PreRelease() != "" && c.op != opEqual {
+		return false
+	}
+
+	cmp := Compare(v, c.version)
+
+	switch c.op {
+	case opEqual:
+		return cmp == 0
+	case opNotEqual:
+		return cmp != 0
+	case opGreater:
+		return cmp > 0
+	case opGreaterOrEqual:
+		return cmp >= 0
+	case opLess:
+		return cmp < 0
+	case opLessOrEqual:
+		return cmp <= 0
+	case opPessimistic:
+		// A pessimistic constraint with a pre-release only matches
+		// pre-releases, which are excluded above
+		if c.version.PreRelease() != "" {
+			return false
+		}
+
+		if cmp < 0 {
+			return false
+		}
+
+		// All segments but the last one specified must match
+		vParts, cParts := v.parts(), c.version.parts()
+		for i := 0; i < c.segments-1; i++ {
+			if compareNumeric(vParts[i], cParts[i]) != 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
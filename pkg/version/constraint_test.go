@@ -0,0 +1,142 @@
+package version
+
+import (
+	"errors"
+	"testing"
+)
+
+type constraintTestData struct {
+	constraint string
+	version    string
+	expect     bool
+}
+
+var (
+	constraintTests = []constraintTestData{
+		{"1.0.0", "1.0.0", true},
+		{"1.0.0", "1.0.1", false},
+		{"= 1.0", "1.0.0", true},
+		{"!= 1.0.0", "1.0.1", true},
+		{"!= 1.0.0", "1.0.0", false},
+		{"> 1.0.0", "1.0.1", true},
+		{"> 1.0.0", "1.0.0", false},
+		{">= 1.9.0", "1.10.0", true},
+		{">= 1.0.0", "1.0.0", true},
+		{"< 2", "1.99.0", true},
+		{"< 2", "2.0.0", false},
+		{"<= 2.0.0", "2.0.0", true},
+		{">= 1.2.0, < 2.0.0", "1.5.3", true},
+		{">= 1.2.0, < 2.0.0", "2.0.0", false},
+		{">= 1.2.0, != 1.3.0", "1.3.0", false},
+		{"~> 1.2", "1.2.0", true},
+		{"~> 1.2", "1.10.0", true},
+		{"~> 1.2", "2.0.0", false},
+		{"~> 1.2", "1.1.0", false},
+		{"~> 1.2.3", "1.2.10", true},
+		{"~> 1.2.3", "1.3.0", false},
+		{"~> 1", "3.0.0", true},
+		{"1.0.0+build", "1.0.0", true},
+
+		// Pre-releases can only be selected by an exact constraint
+		{">= 1.0.0", "2.0.0-beta", false},
+		{"~> 1.0", "1.1.0-rc.1", false},
+		{"!= 1.0.0", "1.1.0-beta", false},
+		{"1.0.0-beta", "1.0.0-beta", true},
+		{"= 1.0.0-beta", "1.0.0-beta", true},
+		{"1.0.0-beta", "1.0.0-rc.1", false},
+		{">= 1.0.0-beta", "1.0.0-rc.1", false},
+		{">= 1.0.0-beta", "1.0.1-rc.1", false},
+		{">= 1.0.0-beta", "1.0.1", true},
+		{"~> 1.0.0-beta", "1.0.0", false},
+		{"~> 1.0.0-beta", "1.0.0-beta.2", false},
+
+		// Invalid versions never match
+		{">= 0.0.0", "latest", false},
+	}
+
+	invalidConstraints = []string{
+		"",
+		"   ",
+		"1.0.0,",
+		"=> 1.0.0",
+		"~ 1.0",
+		"v1.0.0",
+		"1.0.0.0",
+		"01.0.0",
+		"latest",
+	}
+)
+
+func TestConstraints_Check(t *testing.T) {
+	for _, test := range constraintTests {
+		c, err := ParseConstraints(test.constraint)
+		if err != nil {
+			t.Errorf("Parsing %q: unexpected error %v.", test.constraint, err)
+			continue
+		}
+
+		if got := c.Check(Version(test.version)); got != test.expect {
+			t.Errorf("Checking %v against %q: got %v, expecting %v.", test.version, test.constraint, got, test.expect)
+		}
+	}
+}
+
+func TestParseConstraints_Invalid(t *testing.T) {
+	for _, raw := range invalidConstraints {
+		_, err := ParseConstraints(raw)
+		if !errors.Is(err, ErrInvalidConstraint) && !errors.Is(err, ErrEmptyConstraint) {
+			t.Errorf("Parsing %q: got %v, expecting an invalid constraint error.", raw, err)
+		}
+	}
+}
+
+func TestConstraints_String(t *testing.T) {
+	c, err := ParseConstraints(">=1.2,  < 2.0.0, 2.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := c.String(), ">= 1.2, < 2.0.0, = 2.1"; got != want {
+		t.Errorf("String: got %q, expecting %q.", got, want)
+	}
+}
+
+func TestConstraints_Includes(t *testing.T) {
+	tests := []struct {
+		constraints string
+		other       string
+		expect      bool
+	}{
+		{">= 3.0", ">= 4.0", true},
+		{">= 4.0", ">= 3.0", false},
+		{">= 4.0, < 6.0", "< 6.0.0, >= 4", true},
+		{"~> 4.0", ">= 4.0, < 5.0", true},
+		{">= 4.0, < 5.0", "~> 4.0", true},
+		{"~> 4.2", "~> 4.2.1", true},
+		{"~> 4.2.1", "~> 4.2", false},
+		{"~> 4.0", "~> 5.0", false},
+		{">= 4.0, != 4.5.0", ">= 4.0", false},
+		{">= 4.0", ">= 4.0, != 4.5.0", true},
+		{"> 4.0.0", ">= 4.0.1", true},
+		{">= 4.0.1", "> 4.0.0", true},
+		{"< 2.0", "<= 1.9", true},
+		{"<= 1.9", "< 2.0", false},
+		{">= 1.0.0", "= 1.0.0-beta", false},
+	}
+
+	for _, test := range tests {
+		cs, err := ParseConstraints(test.constraints)
+		if err != nil {
+			t.Fatalf("Parsing %q: unexpected error %v.", test.constraints, err)
+		}
+
+		other, err := ParseConstraints(test.other)
+		if err != nil {
+			t.Fatalf("Parsing %q: unexpected error %v.", test.other, err)
+		}
+
+		if got := cs.Includes(other); got != test.expect {
+			t.Errorf("Checking if %q includes %q: got %v, expecting %v.", test.constraints, test.other, got, test.expect)
+		}
+	}
+}
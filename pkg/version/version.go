@@ -56,6 +56,21 @@ func (v Version) BuildMetadata() string {
 	return *p
 }
 
+// parts returns the major, minor, patch, pre-release and build metadata
+// parts of a valid semantic version.
+func (v Version) parts() [5]string {
+	var out [5]string
+
+	matches := versionRegEx.FindStringSubmatch(string(v))
+	if matches == nil {
+		return out
+	}
+
+	copy(out[:], matches[1:])
+
+	return out
+}
+
 func (v Version) part(id int) *string {
 	matches := versionRegEx.FindAllStringSubmatch(string(v), -1)
 